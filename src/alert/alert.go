@@ -0,0 +1,147 @@
+// Package alert delivers alerts raised by monitoring, such as blocks failing
+// seal verification or nodes disagreeing on the chain, to pluggable sinks:
+// webhooks, JSON lines on a writer and PagerDuty. A Router dispatches every
+// alert to the sinks whose minimum severity it reaches.
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidSeverity = errors.New("invalid severity")
+
+// Severity ranks how urgently an alert needs attention.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Critical
+)
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Critical:
+		return "critical"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the severity as its name.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity from its name.
+func (s *Severity) UnmarshalText(text []byte) error {
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// ParseSeverity returns the severity of the given name, in any case.
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "info":
+		return Info, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "critical", "crit":
+		return Critical, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSeverity, name)
+	}
+}
+
+// Kinds of alerts raised by monitoring.
+const (
+	KindInvalidPoW = "invalid-pow" // A block failed seal verification
+	KindDivergence = "divergence"  // Nodes disagree on the block at a height
+)
+
+// Alert is a condition monitoring reports.
+type Alert struct {
+	Severity Severity               `json:"severity"`
+	Kind     string                 `json:"kind"`
+	Summary  string                 `json:"summary"`
+	Source   string                 `json:"source,omitempty"` // Node or component the condition was observed at
+	Key      string                 `json:"key,omitempty"`    // Identifies repeated reports of the same condition
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// DefaultSeverity returns the severity alerts of a kind are raised with:
+// critical for invalid proof-of-work, warning for diverging nodes and info
+// for anything else.
+func DefaultSeverity(kind string) Severity {
+	switch kind {
+	case KindInvalidPoW:
+		return Critical
+	case KindDivergence:
+		return Warning
+	default:
+		return Info
+	}
+}
+
+// Sink delivers alerts to their destination.
+type Sink interface {
+	Send(ctx context.Context, alert Alert) error
+}
+
+// route is a sink along with the minimum severity of the alerts it receives.
+type route struct {
+	sink Sink
+	min  Severity
+}
+
+// Router dispatches alerts to the sinks routed to their severity. The zero
+// value routes to no sink and is ready to use.
+type Router struct {
+	lock   sync.RWMutex
+	routes []route
+}
+
+// Route adds a sink receiving the alerts of at least the given severity.
+func (r *Router) Route(sink Sink, min Severity) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.routes = append(r.routes, route{sink: sink, min: min})
+}
+
+// Send delivers the alert to every sink routed to its severity, stamping it
+// with the current time if it has none. Delivery continues past failing sinks,
+// whose errors are returned joined.
+func (r *Router) Send(ctx context.Context, alert Alert) error {
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var errs []error
+	for _, route := range r.routes {
+		if alert.Severity < route.min {
+			continue
+		}
+		if err := route.sink.Send(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestTimeout bounds the delivery of an alert over HTTP.
+const requestTimeout = 10 * time.Second
+
+// JSONSink writes alerts as JSON objects, one per line.
+type JSONSink struct {
+	lock sync.Mutex // Keeps lines of concurrent alerts apart
+	enc  *json.Encoder
+}
+
+// NewJSONSink creates a sink writing alerts to w, such as os.Stdout.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Send implements Sink.
+func (s *JSONSink) Send(ctx context.Context, alert Alert) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.enc.Encode(alert)
+}
+
+// Webhook posts alerts as JSON objects to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a sink posting alerts to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Send implements Sink.
+func (w *Webhook) Send(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, w.client, w.url, alert)
+}
+
+// PagerDutyEventsURL is the endpoint of version 2 of the PagerDuty Events API.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers incidents through the PagerDuty Events API, or any
+// service compatible with it. Alerts with the same key are deduplicated into
+// the same incident.
+type PagerDuty struct {
+	url        string
+	routingKey string
+	client     *http.Client
+}
+
+// NewPagerDuty creates a sink triggering events on the integration of the given
+// routing key. An empty url selects PagerDutyEventsURL.
+func NewPagerDuty(url, routingKey string) *PagerDuty {
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+	return &PagerDuty{url: url, routingKey: routingKey, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// pagerDutyEvent is a trigger event of the PagerDuty Events API.
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key,omitempty"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Send implements Sink.
+func (p *PagerDuty) Send(ctx context.Context, alert Alert) error {
+	source := alert.Source
+	if source == "" {
+		source = "quai-verify"
+	}
+	event := pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    alert.Key,
+		Payload: pagerDutyPayload{
+			Summary:       alert.Summary,
+			Source:        source,
+			Severity:      alert.Severity.String(), // The API shares the names of the severities
+			Timestamp:     alert.Time.UTC().Format(time.RFC3339),
+			Class:         alert.Kind,
+			CustomDetails: alert.Details,
+		},
+	}
+	return postJSON(ctx, p.client, p.url, event)
+}
+
+// postJSON posts v encoded as JSON to url, failing on any status but success.
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert delivery to %s: %s", url, resp.Status)
+	}
+	return nil
+}
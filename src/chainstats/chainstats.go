@@ -0,0 +1,178 @@
+// Package chainstats folds verified headers into rolling statistics of the
+// chains they belong to: block times, difficulty trends and the share each
+// zone contributes to prime blocks.
+package chainstats
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// DefaultWindow is the number of recent headers per chain the rolling
+// statistics are computed over by default.
+const DefaultWindow = 1024
+
+// sample is the part of a header retained for the rolling statistics.
+type sample struct {
+	time       uint64
+	difficulty *big.Int
+}
+
+// chain accumulates the statistics of a single chain.
+type chain struct {
+	name        string
+	headers     uint64
+	primeBlocks uint64
+
+	samples []sample // Ring buffer of the most recent headers
+	next    int      // Position of the next sample to overwrite
+}
+
+// Stats aggregates statistics over the headers folded into it. It is safe for
+// concurrent use.
+type Stats struct {
+	window int
+
+	lock        sync.Mutex
+	chains      map[string]*chain
+	headers     uint64
+	primeBlocks uint64
+}
+
+// New creates an aggregator computing rolling statistics over the last window
+// headers of each chain. A non-positive window selects DefaultWindow.
+func New(window int) *Stats {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Stats{
+		window: window,
+		chains: make(map[string]*chain),
+	}
+}
+
+// Add folds a verified header into the statistics, along with its order: the
+// most dominant context it is a block of, as determined from its proof-of-work
+// by progpow.Progpow.CalcOrder. Headers of order common.PRIME_CTX are counted
+// as prime blocks of their chain; a negative order, for headers whose PoW was
+// not checked, counts them as zone blocks. Headers are expected in roughly
+// ascending order per chain.
+func (s *Stats) Add(header *types.Header, order int) {
+	name := chainName(header.Location())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c, ok := s.chains[name]
+	if !ok {
+		c = &chain{name: name, samples: make([]sample, 0, s.window)}
+		s.chains[name] = c
+	}
+	s.headers++
+	c.headers++
+
+	if order == common.PRIME_CTX {
+		s.primeBlocks++
+		c.primeBlocks++
+	}
+	smp := sample{time: header.Time(), difficulty: new(big.Int)}
+	if header.Difficulty() != nil {
+		smp.difficulty.Set(header.Difficulty())
+	}
+	if len(c.samples) < s.window {
+		c.samples = append(c.samples, smp)
+	} else {
+		c.samples[c.next] = smp
+		c.next = (c.next + 1) % s.window
+	}
+}
+
+// Reset drops all accumulated statistics.
+func (s *Stats) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.chains = make(map[string]*chain)
+	s.headers, s.primeBlocks = 0, 0
+}
+
+// ChainSummary are the statistics of a single chain.
+type ChainSummary struct {
+	Chain        string   `json:"chain"`
+	Headers      uint64   `json:"headers"`      // Headers folded in since creation
+	AvgBlockTime float64  `json:"avgBlockTime"` // Seconds between headers over the window
+	Difficulty   *big.Int `json:"difficulty"`   // Difficulty of the latest header
+	// DifficultyTrend is the relative difficulty change across the window,
+	// e.g. 0.05 for a 5% increase.
+	DifficultyTrend float64 `json:"difficultyTrend"`
+	PrimeBlocks     uint64  `json:"primeBlocks"`
+	PrimeShare      float64 `json:"primeShare"` // Fraction of all prime blocks
+}
+
+// Summary are the aggregated statistics of all chains.
+type Summary struct {
+	Headers     uint64         `json:"headers"`
+	PrimeBlocks uint64         `json:"primeBlocks"`
+	Chains      []ChainSummary `json:"chains"` // Sorted by chain name
+}
+
+// Summary computes the current statistics.
+func (s *Stats) Summary() Summary {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	summary := Summary{
+		Headers:     s.headers,
+		PrimeBlocks: s.primeBlocks,
+		Chains:      make([]ChainSummary, 0, len(s.chains)),
+	}
+	for _, c := range s.chains {
+		summary.Chains = append(summary.Chains, c.summary(s.primeBlocks))
+	}
+	sort.Slice(summary.Chains, func(i, j int) bool {
+		return summary.Chains[i].Chain < summary.Chains[j].Chain
+	})
+	return summary
+}
+
+// summary computes the statistics of the chain.
+func (c *chain) summary(primeBlocks uint64) ChainSummary {
+	sum := ChainSummary{
+		Chain:       c.name,
+		Headers:     c.headers,
+		PrimeBlocks: c.primeBlocks,
+	}
+	if primeBlocks > 0 {
+		sum.PrimeShare = float64(c.primeBlocks) / float64(primeBlocks)
+	}
+	if len(c.samples) == 0 {
+		return sum
+	}
+	// The oldest sample sits at the overwrite position once the ring is full
+	first, last := c.samples[0], c.samples[len(c.samples)-1]
+	if len(c.samples) == cap(c.samples) && c.next > 0 {
+		first, last = c.samples[c.next], c.samples[c.next-1]
+	}
+	sum.Difficulty = new(big.Int).Set(last.difficulty)
+	if n := len(c.samples); n > 1 && last.time > first.time {
+		sum.AvgBlockTime = float64(last.time-first.time) / float64(n-1)
+	}
+	if first.difficulty.Sign() > 0 {
+		delta := new(big.Float).SetInt(new(big.Int).Sub(last.difficulty, first.difficulty))
+		sum.DifficultyTrend, _ = delta.Quo(delta, new(big.Float).SetInt(first.difficulty)).Float64()
+	}
+	return sum
+}
+
+// chainName returns the name of the chain at loc, without tripping the fatal
+// assertions of common.Location on malformed locations.
+func chainName(loc common.Location) string {
+	if !loc.Valid() {
+		return "invalid-location"
+	}
+	return loc.Name()
+}
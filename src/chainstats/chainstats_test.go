@@ -0,0 +1,57 @@
+package chainstats_test
+
+import (
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/chainstats"
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow/progpowtest"
+)
+
+// Prime blocks are counted by the order of their PoW, not by which zone first
+// reports a new prime number.
+func TestPrimeBlocksByOrder(t *testing.T) {
+	devnet, err := progpowtest.LoadDevnet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := progpow.New(devnet.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := chainstats.New(0)
+	want := make(map[string]uint64)
+	for _, block := range devnet.Blocks {
+		_, order, err := engine.CalcOrder(block.Header)
+		if err != nil {
+			t.Fatalf("block %x: %v", block.Header.Hash(), err)
+		}
+		if order == common.PRIME_CTX {
+			want[block.Header.Location().Name()]++
+		}
+		stats.Add(block.Header, order)
+	}
+	summary := stats.Summary()
+	var total uint64
+	for _, chain := range summary.Chains {
+		if chain.PrimeBlocks != want[chain.Chain] {
+			t.Errorf("%s: %d prime blocks, want %d", chain.Chain, chain.PrimeBlocks, want[chain.Chain])
+		}
+		total += want[chain.Chain]
+	}
+	if total == 0 {
+		t.Fatal("devnet has no prime blocks")
+	}
+	if summary.PrimeBlocks != total {
+		t.Errorf("%d prime blocks in total, want %d", summary.PrimeBlocks, total)
+	}
+	// Headers whose PoW was not checked are no prime blocks
+	stats.Reset()
+	for _, block := range devnet.Blocks {
+		stats.Add(block.Header, -1)
+	}
+	if n := stats.Summary().PrimeBlocks; n != 0 {
+		t.Errorf("%d prime blocks without orders, want 0", n)
+	}
+}
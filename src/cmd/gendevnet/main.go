@@ -0,0 +1,44 @@
+// gendevnet mines the devnet embedded by the progpowtest package: headers at
+// test difficulty in every zone of the default hierarchy, see
+// progpowtest.LoadDevnet. Mining is
+// deterministic, so regenerating it after a change to the header format or
+// the generator only changes what the change affects.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow/progpowtest"
+)
+
+func main() {
+	out := flag.String("out", "", "file to write the devnet to (standard output if empty)")
+	blocks := flag.Int("blocks", progpowtest.DevnetBlocksPerZone, "number of headers to mine in every zone")
+	flag.Parse()
+
+	if err := run(*out, *blocks); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out string, blocks int) error {
+	devnet, err := progpowtest.GenerateDevnet(blocks, progpowtest.DevnetTimeFactor)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return json.NewEncoder(w).Encode(devnet)
+}
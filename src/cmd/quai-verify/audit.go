@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the audit-entropy command.
+var (
+	auditRPC    string
+	auditFrom   uint64
+	auditTo     uint64
+	auditFormat string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit-entropy",
+	Short: "Audit the declared entropy of a block range against recomputation",
+	Long: `Audit-entropy fetches the headers --from through --to from a node's JSON-RPC
+API, verifies their seals and recomputes the intrinsic entropy and order of
+every block. It reports, as CSV or JSON, each block's recomputed values next to
+the ones the chain declares: the order implied by the next block referencing it
+as a dominant parent, and the parent entropy and delta accumulated from the
+previous block. Blocks where any of them disagree are listed with their issues
+and make the command fail after the report is written.`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+func init() {
+	flags := auditCmd.Flags()
+	flags.StringVar(&auditRPC, "rpc", "", "HTTP JSON-RPC endpoint of the node to fetch headers from")
+	flags.Uint64Var(&auditFrom, "from", 0, "number of the first block to audit")
+	flags.Uint64Var(&auditTo, "to", 0, "number of the last block to audit")
+	flags.StringVar(&auditFormat, "format", "csv", "report format, csv or json")
+	auditCmd.MarkFlagRequired("rpc")
+	auditCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(auditCmd)
+}
+
+// auditRecord is the audit result of a single block. Entropies are fixed point
+// numbers as used in the headers; values which cannot be determined are nil.
+type auditRecord struct {
+	Number                uint64      `json:"number"`
+	Hash                  common.Hash `json:"hash"`
+	Valid                 bool        `json:"valid"`
+	Order                 int         `json:"order"`         // Recomputed order, -1 if the seal is invalid
+	DeclaredOrder         int         `json:"declaredOrder"` // Order implied by the next block, -1 if unknown
+	IntrinsicEntropy      *big.Int    `json:"intrinsicEntropy"`
+	TotalEntropy          *big.Int    `json:"totalEntropy"`
+	ParentEntropy         *big.Int    `json:"parentEntropy"`
+	ExpectedParentEntropy *big.Int    `json:"expectedParentEntropy"`
+	ParentDeltaS          *big.Int    `json:"parentDeltaS"`
+	ExpectedParentDeltaS  *big.Int    `json:"expectedParentDeltaS"`
+	Issues                []string    `json:"issues"`
+}
+
+// auditedHeader is a fetched header with its recomputed entropy.
+type auditedHeader struct {
+	header    *types.Header
+	err       error
+	order     int
+	intrinsic *big.Int
+	total     *big.Int
+	delta     *big.Int
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	if auditTo < auditFrom {
+		return fmt.Errorf("--to %d precedes --from %d", auditTo, auditFrom)
+	}
+	if auditFormat != "csv" && auditFormat != "json" {
+		return fmt.Errorf("unknown report format %q", auditFormat)
+	}
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+	// Fetch one block on either side of the range: the previous block declares
+	// the entropy the first one builds on, the next one the order of the last
+	first := auditFrom
+	if first > 0 {
+		first--
+	}
+	var (
+		client  = newRPCClient(auditRPC)
+		headers = make(map[uint64]*auditedHeader)
+	)
+	for number := first; number <= auditTo+1; number++ {
+		header, err := client.headerByNumber(number)
+		if err != nil {
+			if number > auditTo && errors.Is(err, errHeaderNotFound) {
+				break // the range ends at the head of the chain
+			}
+			return err
+		}
+		headers[number] = auditHeader(engine, header)
+	}
+	ctx := headers[auditFrom].header.Location().Ctx()
+
+	var (
+		records    = make([]auditRecord, 0, auditTo-auditFrom+1)
+		mismatches int
+	)
+	for number := auditFrom; number <= auditTo; number++ {
+		// Genesis has no parent, its lookup wraps around to a missing number
+		record := newAuditRecord(ctx, headers[number], headers[number-1], headers[number+1])
+		if len(record.Issues) > 0 {
+			mismatches++
+		}
+		records = append(records, record)
+	}
+	if err := writeAuditReport(cmd.OutOrStdout(), auditFormat, records); err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d blocks disagree with recomputation", mismatches, len(records))
+	}
+	return nil
+}
+
+// auditHeader verifies the seal of a header and recomputes its entropy.
+func auditHeader(engine *progpow.Progpow, header *types.Header) *auditedHeader {
+	audited := &auditedHeader{header: header, order: -1}
+	intrinsic, order, err := engine.CalcOrder(header)
+	if err != nil {
+		audited.err = err
+		return audited
+	}
+	audited.order, audited.intrinsic = order, intrinsic
+	if audited.total, err = engine.TotalLogS(header); err != nil {
+		audited.err = err
+		return audited
+	}
+	if audited.delta, err = engine.DeltaLogS(header); err != nil {
+		audited.err = err
+	}
+	return audited
+}
+
+// newAuditRecord audits a block of the chain of context ctx against its parent
+// and child, either of which may be nil if unknown.
+func newAuditRecord(ctx common.Context, block, parent, child *auditedHeader) auditRecord {
+	header := block.header
+	record := auditRecord{
+		Number:           header.NumberU64In(ctx),
+		Hash:             header.Hash(),
+		Valid:            block.err == nil,
+		Order:            block.order,
+		DeclaredOrder:    -1,
+		IntrinsicEntropy: block.intrinsic,
+		TotalEntropy:     block.total,
+		ParentEntropy:    header.ParentEntropyIn(ctx),
+		ParentDeltaS:     header.ParentDeltaSIn(ctx),
+	}
+	if block.err != nil {
+		record.Issues = append(record.Issues, fmt.Sprintf("seal verification failed: %v", block.err))
+	}
+	// The entropy declared by the block must accumulate on top of its parent's
+	if parent != nil {
+		switch {
+		case header.ParentHashIn(ctx) != parent.header.Hash():
+			record.Issues = append(record.Issues, "parent hash does not match the previous block")
+		case parent.err == nil:
+			record.ExpectedParentEntropy = parent.total
+			record.ExpectedParentDeltaS = parent.delta
+			if parent.order < int(ctx) {
+				// A dominant block starts a new subordinate accumulation
+				record.ExpectedParentDeltaS = new(big.Int)
+			}
+			if record.ParentEntropy.Cmp(record.ExpectedParentEntropy) != 0 {
+				record.Issues = append(record.Issues, "parent entropy disagrees with recomputation")
+			}
+			if record.ParentDeltaS.Cmp(record.ExpectedParentDeltaS) != 0 {
+				record.Issues = append(record.Issues, "parent delta disagrees with recomputation")
+			}
+		}
+	}
+	// The next block references the block as parent in every context of which
+	// it is a block, the most dominant one of those is the declared order
+	if child != nil {
+		hash := header.Hash()
+		if child.header.ParentHashIn(ctx) != hash {
+			record.Issues = append(record.Issues, "next block does not reference the block as parent")
+		} else {
+			for _, order := range common.Contexts[:ctx+1] {
+				if child.header.ParentHashIn(order) == hash {
+					record.DeclaredOrder = int(order)
+					break
+				}
+			}
+			if block.err == nil && record.DeclaredOrder != record.Order {
+				record.Issues = append(record.Issues, fmt.Sprintf("declared order %d disagrees with recomputed order %d", record.DeclaredOrder, record.Order))
+			}
+		}
+	}
+	return record
+}
+
+// writeAuditReport writes the audit records in the given format.
+func writeAuditReport(w io.Writer, format string, records []auditRecord) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"number", "hash", "valid", "order", "declaredOrder",
+		"intrinsicEntropy", "totalEntropy",
+		"parentEntropy", "expectedParentEntropy",
+		"parentDeltaS", "expectedParentDeltaS", "issues",
+	})
+	for _, r := range records {
+		out.Write([]string{
+			strconv.FormatUint(r.Number, 10), r.Hash.Hex(), strconv.FormatBool(r.Valid),
+			strconv.Itoa(r.Order), strconv.Itoa(r.DeclaredOrder),
+			bigString(r.IntrinsicEntropy), bigString(r.TotalEntropy),
+			bigString(r.ParentEntropy), bigString(r.ExpectedParentEntropy),
+			bigString(r.ParentDeltaS), bigString(r.ExpectedParentDeltaS),
+			strings.Join(r.Issues, "; "),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// bigString formats an optional big integer in decimal.
+func bigString(x *big.Int) string {
+	if x == nil {
+		return ""
+	}
+	return x.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the verification engine on this platform",
+}
+
+// Flags of the bench report command.
+var (
+	benchDuration time.Duration
+	benchTags     map[string]string
+)
+
+var benchReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Run the benchmark suite and print a JSON report",
+	Long: `Report runs the standard benchmark suite, timing header hashing and decoding,
+verification cache generation and progpow seal computation and verification,
+and prints a JSON report of the timings per operation. The report records the
+platform it ran on (GOOS, GOARCH, whether it is wasm, the CPU) along with the
+--tag labels, so reports of several platforms, such as those of this command
+and of the bench export of the wasm module, can be aggregated and compared.
+With --test, the tiny test-mode cache is benchmarked instead of the full one.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		report, err := engine.Bench(benchDuration, benchTags)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	},
+}
+
+func init() {
+	benchReportCmd.Flags().DurationVar(&benchDuration, "duration", progpow.DefaultBenchDuration, "minimum time each operation is repeated for")
+	benchReportCmd.Flags().StringToStringVar(&benchTags, "tag", nil, "label recorded in the report, as key=value (repeatable)")
+	benchCmd.AddCommand(benchReportCmd)
+	rootCmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and maintain persisted verification caches",
+}
+
+var cacheVerifyEpoch uint64
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check a cache file in --cachedir against a regenerated cache",
+	Long: `Verify regenerates the verification cache of the given epoch and compares it
+with the file stored in --cachedir, detecting bit-rot or tampering.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		if err := engine.VerifyCacheFile(cacheVerifyEpoch); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "cache for epoch %d is intact\n", cacheVerifyEpoch)
+		return nil
+	},
+}
+
+var cacheWarmFrom, cacheWarmTo uint64
+
+var cacheWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Generate the caches of a block range into --cachedir",
+	Long: `Warm generates the verification caches of every epoch covered by the blocks
+from --from to --to and persists them in --cachedir, so later verification of
+the range loads them instead of stalling on generation. Only the caches of the
+last --caches-on-disk epochs are retained.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cacheDir == "" {
+			return fmt.Errorf("warming caches requires --cachedir")
+		}
+		if cacheWarmFrom > cacheWarmTo {
+			return fmt.Errorf("invalid range: --from %d above --to %d", cacheWarmFrom, cacheWarmTo)
+		}
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		for event := range engine.WarmCaches(cacheWarmFrom, cacheWarmTo) {
+			if event.Err != nil {
+				return fmt.Errorf("epoch %d: %w", event.Epoch, event.Err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "epoch %d ready (%d/%d)\n", event.Epoch, event.Done, event.Total)
+		}
+		return nil
+	},
+}
+
+func init() {
+	cacheVerifyCmd.Flags().Uint64Var(&cacheVerifyEpoch, "epoch", 0, "epoch of the cache file to verify")
+	cacheCmd.AddCommand(cacheVerifyCmd)
+	cacheWarmCmd.Flags().Uint64Var(&cacheWarmFrom, "from", 0, "first block of the range")
+	cacheWarmCmd.Flags().Uint64Var(&cacheWarmTo, "to", 0, "last block of the range")
+	cacheCmd.AddCommand(cacheWarmCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
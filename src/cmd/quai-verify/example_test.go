@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+// sealedHeader is an RLP encoded zone header at difficulty 16, sealed with the
+// test-mode verification cache like the headers of a local devnet.
+const sealedHeader = "0xf901f7f863a00000000000000000000000000000000000000000000000000000000000000011a00000000000000000000000000000000000000000000000000000000000000022a00000000000000000000000000000000000000000000000000000000000000033a00000000000000000000000000000000000000000000000000000000000000077940000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000088a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000f863a00000000000000000000000000000000000000000000000000000000000000044a00000000000000000000000000000000000000000000000000000000000000055a00000000000000000000000000000000000000000000000000000000000000066a0000000000000000000000000000000000000000000000000000000000000000010c58203e8640ac3800507c3010203834c4b40825208843b9aca00820001846553f103846d696e65a046a6983554aaebed3a740d2fd47bd3104e24e6a4467638bebb88f46413ea01c288000000000000000b"
+
+// execute runs quai-verify with args, as given on the command line.
+func execute(args ...string) {
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println("error:", err)
+	}
+}
+
+// This example checks a devnet header, as examples/cli/devnet.sh does against
+// the headers of a running devnet.
+func Example_devnet() {
+	execute("--test", "verify", sealedHeader)
+	execute("sealhash", sealedHeader)
+
+	// Headers sealed with the test-mode cache fail against the full one
+	execute("--test=false", "verify", sealedHeader)
+	// Output:
+	// valid 0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792
+	// 0xfa1a154a963b9d4fcf39a369b7f7e7d5f5f3b8e98bf673a5004cbf914f4806a6
+	// error: seal verification failed: invalid mixHash
+}
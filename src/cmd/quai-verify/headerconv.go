@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+	"github.com/spf13/cobra"
+)
+
+// Encodings of headers handled by the headerconv command.
+const (
+	formatAuto = "auto"
+	formatRLP  = "rlp"  // Hex encoded RLP
+	formatJSON = "json" // JSON-RPC object of go-quai nodes
+	formatBin  = "bin"  // Raw RLP bytes
+)
+
+// Flags of the headerconv command.
+var (
+	convFrom     string
+	convTo       string
+	convUnsealed bool
+)
+
+var headerConvCmd = &cobra.Command{
+	Use:   "headerconv [rlp-hex|json|file|-]",
+	Short: "Convert a header between RLP hex, JSON and binary encodings",
+	Long: `Headerconv decodes a header and prints it in another encoding:
+
+  rlp   hex encoded RLP, as exchanged with go-quai
+  json  the JSON-RPC object served by go-quai nodes
+  bin   the raw RLP bytes, the most compact form
+
+Text encodings are read from the argument or standard input, binary ones from
+the file named by the argument or standard input. With --from auto, the input
+is taken as JSON if it is an object, as RLP hex if it is hex and as binary
+otherwise. Headers failing to decode are reported along with the offending
+field, and decoded headers must pass the structural sanity checks.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHeaderConv,
+}
+
+func init() {
+	headerConvCmd.Flags().StringVar(&convFrom, "from", formatAuto, "encoding of the input (auto, rlp, json or bin)")
+	headerConvCmd.Flags().StringVar(&convTo, "to", formatJSON, "encoding of the output (rlp, json or bin)")
+	headerConvCmd.Flags().BoolVar(&convUnsealed, "unsealed", false, "accept JSON headers lacking the mixHash and nonce")
+	rootCmd.AddCommand(headerConvCmd)
+}
+
+func runHeaderConv(cmd *cobra.Command, args []string) error {
+	switch convTo {
+	case formatRLP, formatJSON, formatBin:
+	default:
+		return fmt.Errorf("unknown output encoding %q", convTo)
+	}
+	input, format, err := readConvInput(args, convFrom)
+	if err != nil {
+		return err
+	}
+	header, err := decodeConvHeader(input, format)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	switch convTo {
+	case formatJSON:
+		enc, err := header.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(enc))
+	case formatRLP:
+		var enc bytes.Buffer
+		if err := rlp.Encode(&enc, header); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, hexutil.Encode(enc.Bytes()))
+	case formatBin:
+		if err := rlp.Encode(out, header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readConvInput reads the header to convert and resolves its encoding if
+// format is auto.
+func readConvInput(args []string, format string) ([]byte, string, error) {
+	var (
+		input []byte
+		err   error
+	)
+	switch {
+	case len(args) > 0 && args[0] != "-" && format == formatBin:
+		input, err = os.ReadFile(args[0])
+	case len(args) > 0 && args[0] != "-":
+		input = []byte(args[0])
+	default:
+		input, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	switch format {
+	case formatRLP, formatJSON:
+		return bytes.TrimSpace(input), format, nil
+	case formatBin:
+		return input, format, nil
+	case formatAuto:
+		text := bytes.TrimSpace(input)
+		if bytes.HasPrefix(text, []byte("{")) {
+			return text, formatJSON, nil
+		}
+		if _, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x")); err == nil && len(text) > 0 {
+			return text, formatRLP, nil
+		}
+		// Arguments which are neither JSON nor hex name a binary file
+		if len(args) > 0 && args[0] != "-" {
+			if input, err = os.ReadFile(args[0]); err != nil {
+				return nil, "", err
+			}
+		}
+		return input, formatBin, nil
+	default:
+		return nil, "", fmt.Errorf("unknown input encoding %q", format)
+	}
+}
+
+// decodeConvHeader decodes a header of the given encoding and checks its
+// structure.
+func decodeConvHeader(input []byte, format string) (*types.Header, error) {
+	var (
+		header *types.Header
+		err    error
+	)
+	switch format {
+	case formatJSON:
+		header = new(types.Header)
+		unmarshal := header.UnmarshalJSON
+		if convUnsealed {
+			unmarshal = header.UnmarshalUnsealedJSON
+		}
+		if err = unmarshal(input); err != nil {
+			return nil, fmt.Errorf("invalid header JSON: %w", err)
+		}
+	case formatRLP:
+		raw, err := hexutil.Decode(withHexPrefix(string(input)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header RLP hex: %w", err)
+		}
+		if header, err = types.DecodeHeaderRLP(raw); err != nil {
+			return nil, fmt.Errorf("invalid header RLP: %w", err)
+		}
+	case formatBin:
+		if header, err = types.DecodeHeaderRLP(input); err != nil {
+			return nil, fmt.Errorf("invalid header RLP: %w", err)
+		}
+	}
+	if err := header.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// withHexPrefix prefixes s with 0x unless it already is.
+func withHexPrefix(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return s
+	}
+	return "0x" + s
+}
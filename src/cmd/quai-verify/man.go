@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var manCmd = &cobra.Command{
+	Use:    "man <dir>",
+	Short:  "Generate man pages for all commands into a directory",
+	Args:   cobra.ExactArgs(1),
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := os.MkdirAll(args[0], 0755); err != nil {
+			return err
+		}
+		header := &doc.GenManHeader{
+			Title:   "QUAI-VERIFY",
+			Section: "1",
+			Source:  "progpow-verification-wasm",
+		}
+		return doc.GenManTree(rootCmd, header, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manCmd)
+}
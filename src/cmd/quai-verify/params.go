@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+var paramsCmd = &cobra.Command{
+	Use:   "params",
+	Short: "Print the consensus parameters of the verification engine as JSON",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(engine.Params())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(paramsCmd)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+	"github.com/spf13/cobra"
+)
+
+var rootCmd = &cobra.Command{
+	Use:               "quai-verify",
+	Short:             "Verify Quai block header proof-of-work seals",
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	PersistentPreRunE: setup,
+}
+
+// Flags shared by all subcommands which need a verification engine.
+var (
+	cacheDir     string
+	cachesInMem  int
+	maxCacheMem  uint64
+	cachesOnDisk int
+	pruneBehind  int
+	verifiedMemo int
+	sealResults  int
+	testMode     bool
+	regions      int
+	zones        int
+)
+
+// Logging flags shared by all subcommands.
+var (
+	logLevel  string
+	logFormat string
+	logOutput string
+)
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&cacheDir, "cachedir", "", "directory to persist verification caches in (disk storage disabled if empty)")
+	flags.IntVar(&cachesInMem, "caches-in-mem", progpow.DefaultCachesInMem, "number of epoch caches to keep in memory")
+	flags.Uint64Var(&maxCacheMem, "max-cache-bytes", 0, "total size the epoch caches kept in memory may take (0 for no limit)")
+	flags.IntVar(&cachesOnDisk, "caches-on-disk", 0, "number of epoch caches to keep on disk (defaults when --cachedir is set)")
+	flags.IntVar(&pruneBehind, "prune-behind", 0, "delete persisted caches more than this many epochs behind the highest verified block, instead of keeping --caches-on-disk (0 disables)")
+	flags.IntVar(&verifiedMemo, "verified-memo", 0, "number of recent seal verification results to remember for dumping (0 disables)")
+	flags.IntVar(&sealResults, "seal-results", 0, "number of recently computed proofs-of-work to reuse when verifying the same seal again (0 disables)")
+	flags.BoolVar(&testMode, "test", false, "use the tiny test-mode verification cache")
+	flags.IntVar(&regions, "regions", common.NumRegionsInPrime, "number of regions of the network headers are verified for")
+	flags.IntVar(&zones, "zones", common.NumZonesInRegion, "number of zones per region of the network headers are verified for")
+	flags.StringVar(&logLevel, "log-level", "info", "lowest level of messages logged (trace, debug, info, warn, error)")
+	flags.StringVar(&logFormat, "log-format", log.FormatText, "format of log lines (text or json)")
+	flags.StringVar(&logOutput, "log-output", log.OutputStderr, "where logs are written (stdout, stderr or a file path)")
+}
+
+// setup applies the persistent flags configuring process wide state.
+func setup(cmd *cobra.Command, args []string) error {
+	if err := common.SetHierarchy(regions, zones); err != nil {
+		return err
+	}
+	return setupLogging(cmd, args)
+}
+
+// setupLogging configures the global logger from the logging flags.
+func setupLogging(cmd *cobra.Command, args []string) error {
+	if err := log.SetLevel(logLevel); err != nil {
+		return err
+	}
+	if err := log.SetFormat(logFormat); err != nil {
+		return err
+	}
+	log.SetOutput(logOutput)
+	return nil
+}
+
+// newEngine creates a progpow engine configured from the persistent flags.
+func newEngine() (*progpow.Progpow, error) {
+	config := progpow.Config{
+		CacheDir:          cacheDir,
+		CachesInMem:       cachesInMem,
+		MaxCacheBytes:     maxCacheMem,
+		CachesOnDisk:      cachesOnDisk,
+		PruneEpochsBehind: pruneBehind,
+		VerifiedMemo:      verifiedMemo,
+		SealResults:       sealResults,
+		Log:               &log.Log,
+	}
+	if testMode {
+		config.PowMode = progpow.ModeTest
+	}
+	return progpow.New(config)
+}
+
+// readInput returns the argument, or standard input if the argument is "-" or
+// absent.
+func readInput(args []string) (string, error) {
+	if len(args) > 0 && args[0] != "-" {
+		return args[0], nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// decodeHeader decodes a hex encoded RLP header, or a header in the JSON-RPC
+// format of the node if the input is a JSON object.
+func decodeHeader(input string) (*types.Header, error) {
+	return decodeHeaderWith(input, (*types.Header).UnmarshalJSON)
+}
+
+// decodeUnsealedHeader decodes a header like decodeHeader, but accepts JSON
+// headers lacking their seal. RLP headers always carry the seal fields, left
+// zero in unsealed ones.
+func decodeUnsealedHeader(input string) (*types.Header, error) {
+	return decodeHeaderWith(input, (*types.Header).UnmarshalUnsealedJSON)
+}
+
+// decodeHeaderWith decodes a hex encoded RLP header, or a JSON header using
+// unmarshal.
+func decodeHeaderWith(input string, unmarshal func(*types.Header, []byte) error) (*types.Header, error) {
+	header, err := parseHeaderWith(input, unmarshal)
+	if err != nil {
+		return nil, err
+	}
+	if err := header.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// parseHeaderWith decodes a header like decodeHeaderWith, without checking
+// that it is well formed.
+func parseHeaderWith(input string, unmarshal func(*types.Header, []byte) error) (*types.Header, error) {
+	header := new(types.Header)
+	if strings.HasPrefix(input, "{") {
+		if err := unmarshal(header, []byte(input)); err != nil {
+			return nil, fmt.Errorf("invalid header JSON: %w", err)
+		}
+	} else {
+		var err error
+		if header, err = types.DecodeHeaderRLP(common.FromHex(input)); err != nil {
+			return nil, fmt.Errorf("invalid header RLP: %w", err)
+		}
+	}
+	return header, nil
+}
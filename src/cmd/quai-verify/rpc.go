@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// errHeaderNotFound is returned by headerByNumber if the node does not know the
+// requested header.
+var errHeaderNotFound = errors.New("header not found")
+
+// rpcClient is a minimal JSON-RPC client of a go-quai node over HTTP.
+type rpcClient struct {
+	url    string
+	client *http.Client
+	id     int
+}
+
+func newRPCClient(url string) *rpcClient {
+	return &rpcClient{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type rpcRequest struct {
+	Version string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// call invokes method with params and returns the raw result.
+func (c *rpcClient) call(method string, params ...interface{}) (json.RawMessage, error) {
+	c.id++
+	body, err := json.Marshal(rpcRequest{Version: "2.0", ID: c.id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	var res rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("%s: invalid response: %w", method, err)
+	}
+	if res.Error != nil {
+		return nil, fmt.Errorf("%s: %s (code %d)", method, res.Error.Message, res.Error.Code)
+	}
+	return res.Result, nil
+}
+
+// headerByNumber fetches the header at the given number of the node's chain.
+func (c *rpcClient) headerByNumber(number uint64) (*types.Header, error) {
+	result, err := c.call("quai_getHeaderByNumber", hexutil.Uint64(number))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return nil, fmt.Errorf("%w: #%d", errHeaderNotFound, number)
+	}
+	header := new(types.Header)
+	if err := json.Unmarshal(result, header); err != nil {
+		return nil, fmt.Errorf("invalid header #%d: %w", number, err)
+	}
+	return header, nil
+}
+
+// blockNumber fetches the number of the head block of the node's chain.
+func (c *rpcClient) blockNumber() (uint64, error) {
+	result, err := c.call("quai_blockNumber")
+	if err != nil {
+		return 0, err
+	}
+	var number hexutil.Uint64
+	if err := json.Unmarshal(result, &number); err != nil {
+		return 0, fmt.Errorf("invalid block number: %w", err)
+	}
+	return uint64(number), nil
+}
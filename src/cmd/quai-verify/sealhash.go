@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var sealHashCmd = &cobra.Command{
+	Use:   "sealhash [rlp-hex|json|-]",
+	Short: "Compute the seal hash a header is mined over",
+	Long: `Sealhash decodes a header, given as argument or on standard input, and prints
+the blake3 hash of its sealed fields, the hash a miner searches a nonce for.
+The header is either hex encoded RLP or a JSON object as returned by the node's
+JSON-RPC API; JSON headers may omit the mixHash and nonce.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, err := readInput(args)
+		if err != nil {
+			return err
+		}
+		header, err := decodeUnsealedHeader(input)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), header.SealHash().Hex())
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sealHashCmd)
+}
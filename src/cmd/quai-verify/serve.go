@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow/server"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the serve command.
+var (
+	serveAddr      string
+	serveCacheSize int
+	serveNetworks  string
+	servePprof     bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve seal verification over HTTP",
+	Long: `Serve runs an HTTP service verifying RLP encoded headers with a shared
+engine. GET /verify?header=<rlp-hex> and POST /verify {"header": "<rlp-hex>"}
+return the verification result as JSON, tagged with an ETag for conditional
+requests. /compute accepts headers the same way and returns the mixHash and
+powHash computed for their nonce, regardless of the mixHash they carry.
+Responses echo the X-Trace-Id header of the request, or a generated trace ID,
+which tags the log lines of the request.
+
+Additional networks can be hosted from the same process with --networks, a
+JSON file mapping network names to engine parameters:
+
+  {
+    "garden":  {"cacheDir": "/var/cache/garden", "cachesInMem": 2},
+    "orchard": {"cacheDir": "/var/cache/orchard", "coldStartBudget": "2s"},
+    "devnet":  {"test": true}
+  }
+
+Each network is then served under /<network>/verify and /<network>/compute.
+GET /params (or /<network>/params) returns the consensus parameters of an
+engine, GET /memory the memory held by its caches and GET /stats statistics of
+the verified headers. With --verified-memo (or "verifiedMemo"), GET /verified
+dumps the most recent seal verification results as newline-delimited JSON.
+Networks forking to other ProgPoW kernel versions list their forks in
+ascending block order, e.g. "forks": [{"block": 1000000, "kernel": 2}].`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		srv := server.New(engine, server.Config{CacheSize: serveCacheSize, Pprof: servePprof})
+		if serveNetworks != "" {
+			if err := addNetworks(srv, serveNetworks); err != nil {
+				return err
+			}
+		}
+		log.Log.Info("Serving seal verification", "addr", serveAddr, "networks", srv.Networks())
+		return http.ListenAndServe(serveAddr, srv)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8580", "address to listen on")
+	serveCmd.Flags().IntVar(&serveCacheSize, "response-cache", server.DefaultCacheSize, "number of verification responses to cache per network (negative disables)")
+	serveCmd.Flags().StringVar(&serveNetworks, "networks", "", "JSON file describing additional networks to serve")
+	serveCmd.Flags().BoolVar(&servePprof, "pprof", false, "expose runtime profiles under /debug/pprof/")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// networkConfig are the engine parameters of a network in the --networks file.
+type networkConfig struct {
+	CacheDir          string `json:"cacheDir"`
+	CachesInMem       int    `json:"cachesInMem"`
+	MaxCacheBytes     uint64 `json:"maxCacheBytes"`
+	CachesOnDisk      int    `json:"cachesOnDisk"`
+	PruneEpochsBehind int    `json:"pruneEpochsBehind"`
+	VerifiedMemo      int    `json:"verifiedMemo"`
+	SealResults       int    `json:"sealResults"`
+	CachesLockMmap    bool   `json:"cachesLockMmap"`
+	Test              bool   `json:"test"`
+	NonBlocking       bool   `json:"nonBlocking"`
+	ColdStartBudget   string `json:"coldStartBudget"`
+
+	Forks []progpow.Fork `json:"forks"` // Kernel versions the network forks to
+}
+
+// addNetworks creates an engine for every network described in the file at
+// path and hosts it on srv.
+func addNetworks(srv *server.Server, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var networks map[string]networkConfig
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return fmt.Errorf("invalid networks file %s: %w", path, err)
+	}
+	for name, nc := range networks {
+		config := progpow.Config{
+			CacheDir:          nc.CacheDir,
+			CachesInMem:       nc.CachesInMem,
+			MaxCacheBytes:     nc.MaxCacheBytes,
+			CachesOnDisk:      nc.CachesOnDisk,
+			PruneEpochsBehind: nc.PruneEpochsBehind,
+			VerifiedMemo:      nc.VerifiedMemo,
+			SealResults:       nc.SealResults,
+			CachesLockMmap:    nc.CachesLockMmap,
+			NonBlocking:       nc.NonBlocking,
+		}
+		if nc.Test {
+			config.PowMode = progpow.ModeTest
+		}
+		if len(nc.Forks) > 0 {
+			config.ChainConfig = &progpow.ChainConfig{Forks: nc.Forks}
+		}
+		if nc.ColdStartBudget != "" {
+			if config.ColdStartBudget, err = time.ParseDuration(nc.ColdStartBudget); err != nil {
+				return fmt.Errorf("network %s: invalid cold start budget: %w", name, err)
+			}
+		}
+		l := log.Log.With("network", name)
+		config.Log = &l
+
+		engine, err := progpow.New(config)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", name, err)
+		}
+		if err := srv.AddNetwork(name, engine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
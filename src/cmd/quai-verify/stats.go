@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/chainstats"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the stats command.
+var (
+	statsWindow   int
+	statsNoVerify bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [file|-]",
+	Short: "Aggregate chain statistics over a list of headers",
+	Long: `Stats reads RLP-hex headers, one per line, from a file or standard input,
+verifies their seals and prints rolling statistics as JSON: average block time,
+difficulty trend and the share of prime blocks per chain. Prime blocks are told
+by the order their proof-of-work qualifies them for. Headers failing
+verification are reported on standard error and left out.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&statsWindow, "window", chainstats.DefaultWindow, "number of recent headers per chain to compute rolling statistics over")
+	statsCmd.Flags().BoolVar(&statsNoVerify, "no-verify", false, "skip seal verification of the headers, counting no prime blocks")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	var in io.Reader = os.Stdin
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+	var (
+		stats   = chainstats.New(statsWindow)
+		scanner = bufio.NewScanner(in)
+	)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" {
+			continue
+		}
+		header, err := decodeHeader(input)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		order := -1
+		if !statsNoVerify {
+			if _, order, err = engine.CalcOrder(header); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "line %d: seal verification failed: %v\n", line, err)
+				continue
+			}
+		}
+		stats.Add(header, order)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats.Summary())
+}
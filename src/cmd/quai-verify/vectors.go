@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vectorEpochs []uint
+	vectorCount  int
+)
+
+var vectorsCmd = &cobra.Command{
+	Use:   "vectors",
+	Short: "Print deterministic progpow test vectors as JSON",
+	Long: `Vectors derives headers from the given epochs and prints them, RLP encoded,
+along with their seal hash, nonce, mixHash and powHash as a JSON array. The
+headers only depend on the epoch and their index, so alternative progpow
+implementations can regenerate and cross-check them against this verifier.
+
+Full epoch caches take a while to generate, use --test for the test-mode cache.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if vectorCount < 1 {
+			return fmt.Errorf("invalid --count %d", vectorCount)
+		}
+		engine, err := newEngine()
+		if err != nil {
+			return err
+		}
+		vectors := []progpow.TestVector{}
+		for _, epoch := range vectorEpochs {
+			epochVectors, err := engine.GenerateTestVectors(uint64(epoch), vectorCount)
+			if err != nil {
+				return err
+			}
+			vectors = append(vectors, epochVectors...)
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(vectors)
+	},
+}
+
+func init() {
+	vectorsCmd.Flags().UintSliceVar(&vectorEpochs, "epoch", []uint{0}, "epochs to generate vectors for (repeatable or comma separated)")
+	vectorsCmd.Flags().IntVar(&vectorCount, "count", 4, "number of vectors per epoch")
+	rootCmd.AddCommand(vectorsCmd)
+}
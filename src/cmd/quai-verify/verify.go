@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [rlp-hex|json|-]",
+	Short: "Verify the proof-of-work seal of a header",
+	Long: `Verify decodes a header, given as argument or on standard input, and checks
+its mixHash and proof-of-work against its difficulty. The header is either hex
+encoded RLP or a JSON object as returned by the node's JSON-RPC API.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	input, err := readInput(args)
+	if err != nil {
+		return err
+	}
+	header, err := decodeHeader(input)
+	if err != nil {
+		return err
+	}
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+	powHash, err := engine.VerifySeal(header)
+	if err != nil {
+		return fmt.Errorf("seal verification failed: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "valid", powHash.Hex())
+	return nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/alert"
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+	"github.com/spf13/cobra"
+)
+
+// Flags of the watch command.
+var (
+	watchRPCs     []string
+	watchState    string
+	watchInterval time.Duration
+	watchFrom     uint64
+
+	alertJSON         bool
+	alertWebhooks     []string
+	alertPagerDuty    string
+	alertPagerDutyURL string
+	alertRoutes       map[string]string
+)
+
+// defaultAlertRoutes are the minimum severities of the alerts each kind of sink
+// receives unless --alert-route says otherwise.
+var defaultAlertRoutes = map[string]alert.Severity{
+	"json":      alert.Info,
+	"webhook":   alert.Warning,
+	"pagerduty": alert.Critical,
+}
+
+// divergenceWindow is the number of blocks per location below the highest
+// one seen that are compared across sources.
+const divergenceWindow = 1024
+
+// verifiedHeaders is the number of valid headers remembered across sources,
+// so a block reported by several of them is verified only once.
+const verifiedHeaders = 1024
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Continuously verify the seals of new blocks of nodes",
+	Long: `Watch polls the JSON-RPC API of one or more nodes, --rpc being repeatable, for
+new blocks and verifies their seals as they arrive, printing a line per block
+until interrupted.
+
+With --state, the number of the last verified block is persisted per source and
+context after every block. On restart, watching resumes right after it,
+backfilling the blocks produced while the command was down, so monitoring has
+no blind spots across restarts. Sources without recorded progress start at
+--from if given, or else at their head block.
+
+Blocks failing verification raise critical alerts, sources reporting different
+blocks at the same height and location warning ones. Alerts are delivered to
+the sinks given: JSON lines on standard output (--alert-json), webhooks
+receiving the same JSON (--alert-webhook) and PagerDuty (--alert-pagerduty).
+By default JSON lines carry all alerts, webhooks warnings and up and PagerDuty
+critical ones; --alert-route changes the minimum severity per sink, as in
+--alert-route pagerduty=warning,webhook=info.`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	flags := watchCmd.Flags()
+	flags.StringSliceVar(&watchRPCs, "rpc", nil, "HTTP JSON-RPC endpoints of the nodes to watch (repeatable or comma separated)")
+	flags.StringVar(&watchState, "state", "", "file to persist the last verified block per source and context in (not persisted if empty)")
+	flags.DurationVar(&watchInterval, "interval", 5*time.Second, "time between polls for new blocks")
+	flags.Uint64Var(&watchFrom, "from", 0, "number of the first block to verify for sources without recorded progress")
+	flags.BoolVar(&alertJSON, "alert-json", false, "write alerts as JSON lines to standard output")
+	flags.StringSliceVar(&alertWebhooks, "alert-webhook", nil, "URLs to post alerts to as JSON (repeatable)")
+	flags.StringVar(&alertPagerDuty, "alert-pagerduty", "", "PagerDuty Events API routing key to trigger incidents with")
+	flags.StringVar(&alertPagerDutyURL, "alert-pagerduty-url", alert.PagerDutyEventsURL, "endpoint of the PagerDuty compatible events API")
+	flags.StringToStringVar(&alertRoutes, "alert-route", nil, "minimum severity (info, warning, critical) per sink (json, webhook, pagerduty)")
+	watchCmd.MarkFlagRequired("rpc")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	if watchInterval <= 0 {
+		return fmt.Errorf("invalid --interval %v", watchInterval)
+	}
+	engine, err := newEngine()
+	if err != nil {
+		return err
+	}
+	progress, err := loadWatchProgress(watchState)
+	if err != nil {
+		return err
+	}
+	alerts, err := newAlertRouter(cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &watcher{
+		out:      cmd.OutOrStdout(),
+		engine:   engine,
+		progress: progress,
+		alerts:   alerts,
+		seen:     make(map[blockKey]sighting),
+		highest:  make(map[string]uint64),
+		verified: types.NewHeaderLRU(verifiedHeaders, 0),
+	}
+	if cmd.Flags().Changed("from") {
+		w.from = &watchFrom
+	}
+	clients := make(map[string]*rpcClient, len(watchRPCs))
+	for _, source := range watchRPCs {
+		clients[source] = newRPCClient(source)
+	}
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, source := range watchRPCs {
+			if err := w.poll(ctx, source, clients[source]); err != nil {
+				if errors.Is(err, errWatchState) {
+					return err
+				}
+				log.Log.Warn("Failed to poll node", "source", source, "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// newAlertRouter routes alerts to the sinks selected by the alert flags, with
+// JSON lines written to out.
+func newAlertRouter(out io.Writer) (*alert.Router, error) {
+	routes := make(map[string]alert.Severity, len(defaultAlertRoutes))
+	for sink, severity := range defaultAlertRoutes {
+		routes[sink] = severity
+	}
+	for sink, name := range alertRoutes {
+		if _, ok := routes[sink]; !ok {
+			return nil, fmt.Errorf("unknown alert sink %q in --alert-route", sink)
+		}
+		severity, err := alert.ParseSeverity(name)
+		if err != nil {
+			return nil, err
+		}
+		routes[sink] = severity
+	}
+	router := new(alert.Router)
+	if alertJSON {
+		router.Route(alert.NewJSONSink(out), routes["json"])
+	}
+	for _, url := range alertWebhooks {
+		router.Route(alert.NewWebhook(url), routes["webhook"])
+	}
+	if alertPagerDuty != "" {
+		router.Route(alert.NewPagerDuty(alertPagerDutyURL, alertPagerDuty), routes["pagerduty"])
+	}
+	return router, nil
+}
+
+// blockKey identifies a block height of a chain across sources.
+type blockKey struct {
+	location string
+	number   uint64
+}
+
+// sighting is the block a source reported at a height.
+type sighting struct {
+	source string
+	hash   common.Hash
+}
+
+// watcher verifies the blocks of the watched sources and raises alerts.
+type watcher struct {
+	out      io.Writer
+	engine   *progpow.Progpow
+	progress *watchProgress
+	alerts   *alert.Router
+	from     *uint64 // First block of sources without progress, nil for their head
+
+	seen     map[blockKey]sighting // Blocks reported per height, compared across sources
+	highest  map[string]uint64     // Highest number seen per location
+	verified *types.HeaderLRU      // Headers found valid, by hash
+}
+
+// poll verifies the blocks of a source from the one after its last verified
+// block, or from w.from or its head block if it has none, up to its head
+// block, recording the progress after every block.
+func (w *watcher) poll(ctx context.Context, source string, client *rpcClient) error {
+	head, err := client.blockNumber()
+	if err != nil {
+		return err
+	}
+	tip, err := client.headerByNumber(head)
+	if err != nil {
+		return err
+	}
+	nodeCtx := tip.Location().Context()
+
+	start := head
+	if last, ok := w.progress.last(source, nodeCtx); ok {
+		start = last + 1
+	} else if w.from != nil {
+		start = *w.from
+	}
+	if start < head {
+		log.Log.Info("Backfilling blocks", "source", source, "context", nodeCtx, "from", start, "to", head)
+	}
+	for number := start; number <= head; number++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		header := tip
+		if number != head {
+			if header, err = client.headerByNumber(number); err != nil {
+				return err
+			}
+		}
+		hash := header.Hash()
+		if known, ok := w.verified.Get(hash); ok {
+			// Reported by another source already, the seal is not checked again
+			powHash, _ := known.PowHash.Load().(common.Hash)
+			fmt.Fprintf(w.out, "%s\t%d\t#%d\t%s\tvalid\t%s\n", source, nodeCtx, number, hash.Hex(), powHash.Hex())
+		} else if powHash, err := w.engine.VerifySeal(header); err != nil {
+			fmt.Fprintf(w.out, "%s\t%d\t#%d\t%s\tinvalid: %v\n", source, nodeCtx, number, hash.Hex(), err)
+			w.raise(ctx, alert.Alert{
+				Severity: alert.DefaultSeverity(alert.KindInvalidPoW),
+				Kind:     alert.KindInvalidPoW,
+				Summary:  fmt.Sprintf("Block #%d %s of %s fails seal verification: %v", number, hash.Hex(), header.Location().Name(), err),
+				Source:   source,
+				Key:      alert.KindInvalidPoW + ":" + hash.Hex(),
+				Details:  map[string]interface{}{"number": number, "hash": hash.Hex(), "location": header.Location().Name(), "error": err.Error()},
+			})
+		} else {
+			w.verified.Add(header)
+			fmt.Fprintf(w.out, "%s\t%d\t#%d\t%s\tvalid\t%s\n", source, nodeCtx, number, hash.Hex(), powHash.Hex())
+		}
+		w.compare(ctx, source, header.Location().Name(), number, hash)
+		if err := w.progress.advance(source, nodeCtx, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compare records the block a source reported at a height of a location and
+// raises an alert if another source reported a different one.
+func (w *watcher) compare(ctx context.Context, source, location string, number uint64, hash common.Hash) {
+	key := blockKey{location: location, number: number}
+	if other, ok := w.seen[key]; ok {
+		if other.hash != hash && other.source != source {
+			w.raise(ctx, alert.Alert{
+				Severity: alert.DefaultSeverity(alert.KindDivergence),
+				Kind:     alert.KindDivergence,
+				Summary:  fmt.Sprintf("Sources disagree on block #%d of %s: %s has %s, %s has %s", number, location, other.source, other.hash.Hex(), source, hash.Hex()),
+				Source:   source,
+				Key:      fmt.Sprintf("%s:%s:%d", alert.KindDivergence, location, number),
+				Details:  map[string]interface{}{"number": number, "location": location, other.source: other.hash.Hex(), source: hash.Hex()},
+			})
+		}
+		return
+	}
+	w.seen[key] = sighting{source: source, hash: hash}
+
+	// Forget heights too far below the highest one to still be compared
+	if number > w.highest[location] {
+		w.highest[location] = number
+		for key := range w.seen {
+			if key.location == location && key.number+divergenceWindow < number {
+				delete(w.seen, key)
+			}
+		}
+	}
+}
+
+// raise delivers an alert, logging delivery failures rather than stopping the
+// watch.
+func (w *watcher) raise(ctx context.Context, a alert.Alert) {
+	if err := w.alerts.Send(ctx, a); err != nil {
+		log.Log.Warn("Failed to deliver alert", "kind", a.Kind, "err", err)
+	}
+}
+
+// errWatchState is returned when the progress of the watch command cannot be
+// loaded or persisted, which stops the command rather than risking a gap.
+var errWatchState = errors.New("watch state")
+
+// watchProgress is the progress of the watch command: the number of the last
+// verified block per source and context, persisted in a JSON file if it has a
+// path.
+type watchProgress struct {
+	path    string
+	Sources map[string]map[int]uint64 `json:"sources"`
+}
+
+// loadWatchProgress reads the progress persisted at path, starting afresh if
+// the file does not exist yet. An empty path keeps the progress in memory.
+func loadWatchProgress(path string) (*watchProgress, error) {
+	progress := &watchProgress{path: path, Sources: make(map[string]map[int]uint64)}
+	if path == "" {
+		return progress, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return progress, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	if err := json.Unmarshal(data, progress); err != nil {
+		return nil, fmt.Errorf("%w: invalid file %s: %v", errWatchState, path, err)
+	}
+	if progress.Sources == nil {
+		progress.Sources = make(map[string]map[int]uint64)
+	}
+	return progress, nil
+}
+
+// last returns the number of the last verified block of a source in a context.
+func (p *watchProgress) last(source string, ctx int) (uint64, bool) {
+	number, ok := p.Sources[source][ctx]
+	return number, ok
+}
+
+// advance records number as the last verified block of a source in a context
+// and persists the progress.
+func (p *watchProgress) advance(source string, ctx int, number uint64) error {
+	if p.Sources[source] == nil {
+		p.Sources[source] = make(map[int]uint64)
+	}
+	p.Sources[source][ctx] = number
+	return p.save()
+}
+
+// save writes the progress to a temporary file renamed into place, so a crash
+// never leaves a truncated file behind.
+func (p *watchProgress) save() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p.path), filepath.Base(p.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	if err := os.Rename(f.Name(), p.path); err != nil {
+		return fmt.Errorf("%w: %v", errWatchState, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// wirespec prints the field order of the RLP wire format of Quai headers,
+// blocks, termini and transactions as JSON, for implementers of the encoding
+// in other languages. The specification is derived from the Go types, so
+// regenerating it after a change to the encoding keeps it in step.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+func main() {
+	out := flag.String("out", "", "file to write the specification to (standard output if empty)")
+	flag.Parse()
+
+	if err := run(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out string) error {
+	w := os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(types.WireSpec())
+}
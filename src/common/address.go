@@ -60,7 +60,8 @@ func (a Address) Equal(b Address) bool {
 // BytesToAddress returns Address with value b.
 // If b is larger than len(h), b will be cropped from the left.
 func BytesToAddress(b []byte) Address {
-	if IsInChainScope(b) {
+	// Addresses which cannot be scoped to the node are taken as external
+	if inScope, err := IsInChainScope(b); inScope && err == nil {
 		var i InternalAddress
 		i.setBytes(b)
 		return Address{&i}
@@ -0,0 +1,44 @@
+package common
+
+import "strconv"
+
+// Context is the level of a chain in the hierarchy, indexing the per-context
+// fields of headers. Unlike the untyped PRIME_CTX, REGION_CTX and ZONE_CTX
+// constants, a plain int does not pass for a Context without an explicit
+// conversion, so accessors taking one cannot be handed some unrelated index
+// by accident.
+type Context int
+
+const (
+	PrimeCtx  Context = PRIME_CTX
+	RegionCtx Context = REGION_CTX
+	ZoneCtx   Context = ZONE_CTX
+)
+
+// Contexts lists the contexts from prime down to zone, in the order of the
+// entries of per-context header fields.
+var Contexts = [HierarchyDepth]Context{PrimeCtx, RegionCtx, ZoneCtx}
+
+// Valid reports whether the context is one of the hierarchy.
+func (c Context) Valid() bool {
+	return c >= PrimeCtx && c < HierarchyDepth
+}
+
+// String implements fmt.Stringer.
+func (c Context) String() string {
+	switch c {
+	case PrimeCtx:
+		return "prime"
+	case RegionCtx:
+		return "region"
+	case ZoneCtx:
+		return "zone"
+	default:
+		return "context" + strconv.Itoa(int(c))
+	}
+}
+
+// Ctx returns the context of the chain the location addresses, like Context.
+func (loc Location) Ctx() Context {
+	return Context(loc.Context())
+}
@@ -18,6 +18,8 @@ package crypto
 
 import (
 	"hash"
+
+	"golang.org/x/crypto/sha3"
 )
 
 // KeccakState wraps sha3.state. In addition to the usual hash methods, it also supports
@@ -27,3 +29,19 @@ type KeccakState interface {
 	hash.Hash
 	Read([]byte) (int, error)
 }
+
+// NewKeccakState creates a new KeccakState
+func NewKeccakState() KeccakState {
+	return sha3.NewLegacyKeccak256().(KeccakState)
+}
+
+// Keccak256 calculates and returns the Keccak256 hash of the input data.
+func Keccak256(data ...[]byte) []byte {
+	b := make([]byte, 32)
+	d := NewKeccakState()
+	for _, b := range data {
+		d.Write(b)
+	}
+	d.Read(b)
+	return b
+}
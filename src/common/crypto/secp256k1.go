@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Public key recovery on the secp256k1 curve is left to the pure Go
+// implementation of dcrd, which builds for every target, WASM included,
+// without cgo, like the nocgo path of go-ethereum.
+
+// SignatureLength is the length of a [R || S || V] signature with V being the
+// recovery id 0 or 1.
+const SignatureLength = 64 + 1
+
+var (
+	secp256k1N     = secp256k1.S256().N
+	secp256k1halfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+var (
+	ErrInvalidSignatureLen = errors.New("invalid signature length")
+	ErrInvalidRecoveryID   = errors.New("invalid signature recovery id")
+	ErrInvalidSignature    = errors.New("invalid signature")
+)
+
+// ValidateSignatureValues verifies whether the signature values are valid
+// with the given chain rules: r and s must lie in [1, n), with s in the lower
+// half of the range to rule out malleated signatures, and v must be a recovery
+// id of 0 or 1.
+func ValidateSignatureValues(v byte, r, s *big.Int) bool {
+	if r.Sign() <= 0 || s.Sign() <= 0 {
+		return false
+	}
+	if s.Cmp(secp256k1halfN) > 0 {
+		return false
+	}
+	return r.Cmp(secp256k1N) < 0 && (v == 0 || v == 1)
+}
+
+// Ecrecover returns the uncompressed public key, 0x04 followed by the 32 byte
+// coordinates, that created the given [R || S || V] signature over the 32 byte
+// hash.
+func Ecrecover(hash, sig []byte) ([]byte, error) {
+	if len(sig) != SignatureLength {
+		return nil, ErrInvalidSignatureLen
+	}
+	if sig[64] > 1 {
+		return nil, ErrInvalidRecoveryID
+	}
+	// Convert to the compact format of dcrd, with the recovery id in front
+	compact := make([]byte, SignatureLength)
+	compact[0] = sig[64] + 27
+	copy(compact[1:], sig)
+
+	pub, _, err := ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return pub.SerializeUncompressed(), nil
+}
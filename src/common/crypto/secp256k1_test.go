@@ -0,0 +1,108 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// Recovery vector of the go-ethereum crypto package.
+var (
+	testMsg    = mustDecode("ce0677bb30baa8cf067c88db9811f4333d131bf8bcf12fe7065d211dce971008")
+	testSig    = mustDecode("90f27b8b488db00b00606796d2987f6a5f59ae62ea05effe84fef5b8b0e549984a691139ad57a3f0b906637673aa2f63d1f55cb1a69199d4009eea23ceaddc9301")
+	testPubkey = mustDecode("04e32df42865e97135acfb65f3bae71bdc86f4d49150ad6a440b6f15878109880a0a2b2667f7e725ceea70c673093bf67663e0312623c8e091b13cf2c0f11ef652")
+)
+
+func mustDecode(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestEcrecover(t *testing.T) {
+	pubkey, err := Ecrecover(testMsg, testSig)
+	if err != nil {
+		t.Fatalf("recover error: %s", err)
+	}
+	if !bytes.Equal(pubkey, testPubkey) {
+		t.Errorf("pubkey mismatch: want: %x have: %x", testPubkey, pubkey)
+	}
+	if _, err := Ecrecover(testMsg, append(testSig[:64:64], 2)); err != ErrInvalidRecoveryID {
+		t.Errorf("recovery id 2: %v, want %v", err, ErrInvalidRecoveryID)
+	}
+}
+
+func TestEcrecoverInvalid(t *testing.T) {
+	// withRS returns the test signature with r and s replaced
+	withRS := func(r, s *big.Int) []byte {
+		sig := append([]byte(nil), testSig...)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:64])
+		return sig
+	}
+	r := new(big.Int).SetBytes(testSig[:32])
+	s := new(big.Int).SetBytes(testSig[32:64])
+
+	tests := []struct {
+		name string
+		sig  []byte
+	}{
+		{"r not on curve", withRS(big.NewInt(5), s)},
+		{"zero r", withRS(new(big.Int), s)},
+		{"zero s", withRS(r, new(big.Int))},
+		{"r of n", withRS(secp256k1N, s)},
+		{"s of n", withRS(r, secp256k1N)},
+	}
+	for _, tt := range tests {
+		if _, err := Ecrecover(testMsg, tt.sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: %v, want %v", tt.name, err, ErrInvalidSignature)
+		}
+	}
+	if _, err := Ecrecover(testMsg, testSig[:64]); err != ErrInvalidSignatureLen {
+		t.Errorf("short signature: %v, want %v", err, ErrInvalidSignatureLen)
+	}
+	// The other parity of y recovers another key
+	flipped := append(testSig[:64:64], testSig[64]^1)
+	if pubkey, err := Ecrecover(testMsg, flipped); err == nil && bytes.Equal(pubkey, testPubkey) {
+		t.Error("mismatched y parity recovered the signing key")
+	}
+}
+
+func TestValidateSignatureValues(t *testing.T) {
+	r := new(big.Int).SetBytes(testSig[:32])
+	s := new(big.Int).SetBytes(testSig[32:64])
+	highS := new(big.Int).Sub(secp256k1N, s)
+
+	tests := []struct {
+		name string
+		v    byte
+		r, s *big.Int
+		want bool
+	}{
+		{"valid", 1, r, s, true},
+		{"half n", 0, r, secp256k1halfN, true},
+		{"high s", 0, r, highS, false},
+		{"half n plus one", 0, r, new(big.Int).Add(secp256k1halfN, big.NewInt(1)), false},
+		{"zero r", 0, new(big.Int), s, false},
+		{"zero s", 0, r, new(big.Int), false},
+		{"r of n", 0, secp256k1N, s, false},
+		{"recovery id 2", 2, r, s, false},
+	}
+	for _, tt := range tests {
+		if got := ValidateSignatureValues(tt.v, tt.r, tt.s); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// The malleated high s signature recovers the signing key as well, which
+	// is why it must be rejected up front
+	malleated := append([]byte(nil), testSig...)
+	highS.FillBytes(malleated[32:64])
+	malleated[64] ^= 1
+	if pubkey, err := Ecrecover(testMsg, malleated); err != nil || !bytes.Equal(pubkey, testPubkey) {
+		t.Errorf("malleated signature: %x, %v", pubkey, err)
+	}
+}
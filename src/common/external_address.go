@@ -129,7 +129,8 @@ func (a ExternalAddress) Value() (driver.Value, error) {
 
 // Location looks up the chain location which contains this address
 func (a ExternalAddress) Location() *Location {
-	R, Z, D := 0, 0, HierarchyDepth
+	params := Hierarchy()
+	R, Z := 0, 0
 	if NodeLocation.HasRegion() {
 		R = NodeLocation.Region()
 	}
@@ -144,23 +145,28 @@ func (a ExternalAddress) Location() *Location {
 	// * we expect `>= Z` `zone` TXs for every `region` TX
 	// * we expect `>= R` `region` TXs for every `prime` TX
 	// * (and by extension) we expect `>= R*Z` `zone` TXs for every `prime` TX
+	// Zones without a prefix range own no addresses
+	contains := func(l Location) bool {
+		ok, err := l.ContainsAddress(Address{&a})
+		return ok && err == nil
+	}
 	primeChecked := false
-	for r := 0; r < NumRegionsInPrime; r++ {
-		for z := 0; z < NumZonesInRegion; z++ {
-			l := Location{byte((r + R) % D), byte((z + Z) % D)}
-			if l.ContainsAddress(Address{&a}) {
+	for r := 0; r < params.Regions; r++ {
+		for z := 0; z < params.Zones; z++ {
+			l := Location{byte((r + R) % params.Regions), byte((z + Z) % params.Zones)}
+			if contains(l) {
 				return &l
 			}
 		}
-		l := Location{byte((r + R) % D)}
-		if l.ContainsAddress(Address{&a}) {
+		l := Location{byte((r + R) % params.Regions)}
+		if contains(l) {
 			return &l
 		}
 		// Check prime on first pass through slice, but not again
 		if !primeChecked {
 			primeChecked = true
 			l := Location{}
-			if l.ContainsAddress(Address{&a}) {
+			if contains(l) {
 				return &l
 			}
 		}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrInvalidHierarchy is returned when setting a hierarchy no location could
+// address.
+var ErrInvalidHierarchy = errors.New("invalid hierarchy")
+
+// maxHierarchyWidth is the most regions or zones a location can index, as
+// its indices are single bytes.
+const maxHierarchyWidth = 256
+
+// HierarchyParams describe the shape of a network: the number of regions
+// below prime and of zones below every region. The number of contexts,
+// HierarchyDepth, is fixed by the encoding of headers, which carry one entry
+// per context in their per-context fields.
+type HierarchyParams struct {
+	Regions int `json:"regions"`
+	Zones   int `json:"zones"`
+}
+
+// DefaultHierarchy is the shape of the Quai networks, which locations are
+// validated against unless SetHierarchy changes it.
+var DefaultHierarchy = HierarchyParams{Regions: NumRegionsInPrime, Zones: NumZonesInRegion}
+
+var hierarchy atomic.Pointer[HierarchyParams]
+
+func init() {
+	params := DefaultHierarchy
+	hierarchy.Store(&params)
+}
+
+// SetHierarchy sets the shape of the network locations are validated against,
+// so headers of networks with more or fewer regions or zones than the
+// default verify. It is meant to be called at startup, before verifying.
+func SetHierarchy(regions, zones int) error {
+	if regions < 1 || regions > maxHierarchyWidth {
+		return fmt.Errorf("%w: %d regions, want 1 to %d", ErrInvalidHierarchy, regions, maxHierarchyWidth)
+	}
+	if zones < 1 || zones > maxHierarchyWidth {
+		return fmt.Errorf("%w: %d zones per region, want 1 to %d", ErrInvalidHierarchy, zones, maxHierarchyWidth)
+	}
+	hierarchy.Store(&HierarchyParams{Regions: regions, Zones: zones})
+	return nil
+}
+
+// Hierarchy returns the shape of the network locations are validated against.
+func Hierarchy() HierarchyParams {
+	return *hierarchy.Load()
+}
+
+// Chains returns the number of chains of the network: prime, the regions and
+// the zones of all regions.
+func (p HierarchyParams) Chains() int {
+	return 1 + p.Regions*(1+p.Zones)
+}
+
+// Contains reports whether loc addresses a chain of the network.
+func (p HierarchyParams) Contains(loc Location) bool {
+	return len(loc) < HierarchyDepth && loc.Region() < p.Regions && loc.Zone() < p.Zones
+}
+
+// Valid reports whether the location addresses a chain of the network set by
+// SetHierarchy. Unlike AssertValid, it never aborts.
+func (loc Location) Valid() bool {
+	return Hierarchy().Contains(loc)
+}
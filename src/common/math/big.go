@@ -103,3 +103,34 @@ func BigPow(a, b int64) *big.Int {
 	r := big.NewInt(a)
 	return r.Exp(r, big.NewInt(b), nil)
 }
+
+// BinaryLog computes the binary logarithm of n, which must be positive. The
+// result is split into the integer part, the characteristic, and the leading
+// mantissaBits bits of the fractional part, the mantissa, such that
+// log2(n) ≈ characteristic + mantissa / 2**mantissaBits. The mantissa is
+// truncated, never rounded up.
+func BinaryLog(n *big.Int, mantissaBits int) (characteristic int, mantissa *big.Int) {
+	if n.Sign() <= 0 || mantissaBits < 0 {
+		panic("invalid argument of BinaryLog")
+	}
+	characteristic = n.BitLen() - 1
+	mantissa = new(big.Int)
+
+	// Track y = n / 2**characteristic, a value in [1, 2), as a fixed point
+	// number of scale bits. Squaring y doubles its logarithm, so every square
+	// reaching 2 contributes the next fractional bit. The square goes to a
+	// separate integer, as squaring in place would reallocate every round.
+	scale := uint(characteristic + mantissaBits)
+	y := new(big.Int).Lsh(n, uint(mantissaBits))
+	sq := new(big.Int)
+	for i := 0; i < mantissaBits; i++ {
+		sq.Mul(y, y)
+		y.Rsh(sq, scale)
+		mantissa.Lsh(mantissa, 1)
+		if uint(y.BitLen()) > scale+1 {
+			y.Rsh(y, 1)
+			mantissa.SetBit(mantissa, 0, 1)
+		}
+	}
+	return characteristic, mantissa
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+var (
+	// ErrInvalidShardPrefixes is returned when setting address prefix ranges
+	// which are reversed or overlap.
+	ErrInvalidShardPrefixes = errors.New("invalid shard prefixes")
+	// ErrUnknownShardPrefix is returned when scoping addresses to a zone
+	// without an address prefix range.
+	ErrUnknownShardPrefix = errors.New("no address prefix range for location")
+)
+
+// PrefixRange is the range of the first bytes of the addresses of a zone,
+// both ends included.
+type PrefixRange struct {
+	Lo uint8 `json:"lo"`
+	Hi uint8 `json:"hi"`
+}
+
+// Contains reports whether an address starting with prefix lies in the range.
+func (r PrefixRange) Contains(prefix byte) bool {
+	return prefix >= r.Lo && prefix <= r.Hi
+}
+
+// defaultShardPrefixes partitions the address space among the zones of the
+// Quai networks, by zone name.
+var defaultShardPrefixes = map[string]PrefixRange{
+	"cyprus1": {0, 29},
+	"cyprus2": {30, 58},
+	"cyprus3": {59, 87},
+	"paxos1":  {88, 115},
+	"paxos2":  {116, 143},
+	"paxos3":  {144, 171},
+	"hydra1":  {172, 199},
+	"hydra2":  {200, 227},
+	"hydra3":  {228, 255},
+}
+
+var shardPrefixes atomic.Pointer[map[string]PrefixRange]
+
+func init() {
+	shardPrefixes.Store(&defaultShardPrefixes)
+}
+
+// SetShardPrefixes sets the address prefix ranges of the zones, by zone name
+// as returned by Location.Name, which addresses are scoped by. Networks whose
+// zones partition the address space differently than Quai's, or with zones
+// beyond the three by three of DefaultHierarchy, set theirs at startup; nil
+// restores the ranges of the Quai networks. Zones left out own no addresses.
+func SetShardPrefixes(prefixes map[string]PrefixRange) error {
+	if prefixes == nil {
+		shardPrefixes.Store(&defaultShardPrefixes)
+		return nil
+	}
+	names := make([]string, 0, len(prefixes))
+	table := make(map[string]PrefixRange, len(prefixes))
+	for name, r := range prefixes {
+		if r.Lo > r.Hi {
+			return fmt.Errorf("%w: %s starts at %d after its end %d", ErrInvalidShardPrefixes, name, r.Lo, r.Hi)
+		}
+		names = append(names, name)
+		table[name] = r
+	}
+	// An address belongs to a single zone, so the ranges must not overlap
+	sort.Slice(names, func(i, j int) bool {
+		return table[names[i]].Lo < table[names[j]].Lo
+	})
+	for i := 1; i < len(names); i++ {
+		if prev, next := table[names[i-1]], table[names[i]]; next.Lo <= prev.Hi {
+			return fmt.Errorf("%w: %s and %s overlap", ErrInvalidShardPrefixes, names[i-1], names[i])
+		}
+	}
+	shardPrefixes.Store(&table)
+	return nil
+}
+
+// ShardPrefixes returns the address prefix ranges of the zones addresses are
+// scoped by.
+func ShardPrefixes() map[string]PrefixRange {
+	table := *shardPrefixes.Load()
+	prefixes := make(map[string]PrefixRange, len(table))
+	for name, r := range table {
+		prefixes[name] = r
+	}
+	return prefixes
+}
+
+// PrefixRange returns the address prefix range of the zone at the location.
+// Locations of other chains, and zones without a range, report
+// ErrUnknownShardPrefix.
+func (l Location) PrefixRange() (PrefixRange, error) {
+	if !l.Valid() {
+		return PrefixRange{}, fmt.Errorf("%w: invalid location %v", ErrUnknownShardPrefix, []byte(l))
+	}
+	r, ok := (*shardPrefixes.Load())[l.Name()]
+	if !ok {
+		return PrefixRange{}, fmt.Errorf("%w: %s", ErrUnknownShardPrefix, l.Name())
+	}
+	return r, nil
+}
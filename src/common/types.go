@@ -19,6 +19,7 @@ package common
 import (
 	"errors"
 	"log"
+	"reflect"
 	"strconv"
 
 	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
@@ -36,13 +37,18 @@ const (
 	REGION_CTX = 1
 	ZONE_CTX   = 2
 
-	// Depth of the hierarchy of chains
+	// Depth of the hierarchy of chains. The region and zone counts are those
+	// of DefaultHierarchy, see SetHierarchy for networks of other shapes.
 	NumRegionsInPrime = 3
 	NumZonesInRegion  = 3
 	HierarchyDepth    = 3
 	NumChains         = 1 + NumRegionsInPrime*(1+NumZonesInRegion) // Prime + R regions + RxZ zones
 )
 
+var (
+	hashT = reflect.TypeOf(Hash{})
+)
+
 var (
 	// Default to prime node, but changed at startup by config.
 	NodeLocation = Location{}
@@ -66,12 +72,31 @@ func BytesToHash(b []byte) Hash {
 	return h
 }
 
+// HexToHash sets byte representation of s to hash.
+// If b is larger than len(h), b will be cropped from the left.
+func HexToHash(s string) Hash { return BytesToHash(FromHex(s)) }
+
 // Bytes gets the byte representation of the underlying hash.
 func (h Hash) Bytes() []byte { return h[:] }
 
 // Hex converts a hash to a hex string.
 func (h Hash) Hex() string { return hexutil.Encode(h[:]) }
 
+// MarshalText returns the hex representation of h.
+func (h Hash) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(h[:]).MarshalText()
+}
+
+// UnmarshalText parses a hash in hex syntax.
+func (h *Hash) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("Hash", input, h[:])
+}
+
+// UnmarshalJSON parses a hash in hex syntax.
+func (h *Hash) UnmarshalJSON(input []byte) error {
+	return hexutil.UnmarshalFixedJSON(hashT, input, h[:])
+}
+
 // SetBytes sets the hash to the value of b.
 // If b is larger than len(h), b will be cropped from the left.
 func (h *Hash) SetBytes(b []byte) {
@@ -84,13 +109,6 @@ func (h *Hash) SetBytes(b []byte) {
 
 /////////// Address
 
-type addrPrefixRange struct {
-	lo uint8
-	hi uint8
-}
-
-var locationToPrefixRange = make(map[string]addrPrefixRange)
-
 // Location of a chain within the Quai hierarchy
 // Location is encoded as a path from the root of the tree to the specified
 // chain. Not all indices need to be populated, e.g:
@@ -127,10 +145,11 @@ func (loc Location) AssertValid() {
 	if !loc.HasRegion() && loc.HasZone() {
 		log.Fatal("cannot specify zone without also specifying region.")
 	}
-	if loc.Region() >= NumRegionsInPrime {
+	params := Hierarchy()
+	if loc.Region() >= params.Regions {
 		log.Fatal("region index is not valid.")
 	}
-	if loc.Zone() >= NumZonesInRegion {
+	if loc.Zone() >= params.Zones {
 		log.Fatal("zone index is not valid.")
 	}
 }
@@ -156,7 +175,8 @@ func (loc Location) Name() string {
 	case 2:
 		regionName = "hydra"
 	default:
-		regionName = "unknownregion"
+		// Regions beyond those of the default hierarchy are numbered
+		regionName = "region" + strconv.Itoa(loc.Region()+1)
 	}
 	zoneNum := strconv.Itoa(loc.Zone() + 1)
 	switch loc.Context() {
@@ -172,34 +192,46 @@ func (loc Location) Name() string {
 	}
 }
 
-func (l Location) ContainsAddress(a Address) bool {
-	// ContainAddress can only be called for a zone chain
-	if l.Context() != ZONE_CTX {
-		return false
+// CommonDom identifies the highest context chain which dominates both
+// locations, e.g. the region containing two zones of that region, or prime for
+// zones in different regions.
+func (l Location) CommonDom(cmp Location) Location {
+	common := Location{}
+	if l.HasRegion() && l.Region() == cmp.Region() {
+		common = append(common, byte(l.Region()))
+		if l.HasZone() && l.Zone() == cmp.Zone() {
+			common = append(common, byte(l.Zone()))
+		}
+	}
+	return common
+}
+
+// ContainsAddress reports whether the address belongs to the zone at the
+// location, by the prefix ranges set with SetShardPrefixes. Only zones contain
+// addresses; zones without a prefix range report ErrUnknownShardPrefix.
+func (l Location) ContainsAddress(a Address) (bool, error) {
+	if l.Valid() && l.Context() != ZONE_CTX {
+		return false, nil
 	}
-	prefix := a.Bytes()[0]
-	prefixRange, ok := locationToPrefixRange[l.Name()]
-	if !ok {
-		log.Fatal("unable to get address prefix range for location")
+	prefixRange, err := l.PrefixRange()
+	if err != nil {
+		return false, err
 	}
-	// Ranges are fully inclusive
-	return uint8(prefix) >= prefixRange.lo && uint8(prefix) <= prefixRange.hi
+	return prefixRange.Contains(a.Bytes()[0]), nil
 }
 
-func IsInChainScope(b []byte) bool {
-	nodeCtx := NodeLocation.Context()
-	// IsInChainScope only be called for a zone chain
-	if nodeCtx != ZONE_CTX {
-		return false
+// IsInChainScope reports whether the address bytes b belong to the zone of the
+// node, like ContainsAddress. Only zone nodes have addresses in scope.
+func IsInChainScope(b []byte) (bool, error) {
+	if NodeLocation.Valid() && NodeLocation.Context() != ZONE_CTX {
+		return false, nil
 	}
 	if BytesToHash(b) == ZeroAddr.Hash() {
-		return true
+		return true, nil
 	}
-	prefix := b[0]
-	prefixRange, ok := locationToPrefixRange[NodeLocation.Name()]
-	if !ok {
-		log.Fatal("unable to get address prefix range for location")
+	prefixRange, err := NodeLocation.PrefixRange()
+	if err != nil {
+		return false, err
 	}
-	// Ranges are fully inclusive
-	return uint8(prefix) >= prefixRange.lo && uint8(prefix) <= prefixRange.hi
+	return prefixRange.Contains(b[0]), nil
 }
@@ -0,0 +1,55 @@
+// Package browser holds the browser example, which verifies header seals with
+// the WASM module on a web page. Its test runs the example under Node.js.
+package browser
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sealedHeader is an RLP encoded zone header at difficulty 16, sealed with the
+// test-mode verification cache like the headers of a local devnet.
+const sealedHeader = "0xf901f7f863a00000000000000000000000000000000000000000000000000000000000000011a00000000000000000000000000000000000000000000000000000000000000022a00000000000000000000000000000000000000000000000000000000000000033a00000000000000000000000000000000000000000000000000000000000000077940000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000088a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000f863a00000000000000000000000000000000000000000000000000000000000000044a00000000000000000000000000000000000000000000000000000000000000055a00000000000000000000000000000000000000000000000000000000000000066a0000000000000000000000000000000000000000000000000000000000000000010c58203e8640ac3800507c3010203834c4b40825208843b9aca00820001846553f103846d696e65a046a6983554aaebed3a740d2fd47bd3104e24e6a4467638bebb88f46413ea01c288000000000000000b"
+
+func TestBrowserExample(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the WASM module")
+	}
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		t.Fatalf("locating GOROOT: %v", err)
+	}
+	// The loader moved from misc/wasm to lib/wasm in Go 1.24
+	var loader string
+	for _, dir := range []string{"lib", "misc"} {
+		path := filepath.Join(strings.TrimSpace(string(goroot)), dir, "wasm", "wasm_exec.js")
+		if _, err := os.Stat(path); err == nil {
+			loader = path
+			break
+		}
+	}
+	if loader == "" {
+		t.Skip("wasm_exec.js not found")
+	}
+	wasm := filepath.Join(t.TempDir(), "progpow.wasm")
+	build := exec.Command("go", "build", "-o", wasm, "../..")
+	build.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building the module: %v\n%s", err, out)
+	}
+	out, err := exec.Command(node, "node.js", loader, wasm, sealedHeader).Output()
+	if err != nil {
+		t.Fatalf("running the example: %v", err)
+	}
+	want := "valid 0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Fatalf("example printed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,17 @@
+// Runs the browser example under Node.js, verifying a devnet header:
+//
+//   node node.js <wasm_exec.js> <progpow.wasm> <rlp-hex-header>
+require(process.argv[2]);
+const fs = require('fs');
+const { verifyHeader } = require('./verify.js');
+
+const go = new Go();
+WebAssembly.instantiate(fs.readFileSync(process.argv[3]), go.importObject).then(async ({ instance }) => {
+  go.run(instance);
+  try {
+    console.log(await verifyHeader(process.argv[4], { test: true }));
+  } catch (err) {
+    console.log(`error: ${err.message}`);
+  }
+  process.exit(0);
+});
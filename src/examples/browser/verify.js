@@ -0,0 +1,11 @@
+// verifyHeader checks the seal of a header, given as RLP hex or as a JSON-RPC
+// header object, with the progpow module running on the page, and resolves
+// with a line describing the result.
+async function verifyHeader(header, options) {
+  const result = await verifySeal(header, options);
+  return result.valid ? `valid ${result.powHash}` : `invalid: ${result.error}`;
+}
+
+if (typeof module !== 'undefined') {
+  module.exports = { verifyHeader };
+}
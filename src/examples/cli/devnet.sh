@@ -0,0 +1,20 @@
+#!/bin/sh
+# Walks through quai-verify against a local devnet, whose headers are sealed
+# with the test-mode verification cache. Pass an RLP-hex header exported from
+# the devnet node as the first argument.
+set -e
+
+HEADER=${1:?usage: devnet.sh <rlp-hex-header>}
+CACHEDIR=${CACHEDIR:-$(mktemp -d)}
+
+# Check the embedded golden vectors before trusting the binary
+go run ./cmd/quai-verify selftest
+
+# Print the parameters the engine verifies with
+go run ./cmd/quai-verify params --test
+
+# Verify the header, persisting the generated cache
+go run ./cmd/quai-verify --test --cachedir "$CACHEDIR" verify "$HEADER"
+
+# Confirm the persisted cache matches a freshly generated one
+go run ./cmd/quai-verify --test --cachedir "$CACHEDIR" cache verify --epoch 0
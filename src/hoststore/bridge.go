@@ -0,0 +1,122 @@
+//go:build js && wasm
+// +build js,wasm
+
+package hoststore
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+var (
+	ErrNotFound    = errors.New("key not found in host storage")
+	ErrInvalidHost = errors.New("invalid host storage")
+)
+
+// Bridge calls the get, put and delete methods of a host storage object. Its
+// methods block the calling goroutine until the host settles the call, so they
+// must not be called from the JavaScript event loop itself, e.g. directly
+// inside a js.FuncOf callback.
+type Bridge struct {
+	host js.Value
+}
+
+// NewBridge wraps a host storage object, checking that it provides the get,
+// put and delete methods.
+func NewBridge(host js.Value) (*Bridge, error) {
+	if host.Type() != js.TypeObject {
+		return nil, fmt.Errorf("%w: %s is not an object", ErrInvalidHost, host.Type())
+	}
+	for _, method := range []string{"get", "put", "delete"} {
+		if host.Get(method).Type() != js.TypeFunction {
+			return nil, fmt.Errorf("%w: missing method %s", ErrInvalidHost, method)
+		}
+	}
+	return &Bridge{host: host}, nil
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (b *Bridge) Get(key string) ([]byte, error) {
+	result, err := b.call("get", key)
+	if err != nil {
+		return nil, err
+	}
+	if result.IsUndefined() || result.IsNull() {
+		return nil, ErrNotFound
+	}
+	if !result.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, fmt.Errorf("host storage: unexpected value %s for %q", result.Type(), key)
+	}
+	data := make([]byte, result.Get("byteLength").Int())
+	js.CopyBytesToGo(data, result)
+	return data, nil
+}
+
+// Put stores value under key, replacing any previous value.
+func (b *Bridge) Put(key string, value []byte) error {
+	array := js.Global().Get("Uint8Array").New(len(value))
+	js.CopyBytesToJS(array, value)
+
+	_, err := b.call("put", key, array)
+	return err
+}
+
+// Delete removes the value stored under key.
+func (b *Bridge) Delete(key string) error {
+	_, err := b.call("delete", key)
+	return err
+}
+
+// call invokes a method of the host and waits for the result, awaiting it if
+// the host returns a promise. Exceptions thrown and promises rejected by the
+// host are returned as errors.
+func (b *Bridge) call(method string, args ...interface{}) (result js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if jsErr, ok := r.(js.Error); ok {
+				err = fmt.Errorf("host storage %s: %s", method, jsErr.Error())
+				return
+			}
+			panic(r)
+		}
+	}()
+	result = b.host.Call(method, args...)
+	if result.Type() != js.TypeObject || result.Get("then").Type() != js.TypeFunction {
+		return result, nil
+	}
+	return await(method, result)
+}
+
+// await blocks until a promise settles and returns its value.
+func await(method string, promise js.Value) (js.Value, error) {
+	type outcome struct {
+		value js.Value
+		err   error
+	}
+	done := make(chan outcome, 1)
+
+	resolve := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		value := js.Undefined()
+		if len(args) > 0 {
+			value = args[0]
+		}
+		done <- outcome{value: value}
+		return nil
+	})
+	defer resolve.Release()
+
+	reject := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		msg := "unknown error"
+		if len(args) > 0 {
+			msg = js.Global().Get("String").Invoke(args[0]).String()
+		}
+		done <- outcome{err: fmt.Errorf("host storage %s: %s", method, msg)}
+		return nil
+	})
+	defer reject.Release()
+
+	promise.Call("then", resolve, reject)
+	result := <-done
+	return result.value, result.err
+}
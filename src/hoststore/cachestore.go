@@ -0,0 +1,40 @@
+//go:build js && wasm
+// +build js,wasm
+
+package hoststore
+
+import (
+	"errors"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+)
+
+// CacheStore is a progpow.CacheStore persisting verification caches in host
+// storage, under their cache keys.
+type CacheStore struct {
+	bridge *Bridge
+}
+
+// NewCacheStore creates a cache store on top of a host storage bridge.
+func NewCacheStore(bridge *Bridge) *CacheStore {
+	return &CacheStore{bridge: bridge}
+}
+
+// Load implements progpow.CacheStore.
+func (s *CacheStore) Load(key string) ([]byte, error) {
+	data, err := s.bridge.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, progpow.ErrCacheNotStored
+	}
+	return data, err
+}
+
+// Store implements progpow.CacheStore.
+func (s *CacheStore) Store(key string, data []byte) error {
+	return s.bridge.Put(key, data)
+}
+
+// Delete implements progpow.CacheStore.
+func (s *CacheStore) Delete(key string) error {
+	return s.bridge.Delete(key)
+}
@@ -0,0 +1,15 @@
+// Package hoststore bridges persistence to storage supplied by the JavaScript
+// host of the WASM build, such as Electron, React Native or browser extension
+// storage, where IndexedDB is unavailable or not the storage of choice.
+//
+// The host hands in an object with three methods, each returning a promise or
+// a plain value:
+//
+//	get(key)        -> Uint8Array, or null/undefined if absent
+//	put(key, value) -> settles once value, a Uint8Array, is persisted
+//	delete(key)     -> settles once key is removed, even if it was absent
+//
+// Bridge adapts such an object for Go, CacheStore persists verification
+// caches through it and HeaderStore serves headers from it. The package is
+// only available on js/wasm.
+package hoststore
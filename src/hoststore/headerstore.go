@@ -0,0 +1,89 @@
+//go:build js && wasm
+// +build js,wasm
+
+package hoststore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// HeaderStore is an interop.HeaderStore keeping the headers of a single chain
+// in host storage: every header RLP encoded under its hash, and the hash of the
+// header at every number in the chain's context under that number.
+type HeaderStore struct {
+	bridge *Bridge
+	ctx    common.Context
+}
+
+// NewHeaderStore creates a store for the chain of the given context on top of
+// a host storage bridge.
+func NewHeaderStore(bridge *Bridge, ctx int) *HeaderStore {
+	return &HeaderStore{bridge: bridge, ctx: common.Context(ctx)}
+}
+
+// Add stores headers, replacing any header at the same number as the one
+// served for it.
+func (s *HeaderStore) Add(headers ...*types.Header) error {
+	for _, header := range headers {
+		var enc bytes.Buffer
+		if err := rlp.Encode(&enc, header); err != nil {
+			return err
+		}
+		hash := header.Hash()
+		if err := s.bridge.Put(headerKey(hash), enc.Bytes()); err != nil {
+			return err
+		}
+		if err := s.bridge.Put(s.numberKey(header.NumberU64In(s.ctx)), hash.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HeaderByHash implements interop.HeaderStore. Headers the host fails to
+// return are logged and reported missing.
+func (s *HeaderStore) HeaderByHash(hash common.Hash) *types.Header {
+	data, err := s.bridge.Get(headerKey(hash))
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Warn("Failed to load header from host storage", "hash", hash, "err", err)
+		}
+		return nil
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(data, header); err != nil {
+		log.Warn("Invalid header in host storage", "hash", hash, "err", err)
+		return nil
+	}
+	return header
+}
+
+// HeaderByNumber implements interop.HeaderStore.
+func (s *HeaderStore) HeaderByNumber(number uint64) *types.Header {
+	data, err := s.bridge.Get(s.numberKey(number))
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Warn("Failed to load header number from host storage", "number", number, "err", err)
+		}
+		return nil
+	}
+	return s.HeaderByHash(common.BytesToHash(data))
+}
+
+// headerKey returns the key a header is stored under.
+func headerKey(hash common.Hash) string {
+	return fmt.Sprintf("header-%x", hash)
+}
+
+// numberKey returns the key the hash of the header at a number is stored
+// under.
+func (s *HeaderStore) numberKey(number uint64) string {
+	return fmt.Sprintf("number-%d-%d", int(s.ctx), number)
+}
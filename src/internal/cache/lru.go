@@ -0,0 +1,196 @@
+// Package cache implements a generic least-recently-used cache with per-item
+// cost accounting, shared by the verification caches and other in-memory
+// stores of this module.
+package cache
+
+import "container/list"
+
+// entry is a single item tracked by an LRU.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	cost  uint64
+}
+
+// LRU is a least-recently-used cache bounded by item count and/or total item
+// cost (typically bytes). Eviction skips items reported as pinned by the
+// optional pin callback, unless every item is pinned.
+//
+// LRU is not safe for concurrent use; callers are expected to serialise access.
+type LRU[K comparable, V any] struct {
+	maxItems int    // Maximum number of items, zero means unbounded
+	maxCost  uint64 // Maximum total cost, zero means unbounded
+	cost     uint64 // Total cost of all items currently held
+
+	items map[K]*list.Element
+	order *list.List // Most recently used item at the front
+
+	pin     func(key K) bool
+	onEvict func(key K, value V)
+}
+
+// New creates an LRU holding at most maxItems items with a total cost of at most
+// maxCost. A zero limit disables that bound. onEvict, if non-nil, is invoked for
+// every item evicted to make room for new ones.
+func New[K comparable, V any](maxItems int, maxCost uint64, onEvict func(key K, value V)) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxItems: maxItems,
+		maxCost:  maxCost,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		onEvict:  onEvict,
+	}
+}
+
+// SetPinned installs a callback reporting which keys should survive eviction
+// while unpinned items are available.
+func (c *LRU[K, V]) SetPinned(pin func(key K) bool) {
+	c.pin = pin
+}
+
+// Resize changes the bounds of the cache, evicting items if needed. It returns
+// the number of evicted items.
+func (c *LRU[K, V]) Resize(maxItems int, maxCost uint64) int {
+	c.maxItems, c.maxCost = maxItems, maxCost
+	return c.shrink(0, 0)
+}
+
+// Add inserts or replaces the value for key with the given cost and marks it as
+// most recently used. It returns the number of items evicted to make room.
+func (c *LRU[K, V]) Add(key K, value V, cost uint64) int {
+	if elem, ok := c.items[key]; ok {
+		ent := elem.Value.(*entry[K, V])
+		c.cost = c.cost - ent.cost + cost
+		ent.value, ent.cost = value, cost
+		c.order.MoveToFront(elem)
+		return c.shrink(0, 0)
+	}
+	evicted := c.shrink(1, cost)
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, cost: cost})
+	c.cost += cost
+	return evicted
+}
+
+// Get returns the value for key and marks it as most recently used.
+func (c *LRU[K, V]) Get(key K) (value V, ok bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return value, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// Peek returns the value for key without updating its recency.
+func (c *LRU[K, V]) Peek(key K) (value V, ok bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return value, false
+	}
+	return elem.Value.(*entry[K, V]).value, true
+}
+
+// Contains reports whether key is in the cache without updating its recency.
+func (c *LRU[K, V]) Contains(key K) bool {
+	_, ok := c.items[key]
+	return ok
+}
+
+// Remove deletes key from the cache, reporting whether it was present. The
+// eviction callback is not invoked.
+func (c *LRU[K, V]) Remove(key K) bool {
+	elem, ok := c.items[key]
+	if ok {
+		c.removeElement(elem)
+	}
+	return ok
+}
+
+// RemoveOldest deletes the least recently used item, regardless of pinning, and
+// returns it.
+func (c *LRU[K, V]) RemoveOldest() (key K, value V, ok bool) {
+	elem := c.order.Back()
+	if elem == nil {
+		return key, value, false
+	}
+	ent := elem.Value.(*entry[K, V])
+	c.removeElement(elem)
+	return ent.key, ent.value, true
+}
+
+// Keys returns the keys in the cache, from least to most recently used.
+func (c *LRU[K, V]) Keys() []K {
+	keys := make([]K, 0, len(c.items))
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		keys = append(keys, elem.Value.(*entry[K, V]).key)
+	}
+	return keys
+}
+
+// Len returns the number of items in the cache.
+func (c *LRU[K, V]) Len() int {
+	return len(c.items)
+}
+
+// Cost returns the total cost of the items in the cache.
+func (c *LRU[K, V]) Cost() uint64 {
+	return c.cost
+}
+
+// ItemCost returns the cost recorded for key.
+func (c *LRU[K, V]) ItemCost(key K) (uint64, bool) {
+	elem, ok := c.items[key]
+	if !ok {
+		return 0, false
+	}
+	return elem.Value.(*entry[K, V]).cost, true
+}
+
+// Purge removes all items from the cache without invoking the eviction callback.
+func (c *LRU[K, V]) Purge() {
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+	c.cost = 0
+}
+
+// shrink evicts items until the given number of extra items with the given total
+// cost fit within the bounds, preferring the least recently used unpinned items.
+// It returns the number of evicted items.
+func (c *LRU[K, V]) shrink(items int, cost uint64) int {
+	evicted := 0
+	for len(c.items) > 0 && c.overflows(items, cost) {
+		victim := c.order.Back()
+		if c.pin != nil {
+			for elem := victim; elem != nil; elem = elem.Prev() {
+				if !c.pin(elem.Value.(*entry[K, V]).key) {
+					victim = elem
+					break
+				}
+			}
+		}
+		ent := victim.Value.(*entry[K, V])
+		c.removeElement(victim)
+		if c.onEvict != nil {
+			c.onEvict(ent.key, ent.value)
+		}
+		evicted++
+	}
+	return evicted
+}
+
+// overflows reports whether adding the given number of items with the given
+// total cost would exceed the bounds of the cache.
+func (c *LRU[K, V]) overflows(items int, cost uint64) bool {
+	if c.maxItems > 0 && len(c.items)+items > c.maxItems {
+		return true
+	}
+	return c.maxCost > 0 && c.cost+cost > c.maxCost
+}
+
+// removeElement unlinks elem from the cache.
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	ent := elem.Value.(*entry[K, V])
+	c.order.Remove(elem)
+	delete(c.items, ent.key)
+	c.cost -= ent.cost
+}
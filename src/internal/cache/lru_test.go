@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+// evictions records the keys an LRU evicts, in order.
+type evictions []int
+
+func (e *evictions) record(key int, value string) {
+	*e = append(*e, key)
+}
+
+func TestLRUItemBound(t *testing.T) {
+	var evicted evictions
+	c := New[int, string](2, 0, evicted.record)
+
+	c.Add(1, "one", 0)
+	c.Add(2, "two", 0)
+	c.Get(1) // Make 2 the least recently used
+	if n := c.Add(3, "three", 0); n != 1 {
+		t.Fatalf("evicted %d items, want 1", n)
+	}
+	if want := (evictions{2}); !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	if want := []int{1, 3}; !reflect.DeepEqual(c.Keys(), want) {
+		t.Fatalf("keys %v, want %v", c.Keys(), want)
+	}
+}
+
+func TestLRUCostBound(t *testing.T) {
+	var evicted evictions
+	c := New[int, string](0, 100, evicted.record)
+
+	c.Add(1, "one", 40)
+	c.Add(2, "two", 40)
+	c.Add(3, "three", 10)
+	if c.Cost() != 90 {
+		t.Fatalf("cost %d, want 90", c.Cost())
+	}
+	if n := c.Add(4, "four", 50); n != 1 {
+		t.Fatalf("evicted %d items, want 1", n)
+	}
+	if c.Cost() != 100 {
+		t.Fatalf("cost %d, want 100", c.Cost())
+	}
+	if n := c.Add(5, "five", 30); n != 1 {
+		t.Fatalf("evicted %d items, want 1", n)
+	}
+	if want := (evictions{1, 2}); !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	if c.Cost() != 90 {
+		t.Fatalf("cost %d, want 90", c.Cost())
+	}
+	// Replacing an item accounts for its new cost
+	if n := c.Add(5, "five", 60); n != 2 {
+		t.Fatalf("evicted %d items on replacement, want 2", n)
+	}
+	if want := []int{5}; !reflect.DeepEqual(c.Keys(), want) {
+		t.Fatalf("keys %v, want %v", c.Keys(), want)
+	}
+	if cost, _ := c.ItemCost(5); cost != 60 || c.Cost() != 60 {
+		t.Fatalf("item cost %d, total %d, want 60", cost, c.Cost())
+	}
+	// Shrinking the bounds evicts down to them
+	c.Add(6, "six", 10)
+	if n := c.Resize(0, 20); n != 1 || c.Cost() != 10 || !c.Contains(6) {
+		t.Fatalf("resize evicted %d items leaving cost %d, want 1 leaving 10", n, c.Cost())
+	}
+}
+
+func TestLRUOversizedItem(t *testing.T) {
+	c := New[int, string](0, 100, nil)
+
+	c.Add(1, "one", 40)
+	c.Add(2, "two", 150)
+	if want := []int{2}; !reflect.DeepEqual(c.Keys(), want) {
+		t.Fatalf("keys %v, want %v", c.Keys(), want)
+	}
+	if c.Cost() != 150 {
+		t.Fatalf("cost %d, want 150", c.Cost())
+	}
+}
+
+func TestLRUPinned(t *testing.T) {
+	var evicted evictions
+	c := New[int, string](0, 100, evicted.record)
+	pinned := map[int]bool{1: true, 2: true}
+	c.SetPinned(func(key int) bool { return pinned[key] })
+
+	c.Add(1, "one", 30)
+	c.Add(2, "two", 30)
+	c.Add(3, "three", 30)
+
+	// The unpinned item goes first, even though it is the most recent
+	c.Add(4, "four", 30)
+	if want := (evictions{3}); !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	// Pinned items exceeding the budget are evicted oldest first once no
+	// unpinned item is left
+	pinned[4] = true
+	c.Add(5, "five", 60)
+	if want := (evictions{3, 1, 2}); !reflect.DeepEqual(evicted, want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+	if want := []int{4, 5}; !reflect.DeepEqual(c.Keys(), want) {
+		t.Fatalf("keys %v, want %v", c.Keys(), want)
+	}
+}
+
+func TestLRUPeek(t *testing.T) {
+	c := New[int, string](2, 0, nil)
+
+	c.Add(1, "one", 0)
+	c.Add(2, "two", 0)
+	if v, ok := c.Peek(1); !ok || v != "one" {
+		t.Fatalf("peek 1 = %q, %v", v, ok)
+	}
+	if _, ok := c.Peek(3); ok {
+		t.Fatal("peeked missing key")
+	}
+	// Peeking does not refresh 1, so it is still the oldest
+	c.Add(3, "three", 0)
+	if c.Contains(1) || !c.Contains(2) {
+		t.Fatalf("keys %v after peek, want [2 3]", c.Keys())
+	}
+}
+
+func TestLRURemove(t *testing.T) {
+	var evicted evictions
+	c := New[int, string](0, 0, evicted.record)
+
+	c.Add(1, "one", 10)
+	c.Add(2, "two", 20)
+	c.Add(3, "three", 30)
+	if !c.Remove(2) {
+		t.Fatal("remove of present key reported missing")
+	}
+	if c.Remove(2) {
+		t.Fatal("remove of missing key reported present")
+	}
+	if c.Len() != 2 || c.Cost() != 40 {
+		t.Fatalf("len %d, cost %d after remove, want 2, 40", c.Len(), c.Cost())
+	}
+	key, value, ok := c.RemoveOldest()
+	if !ok || key != 1 || value != "one" {
+		t.Fatalf("removed oldest %d %q %v, want 1 one true", key, value, ok)
+	}
+	c.Purge()
+	if c.Len() != 0 || c.Cost() != 0 {
+		t.Fatalf("len %d, cost %d after purge, want 0", c.Len(), c.Cost())
+	}
+	if _, _, ok := c.RemoveOldest(); ok {
+		t.Fatal("removed oldest of empty cache")
+	}
+	// Removal is not eviction
+	if len(evicted) != 0 {
+		t.Fatalf("evicted %v, want none", evicted)
+	}
+}
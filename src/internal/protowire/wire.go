@@ -0,0 +1,100 @@
+// Package protowire implements the subset of the protocol buffers wire format
+// the protobuf encodings of this module need: varints and length-delimited
+// fields, skipping fields of the other wire types on decoding. It spares the
+// module, and the WASM build in particular, a dependency on the protobuf
+// runtime.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types of the protobuf encoding.
+const (
+	VarintType  = 0
+	Fixed64Type = 1
+	BytesType   = 2
+	Fixed32Type = 5
+)
+
+var ErrMalformed = errors.New("malformed protobuf")
+
+// AppendVarint appends the field num holding v as a varint.
+func AppendVarint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|VarintType)
+	return binary.AppendUvarint(b, v)
+}
+
+// AppendBytes appends the length-delimited field num holding v, which may be
+// the encoding of an embedded message.
+func AppendBytes(b []byte, num int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|BytesType)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// Field is a decoded field of a message.
+type Field struct {
+	Num    int
+	Type   int
+	Varint uint64 // Value of varint fields
+	Bytes  []byte // Content of length-delimited fields, aliasing the input
+}
+
+// Next decodes the field at the start of b and returns it along with the
+// remainder of b.
+func Next(b []byte) (Field, []byte, error) {
+	tag, n := binary.Uvarint(b)
+	if n <= 0 {
+		return Field{}, nil, fmt.Errorf("%w: truncated tag", ErrMalformed)
+	}
+	b = b[n:]
+	field := Field{Num: int(tag >> 3), Type: int(tag & 7)}
+	if field.Num <= 0 || tag>>3 > 1<<29-1 {
+		return Field{}, nil, fmt.Errorf("%w: invalid field number %d", ErrMalformed, tag>>3)
+	}
+	switch field.Type {
+	case VarintType:
+		if field.Varint, n = binary.Uvarint(b); n <= 0 {
+			return Field{}, nil, fmt.Errorf("%w: truncated varint in field %d", ErrMalformed, field.Num)
+		}
+		return field, b[n:], nil
+	case BytesType:
+		size, n := binary.Uvarint(b)
+		if n <= 0 || size > uint64(len(b)-n) {
+			return Field{}, nil, fmt.Errorf("%w: truncated bytes in field %d", ErrMalformed, field.Num)
+		}
+		field.Bytes = b[n : n+int(size)]
+		return field, b[n+int(size):], nil
+	case Fixed64Type:
+		if len(b) < 8 {
+			return Field{}, nil, fmt.Errorf("%w: truncated fixed64 in field %d", ErrMalformed, field.Num)
+		}
+		return field, b[8:], nil
+	case Fixed32Type:
+		if len(b) < 4 {
+			return Field{}, nil, fmt.Errorf("%w: truncated fixed32 in field %d", ErrMalformed, field.Num)
+		}
+		return field, b[4:], nil
+	default:
+		return Field{}, nil, fmt.Errorf("%w: unsupported wire type %d in field %d", ErrMalformed, field.Type, field.Num)
+	}
+}
+
+// Range calls fn for every field of the message b in order, stopping at the
+// first error.
+func Range(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		field, rest, err := Next(b)
+		if err != nil {
+			return err
+		}
+		if err := fn(field); err != nil {
+			return err
+		}
+		b = rest
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package interop implements the header exchange messages of the Quai wire
+// protocol (eth/66 style request ids over devp2p), so headers produced by this
+// package can be served to go-quai peers in integration tests.
+//
+// Only the message layer is implemented. Establishing the RLPx session, the
+// protocol handshake and peer discovery are left to the devp2p stack of the
+// test harness, which hands each session to Responder.Serve as a
+// MsgReadWriter.
+package interop
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// Message codes of the header exchange, matching the quai protocol.
+const (
+	GetBlockHeadersMsg = 0x03
+	BlockHeadersMsg    = 0x04
+)
+
+// MaxHeadersServe is the maximum number of headers served in reply to a
+// single request.
+const MaxHeadersServe = 1024
+
+var (
+	ErrUnexpectedMsg = errors.New("unexpected message code")
+	ErrInvalidOrigin = errors.New("invalid header request origin")
+)
+
+// Msg is a devp2p message: a code and its RLP encoded payload.
+type Msg struct {
+	Code    uint64
+	Payload []byte
+}
+
+// MsgReadWriter reads and writes the messages of a protocol session, like
+// p2p.MsgReadWriter of the devp2p stack.
+type MsgReadWriter interface {
+	ReadMsg() (Msg, error)
+	WriteMsg(Msg) error
+}
+
+// HashOrNumber is a combined field for specifying an origin block.
+type HashOrNumber struct {
+	Hash   common.Hash // Block hash from which to retrieve headers (excludes Number)
+	Number uint64      // Block number from which to retrieve headers (excludes Hash)
+}
+
+// EncodeRLP is a specialized encoder for HashOrNumber to encode only one of the
+// two contained union fields.
+func (hn *HashOrNumber) EncodeRLP(w io.Writer) error {
+	if hn.Hash == (common.Hash{}) {
+		return rlp.Encode(w, hn.Number)
+	}
+	if hn.Number != 0 {
+		return fmt.Errorf("%w: both hash (%x) and number (%d) provided", ErrInvalidOrigin, hn.Hash, hn.Number)
+	}
+	return rlp.Encode(w, hn.Hash)
+}
+
+// DecodeRLP is a specialized decoder for HashOrNumber to decode the contents
+// into either a block hash or a block number.
+func (hn *HashOrNumber) DecodeRLP(s *rlp.Stream) error {
+	_, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case size == common.HashLength:
+		hn.Number = 0
+		return s.Decode(&hn.Hash)
+	case size <= 8:
+		hn.Hash = common.Hash{}
+		hn.Number, err = s.Uint()
+		return err
+	default:
+		return fmt.Errorf("%w: input size %d", ErrInvalidOrigin, size)
+	}
+}
+
+// GetBlockHeadersPacket represents a block header query.
+type GetBlockHeadersPacket struct {
+	Origin  HashOrNumber // Block from which to retrieve headers
+	Amount  uint64       // Maximum number of headers to retrieve
+	Skip    uint64       // Blocks to skip between consecutive headers
+	Reverse bool         // Query direction (false = rising towards latest, true = falling towards genesis)
+}
+
+// GetBlockHeadersPacket66 represents a block header query with request id.
+type GetBlockHeadersPacket66 struct {
+	RequestId uint64
+	*GetBlockHeadersPacket
+}
+
+// BlockHeadersPacket66 represents a block header response with request id.
+type BlockHeadersPacket66 struct {
+	RequestId uint64
+	Headers   []*types.Header
+}
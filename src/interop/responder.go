@@ -0,0 +1,147 @@
+package interop
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// HeaderStore provides the headers served to peers.
+type HeaderStore interface {
+	// HeaderByHash returns the header with the given hash, or nil.
+	HeaderByHash(hash common.Hash) *types.Header
+
+	// HeaderByNumber returns the header at the given number in the context of
+	// the served chain, or nil.
+	HeaderByNumber(number uint64) *types.Header
+}
+
+// MemoryHeaderStore is a HeaderStore holding the headers of a single chain in
+// memory, indexed by hash and by their number in the chain's context.
+type MemoryHeaderStore struct {
+	ctx      common.Context
+	lock     sync.RWMutex
+	byHash   map[common.Hash]*types.Header
+	byNumber map[uint64]*types.Header
+}
+
+// NewMemoryHeaderStore creates an empty store for the chain of the given
+// context.
+func NewMemoryHeaderStore(ctx int) *MemoryHeaderStore {
+	return &MemoryHeaderStore{
+		ctx:      common.Context(ctx),
+		byHash:   make(map[common.Hash]*types.Header),
+		byNumber: make(map[uint64]*types.Header),
+	}
+}
+
+// Add inserts headers into the store, replacing any header at the same number.
+func (s *MemoryHeaderStore) Add(headers ...*types.Header) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, header := range headers {
+		number := header.NumberU64In(s.ctx)
+		if old, ok := s.byNumber[number]; ok {
+			delete(s.byHash, old.Hash())
+		}
+		s.byHash[header.Hash()] = header
+		s.byNumber[number] = header
+	}
+}
+
+// HeaderByHash implements HeaderStore.
+func (s *MemoryHeaderStore) HeaderByHash(hash common.Hash) *types.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.byHash[hash]
+}
+
+// HeaderByNumber implements HeaderStore.
+func (s *MemoryHeaderStore) HeaderByNumber(number uint64) *types.Header {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.byNumber[number]
+}
+
+// Responder answers the header queries of a peer from a HeaderStore.
+type Responder struct {
+	store HeaderStore
+	ctx   common.Context // Context of the served chain, numbering its headers
+}
+
+// NewResponder creates a responder serving the headers of the chain of the
+// given context from store.
+func NewResponder(store HeaderStore, ctx int) *Responder {
+	return &Responder{store: store, ctx: common.Context(ctx)}
+}
+
+// Serve answers the header queries read from rw until reading fails, such as
+// when the session is closed. Any message other than a header query is a
+// protocol violation and ends the session with ErrUnexpectedMsg.
+func (r *Responder) Serve(rw MsgReadWriter) error {
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Code != GetBlockHeadersMsg {
+			return fmt.Errorf("%w: 0x%02x", ErrUnexpectedMsg, msg.Code)
+		}
+		var query GetBlockHeadersPacket66
+		if err := rlp.DecodeBytes(msg.Payload, &query); err != nil {
+			return fmt.Errorf("invalid GetBlockHeaders: %w", err)
+		}
+		var payload bytes.Buffer
+		if err := rlp.Encode(&payload, &BlockHeadersPacket66{
+			RequestId: query.RequestId,
+			Headers:   r.answer(query.GetBlockHeadersPacket),
+		}); err != nil {
+			return err
+		}
+		if err := rw.WriteMsg(Msg{Code: BlockHeadersMsg, Payload: payload.Bytes()}); err != nil {
+			return err
+		}
+	}
+}
+
+// answer collects the headers matching a query, stopping at the first one
+// missing from the store.
+func (r *Responder) answer(query *GetBlockHeadersPacket) []*types.Header {
+	var (
+		headers []*types.Header
+		amount  = query.Amount
+	)
+	if amount > MaxHeadersServe {
+		amount = MaxHeadersServe
+	}
+	// Resolve the origin, afterwards the query walks by number
+	var header *types.Header
+	if query.Origin.Hash != (common.Hash{}) {
+		header = r.store.HeaderByHash(query.Origin.Hash)
+	} else {
+		header = r.store.HeaderByNumber(query.Origin.Number)
+	}
+	step := query.Skip + 1
+	for header != nil && uint64(len(headers)) < amount {
+		headers = append(headers, header)
+
+		number := header.NumberU64In(r.ctx)
+		if query.Reverse {
+			if number < step {
+				break
+			}
+			header = r.store.HeaderByNumber(number - step)
+		} else {
+			if number+step < number {
+				break // overflow, e.g. by a hostile skip
+			}
+			header = r.store.HeaderByNumber(number + step)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// contextKey is the key under which a Logger is stored in a context.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the logger l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the global logger if there
+// is none.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
+	}
+	return Log
+}
+
+// WithTraceID returns a copy of ctx whose logger tags every message with the
+// given trace ID, so all log lines of a single request can be correlated.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return NewContext(ctx, FromContext(ctx).With("trace", id))
+}
+
+// NewTraceID returns a random 16 character hexadecimal trace ID.
+func NewTraceID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
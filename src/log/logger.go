@@ -2,81 +2,226 @@ package log
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
-	"github.com/natefinch/lumberjack"
 	"github.com/sirupsen/logrus"
 )
 
 type Logger struct {
 	*logrus.Logger
+	fields []interface{} // Key-value pairs prepended to every message
 }
 
-var Log Logger = Logger{logrus.New()}
+var Log Logger = Logger{Logger: logrus.New()}
 
+// Log line formats.
+const (
+	FormatText = "text" // Human readable lines, key-value pairs after the message
+	FormatJSON = "json" // One JSON object per line, key-value pairs as fields
+)
+
+// Output targets besides file paths.
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Options configures the destination of a Logger.
+type Options struct {
+	// Output receives the log lines. If nil, a rotating file at Filename is
+	// used instead.
+	Output io.Writer
+	// JSON writes log lines as JSON objects rather than text.
+	JSON bool
+
+	// Rotation settings for file output. Zero values select the defaults.
+	Filename   string
+	MaxSize    int // megabytes
+	MaxBackups int
+	MaxAge     int // days
+}
+
+// New creates a logger writing to a rotating file at out_path.
 func New(out_path string) Logger {
+	return NewWithOptions(Options{Filename: out_path})
+}
+
+// NewWithWriter creates a logger writing to w, e.g. os.Stdout or a journald
+// connection.
+func NewWithWriter(w io.Writer) Logger {
+	return NewWithOptions(Options{Output: w})
+}
+
+// NewWithOptions creates a logger with the given output configuration.
+func NewWithOptions(opts Options) Logger {
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 500
+	}
+	if opts.MaxBackups == 0 {
+		opts.MaxBackups = 3
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = 28
+	}
+	out := opts.Output
+	if out == nil {
+		out = rotatingWriter(opts)
+	}
 	logger := logrus.New()
-	logger.SetOutput(&lumberjack.Logger{
-		Filename:   out_path,
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     28, //days
-	})
-	return Logger{logger}
+	logger.SetOutput(out)
+	if opts.JSON {
+		logger.SetFormatter(new(logrus.JSONFormatter))
+	}
+	return Logger{Logger: logger}
+}
+
+// SetLevel sets the lowest level the global logger writes, one of "trace",
+// "debug", "info", "warn", "error", "fatal" or "panic".
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	Log.Logger.SetLevel(lvl)
+	return nil
+}
+
+// SetFormat switches the global logger to FormatText or FormatJSON lines.
+func SetFormat(format string) error {
+	switch format {
+	case FormatText:
+		Log.Logger.SetFormatter(new(logrus.TextFormatter))
+	case FormatJSON:
+		Log.Logger.SetFormatter(new(logrus.JSONFormatter))
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	return nil
+}
+
+// SetOutput directs the global logger to OutputStdout, OutputStderr, or any
+// other target taken as the path of a rotating log file. Without a
+// filesystem, as on js/wasm, file targets fall back to stderr.
+func SetOutput(target string) {
+	switch target {
+	case OutputStdout:
+		Log.Logger.SetOutput(os.Stdout)
+	case OutputStderr:
+		Log.Logger.SetOutput(os.Stderr)
+	default:
+		Log.Logger.SetOutput(NewWithOptions(Options{Filename: target}).Out)
+	}
+}
+
+// With returns a copy of the logger which adds the given key-value pairs to
+// every message it logs.
+func (l Logger) With(args ...interface{}) Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(args))
+	fields = append(fields, l.fields...)
+	fields = append(fields, args...)
+	return Logger{Logger: l.Logger, fields: fields}
+}
+
+// withFields prepends the logger's own key-value pairs to args.
+func (l Logger) withFields(args []interface{}) []interface{} {
+	if len(l.fields) == 0 {
+		return args
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(args))
+	fields = append(fields, l.fields...)
+	return append(fields, args...)
 }
 
 // Uses of the global logger will use the following static method.
 func Trace(msg string, args ...interface{}) {
-	Log.Trace(constructLogMessage(msg, args...))
+	Log.log(logrus.TraceLevel, msg, args)
 }
 
 // Individual logging instances will use the following method.
 func (l Logger) Trace(msg string, args ...interface{}) {
-	l.Logger.Trace(constructLogMessage(msg, args...))
+	l.log(logrus.TraceLevel, msg, l.withFields(args))
 }
 
 func Debug(msg string, args ...interface{}) {
-	Log.Debug(constructLogMessage(msg, args...))
+	Log.log(logrus.DebugLevel, msg, args)
 }
+
 func (l Logger) Debug(msg string, args ...interface{}) {
-	l.Logger.Debug(constructLogMessage(msg, args...))
+	l.log(logrus.DebugLevel, msg, l.withFields(args))
 }
 
 func Info(msg string, args ...interface{}) {
-	Log.Info(constructLogMessage(msg, args...))
+	Log.log(logrus.InfoLevel, msg, args)
 }
+
 func (l Logger) Info(msg string, args ...interface{}) {
-	l.Logger.Info(constructLogMessage(msg, args...))
+	l.log(logrus.InfoLevel, msg, l.withFields(args))
 }
 
 func Warn(msg string, args ...interface{}) {
-	Log.Warn(constructLogMessage(msg, args...))
+	Log.log(logrus.WarnLevel, msg, args)
 }
+
 func (l Logger) Warn(msg string, args ...interface{}) {
-	l.Logger.Warn(constructLogMessage(msg, args...))
+	l.log(logrus.WarnLevel, msg, l.withFields(args))
 }
 
 func Error(msg string, args ...interface{}) {
-	Log.Error(constructLogMessage(msg, args...))
+	Log.log(logrus.ErrorLevel, msg, args)
 }
+
 func (l Logger) Error(msg string, args ...interface{}) {
-	l.Logger.Error(constructLogMessage(msg, args...))
+	l.log(logrus.ErrorLevel, msg, l.withFields(args))
 }
 
 func Fatal(msg string, args ...interface{}) {
-	Log.Fatal(constructLogMessage(msg, args...))
+	Log.log(logrus.FatalLevel, msg, args)
 }
+
 func (l Logger) Fatal(msg string, args ...interface{}) {
-	l.Logger.Fatal(constructLogMessage(msg, args...))
+	l.log(logrus.FatalLevel, msg, l.withFields(args))
 }
 
 func Panic(msg string, args ...interface{}) {
-	Log.Panic(constructLogMessage(msg, args...))
+	Log.log(logrus.PanicLevel, msg, args)
 }
+
 func (l Logger) Panic(msg string, args ...interface{}) {
-	l.Logger.Panic(constructLogMessage(msg, args...))
+	l.log(logrus.PanicLevel, msg, l.withFields(args))
+}
+
+// log writes a message with its key-value pairs at the given level. Loggers
+// formatting JSON carry the pairs as fields, others append them to the text.
+// Fatal messages exit and panic messages panic after being written.
+func (l Logger) log(level logrus.Level, msg string, fields []interface{}) {
+	if !l.Logger.IsLevelEnabled(level) {
+		return
+	}
+	if _, ok := l.Logger.Formatter.(*logrus.JSONFormatter); ok {
+		entry := l.Logger.WithFields(constructLogFields(fields...))
+		if lineInfo := reportLineNumber(2); lineInfo != "" {
+			entry = entry.WithField("caller", lineInfo)
+		}
+		write(entry, level, msg)
+		return
+	}
+	write(logrus.NewEntry(l.Logger), level, constructLogMessage(msg, fields...))
+}
+
+// write logs msg through entry, exiting or panicking as the level requires.
+func write(entry *logrus.Entry, level logrus.Level, msg string) {
+	switch level {
+	case logrus.FatalLevel:
+		entry.Fatal(msg)
+	case logrus.PanicLevel:
+		entry.Panic(msg)
+	default:
+		entry.Log(level, msg)
+	}
 }
 
 func reportLineNumber(skiplevel int) string {
@@ -91,10 +236,26 @@ func reportLineNumber(skiplevel int) string {
 	return fmt.Sprintf("%s:%d", fileAndDir, line)
 }
 
+// constructLogFields converts key-value pairs into logrus fields.
+func constructLogFields(fields ...interface{}) logrus.Fields {
+	result := make(logrus.Fields, len(fields)/2)
+	if len(fields) == 1 {
+		// Sometimes we want to log a single string,
+		return result
+	}
+	if len(fields)%2 != 0 {
+		fields = append(fields, "MISSING VALUE")
+	}
+	for i := 0; i < len(fields); i += 2 {
+		result[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	return result
+}
+
 func constructLogMessage(msg string, fields ...interface{}) string {
 	var pairs []string
 
-	lineInfo := reportLineNumber(2)
+	lineInfo := reportLineNumber(3)
 
 	if len(fields) != 1 {
 		// Sometimes we want to log a single string,
@@ -0,0 +1,20 @@
+//go:build !js
+// +build !js
+
+package log
+
+import (
+	"io"
+
+	"github.com/natefinch/lumberjack"
+)
+
+// rotatingWriter returns a size and age based rotating file writer.
+func rotatingWriter(opts Options) io.Writer {
+	return &lumberjack.Logger{
+		Filename:   opts.Filename,
+		MaxSize:    opts.MaxSize,
+		MaxBackups: opts.MaxBackups,
+		MaxAge:     opts.MaxAge,
+	}
+}
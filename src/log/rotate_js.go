@@ -0,0 +1,15 @@
+//go:build js
+// +build js
+
+package log
+
+import (
+	"io"
+	"os"
+)
+
+// rotatingWriter falls back to stderr, which the js/wasm runtime forwards to
+// the console, as there is no filesystem to write log files to.
+func rotatingWriter(opts Options) io.Writer {
+	return os.Stderr
+}
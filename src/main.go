@@ -1,33 +1,818 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Command progpow-verification-wasm exposes seal verification to JavaScript.
+// Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o progpow.wasm .
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/misc/wasm (or
+// lib/wasm on newer releases). Browser extensions running verification in
+// their service worker build with -tags extension instead, which keeps cache
+// generation yielding to the event loop even if configure disables yielding,
+// rather than blocking the worker for seconds. Neither build evaluates code at
+// runtime, so the extension only needs 'wasm-unsafe-eval' in its content
+// security policy to instantiate the module. Once running, it registers the following global
+// functions, all returning promises:
+//
+//	verifySeal(header, options)      -> {valid, powHash, error}
+//	computePowLight(header, options) -> {mixHash, powHash}
+//	sealHash(header)                 -> "0x..."
+//	seal(header, options)            -> {nonce, mixHash, header}
+//	configure(options)               -> memoryInfo()
+//	memoryInfo()                     -> {sysBytes, heapBytes, ceilingBytes, cacheBytes, guardedBytes}
+//	bench(options)                   -> {platform, mode, tags, time, results}
+//	importCache(data, options)       -> null
+//	params(options)                  -> {powMode, epochLength, timeFactor, ...}
+//
+// A panic inside any of them is recovered and rejects the promise with an
+// Error named PanicError, whose function and panic properties tell where and
+// what; the stack trace is logged. The module keeps serving later calls.
+//
+// In browsers, generated verification caches are persisted in IndexedDB, so
+// later page loads skip regenerating them.
+//
+// Hosts without IndexedDB, or preferring their own persistence, such as
+// Electron, React Native or extensions, pass configure({storage}) an object
+// with async get, put and delete methods (see package hoststore) before the
+// first verification.
+//
+// Calling configure({preallocate: true}) before the first verification creates
+// the engine up front and grows the WASM memory to the size cache generation
+// needs in one go, rather than in many pauses during the first verification.
+// It accepts the test option like the other functions. memoryInfo reports the
+// memory the module holds, the ceiling it may grow to, the heap a cache takes
+// to generate and the bytes counted against the memory ceiling of the engines.
+//
+// The verification caches of both engines are capped at half the addressable
+// memory, or at configure({memoryCeiling}) bytes. Verifications needing a
+// cache beyond the ceiling evict the least recently used ones, or reject their
+// promise with a "memory ceiling reached" error if that does not make room,
+// rather than aborting the module once its memory cannot grow further.
+//
+// While a verification cache is generated, the global function
+// onCacheProgress(epoch, pct), if the page defines one, is called with the
+// percentage generated so far, so the page can render a progress bar.
+//
+// Cache generation and batch verification yield to the event loop every few
+// dozen milliseconds, through scheduler.yield where available and setTimeout
+// otherwise, so the page stays responsive during multi-second work. Workers
+// nothing else runs in can opt out with configure({yield: false}).
+//
+// verifySeal and computePowLight take an AbortSignal as options.signal, such
+// as AbortSignal.timeout(ms), to bound how long they wait for a verification
+// cache to be generated. Once it aborts, the promise rejects and the
+// generation stops, unless other calls still wait for it.
+//
+// bench runs the benchmark suite of the engine, each operation for at least
+// options.duration milliseconds (one second by default), and resolves with a
+// report in the format of quai-verify bench report, labelled with the string
+// properties of options.tags, such as the browser. Reports of the module and
+// of native builds can thus be aggregated and compared.
+//
+// importCache installs the verification cache of epoch options.epoch (zero by
+// default) exported by ExportCache of a native engine, passed as a Uint8Array,
+// so the page skips generating it. Exports failing their checks reject the
+// promise.
+//
+// params resolves with the consensus parameters of the engine, in the format
+// of quai-verify params and of the /params endpoint of quai-verify serve, so
+// frontends need not hardcode values such as the epoch length or the time
+// factor the order thresholds scale with.
+//
+// Headers are passed either as RLP encoded hex strings, or as header objects
+// (or their JSON text) in the format returned by the node's JSON-RPC API, such
+// as the result of quai_getHeaderByNumber; sealHash also accepts JSON headers
+// without mixHash and nonce, as miners building work packages have them. The
+// options object is optional; {test: true} selects the tiny test-mode
+// verification cache used by devnets.
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
 
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/hoststore"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
 	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
 	"github.com/dominant-strategies/progpow-verification-wasm/types"
 )
 
+var (
+	errUnsupportedHeader  = errors.New("header must be an RLP encoded hex string or a JSON-RPC header object")
+	errInvalidCeiling     = errors.New("memoryCeiling must be a positive number of bytes")
+	errInvalidCacheBudget = errors.New("maxCacheBytes must be a positive number of bytes")
+	errInvalidLogOutput   = errors.New(`logOutput must be "stdout" or "stderr"`)
+	errSealAborted        = errors.New("sealing aborted")
+	errCallAborted        = errors.New("aborted by signal")
+	errInvalidCacheData   = errors.New("cache export must be a Uint8Array")
+	errInvalidPrefixes    = errors.New("shardPrefixes must map zone names to [lo, hi] byte ranges")
+)
+
+// engines holds the lazily created verification engines per mode, shared by
+// all calls so epoch caches are generated only once.
+var engines struct {
+	lock   sync.Mutex
+	normal *progpow.Progpow
+	test   *progpow.Progpow
+
+	preallocate bool                  // Reserve cache memory when creating an engine
+	guard       *progpow.MemoryGuard  // Memory ceiling of the engines, created on first use
+	noYield     bool                  // Run long computations without yielding to the event loop
+	hostStore   *hoststore.CacheStore // Cache store on storage supplied by the host, if any
+	maxCache    uint64                // Total size of the caches each engine keeps, zero if unbounded
+
+	storeOnce sync.Once
+	store     *progpow.IndexedDBCacheStore
+}
+
+// indexedDBName is the IndexedDB database verification caches are kept in.
+const indexedDBName = "progpow-verification"
+
 func main() {
-	// Initialize a Progpow instance
-	// Note: This example assumes Progpow doesn't require special initialization.
-	// You might need to provide configuration or other dependencies here.
-	progpowInstance := progpow.Progpow{}
+	export("verifySeal", verifySeal)
+	export("computePowLight", computePowLight)
+	export("sealHash", sealHash)
+	export("seal", seal)
+	export("configure", configure)
+	export("memoryInfo", memoryInfo)
+	export("bench", bench)
+	export("importCache", importCache)
+	export("params", params)
+
+	// Keep the exported functions alive for the lifetime of the page
+	select {}
+}
+
+// verifySeal checks the seal of a header. Malformed input, or verification
+// shed for lack of memory, rejects the promise, a header failing verification
+// resolves it with valid set to false. Passing an AbortSignal as the signal
+// option, such as AbortSignal.timeout(ms), rejects the promise once it aborts
+// while the verification cache is generated, and stops the generation unless
+// other calls wait for it.
+func verifySeal(this js.Value, args []js.Value) interface{} {
+	return promise("verifySeal", func() (interface{}, error) {
+		header, engine, err := parseArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := signalContext(args)
+		defer cancel()
+
+		result := map[string]interface{}{"valid": false}
+		powHash, err := engine.VerifySealCtx(ctx, header)
+		if errors.Is(err, context.Canceled) {
+			return nil, errCallAborted
+		}
+		if errors.Is(err, progpow.ErrOverloaded) {
+			return nil, err
+		}
+		if err != nil {
+			result["error"] = err.Error()
+		} else {
+			result["valid"] = true
+			result["powHash"] = powHash.Hex()
+		}
+		return result, nil
+	})
+}
+
+// computePowLight computes the mixHash and powHash of a header. It takes the
+// signal option like verifySeal.
+func computePowLight(this js.Value, args []js.Value) interface{} {
+	return promise("computePowLight", func() (interface{}, error) {
+		header, engine, err := parseArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := signalContext(args)
+		defer cancel()
+
+		mixHash, powHash, err := engine.ComputePowLightCtx(ctx, header)
+		if err != nil {
+			return nil, errCallAborted
+		}
+		return map[string]interface{}{
+			"mixHash": mixHash.Hex(),
+			"powHash": powHash.Hex(),
+		}, nil
+	})
+}
+
+// sealHash computes the hash a header is sealed over.
+func sealHash(this js.Value, args []js.Value) interface{} {
+	return promise("sealHash", func() (interface{}, error) {
+		if len(args) == 0 {
+			return nil, errUnsupportedHeader
+		}
+		header, err := decodeHeader(args[0], false)
+		if err != nil {
+			return nil, err
+		}
+		return header.SealHash().Hex(), nil
+	})
+}
+
+// seal searches for a nonce sealing a header at its difficulty and resolves
+// with the seal found, along with the RLP encoded sealed header. Passing an
+// AbortSignal as the signal option stops the search and rejects the promise.
+// Sealing uses the light verification path, so it is only practical at the
+// difficulties of testnet demos.
+func seal(this js.Value, args []js.Value) interface{} {
+	return promise("seal", func() (interface{}, error) {
+		if len(args) == 0 {
+			return nil, errUnsupportedHeader
+		}
+		header, err := decodeHeader(args[0], false)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			test   bool
+			signal = js.Undefined()
+		)
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			test = args[1].Get("test").Truthy()
+			signal = args[1].Get("signal")
+		}
+		engine, err := engine(test)
+		if err != nil {
+			return nil, err
+		}
+		stop := make(chan struct{})
+		if signal.Type() == js.TypeObject {
+			if signal.Get("aborted").Truthy() {
+				return nil, errSealAborted
+			}
+			var once sync.Once
+			abort := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				once.Do(func() { close(stop) })
+				return nil
+			})
+			defer abort.Release()
+			signal.Call("addEventListener", "abort", abort)
+			defer signal.Call("removeEventListener", "abort", abort)
+		}
+		results := make(chan *types.Header, 1)
+		if err := engine.Seal(header, stop, results); err != nil {
+			return nil, err
+		}
+		select {
+		case sealed := <-results:
+			var enc bytes.Buffer
+			if err := rlp.Encode(&enc, sealed); err != nil {
+				return nil, err
+			}
+			nonce := sealed.Nonce()
+			return map[string]interface{}{
+				"nonce":   hexutil.Encode(nonce[:]),
+				"mixHash": sealed.MixHash().Hex(),
+				"header":  hexutil.Encode(enc.Bytes()),
+			}, nil
+		case <-stop:
+			return nil, errSealAborted
+		}
+	})
+}
+
+// wasmCeiling is the most linear memory a wasm32 module can address. Go does
+// not declare a lower maximum for its memory, though browsers may refuse to
+// grow it that far.
+const wasmCeiling = 4 << 30
+
+// defaultMemoryCeiling caps the verification caches when configure sets no
+// ceiling. The rest of the memory is left to the garbage collector, which
+// needs room to spare to reclaim evicted caches.
+const defaultMemoryCeiling = wasmCeiling / 2
+
+// configure sets the options engines are created with. Engines are created
+// lazily, so it only affects engines not used yet; with preallocate set, it
+// creates the engine of the selected mode right away. A storage object, see
+// package hoststore, replaces IndexedDB for persisting caches. Setting yield
+// to false lets cache generation and batch verification run without yielding
+// to the event loop, which is faster in workers nothing else runs in. A
+// memoryCeiling in bytes replaces the default cap on verification caches, and
+// maxCacheBytes bounds the caches each engine keeps, evicting the least
+// recently used ones rather than rejecting verifications.
+// logLevel, logFormat ("text" or "json") and logOutput ("stdout" or
+// "stderr", console.log and console.error respectively) configure logging,
+// regions and zones the shape of the network headers are verified for, and
+// shardPrefixes the first address bytes of every zone, as an object of
+// [lo, hi] ranges by zone name, all taking effect right away. Options left out
+// keep the values of earlier calls; a null storage restores IndexedDB.
+func configure(this js.Value, args []js.Value) interface{} {
+	return promise("configure", func() (interface{}, error) {
+		var (
+			test        bool
+			preallocate *bool
+			noYield     *bool
+			hostStore   **hoststore.CacheStore
+			guard       *progpow.MemoryGuard
+			maxCache    *uint64
+		)
+		if len(args) > 0 && args[0].Type() == js.TypeObject {
+			test = args[0].Get("test").Truthy()
+			if option := args[0].Get("preallocate"); !option.IsUndefined() {
+				value := option.Truthy()
+				preallocate = &value
+			}
+			if yield := args[0].Get("yield"); !yield.IsUndefined() {
+				value := !yield.Truthy()
+				noYield = &value
+			}
+			if err := configureLogging(args[0]); err != nil {
+				return nil, err
+			}
+			if err := configureHierarchy(args[0]); err != nil {
+				return nil, err
+			}
+			if err := configureShardPrefixes(args[0]); err != nil {
+				return nil, err
+			}
+			if ceiling := args[0].Get("memoryCeiling"); ceiling.Type() == js.TypeNumber {
+				if ceiling.Float() <= 0 {
+					return nil, errInvalidCeiling
+				}
+				guard = progpow.NewMemoryGuard(uint64(ceiling.Float()))
+			}
+			if budget := args[0].Get("maxCacheBytes"); budget.Type() == js.TypeNumber {
+				if budget.Float() <= 0 {
+					return nil, errInvalidCacheBudget
+				}
+				value := uint64(budget.Float())
+				maxCache = &value
+			}
+			if storage := args[0].Get("storage"); !storage.IsUndefined() {
+				var store *hoststore.CacheStore
+				if !storage.IsNull() {
+					bridge, err := hoststore.NewBridge(storage)
+					if err != nil {
+						return nil, err
+					}
+					store = hoststore.NewCacheStore(bridge)
+				}
+				hostStore = &store
+			}
+		}
+		engines.lock.Lock()
+		if preallocate != nil {
+			engines.preallocate = *preallocate
+		}
+		if noYield != nil {
+			engines.noYield = *noYield
+		}
+		if hostStore != nil {
+			engines.hostStore = *hostStore
+		}
+		if maxCache != nil {
+			engines.maxCache = *maxCache
+		}
+		if guard != nil {
+			engines.guard = guard
+		}
+		engines.lock.Unlock()
+
+		if preallocate != nil && *preallocate {
+			if _, err := engine(test); err != nil {
+				return nil, err
+			}
+		}
+		return memoryStats(test)
+	})
+}
+
+// configureHierarchy applies the regions and zones options of configure,
+// keeping the current count of whichever is not given.
+func configureHierarchy(options js.Value) error {
+	params := common.Hierarchy()
+	if regions := options.Get("regions"); regions.Type() == js.TypeNumber {
+		params.Regions = regions.Int()
+	}
+	if zones := options.Get("zones"); zones.Type() == js.TypeNumber {
+		params.Zones = zones.Int()
+	}
+	return common.SetHierarchy(params.Regions, params.Zones)
+}
+
+// configureShardPrefixes applies the shardPrefixes option of configure, null
+// restoring the prefix ranges of the Quai networks.
+func configureShardPrefixes(options js.Value) error {
+	ranges := options.Get("shardPrefixes")
+	if ranges.IsUndefined() {
+		return nil
+	}
+	if ranges.IsNull() {
+		return common.SetShardPrefixes(nil)
+	}
+	if ranges.Type() != js.TypeObject {
+		return errInvalidPrefixes
+	}
+	keys := js.Global().Get("Object").Call("keys", ranges)
+	prefixes := make(map[string]common.PrefixRange, keys.Length())
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		r := ranges.Get(name)
+		if !js.Global().Get("Array").Call("isArray", r).Bool() || r.Length() != 2 {
+			return errInvalidPrefixes
+		}
+		lo, hi := r.Index(0), r.Index(1)
+		if lo.Type() != js.TypeNumber || hi.Type() != js.TypeNumber {
+			return errInvalidPrefixes
+		}
+		if lo.Int() < 0 || lo.Int() > 255 || hi.Int() < 0 || hi.Int() > 255 {
+			return errInvalidPrefixes
+		}
+		prefixes[name] = common.PrefixRange{Lo: uint8(lo.Int()), Hi: uint8(hi.Int())}
+	}
+	return common.SetShardPrefixes(prefixes)
+}
+
+// configureLogging applies the logging options of configure.
+func configureLogging(options js.Value) error {
+	if level := options.Get("logLevel"); level.Type() == js.TypeString {
+		if err := log.SetLevel(level.String()); err != nil {
+			return err
+		}
+	}
+	if format := options.Get("logFormat"); format.Type() == js.TypeString {
+		if err := log.SetFormat(format.String()); err != nil {
+			return err
+		}
+	}
+	if output := options.Get("logOutput"); output.Type() == js.TypeString {
+		switch target := output.String(); target {
+		case log.OutputStdout, log.OutputStderr:
+			log.SetOutput(target)
+		default:
+			return errInvalidLogOutput
+		}
+	}
+	return nil
+}
+
+// memoryInfo reports the memory held by the module.
+func memoryInfo(this js.Value, args []js.Value) interface{} {
+	return promise("memoryInfo", func() (interface{}, error) {
+		test := len(args) > 0 && args[0].Type() == js.TypeObject && args[0].Get("test").Truthy()
+		return memoryStats(test)
+	})
+}
+
+// memoryStats reports the memory held by the module, along with the heap an
+// engine of the given mode needs to generate a verification cache. The sizing
+// engine is a throwaway, so reporting does not create the shared engines with
+// options configure has yet to set.
+func memoryStats(test bool) (interface{}, error) {
+	engines.lock.Lock()
+	config := progpow.Config{CacheStore: cacheStore()}
+	guarded := memoryGuard().Usage()
+	engines.lock.Unlock()
 
-	// Create a types.Header instance for demonstration purposes
-	// You'll need to fill this with actual data relevant to your application
-	header := &types.Header{
-		// Populate the fields as necessary
+	if test {
+		config.PowMode = progpow.ModeTest
+	}
+	engine, err := progpow.New(config)
+	if err != nil {
+		return nil, err
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return map[string]interface{}{
+		"sysBytes":     stats.Sys,
+		"heapBytes":    stats.HeapSys,
+		"ceilingBytes": wasmCeiling,
+		"cacheBytes":   engine.CacheGenerationBytes(0),
+		"guardedBytes": guarded,
+	}, nil
+}
+
+// bench runs the benchmark suite and reports the timings.
+func bench(this js.Value, args []js.Value) interface{} {
+	return promise("bench", func() (interface{}, error) {
+		var (
+			test     bool
+			duration time.Duration
+			tags     map[string]string
+		)
+		if len(args) > 0 && args[0].Type() == js.TypeObject {
+			options := args[0]
+			test = options.Get("test").Truthy()
+			if ms := options.Get("duration"); ms.Type() == js.TypeNumber {
+				duration = time.Duration(ms.Float() * float64(time.Millisecond))
+			}
+			if labels := options.Get("tags"); labels.Type() == js.TypeObject {
+				keys := js.Global().Get("Object").Call("keys", labels)
+				tags = make(map[string]string, keys.Length())
+				for i := 0; i < keys.Length(); i++ {
+					key := keys.Index(i).String()
+					tags[key] = labels.Get(key).String()
+				}
+			}
+		}
+		engine, err := engine(test)
+		if err != nil {
+			return nil, err
+		}
+		report, err := engine.Bench(duration, tags)
+		if err != nil {
+			return nil, err
+		}
+		// Round-trip through JSON, so the report takes the same shape as
+		// those of native builds
+		return jsonValue(report)
+	})
+}
+
+// jsonValue converts v to the plain values js.ValueOf accepts, by way of its
+// JSON encoding.
+func jsonValue(v interface{}) (interface{}, error) {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
 	}
+	var result interface{}
+	if err := json.Unmarshal(enc, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// params reports the consensus parameters of the engine.
+func params(this js.Value, args []js.Value) interface{} {
+	return promise("params", func() (interface{}, error) {
+		test := len(args) > 0 && args[0].Type() == js.TypeObject && args[0].Get("test").Truthy()
+		engine, err := engine(test)
+		if err != nil {
+			return nil, err
+		}
+		return jsonValue(engine.Params())
+	})
+}
+
+// importCache installs an exported verification cache.
+func importCache(this js.Value, args []js.Value) interface{} {
+	return promise("importCache", func() (interface{}, error) {
+		if len(args) == 0 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+			return nil, errInvalidCacheData
+		}
+		data := make([]byte, args[0].Length())
+		js.CopyBytesToGo(data, args[0])
+
+		var (
+			test  bool
+			epoch uint64
+		)
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			options := args[1]
+			test = options.Get("test").Truthy()
+			if e := options.Get("epoch"); e.Type() == js.TypeNumber {
+				epoch = uint64(e.Int())
+			}
+		}
+		engine, err := engine(test)
+		if err != nil {
+			return nil, err
+		}
+		return nil, engine.ImportCache(epoch, data)
+	})
+}
 
-	// Call VerifySeal
-	powHash, err := progpowInstance.VerifySeal(header)
+// parseArgs decodes the header and options arguments of a call and returns
+// the engine selected by the options.
+func parseArgs(args []js.Value) (*types.Header, *progpow.Progpow, error) {
+	if len(args) == 0 {
+		return nil, nil, errUnsupportedHeader
+	}
+	header, err := decodeHeader(args[0], true)
+	if err != nil {
+		return nil, nil, err
+	}
+	test := false
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		test = args[1].Get("test").Truthy()
+	}
+	engine, err := engine(test)
 	if err != nil {
-		fmt.Println("VerifySeal error:", err)
+		return nil, nil, err
+	}
+	return header, engine, nil
+}
+
+// signalContext returns a context cancelled once the AbortSignal passed as the
+// signal option of a call aborts, along with the function releasing it.
+func signalContext(args []js.Value) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if len(args) < 2 || args[1].Type() != js.TypeObject {
+		return ctx, cancel
+	}
+	signal := args[1].Get("signal")
+	if signal.Type() != js.TypeObject {
+		return ctx, cancel
+	}
+	if signal.Get("aborted").Truthy() {
+		cancel()
+		return ctx, cancel
+	}
+	abort := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		cancel()
+		return nil
+	})
+	signal.Call("addEventListener", "abort", abort)
+	return ctx, func() {
+		signal.Call("removeEventListener", "abort", abort)
+		abort.Release()
+		cancel()
+	}
+}
+
+// decodeHeader decodes a header passed from JavaScript and checks it is well
+// formed. Unless sealed is set, JSON headers may omit the seal fields.
+func decodeHeader(v js.Value, sealed bool) (*types.Header, error) {
+	var input string
+	switch v.Type() {
+	case js.TypeString:
+		input = strings.TrimSpace(v.String())
+	case js.TypeObject:
+		input = js.Global().Get("JSON").Call("stringify", v).String()
+	default:
+		return nil, errUnsupportedHeader
+	}
+	header := new(types.Header)
+	if strings.HasPrefix(input, "{") {
+		unmarshal := header.UnmarshalJSON
+		if !sealed {
+			unmarshal = header.UnmarshalUnsealedJSON
+		}
+		if err := unmarshal([]byte(input)); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if header, err = types.DecodeHeaderRLP(common.FromHex(input)); err != nil {
+			return nil, err
+		}
+	}
+	if err := header.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// engine returns the shared engine of the given mode, creating it on first use.
+func engine(test bool) (*progpow.Progpow, error) {
+	engines.lock.Lock()
+	defer engines.lock.Unlock()
+
+	var err error
+	if test {
+		if engines.test == nil {
+			engines.test, err = progpow.New(progpow.Config{PowMode: progpow.ModeTest, CacheStore: cacheStore(), OnCacheProgress: cacheProgress, Preallocate: engines.preallocate, Yield: yieldHook(), MemoryGuard: memoryGuard(), MaxCacheBytes: engines.maxCache})
+		}
+		return engines.test, err
+	}
+	if engines.normal == nil {
+		engines.normal, err = progpow.New(progpow.Config{CacheStore: cacheStore(), OnCacheProgress: cacheProgress, Preallocate: engines.preallocate, Yield: yieldHook(), MemoryGuard: memoryGuard(), MaxCacheBytes: engines.maxCache})
+	}
+	return engines.normal, err
+}
+
+// cacheStore returns the store persisting verification caches across page
+// loads, shared by both engines: the storage supplied by the host through
+// configure if any, or else IndexedDB, opened on first use. It returns nil
+// where neither is available, leaving caches in memory only. The caller must
+// hold engines.lock.
+func cacheStore() progpow.CacheStore {
+	if engines.hostStore != nil {
+		return engines.hostStore
+	}
+	engines.storeOnce.Do(func() {
+		store, err := progpow.NewIndexedDBCacheStore(indexedDBName)
+		if err != nil {
+			return
+		}
+		engines.store = store
+	})
+	if engines.store == nil {
+		return nil
+	}
+	return engines.store
+}
+
+// memoryGuard returns the guard capping the caches of the engines, creating it
+// with the default ceiling if configure set none. The caller must hold
+// engines.lock.
+func memoryGuard() *progpow.MemoryGuard {
+	if engines.guard == nil {
+		engines.guard = progpow.NewMemoryGuard(defaultMemoryCeiling)
+	}
+	return engines.guard
+}
+
+// yieldHook returns the hook long computations yield through, or nil if the
+// page disabled yielding. The caller must hold engines.lock.
+func yieldHook() func() {
+	if engines.noYield {
+		return nil
+	}
+	return yieldToEventLoop
+}
+
+// yieldToEventLoop blocks the calling goroutine until the JavaScript event
+// loop had a turn, awaiting scheduler.yield where the browser supports it, so
+// the computation resumes ahead of other queued tasks, and setTimeout(0)
+// otherwise. Blocking the goroutine hands control back to the event loop once
+// no other goroutine is runnable.
+func yieldToEventLoop() {
+	done := make(chan struct{})
+	resume := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		close(done)
+		return nil
+	})
+	defer resume.Release()
+
+	global := js.Global()
+	if scheduler := global.Get("scheduler"); scheduler.Type() == js.TypeObject && scheduler.Get("yield").Type() == js.TypeFunction {
+		scheduler.Call("yield").Call("then", resume)
 	} else {
-		fmt.Println("VerifySeal success, powHash:", powHash)
+		global.Call("setTimeout", resume, 0)
 	}
+	<-done
+}
+
+// cacheProgress forwards cache generation progress to the onCacheProgress
+// function of the page, looked up on every call so it can be installed at any
+// time.
+func cacheProgress(epoch uint64, pct float64) {
+	if fn := js.Global().Get("onCacheProgress"); fn.Type() == js.TypeFunction {
+		fn.Invoke(epoch, pct)
+	}
+}
+
+// export registers fn as the global function name behind a recover boundary,
+// so a panic while it runs rejects the promise it returns, rather than
+// terminating the module for the whole page.
+func export(name string, fn func(this js.Value, args []js.Value) interface{}) {
+	js.Global().Set(name, js.FuncOf(func(this js.Value, args []js.Value) (result interface{}) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = js.Global().Get("Promise").Call("reject", panicError(name, r))
+			}
+		}()
+		return fn(this, args)
+	}))
+}
+
+// panicError logs a panic recovered in the exported function name along with
+// its stack trace, and converts it into a JavaScript error named PanicError,
+// carrying the function and the panic value in its function and panic
+// properties.
+func panicError(name string, r interface{}) js.Value {
+	log.Error("Recovered from panic in exported function", "function", name, "panic", r, "stack", string(debug.Stack()))
+
+	err := js.Global().Get("Error").New(fmt.Sprintf("internal error in %s: %v", name, r))
+	err.Set("name", "PanicError")
+	err.Set("function", name)
+	err.Set("panic", fmt.Sprint(r))
+	return err
+}
+
+// promise runs fn on a new goroutine, so it can block without stalling the
+// JavaScript event loop, and settles a promise with its outcome. A panic in fn
+// rejects the promise with a PanicError, see panicError, on behalf of the
+// exported function name.
+func promise(name string, fn func() (interface{}, error)) js.Value {
+	var handler js.Func
+	handler = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolve, reject := args[0], args[1]
+		go func() {
+			defer handler.Release()
+			defer func() {
+				if r := recover(); r != nil {
+					reject.Invoke(panicError(name, r))
+				}
+			}()
 
-	// Call ComputePowLight
-	mixHash, powHash := progpowInstance.ComputePowLight(header)
-	fmt.Println("ComputePowLight success, mixHash:", mixHash, "powHash:", powHash)
+			result, err := fn()
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(result)
+		}()
+		return nil
+	})
+	return js.Global().Get("Promise").New(handler)
 }
@@ -0,0 +1,8 @@
+// Package prometheus registers the engine metrics with a Prometheus registry.
+// It pulls in the Prometheus client, which WASM builds should not carry, and
+// is therefore a module of its own, pinning the client, which builds of the
+// engine module leave out:
+//
+//	cd src/metrics/prometheus
+//	go build ./...
+package prometheus
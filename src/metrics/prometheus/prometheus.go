@@ -0,0 +1,93 @@
+package prometheus
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics creates engine instruments as Prometheus collectors and registers
+// them on first use.
+type Metrics struct {
+	namespace  string
+	registerer prometheus.Registerer
+
+	lock       sync.Mutex
+	collectors map[string]prometheus.Collector
+}
+
+// New creates the metrics of an engine, named namespace_name and registered
+// with registerer, or with prometheus.DefaultRegisterer if it is nil. Creating
+// an instrument panics if the registry rejects it for reasons other than being
+// registered already, such as an invalid namespace.
+func New(namespace string, registerer prometheus.Registerer) *Metrics {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	return &Metrics{
+		namespace:  namespace,
+		registerer: registerer,
+		collectors: make(map[string]prometheus.Collector),
+	}
+}
+
+// Counter implements progpow.Metrics. Labelled counters are the children of
+// a counter vector with the label names of the first request for the name.
+func (m *Metrics) Counter(name string, labels ...progpow.Label) progpow.Counter {
+	opts := prometheus.CounterOpts{Namespace: m.namespace, Name: name, Help: name}
+	if len(labels) == 0 {
+		return m.collector(name, func() prometheus.Collector {
+			return prometheus.NewCounter(opts)
+		}).(prometheus.Counter)
+	}
+	var (
+		names  = make([]string, len(labels))
+		values = make(prometheus.Labels, len(labels))
+	)
+	for i, label := range labels {
+		names[i] = label.Name
+		values[label.Name] = label.Value
+	}
+	return m.collector(name, func() prometheus.Collector {
+		return prometheus.NewCounterVec(opts, names)
+	}).(*prometheus.CounterVec).With(values)
+}
+
+// Gauge implements progpow.Metrics.
+func (m *Metrics) Gauge(name string) progpow.Gauge {
+	return m.collector(name, func() prometheus.Collector {
+		return prometheus.NewGauge(prometheus.GaugeOpts{Namespace: m.namespace, Name: name, Help: name})
+	}).(prometheus.Gauge)
+}
+
+// Histogram implements progpow.Metrics, with the default buckets of the
+// Prometheus client.
+func (m *Metrics) Histogram(name string) progpow.Histogram {
+	return m.collector(name, func() prometheus.Collector {
+		return prometheus.NewHistogram(prometheus.HistogramOpts{Namespace: m.namespace, Name: name, Help: name, Buckets: prometheus.DefBuckets})
+	}).(prometheus.Histogram)
+}
+
+// collector returns the collector of a name, creating and registering it on
+// first use. A collector registered under the same name before, such as by an
+// earlier engine on the same registry, is reused.
+func (m *Metrics) collector(name string, create func() prometheus.Collector) prometheus.Collector {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if c, ok := m.collectors[name]; ok {
+		return c
+	}
+	c := create()
+	if err := m.registerer.Register(c); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if !errors.As(err, &registered) {
+			panic(err)
+		}
+		c = registered.ExistingCollector
+	}
+	m.collectors[name] = c
+	return c
+}
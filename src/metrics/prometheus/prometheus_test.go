@@ -0,0 +1,35 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelledCounter(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := New("progpow", registry)
+
+	metrics.Counter("seal_failures_total", progpow.Label{Name: "reason", Value: "pow"}).Add(2)
+	metrics.Counter("seal_failures_total", progpow.Label{Name: "reason", Value: "mixhash"}).Add(1)
+	metrics.Counter("seals_verified_total").Add(3)
+
+	// Engines on the same registry share the collectors
+	New("progpow", registry).Counter("seal_failures_total", progpow.Label{Name: "reason", Value: "pow"}).Add(1)
+
+	vec := metrics.collector("seal_failures_total", nil).(*prometheus.CounterVec)
+	if got := testutil.ToFloat64(vec.WithLabelValues("pow")); got != 3 {
+		t.Errorf("pow failures %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(vec.WithLabelValues("mixhash")); got != 1 {
+		t.Errorf("mixhash failures %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(registry, "progpow_seal_failures_total"); got != 2 {
+		t.Errorf("%d failure series, want 2", got)
+	}
+	if got := testutil.ToFloat64(metrics.Counter("seals_verified_total").(prometheus.Counter)); got != 3 {
+		t.Errorf("verified seals %v, want 3", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Package statsd adapts the engine metrics to the StatsD line protocol, sent
+// over UDP to a StatsD daemon or an agent speaking its protocol, such as the
+// Datadog or Telegraf agents. It has no dependencies beyond the standard
+// library.
+package statsd
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+)
+
+// Client sends the measurements of its instruments to a StatsD daemon. Each
+// measurement is sent in a datagram of its own as soon as it is taken; lost
+// datagrams are not retried, in line with StatsD's fire and forget design.
+type Client struct {
+	conn   net.Conn
+	prefix string
+	lock   sync.Mutex // Serialises writes to conn
+}
+
+// Dial creates a client sending to the daemon at addr, a host:port pair.
+// Metric names are prefixed with prefix and a dot, unless prefix is empty.
+func Dial(addr, prefix string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefix += "."
+	}
+	return &Client{conn: conn, prefix: prefix}, nil
+}
+
+// Close closes the connection to the daemon.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Counter implements progpow.Metrics, sending counts. StatsD has no labels,
+// so they are appended to the name as dotted name and value pairs, counting
+// seal_failures_total with reason pow as seal_failures_total.reason.pow.
+func (c *Client) Counter(name string, labels ...progpow.Label) progpow.Counter {
+	for _, label := range labels {
+		name += "." + label.Name + "." + label.Value
+	}
+	return instrument{client: c, name: name, kind: "c"}
+}
+
+// Gauge implements progpow.Metrics, sending gauge values.
+func (c *Client) Gauge(name string) progpow.Gauge {
+	return instrument{client: c, name: name, kind: "g"}
+}
+
+// Histogram implements progpow.Metrics, sending histogram samples, which
+// StatsD aggregates like timers.
+func (c *Client) Histogram(name string) progpow.Histogram {
+	return instrument{client: c, name: name, kind: "h"}
+}
+
+// send writes a measurement as name:value|kind.
+func (c *Client) send(name string, value float64, kind string) {
+	line := make([]byte, 0, len(c.prefix)+len(name)+24)
+	line = append(line, c.prefix...)
+	line = append(line, name...)
+	line = append(line, ':')
+	line = strconv.AppendFloat(line, value, 'f', -1, 64)
+	line = append(line, '|')
+	line = append(line, kind...)
+
+	c.lock.Lock()
+	c.conn.Write(line)
+	c.lock.Unlock()
+}
+
+// instrument is a StatsD counter, gauge or histogram.
+type instrument struct {
+	client *Client
+	name   string
+	kind   string
+}
+
+func (i instrument) Add(delta float64)     { i.client.send(i.name, delta, i.kind) }
+func (i instrument) Set(value float64)     { i.client.send(i.name, value, i.kind) }
+func (i instrument) Observe(value float64) { i.client.send(i.name, value, i.kind) }
@@ -0,0 +1,201 @@
+// Package mobile exposes seal verification to Android and iOS apps. Build the
+// bindings with gomobile:
+//
+//	gomobile bind -target=android -o progpow.aar ./mobile
+//	gomobile bind -target=ios -o Progpow.xcframework ./mobile
+//
+// The API sticks to the types gomobile can bind: headers are passed as their
+// RLP encoding or as the JSON text returned by the node's JSON-RPC API, and
+// hashes are returned as 0x-prefixed hex strings.
+package mobile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// cacheSubdir is the directory below the cache directory of the app the
+// verification caches are persisted in, so clearing them never touches other
+// files of the app.
+const cacheSubdir = "progpow"
+
+// Verifier verifies the seals of headers. Its methods are safe for concurrent
+// use from any thread.
+type Verifier struct {
+	engine *progpow.Progpow
+	dir    string // Directory the caches are persisted in, empty if none
+}
+
+// NewVerifier creates a verifier persisting its verification cache below
+// cacheDir, which should be the cache directory of the app (Context.getCacheDir
+// on Android, the Caches directory on iOS): the cache can be regenerated, so
+// the system reclaiming the space on low storage only costs a regeneration.
+// An empty cacheDir keeps the cache in memory only, regenerating it on every
+// start. Test selects the tiny test-mode cache of devnets.
+func NewVerifier(cacheDir string, test bool) (*Verifier, error) {
+	// Phones are short on memory and storage, and all blocks of a network
+	// share a single epoch, so there is no use keeping more than one cache
+	config := progpow.Config{CachesInMem: 1}
+	if test {
+		config.PowMode = progpow.ModeTest
+	}
+	var dir string
+	if cacheDir != "" {
+		dir = filepath.Join(cacheDir, cacheSubdir)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, err
+		}
+		config.CacheDir = dir
+		config.CachesOnDisk = 1
+	}
+	engine, err := progpow.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{engine: engine, dir: dir}, nil
+}
+
+// VerifyResult is the outcome of verifying the seal of a header.
+type VerifyResult struct {
+	Valid   bool
+	PowHash string // Hex encoded, also set if the PoW hash merely misses the difficulty
+	Error   string // Why the seal is invalid, empty if it is valid
+}
+
+// VerifySeal verifies the seal of an RLP encoded header. The error reports
+// headers failing to decode; invalid seals are reported by the result.
+func (v *Verifier) VerifySeal(header []byte) (*VerifyResult, error) {
+	h, err := decodeRLP(header)
+	if err != nil {
+		return nil, err
+	}
+	return v.verify(h), nil
+}
+
+// VerifySealJSON verifies the seal of a header in the JSON-RPC format of the
+// node.
+func (v *Verifier) VerifySealJSON(header string) (*VerifyResult, error) {
+	h, err := decodeJSON(header, (*types.Header).UnmarshalJSON)
+	if err != nil {
+		return nil, err
+	}
+	return v.verify(h), nil
+}
+
+func (v *Verifier) verify(header *types.Header) *VerifyResult {
+	powHash, err := v.engine.VerifySeal(header)
+	result := &VerifyResult{Valid: err == nil, PowHash: powHash.Hex()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// PowResult are the outputs of the progpow hash of a header.
+type PowResult struct {
+	MixHash string // Hex encoded
+	PowHash string // Hex encoded
+}
+
+// ComputePowLight computes the mixHash and PoW hash of an RLP encoded header
+// for its nonce, regardless of the mixHash it carries.
+func (v *Verifier) ComputePowLight(header []byte) (*PowResult, error) {
+	h, err := decodeRLP(header)
+	if err != nil {
+		return nil, err
+	}
+	return v.compute(h)
+}
+
+// ComputePowLightJSON computes the mixHash and PoW hash of a header in the
+// JSON-RPC format of the node. The mixHash and nonce may be absent, the nonce
+// then being zero.
+func (v *Verifier) ComputePowLightJSON(header string) (*PowResult, error) {
+	h, err := decodeJSON(header, (*types.Header).UnmarshalUnsealedJSON)
+	if err != nil {
+		return nil, err
+	}
+	return v.compute(h)
+}
+
+func (v *Verifier) compute(header *types.Header) (*PowResult, error) {
+	mixHash, powHash, err := v.engine.ComputePowLightCtx(context.Background(), header)
+	if err != nil {
+		return nil, err
+	}
+	return &PowResult{MixHash: mixHash.Hex(), PowHash: powHash.Hex()}, nil
+}
+
+// CacheDir returns the directory the verification cache is persisted in,
+// empty if it is kept in memory only.
+func (v *Verifier) CacheDir() string {
+	return v.dir
+}
+
+// CacheBytes returns the storage taken by the persisted verification caches.
+func (v *Verifier) CacheBytes() (int64, error) {
+	if v.dir == "" {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(v.dir)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed meanwhile
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// ClearCaches deletes the persisted verification caches, e.g. from a storage
+// settings screen. Caches in use stay valid until released, and are
+// regenerated when needed again.
+func (v *Verifier) ClearCaches() error {
+	if v.dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(v.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(v.dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeRLP decodes an RLP encoded header and checks its structure.
+func decodeRLP(raw []byte) (*types.Header, error) {
+	header, err := types.DecodeHeaderRLP(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := header.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// decodeJSON decodes a header in the JSON-RPC format using unmarshal and
+// checks its structure.
+func decodeJSON(text string, unmarshal func(*types.Header, []byte) error) (*types.Header, error) {
+	header := new(types.Header)
+	if err := unmarshal(header, []byte(text)); err != nil {
+		return nil, err
+	}
+	if err := header.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
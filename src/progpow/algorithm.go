@@ -1,10 +1,13 @@
 package progpow
 
 import (
+	"bytes"
 	"encoding/binary"
 	"hash"
 	"math/big"
 	"reflect"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -33,6 +36,9 @@ const (
 // block number.
 func cacheSize(block uint64) uint64 {
 	epoch := int(block / epochLength)
+	if s := activeSchedule.Load(); s != nil {
+		return s.cacheSize(epoch)
+	}
 	if epoch < maxEpoch {
 		return cacheSizes[epoch]
 	}
@@ -54,6 +60,9 @@ func calcCacheSize(epoch int) uint64 {
 // block number.
 func datasetSize(block uint64) uint64 {
 	epoch := int(block / epochLength)
+	if s := activeSchedule.Load(); s != nil {
+		return s.datasetSize(epoch)
+	}
 	if epoch < maxEpoch {
 		return datasetSizes[epoch]
 	}
@@ -117,20 +126,48 @@ func SeedHash(block uint64) []byte {
 	return seedHash(block)
 }
 
+// Epoch returns the epoch a block number belongs to.
+func Epoch(block uint64) uint64 {
+	return block / epochLength
+}
+
+// CacheSize returns the size in bytes of the verification cache of the epoch a
+// block number belongs to, as used by full sized engines under the active size
+// schedule.
+func CacheSize(block uint64) uint64 {
+	return cacheSize(block)
+}
+
+// DatasetSize returns the size in bytes of the mining dataset of the epoch a
+// block number belongs to, as used by full sized engines under the active size
+// schedule. GPU miners generate the dataset from the verification cache.
+func DatasetSize(block uint64) uint64 {
+	return datasetSize(block)
+}
+
 // generateCache creates a verification cache of a given size for an input seed.
 // The cache production process involves first sequentially filling up 32 MB of
 // memory, then performing two passes of Sergio Demian Lerner's RandMemoHash
 // algorithm from Strict Memory Hard Hashing Functions (2014). The output is a
 // set of 524288 64-byte values.
 // This method places the result into dest in machine byte order.
-func generateCache(dest []uint32, epoch uint64, seed []byte) {
+//
+// Every row depends on the one produced before it, so the generation cannot be
+// split across goroutines. Progress, if non-nil, is called with the completed
+// percentage whenever it advances by a whole percent, short of 100, and the
+// generation is logged through logger. It returns false if the generation was
+// aborted through the yield settings, leaving dest partially filled.
+func generateCache(dest []uint32, epoch uint64, seed []byte, logger log.Logger, progress func(pct float64), yield yieldSettings) (generated bool) {
 	// Print some debug logs to allow analysis on low end devices
-	logger := log.New("epoch")
+	logger = logger.With("epoch", epoch)
 
 	start := time.Now()
 	defer func() {
 		elapsed := time.Since(start)
-
+		if !generated {
+			logger.Debug("Aborted ethash verification cache generation", "elapsed", common.PrettyDuration(elapsed))
+			return
+		}
 		logFn := logger.Debug
 		if elapsed > 3*time.Second {
 			logFn = logger.Info
@@ -148,7 +185,26 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	rows := int(size) / hashBytes
 
 	// Start a monitoring goroutine to report progress on low end devices
-	var progress uint32
+	var (
+		rowsDone uint32
+		total    = uint32(rows) * (cacheRounds + 1)
+		reported uint32
+		yielder  = yield.newYielder(1024)
+	)
+	advance := func() bool {
+		done := atomic.AddUint32(&rowsDone, 1)
+		if !yielder.step() {
+			return false
+		}
+		if progress == nil {
+			return true
+		}
+		if pct := uint32(uint64(done) * 100 / uint64(total)); pct > reported && pct < 100 {
+			reported = pct
+			progress(float64(pct))
+		}
+		return true
+	}
 
 	done := make(chan struct{})
 	defer close(done)
@@ -159,7 +215,7 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 			case <-done:
 				return
 			case <-time.After(3 * time.Second):
-				logger.Info("Generating ethash verification cache", "percentage", atomic.LoadUint32(&progress)*100/uint32(rows)/4, "elapsed", common.PrettyDuration(time.Since(start)))
+				logger.Info("Generating ethash verification cache", "percentage", uint64(atomic.LoadUint32(&rowsDone))*100/uint64(total), "elapsed", common.PrettyDuration(time.Since(start)))
 			}
 		}
 	}()
@@ -170,7 +226,9 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 	keccak512(cache, seed)
 	for offset := uint64(hashBytes); offset < size; offset += hashBytes {
 		keccak512(cache[offset:], cache[offset-hashBytes:offset])
-		atomic.AddUint32(&progress, 1)
+		if !advance() {
+			return false
+		}
 	}
 	// Use a low-round version of randmemohash
 	temp := make([]byte, hashBytes)
@@ -185,34 +243,104 @@ func generateCache(dest []uint32, epoch uint64, seed []byte) {
 			bitutil.XORBytes(temp, cache[srcOff:srcOff+hashBytes], cache[xorOff:xorOff+hashBytes])
 			keccak512(cache[dstOff:], temp)
 
-			atomic.AddUint32(&progress, 1)
+			if !advance() {
+				return false
+			}
 		}
 	}
 	// Swap the byte order on big endian systems and return
 	if !isLittleEndian() {
 		swap(cache)
 	}
+	return true
+}
+
+// cacheTailValid reports whether the last rows of a verification cache derive
+// from the rows before them like the last round of generateCache derives them:
+// each is the hash of the row before it XORed with another row. The rounds
+// rewrite every row, so no row can be traced back to the seed, and only
+// regenerating a cache proves it belongs to its epoch. The check rejects data
+// not produced by the algorithm, such as caches of another revision or decoded
+// in the wrong byte order, for at most two hashes per row.
+//
+// The other row is picked by the previous value of the row, which may pick a
+// row the last round has not rewritten yet. Two rows are tried, so a genuine
+// cache only fails if both of them made such a pick.
+func cacheTailValid(cache []uint32) bool {
+	rows := len(cache) * 4 / hashBytes
+	if rows < 3 {
+		return false
+	}
+	data := make([]byte, rows*hashBytes)
+	for i := range data[:len(data)/4] {
+		binary.LittleEndian.PutUint32(data[i*4:], cache[i])
+	}
+	var (
+		keccak512 = makeHasher(sha3.NewLegacyKeccak512())
+		temp      = make([]byte, hashBytes)
+		sum       = make([]byte, hashBytes)
+	)
+	for j := rows - 1; j >= rows-2; j-- {
+		src, dst := data[(j-1)*hashBytes:j*hashBytes], data[j*hashBytes:(j+1)*hashBytes]
+		for x := 0; x < j; x++ {
+			bitutil.XORBytes(temp, src, data[x*hashBytes:(x+1)*hashBytes])
+			keccak512(sum, temp)
+			if bytes.Equal(sum, dst) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // generateCDag generates the cDag used for progpow. If the 'cDag' is nil, this method is a no-op. Otherwise
-// it expects the cDag to be of size progpowCacheWords
-func generateCDag(cDag, cache []uint32, epoch uint64) {
+// it expects the cDag to be of size progpowCacheWords. Its items are independent of each other, so they are
+// split across GOMAXPROCS goroutines. It returns false if the generation was aborted through the yield
+// settings. The generation is logged through logger.
+func generateCDag(cDag, cache []uint32, epoch uint64, logger log.Logger, yield yieldSettings) bool {
 	if cDag == nil {
-		return
+		return true
 	}
 	start := time.Now()
-	keccak512 := makeHasher(sha3.NewLegacyKeccak512())
 
-	for i := uint32(0); i < progpowCacheWords/16; i++ {
-		rawData := generateDatasetItem(cache, i, keccak512)
-		// 64 bytes in rawData -> 16 uint32
-		for j := uint32(0); j < 16; j++ {
-			cDag[i*16+j] = binary.LittleEndian.Uint32(rawData[4*j:])
-		}
+	const items = progpowCacheWords / 16
+	workers := runtime.GOMAXPROCS(0)
+	if workers > items {
+		workers = items
 	}
+	var (
+		pend    sync.WaitGroup
+		aborted atomic.Bool
+	)
+	pend.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(first, last uint32) {
+			defer pend.Done()
+
+			keccak512 := makeHasher(sha3.NewLegacyKeccak512())
+			yielder := yield.newYielder(16)
+			for i := first; i < last; i++ {
+				if !yielder.step() {
+					aborted.Store(true)
+					return
+				}
+				rawData := generateDatasetItem(cache, i, keccak512)
+				// 64 bytes in rawData -> 16 uint32
+				for j := uint32(0); j < 16; j++ {
+					cDag[i*16+j] = binary.LittleEndian.Uint32(rawData[4*j:])
+				}
+			}
+		}(uint32(w*items/workers), uint32((w+1)*items/workers))
+	}
+	pend.Wait()
 
 	elapsed := time.Since(start)
-	log.Debug("Generated progpow cDag", "elapsed", common.PrettyDuration(elapsed), "epoch", epoch)
+	if aborted.Load() {
+		logger.Debug("Aborted progpow cDag generation", "elapsed", common.PrettyDuration(elapsed), "epoch", epoch)
+		return false
+	}
+	logger.Debug("Generated progpow cDag", "elapsed", common.PrettyDuration(elapsed), "epoch", epoch, "workers", workers)
+	return true
 }
 
 // swap changes the byte order of the buffer assuming a uint32 representation.
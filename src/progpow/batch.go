@@ -0,0 +1,102 @@
+package progpow
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// VerifySeals checks the seals of a batch of headers concurrently, returning
+// the powHash and verification error of every header at the same index as the
+// header itself. The verification cache of every epoch spanned by the batch is
+// retrieved once up front and shared by all workers, instead of each seal
+// going through the cache lookup separately. If workers is not positive, one
+// worker is started per available CPU. If those caches do not fit under the
+// ceiling of the engine's MemoryGuard, no header is verified and every one
+// fails with ErrOverloaded.
+func (progpow *Progpow) VerifySeals(headers []*types.Header, workers int) ([]common.Hash, []error) {
+	// If we're running a shared PoW, delegate verification to it
+	if progpow.shared != nil {
+		return progpow.shared.VerifySeals(headers, workers)
+	}
+	var (
+		hashes = make([]common.Hash, len(headers))
+		errs   = make([]error, len(headers))
+	)
+	if len(headers) == 0 {
+		return hashes, errs
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(headers) {
+		workers = len(headers)
+	}
+	// Resolve the caches of all epochs spanned by the batch, unless the seals
+	// are faked and no cache is ever needed
+	type epochCache struct {
+		cache *cache
+		err   error
+	}
+	caches := make(map[uint64]epochCache)
+	if progpow.config.PowMode != ModeFake && progpow.config.PowMode != ModeFullFake {
+		var epochs []uint64
+		for _, header := range headers {
+			epoch := header.NumberU64() / epochLength
+			if _, ok := caches[epoch]; !ok {
+				caches[epoch] = epochCache{}
+				epochs = append(epochs, epoch)
+			}
+		}
+		// Shed the whole batch if its caches do not fit under the memory
+		// ceiling, rather than verifying part of it
+		release, err := progpow.admit(epochs...)
+		if err != nil {
+			for i := range errs {
+				errs[i] = err
+			}
+			return hashes, errs
+		}
+		for _, epoch := range epochs {
+			cache, err := progpow.sealCache(epoch * epochLength)
+			caches[epoch] = epochCache{cache, err}
+		}
+		release()
+	}
+	lookup := func(block uint64) (*cache, error) {
+		entry := caches[block/epochLength]
+		return entry.cache, entry.err
+	}
+	// Spread the headers across the workers and wait for all of them
+	var (
+		jobs = make(chan int, len(headers))
+		pend sync.WaitGroup
+	)
+	for i := range headers {
+		jobs <- i
+	}
+	close(jobs)
+
+	pend.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer pend.Done()
+
+			yielder := progpow.config.yieldSettings().newYielder(1)
+			for i := range jobs {
+				yielder.step()
+				hashes[i], errs[i] = progpow.verifySealWith(headers[i], lookup)
+				progpow.metrics.observeSeal(errs[i])
+			}
+		}()
+	}
+	pend.Wait()
+
+	// Caches are unmapped in a finalizer. Ensure that they stay alive until
+	// every worker is done with them.
+	runtime.KeepAlive(caches)
+
+	return hashes, errs
+}
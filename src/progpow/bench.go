@@ -0,0 +1,184 @@
+package progpow
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// DefaultBenchDuration is how long each operation of the benchmark suite is
+// repeated for when no duration is given.
+const DefaultBenchDuration = time.Second
+
+// BenchPlatform describes the platform a benchmark report was produced on.
+type BenchPlatform struct {
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	Wasm       bool   `json:"wasm"`
+	GoVersion  string `json:"goVersion"`
+	NumCPU     int    `json:"numCPU"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	CPU        string `json:"cpu,omitempty"` // Model name, if the platform reveals it
+}
+
+// BenchResult is the timing of one operation of the benchmark suite.
+type BenchResult struct {
+	Name    string  `json:"name"`
+	Ops     int     `json:"ops"`     // Number of times the operation ran
+	TotalNs int64   `json:"totalNs"` // Time all runs took together
+	NsPerOp float64 `json:"nsPerOp"`
+}
+
+// BenchReport is the outcome of a run of the benchmark suite, along with
+// what is needed to compare it against runs on other platforms.
+type BenchReport struct {
+	Platform          BenchPlatform     `json:"platform"`
+	Mode              string            `json:"mode"`
+	AlgorithmRevision int               `json:"algorithmRevision"`
+	Tags              map[string]string `json:"tags,omitempty"` // Free-form labels of the caller, e.g. the host or browser
+	Time              time.Time         `json:"time"`
+	Results           []BenchResult     `json:"results"`
+}
+
+// benchPlatform returns the description of the running platform.
+func benchPlatform() BenchPlatform {
+	return BenchPlatform{
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		Wasm:       runtime.GOARCH == "wasm",
+		GoVersion:  runtime.Version(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		CPU:        cpuModel(),
+	}
+}
+
+// cpuModel returns the model name of the CPU as reported by Linux, or an empty
+// string where it is unavailable.
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Bench runs the standard benchmark suite: hashing and decoding a header,
+// generating the verification cache of the first epoch and computing and
+// verifying the progpow seal. Each operation is repeated for at least d, or
+// DefaultBenchDuration if d is zero, and runs once at the least; cache
+// generation typically runs only once. The suite uses a dedicated in-memory
+// engine of the same mode, so the caches of progpow are left untouched, and
+// yields like the other long computations of the engine.
+func (progpow *Progpow) Bench(d time.Duration, tags map[string]string) (*BenchReport, error) {
+	if d <= 0 {
+		d = DefaultBenchDuration
+	}
+	// Pick the golden vector matching the mode for realistic input
+	var vectors struct {
+		Vectors []selfTestVector `json:"vectors"`
+	}
+	if err := json.Unmarshal(selfTestJSON, &vectors); err != nil {
+		return nil, err
+	}
+	mode := "normal"
+	if progpow.config.PowMode == ModeTest {
+		mode = "test"
+	}
+	var encoded string
+	for _, vector := range vectors.Vectors {
+		if vector.Mode == mode {
+			encoded = vector.Header
+			break
+		}
+	}
+	header, err := decodeSelfTestHeader(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("bench header: %v", err)
+	}
+	engine, err := New(Config{
+		PowMode:       progpow.config.PowMode,
+		Yield:         progpow.config.Yield,
+		YieldInterval: progpow.config.YieldInterval,
+		Log:           progpow.config.Log,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var (
+		yield      = progpow.config.yieldSettings().newYielder(1)
+		raw        = common.FromHex(encoded)
+		epochCache *cache
+		nonce      uint64
+	)
+	suite := []struct {
+		name string
+		op   func()
+	}{
+		{"rlpDecodeHeader", func() { rlp.DecodeBytes(raw, new(types.Header)) }},
+		{"sealHash", func() { header.SealHash() }},
+		{"headerHash", func() { header.Hash() }},
+		{"cacheGeneration", func() {
+			// Generate a fresh cache on every run rather than hitting the lru
+			epochCache = newCache(0)
+			epochCache.generate(&engine.config, engine.randInt, log.Log)
+		}},
+		{"progpowLight", func() {
+			nonce++
+			header.SetNonce(types.EncodeNonce(nonce))
+			engine.computePowLight(header, epochCache)
+		}},
+		{"verifySeal", func() {
+			nonce++
+			header.SetNonce(types.EncodeNonce(nonce))
+			engine.verifySealWith(header, func(uint64) (*cache, error) { return epochCache, nil })
+		}},
+	}
+	report := &BenchReport{
+		Platform:          benchPlatform(),
+		Mode:              progpow.config.PowMode.String(),
+		AlgorithmRevision: algorithmRevision,
+		Tags:              tags,
+		Time:              time.Now(),
+	}
+	for _, bench := range suite {
+		// Time the runs alone, yields to the host are not part of the work
+		var (
+			ops   int
+			taken time.Duration
+		)
+		for ops == 0 || taken < d {
+			start := time.Now()
+			bench.op()
+			taken += time.Since(start)
+			ops++
+			yield.step()
+		}
+		report.Results = append(report.Results, BenchResult{
+			Name:    bench.name,
+			Ops:     ops,
+			TotalNs: taken.Nanoseconds(),
+			NsPerOp: float64(taken.Nanoseconds()) / float64(ops),
+		})
+		progpow.config.Log.Debug("Benchmarked operation", "name", bench.name, "ops", ops, "elapsed", taken)
+	}
+	return report, nil
+}
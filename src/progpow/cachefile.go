@@ -0,0 +1,46 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+)
+
+var (
+	ErrCacheDirDisabled = errors.New("cache directory not configured")
+	ErrCacheMismatch    = errors.New("cache file does not match regenerated cache")
+)
+
+// VerifyCacheFile regenerates the verification cache of an epoch in memory and
+// compares it word by word against the file persisted in the configured cache
+// directory, detecting bit-rot or tampering of long-lived cache files.
+func (progpow *Progpow) VerifyCacheFile(epoch uint64) error {
+	if progpow.config.CacheDir == "" {
+		return ErrCacheDirDisabled
+	}
+	path := cachePath(progpow.config.CacheDir, epoch)
+	dump, mem, stored, err := memoryMap(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to load cache file %s: %w", path, err)
+	}
+	defer dump.Close()
+	defer mem.Unmap()
+
+	size := cacheSize(epoch*epochLength + 1)
+	if progpow.config.PowMode == ModeTest {
+		size = 1024
+	}
+	if uint64(len(stored))*4 != size {
+		return fmt.Errorf("%w: %s holds %d bytes, want %d", ErrCacheMismatch, path, len(stored)*4, size)
+	}
+	expected := make([]uint32, size/4)
+	generateCache(expected, epoch, seedHash(epoch*epochLength+1), log.Log, nil, yieldSettings{})
+
+	for i := range expected {
+		if stored[i] != expected[i] {
+			return fmt.Errorf("%w: %s differs at word %d", ErrCacheMismatch, path, i)
+		}
+	}
+	return nil
+}
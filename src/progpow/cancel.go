@@ -0,0 +1,117 @@
+package progpow
+
+import (
+	"context"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// VerifySealCtx verifies the seal of a header like VerifySeal, unless ctx is
+// done while the verification cache of its epoch is generated, in which case
+// it returns ctx.Err(). Giving up aborts the generation, if no other caller
+// waits for the cache, rather than leaving it to occupy the host in the
+// background; a later verification starts it over. Under Config.NonBlocking
+// or Config.ColdStartBudget the generation carries on in the background
+// instead, as for VerifySeal. Generation started by the call logs through the
+// logger of ctx, see log.FromContext.
+func (progpow *Progpow) VerifySealCtx(ctx context.Context, header *types.Header) (common.Hash, error) {
+	// If we're running a shared PoW, delegate verification to it
+	if progpow.shared != nil {
+		return progpow.shared.VerifySealCtx(ctx, header)
+	}
+	powHash, err := progpow.verifySealWith(header, func(block uint64) (*cache, error) {
+		return progpow.admitted(block, func(block uint64) (*cache, error) {
+			return progpow.sealCacheCtx(ctx, block)
+		})
+	})
+	progpow.metrics.observeSeal(err)
+	return powHash, err
+}
+
+// ComputePowLightCtx computes the mixHash and powHash of a header like
+// ComputePowLight, unless ctx is done while the verification cache of its
+// epoch is generated, see VerifySealCtx, or the MemoryGuard has no room for
+// the cache.
+func (progpow *Progpow) ComputePowLightCtx(ctx context.Context, header *types.Header) (mixHash, powHash common.Hash, err error) {
+	// If we're running a shared PoW, delegate the computation to it
+	if progpow.shared != nil {
+		return progpow.shared.ComputePowLightCtx(ctx, header)
+	}
+	cache, err := progpow.admitted(header.NumberU64(), func(block uint64) (*cache, error) {
+		return progpow.cacheCtx(ctx, block)
+	})
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	mixHash, powHash = progpow.computePowLight(header, cache)
+	return mixHash, powHash, nil
+}
+
+// sealCacheCtx returns the verification cache for a block number like
+// sealCache, unless ctx is done first.
+func (progpow *Progpow) sealCacheCtx(ctx context.Context, block uint64) (*cache, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if budget, ok := progpow.coldStartBudget(); ok {
+		return progpow.cacheWithin(ctx, block, budget)
+	}
+	return progpow.cacheCtx(ctx, block)
+}
+
+// cacheCtx retrieves the verification cache for the specified block number
+// like cache, unless ctx is done first. Generation logs through the logger of
+// ctx.
+func (progpow *Progpow) cacheCtx(ctx context.Context, block uint64) (*cache, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	epoch := block / epochLength
+	current, future := progpow.caches.get(epoch)
+
+	// If we need a new future cache, now's a good time to regenerate it.
+	if future != nil {
+		go future.generate(&progpow.config, progpow.randInt, log.FromContext(ctx))
+	}
+	if err := current.wait(ctx, &progpow.config, progpow.randInt); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// wait ensures that the cache content is generated before use like generate,
+// unless ctx is done first. The generation is then abandoned, and aborted if
+// no other caller waits for it.
+func (c *cache) wait(ctx context.Context, config *Config, randInt func() int) error {
+	for !c.ready() {
+		run, start := c.join(false, log.FromContext(ctx))
+		if start {
+			go c.execute(run, config, randInt)
+		}
+		select {
+		case <-run.ended:
+		case <-ctx.Done():
+			c.abandon(run)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// abandon withdraws a caller waiting for a generation run through wait,
+// aborting the run if it was the last caller waiting for it.
+func (c *cache) abandon(run *cacheRun) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if run.waiters--; run.waiters > 0 || run.kept {
+		return
+	}
+	select {
+	case <-run.abort:
+	default:
+		close(run.abort)
+	}
+}
@@ -0,0 +1,226 @@
+package progpow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/sirupsen/logrus"
+)
+
+// lockedBuffer collects log lines written from several goroutines.
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+// Cache generation started by a verification logs through the logger of its
+// context, so the lines carry the trace ID of the request.
+func TestVerifySealCtxLogger(t *testing.T) {
+	engine, err := New(Config{PowMode: ModeTest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out lockedBuffer
+	logger := log.NewWithWriter(&out)
+	logger.SetLevel(logrus.DebugLevel)
+	ctx := log.WithTraceID(log.NewContext(context.Background(), logger), "0123456789abcdef")
+
+	if _, err := engine.VerifySealCtx(ctx, decodeSealed(t)); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"Generated ethash verification cache", "Generated progpow cDag"} {
+		var found bool
+		for _, line := range strings.Split(out.String(), "\n") {
+			if strings.Contains(line, msg) && strings.Contains(line, "epoch=0") {
+				if !strings.Contains(line, "trace=0123456789abcdef") {
+					t.Errorf("log line without trace ID: %s", line)
+				}
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%q not logged through the context logger", msg)
+		}
+	}
+}
+
+// cacheGate holds cache generation at its yield points until it is opened.
+type cacheGate struct {
+	held chan struct{} // Receives once a generation is held
+	open chan struct{} // Closed to let generations carry on
+}
+
+// gatedEngine returns a test mode engine whose cache generations are held by
+// the returned gate. Generations yield throughout the cDag only if it is
+// derived by a single goroutine, so callers limit GOMAXPROCS to one.
+func gatedEngine(t *testing.T) (*Progpow, *cacheGate) {
+	t.Helper()
+	gate := &cacheGate{held: make(chan struct{}, 1), open: make(chan struct{})}
+	engine, err := New(Config{PowMode: ModeTest, YieldInterval: time.Nanosecond, Yield: func() {
+		select {
+		case gate.held <- struct{}{}:
+		default:
+		}
+		<-gate.open
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine, gate
+}
+
+// currentRun returns the generation run of the cache once it satisfies cond.
+func currentRun(t *testing.T, c *cache, cond func(run *cacheRun) bool) *cacheRun {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		c.lock.Lock()
+		run := c.run
+		ok := run != nil && cond(run)
+		c.lock.Unlock()
+		if ok {
+			return run
+		}
+	}
+	t.Fatal("generation run not in the expected state")
+	return nil
+}
+
+// aborted reports whether the run was aborted.
+func aborted(run *cacheRun) bool {
+	select {
+	case <-run.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// A waiter giving up leaves the generation to the other waiters.
+func TestCacheWaitAbandon(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	engine, gate := gatedEngine(t)
+	c := newCache(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first, second := make(chan error, 1), make(chan error, 1)
+	go func() { first <- c.wait(ctx, &engine.config, engine.randInt) }()
+	<-gate.held
+	go func() { second <- c.wait(context.Background(), &engine.config, engine.randInt) }()
+	run := currentRun(t, c, func(run *cacheRun) bool { return run.waiters == 2 })
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("abandoned wait: %v, want %v", err, context.Canceled)
+	}
+	if aborted(run) {
+		t.Fatal("generation aborted while a waiter remains")
+	}
+	close(gate.open)
+	if err := <-second; err != nil {
+		t.Fatalf("remaining wait: %v", err)
+	}
+	if !c.ready() {
+		t.Fatal("cache not generated for the remaining waiter")
+	}
+}
+
+// The last waiter giving up aborts the generation, and a later verification
+// starts it over.
+func TestCacheWaitAbort(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	engine, gate := gatedEngine(t)
+	c := newCache(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.wait(ctx, &engine.config, engine.randInt) }()
+	<-gate.held
+	run := currentRun(t, c, func(run *cacheRun) bool { return true })
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("abandoned wait: %v, want %v", err, context.Canceled)
+	}
+	if !aborted(run) {
+		t.Fatal("generation not aborted once its last waiter gave up")
+	}
+	close(gate.open)
+	<-run.ended
+	if c.ready() || c.cache != nil || c.cDag != nil {
+		t.Fatal("aborted generation left cache content behind")
+	}
+
+	if err := c.wait(context.Background(), &engine.config, engine.randInt); err != nil {
+		t.Fatalf("wait after abort: %v", err)
+	}
+	if !c.ready() {
+		t.Fatal("cache not generated after an aborted run")
+	}
+	want := make([]uint32, len(c.cache))
+	generateCache(want, 0, seedHash(1), log.Log, nil, yieldSettings{})
+	for i := range want {
+		if c.cache[i] != want[i] {
+			t.Fatalf("cache word %d is %#x after restart, want %#x", i, c.cache[i], want[i])
+		}
+	}
+}
+
+// Callers waiting for a generation until it ends get the cache, whether they
+// join a run before or after its last abandoning waiter aborted it.
+func TestCacheGenerateRacingAbort(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	for _, abortFirst := range []bool{false, true} {
+		engine, gate := gatedEngine(t)
+		c := newCache(0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		waited := make(chan error, 1)
+		go func() { waited <- c.wait(ctx, &engine.config, engine.randInt) }()
+		<-gate.held
+		run := currentRun(t, c, func(run *cacheRun) bool { return true })
+
+		generated := make(chan struct{})
+		if abortFirst {
+			cancel()
+			<-waited
+		}
+		go func() {
+			c.generate(&engine.config, engine.randInt, log.Log)
+			close(generated)
+		}()
+		currentRun(t, c, func(run *cacheRun) bool { return run.kept })
+		if !abortFirst {
+			cancel()
+			<-waited
+		}
+		if aborted(run) != abortFirst {
+			t.Fatalf("abort first %v: run aborted %v", abortFirst, aborted(run))
+		}
+		close(gate.open)
+		<-generated
+		if !c.ready() {
+			t.Fatalf("abort first %v: generate returned without the cache", abortFirst)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package progpow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var (
+	ErrUnknownParent    = errors.New("header does not build on its predecessor")
+	ErrInvalidNumber    = errors.New("invalid block number")
+	ErrOlderBlockTime   = errors.New("timestamp older than parent")
+	ErrLocationMismatch = errors.New("header location differs from its predecessor")
+)
+
+// VerifyHeaderChain checks that headers form a valid chain segment, in order
+// from oldest to newest: each header must carry a valid seal, and every header
+// after the first must build on its predecessor in the context of its location.
+// Building on the predecessor means referencing its hash as parent, being
+// numbered one above it in its own context and at most one above it in every
+// dominant context, never going back in any, and having a timestamp not older
+// than it. The first header is the trusted anchor of the segment, only its seal
+// is checked.
+//
+// Seals are verified concurrently through VerifySeals. The first violation
+// found, in chain order, is returned, wrapped with the index of the header.
+func (progpow *Progpow) VerifyHeaderChain(headers []*types.Header) error {
+	_, errs := progpow.VerifySeals(headers, 0)
+	for i, header := range headers {
+		if errs[i] != nil {
+			return fmt.Errorf("header %d: %w", i, errs[i])
+		}
+		if i == 0 {
+			continue
+		}
+		if err := verifyParent(header, headers[i-1]); err != nil {
+			return fmt.Errorf("header %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyParent checks that header builds on parent.
+func verifyParent(header, parent *types.Header) error {
+	if !bytes.Equal(header.Location(), parent.Location()) {
+		return fmt.Errorf("%w: %v, parent %v", ErrLocationMismatch, header.Location(), parent.Location())
+	}
+	ctx := header.Location().Ctx()
+	if header.ParentHashIn(ctx) != parent.Hash() {
+		return fmt.Errorf("%w: parent hash %x, predecessor %x", ErrUnknownParent, header.ParentHashIn(ctx), parent.Hash())
+	}
+	for _, c := range common.Contexts[:ctx+1] {
+		number, parentNumber := header.NumberU64In(c), parent.NumberU64In(c)
+		switch {
+		case c == ctx && number != parentNumber+1:
+			return fmt.Errorf("%w: %d in context %d, parent %d", ErrInvalidNumber, number, c, parentNumber)
+		case number < parentNumber || number > parentNumber+1:
+			return fmt.Errorf("%w: %d in context %d, parent %d", ErrInvalidNumber, number, c, parentNumber)
+		}
+	}
+	if header.Time() < parent.Time() {
+		return fmt.Errorf("%w: %d, parent %d", ErrOlderBlockTime, header.Time(), parent.Time())
+	}
+	return nil
+}
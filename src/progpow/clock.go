@@ -0,0 +1,128 @@
+package progpow
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by an engine, allowing time dependent
+// behaviour to be driven deterministically in tests.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a one-shot timer created by a Clock.
+type Timer interface {
+	// C returns the channel the current time is delivered on once the timer
+	// expires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, reporting whether it was stopped
+	// before expiring.
+	Stop() bool
+}
+
+// SystemClock is a Clock backed by the system time.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time        { return time.Now() }
+func (SystemClock) Sleep(d time.Duration) { time.Sleep(d) }
+func (SystemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer wraps a time.Timer to implement Timer.
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// SimulatedClock is a Clock whose time only moves when advanced explicitly,
+// so tests can exercise timeouts without sleeping. Sleeping on it blocks until
+// another goroutine advances the clock far enough.
+type SimulatedClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*simulatedTimer // Pending timers, soonest first
+}
+
+// NewSimulatedClock creates a simulated clock starting at the given time.
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{now: start}
+}
+
+// Now returns the current simulated time.
+func (c *SimulatedClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// Sleep blocks until the clock has been advanced by d.
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+// NewTimer creates a timer firing once the clock has been advanced by d.
+func (c *SimulatedClock) NewTimer(d time.Duration) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := &simulatedTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	return t
+}
+
+// Advance moves the clock forward by d, firing every timer that expires on the
+// way in order.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(c.now) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		t.ch <- t.at
+	}
+}
+
+// Pending returns the number of timers waiting to fire, letting tests wait for
+// a goroutine to block on the clock before advancing it.
+func (c *SimulatedClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.timers)
+}
+
+// simulatedTimer is a Timer of a SimulatedClock.
+type simulatedTimer struct {
+	clock *SimulatedClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func (t *simulatedTimer) C() <-chan time.Time { return t.ch }
+
+func (t *simulatedTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+)
+
+const (
+	// DefaultCachesInMem is the number of epoch caches kept in memory when the
+	// configuration does not specify one.
+	DefaultCachesInMem = 3
+	// DefaultCachesOnDisk is the number of epoch caches retained in CacheDir
+	// when disk storage is enabled and the configuration does not specify one.
+	DefaultCachesOnDisk = 3
+)
+
+var ErrInvalidConfig = errors.New("invalid progpow config")
+
+// Option adjusts the configuration of an engine created by New.
+type Option func(*Config)
+
+// WithMode selects the proof-of-work mode.
+func WithMode(mode Mode) Option {
+	return func(c *Config) { c.PowMode = mode }
+}
+
+// WithCacheDir persists verification caches in dir.
+func WithCacheDir(dir string) Option {
+	return func(c *Config) { c.CacheDir = dir }
+}
+
+// WithCachesInMem sets the number of epoch caches kept in memory.
+func WithCachesInMem(n int) Option {
+	return func(c *Config) { c.CachesInMem = n }
+}
+
+// WithLogger sets the logger of the engine.
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Config) { c.Log = logger }
+}
+
+// Sanitize fills unset configuration fields with their defaults and rejects
+// values and combinations which cannot be honoured. New calls it on its
+// configuration, so calling it beforehand is only needed to validate a
+// configuration without creating an engine.
+func (c *Config) Sanitize() error {
+	if c.Log == nil {
+		c.Log = &log.Log
+	}
+	if c.Metrics == nil {
+		c.Metrics = NoopMetrics{}
+	}
+	if c.Clock == nil {
+		c.Clock = SystemClock{}
+	}
+	if c.PowMode > ModeFullFake {
+		return fmt.Errorf("%w: unknown pow mode %d", ErrInvalidConfig, c.PowMode)
+	}
+	if c.CachesInMem < 0 {
+		return fmt.Errorf("%w: negative CachesInMem %d", ErrInvalidConfig, c.CachesInMem)
+	}
+	if c.CachesInMem == 0 && c.MaxCacheBytes == 0 {
+		c.CachesInMem = DefaultCachesInMem
+	}
+	if c.YieldInterval < 0 {
+		return fmt.Errorf("%w: negative YieldInterval %v", ErrInvalidConfig, c.YieldInterval)
+	}
+	if c.CachesOnDisk < 0 {
+		return fmt.Errorf("%w: negative CachesOnDisk %d", ErrInvalidConfig, c.CachesOnDisk)
+	}
+	if c.VerifiedMemo < 0 {
+		return fmt.Errorf("%w: negative VerifiedMemo %d", ErrInvalidConfig, c.VerifiedMemo)
+	}
+	if c.SealResults < 0 {
+		return fmt.Errorf("%w: negative SealResults %d", ErrInvalidConfig, c.SealResults)
+	}
+	if c.PruneEpochsBehind < 0 {
+		return fmt.Errorf("%w: negative PruneEpochsBehind %d", ErrInvalidConfig, c.PruneEpochsBehind)
+	}
+	if c.CacheDir != "" && !diskSupported {
+		return fmt.Errorf("%w: CacheDir is not supported on this platform", ErrInvalidConfig)
+	}
+	if c.CacheDir != "" && c.CacheStore != nil {
+		return fmt.Errorf("%w: CacheDir and CacheStore are mutually exclusive", ErrInvalidConfig)
+	}
+	if c.CacheDir == "" {
+		// Memory mapping is disabled, so options tuning it make no sense
+		if c.CachesLockMmap {
+			return fmt.Errorf("%w: CachesLockMmap set without a CacheDir", ErrInvalidConfig)
+		}
+	}
+	if c.CacheDir == "" && c.CacheStore == nil {
+		// Persistence is disabled, so there is nothing to retain
+		if c.CachesOnDisk > 0 {
+			return fmt.Errorf("%w: CachesOnDisk set without a CacheDir or CacheStore", ErrInvalidConfig)
+		}
+		if c.PruneEpochsBehind > 0 {
+			return fmt.Errorf("%w: PruneEpochsBehind set without a CacheDir or CacheStore", ErrInvalidConfig)
+		}
+	} else if c.CachesOnDisk == 0 {
+		// Retaining zero caches would delete each cache right after
+		// generating it
+		c.CachesOnDisk = DefaultCachesOnDisk
+	}
+	if c.MinDifficulty == nil {
+		c.MinDifficulty = DefaultMinDifficulty
+	} else if c.MinDifficulty.Sign() <= 0 {
+		return fmt.Errorf("%w: non-positive MinDifficulty %v", ErrInvalidConfig, c.MinDifficulty)
+	}
+	if err := sanitizeZoneOverrides(c.ZoneOverrides); err != nil {
+		return err
+	}
+	if c.ChainConfig != nil {
+		if err := c.ChainConfig.validate(); err != nil {
+			return err
+		}
+	}
+	if c.DurationLimit == nil {
+		c.DurationLimit = DefaultDurationLimit
+	} else if c.DurationLimit.Sign() <= 0 {
+		return fmt.Errorf("%w: non-positive DurationLimit %v", ErrInvalidConfig, c.DurationLimit)
+	}
+	if c.ColdStartBudget < 0 {
+		return fmt.Errorf("%w: negative ColdStartBudget %v", ErrInvalidConfig, c.ColdStartBudget)
+	}
+	if c.NonBlocking && c.ColdStartBudget > 0 {
+		return fmt.Errorf("%w: NonBlocking and ColdStartBudget are mutually exclusive", ErrInvalidConfig)
+	}
+	return nil
+}
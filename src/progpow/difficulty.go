@@ -0,0 +1,99 @@
+package progpow
+
+import (
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var (
+	// DifficultyBoundDivisor is the bound divisor of the difficulty, used in
+	// the update calculations.
+	DifficultyBoundDivisor = big.NewInt(2048)
+
+	// DefaultDurationLimit is the block time, in seconds, at which difficulty
+	// stays put, used if Config.DurationLimit is unset.
+	DefaultDurationLimit = big.NewInt(12)
+
+	// DefaultMinDifficulty is the minimum the difficulty may ever be, used if
+	// Config.MinDifficulty is unset.
+	DefaultMinDifficulty = big.NewInt(131072)
+
+	bigMinus99 = big.NewInt(-99)
+)
+
+// DifficultyParams are the parameters of the difficulty adjustment algorithm.
+type DifficultyParams struct {
+	DurationLimit *big.Int // Block time in seconds above which difficulty decreases
+	MinDifficulty *big.Int // Minimum the difficulty may ever be
+	BoundDivisor  *big.Int // Divisor of the parent difficulty bounding a single adjustment
+}
+
+// DifficultyParams returns the difficulty adjustment parameters the engine is
+// configured with.
+func (progpow *Progpow) DifficultyParams() DifficultyParams {
+	return DifficultyParams{
+		DurationLimit: progpow.config.DurationLimit,
+		MinDifficulty: progpow.config.MinDifficulty,
+		BoundDivisor:  DifficultyBoundDivisor,
+	}
+}
+
+// DifficultyParamsAt returns the difficulty adjustment parameters of blocks at
+// loc, which differ from DifficultyParams where a ZoneOverride applies.
+func (progpow *Progpow) DifficultyParamsAt(loc common.Location) DifficultyParams {
+	params := progpow.DifficultyParams()
+	params.MinDifficulty = progpow.minDifficultyAt(loc)
+	return params
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the
+// difficulty that a new block should have, given its parent and the timestamp
+// of the parent's own parent, at the location of the parent. A parent without a known grandparent, such as a
+// direct descendant of genesis, passes its difficulty on unchanged; callers
+// signal this by passing a grandparent time of zero.
+func (progpow *Progpow) CalcDifficulty(parent *types.Header, grandparentTime uint64) *big.Int {
+	if grandparentTime == 0 {
+		return new(big.Int).Set(parent.Difficulty())
+	}
+	var interval uint64
+	if parent.Time() > grandparentTime {
+		interval = parent.Time() - grandparentTime
+	}
+	uncles := parent.UncleHash() != types.EmptyUncleHash
+	return calcDifficulty(progpow.DifficultyParamsAt(parent.Location()), parent.Difficulty(), interval, uncles)
+}
+
+// calcDifficulty computes the difficulty following a parent of the given
+// difficulty, which was mined interval seconds after its own parent.
+//
+// The algorithm:
+// diff = parent_diff + parent_diff / 2048 * max((2 if parent_uncles else 1) - interval // duration_limit, -99)
+func calcDifficulty(params DifficultyParams, parentDifficulty *big.Int, interval uint64, uncles bool) *big.Int {
+	// holds intermediate values to make the algo easier to read & audit
+	x := new(big.Int).SetUint64(interval)
+	y := new(big.Int)
+
+	// (2 if parent_uncles else 1) - interval // duration_limit
+	x.Div(x, params.DurationLimit)
+	if uncles {
+		x.Sub(common.Big2, x)
+	} else {
+		x.Sub(common.Big1, x)
+	}
+	// max((2 if parent_uncles else 1) - interval // duration_limit, -99)
+	if x.Cmp(bigMinus99) < 0 {
+		x.Set(bigMinus99)
+	}
+	// parent_diff + parent_diff / 2048 * max(...)
+	y.Div(parentDifficulty, params.BoundDivisor)
+	x.Mul(y, x)
+	x.Add(parentDifficulty, x)
+
+	// minimum difficulty can ever be
+	if x.Cmp(params.MinDifficulty) < 0 {
+		x.Set(params.MinDifficulty)
+	}
+	return x
+}
@@ -0,0 +1,228 @@
+package progpow
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/math"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// mantBits is the number of fractional bits of the fixed point entropy values.
+const mantBits = 64
+
+// TimeFactor is the ratio of the block intervals of adjacent contexts: a
+// region block takes TimeFactor*HierarchyDepth zone blocks worth of entropy.
+// Zones may scale their thresholds with another factor, see ZoneOverride.
+var TimeFactor = big.NewInt(7)
+
+// IntrinsicLogS returns the logarithm of the intrinsic entropy reduction of a
+// PoW hash, log2(2^256 / powHash), as a fixed point number with mantBits
+// fractional bits.
+func (progpow *Progpow) IntrinsicLogS(powHash common.Hash) *big.Int {
+	return intrinsicLogS(powHash)
+}
+
+// IntrinsicLogEntropy returns the logarithm of the intrinsic entropy reduction
+// of a PoW hash like Progpow.IntrinsicLogS, for callers without an engine.
+func IntrinsicLogEntropy(powHash common.Hash) *big.Int {
+	return intrinsicLogS(powHash)
+}
+
+func intrinsicLogS(powHash common.Hash) *big.Int {
+	x, d := bigPool.Get().(*big.Int), bigPool.Get().(*big.Int)
+	defer bigPool.Put(x)
+	defer bigPool.Put(d)
+
+	x.SetBytes(powHash[:])
+	if x.Sign() == 0 {
+		x.SetUint64(1)
+	}
+	c, m := math.BinaryLog(d.Div(big2e256, x), mantBits)
+	bits := new(big.Int).Lsh(big.NewInt(int64(c)), mantBits)
+	return bits.Add(bits, m)
+}
+
+// IsCoincident reports whether header is a coincident block, one whose PoW
+// also clears the thresholds of a dominant context, making it a region or
+// prime block along with a zone block. It returns the order of the header,
+// the most dominant context it is a block of. Headers whose seal fails to
+// verify are not coincident and have order -1; use CalcOrder to learn why.
+func (progpow *Progpow) IsCoincident(header *types.Header) (int, bool) {
+	_, order, err := progpow.CalcOrder(header)
+	if err != nil {
+		return -1, false
+	}
+	return order, order < common.ZONE_CTX
+}
+
+// IsBlockOf reports whether header is a block of the chain of context ctx:
+// every header is a zone block, and those of order ctx or more dominant are
+// blocks of ctx as well. Headers whose seal fails to verify are blocks of no
+// context.
+func (progpow *Progpow) IsBlockOf(header *types.Header, ctx common.Context) bool {
+	_, order, err := progpow.CalcOrder(header)
+	return err == nil && order <= int(ctx)
+}
+
+// zoneThresholdLogS returns the intrinsic entropy of a block whose PoW hash
+// just meets difficulty. Like in go-quai, the target is cropped to a hash, so
+// the target 2^256 of difficulty 1 becomes the zero hash and the threshold
+// 256 bits.
+func zoneThresholdLogS(difficulty *big.Int) *big.Int {
+	var target common.Hash
+	t := bigPool.Get().(*big.Int)
+	if t.Div(big2e256, difficulty).Cmp(big2e256) == 0 {
+		t.SetUint64(0)
+	}
+	t.FillBytes(target[:])
+	bigPool.Put(t)
+	return intrinsicLogS(target)
+}
+
+// CalcOrder verifies the seal of a header and returns its intrinsic entropy
+// along with its order: the most dominant context the header is a block of.
+// A header whose entropy clears the prime thresholds is a prime block, one
+// clearing the region thresholds a region block, any other a zone block.
+// Genesis is a prime block without intrinsic entropy.
+func (progpow *Progpow) CalcOrder(header *types.Header) (*big.Int, int, error) {
+	number, err := sealNumber(header)
+	if err != nil {
+		return new(big.Int), -1, err
+	}
+	if number == 0 {
+		return new(big.Int), common.PRIME_CTX, nil
+	}
+	powHash, err := progpow.verifySeal(header)
+	if err != nil {
+		return new(big.Int), -1, err
+	}
+	intrinsicS := intrinsicLogS(powHash)
+	order, err := progpow.orderOf(header, intrinsicS)
+	if err != nil {
+		return new(big.Int), -1, err
+	}
+	return intrinsicS, order, nil
+}
+
+// OrderOf returns the order of a header whose seal verified with powHash, as
+// returned by VerifySeal, like CalcOrder but without verifying the seal again.
+// Genesis is a prime block. Malformed headers have order -1.
+func (progpow *Progpow) OrderOf(header *types.Header, powHash common.Hash) int {
+	number, err := sealNumber(header)
+	if err != nil {
+		return -1
+	}
+	if number == 0 {
+		return common.PRIME_CTX
+	}
+	order, err := progpow.orderOf(header, intrinsicLogS(powHash))
+	if err != nil {
+		return -1
+	}
+	return order
+}
+
+// WorkShareOrder returns the order a sealed header qualifies for from its PoW
+// hash alone, the most dominant context whose thresholds it clears, without
+// requiring the hash to meet the zone difficulty. Hashes falling short of it
+// can at most count as workshares and yield common.HierarchyDepth, one past
+// the zone context. The mixHash of the header must still check out, or the
+// error of its verification is returned along with order -1. Genesis is a
+// prime block.
+func (progpow *Progpow) WorkShareOrder(header *types.Header) (int, error) {
+	number, err := sealNumber(header)
+	if err != nil {
+		return -1, err
+	}
+	if number == 0 {
+		return common.PRIME_CTX, nil
+	}
+	powHash, err := progpow.verifySealWith(header, progpow.admittedCache)
+	if errors.Is(err, errInvalidPoW) && powHash != (common.Hash{}) {
+		return common.HierarchyDepth, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return progpow.orderOf(header, intrinsicLogS(powHash))
+}
+
+// orderOf returns the order of a header whose PoW hash has the given intrinsic
+// entropy and meets the zone difficulty, under the overrides of its zone. The
+// parent entropy deltas are not covered by the seal, so they are checked here.
+func (progpow *Progpow) orderOf(header *types.Header, intrinsicS *big.Int) (int, error) {
+	for _, ctx := range []common.Context{common.RegionCtx, common.ZoneCtx} {
+		if _, err := header.ParentDeltaSSafe(ctx); err != nil {
+			return -1, err
+		}
+	}
+	return orderOf(header, intrinsicS, targetsFor(header.Difficulty(), progpow.timeFactorAt(header.Location()))), nil
+}
+
+// orderOf returns the order of a header whose PoW hash has the given intrinsic
+// entropy and meets the thresholds of its zone difficulty, targets.
+func orderOf(header *types.Header, intrinsicS *big.Int, targets Targets) int {
+	// Prime case
+	totalDeltaS := new(big.Int).Add(header.ParentDeltaSIn(common.RegionCtx), header.ParentDeltaSIn(common.ZoneCtx))
+	totalDeltaS.Add(totalDeltaS, intrinsicS)
+	if intrinsicS.Cmp(targets.PrimeBlockS) > 0 && totalDeltaS.Cmp(targets.PrimeS) > 0 {
+		return common.PRIME_CTX
+	}
+	// Region case
+	totalDeltaS = new(big.Int).Add(header.ParentDeltaSIn(common.ZoneCtx), intrinsicS)
+	if intrinsicS.Cmp(targets.RegionBlockS) > 0 && totalDeltaS.Cmp(targets.RegionS) > 0 {
+		return common.REGION_CTX
+	}
+	// Zone case
+	return common.ZONE_CTX
+}
+
+// TotalLogS returns the total entropy of the chain ending in header, as seen
+// from the context of its order: the parent entropy of that context plus the
+// entropy accumulated in the subordinate contexts since their last dominant
+// block, plus the intrinsic entropy of the header itself.
+func (progpow *Progpow) TotalLogS(header *types.Header) (*big.Int, error) {
+	intrinsicS, order, err := progpow.CalcOrder(header)
+	if err != nil {
+		return nil, err
+	}
+	return entropyIn(header, common.Context(order), intrinsicS)
+}
+
+// entropyIn returns the total entropy of the chain ending in header as seen
+// from context ctx, given the intrinsic entropy of header. The entropy fields
+// are not covered by the seal, so missing entries are reported as errors.
+func entropyIn(header *types.Header, ctx common.Context, intrinsicS *big.Int) (*big.Int, error) {
+	parentS, err := header.ParentEntropySafe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totalS := new(big.Int).Set(parentS)
+	for _, c := range common.Contexts[ctx+1:] {
+		deltaS, err := header.ParentDeltaSSafe(c)
+		if err != nil {
+			return nil, err
+		}
+		totalS.Add(totalS, deltaS)
+	}
+	return totalS.Add(totalS, intrinsicS), nil
+}
+
+// DeltaLogS returns the entropy the header adds to the chains subordinate to
+// its order since their last dominant block. A prime block resets it.
+func (progpow *Progpow) DeltaLogS(header *types.Header) (*big.Int, error) {
+	intrinsicS, order, err := progpow.CalcOrder(header)
+	if err != nil {
+		return nil, err
+	}
+	if order == common.PRIME_CTX {
+		return new(big.Int), nil
+	}
+	deltaS := new(big.Int).Set(intrinsicS)
+	for _, ctx := range common.Contexts[order:] {
+		deltaS.Add(deltaS, header.ParentDeltaSIn(ctx))
+	}
+	return deltaS, nil
+}
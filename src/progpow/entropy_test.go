@@ -0,0 +1,113 @@
+package progpow
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+func TestZoneThresholdLogS(t *testing.T) {
+	tests := []struct {
+		difficulty *big.Int
+		bits       int64
+	}{
+		// The target 2^256 is cropped to the zero hash like in go-quai
+		{big.NewInt(1), 256},
+		{big.NewInt(2), 1},
+		{big.NewInt(1 << 20), 20},
+		{new(big.Int).Lsh(common.Big1, 255), 255},
+		// Targets below one are the zero hash as well
+		{new(big.Int).Lsh(common.Big1, 257), 256},
+	}
+	for _, tt := range tests {
+		want := new(big.Int).Lsh(big.NewInt(tt.bits), mantBits)
+		if got := zoneThresholdLogS(tt.difficulty); got.Cmp(want) != 0 {
+			t.Errorf("difficulty %v: threshold %v, want %v", tt.difficulty, got, want)
+		}
+	}
+}
+
+func BenchmarkIntrinsicLogS(b *testing.B) {
+	powHash := common.HexToHash("0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		intrinsicLogS(powHash)
+	}
+}
+
+func BenchmarkZoneThresholdLogS(b *testing.B) {
+	difficulty := big.NewInt(1_000_000_000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		zoneThresholdLogS(difficulty)
+	}
+}
+
+// sealedHeader is an RLP encoded zone header at difficulty 16, sealed for
+// test mode engines with nonce 11.
+const sealedHeader = "0xf901f7f863a00000000000000000000000000000000000000000000000000000000000000011a00000000000000000000000000000000000000000000000000000000000000022a00000000000000000000000000000000000000000000000000000000000000033a00000000000000000000000000000000000000000000000000000000000000077940000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000088a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000f863a00000000000000000000000000000000000000000000000000000000000000044a00000000000000000000000000000000000000000000000000000000000000055a00000000000000000000000000000000000000000000000000000000000000066a0000000000000000000000000000000000000000000000000000000000000000010c58203e8640ac3800507c3010203834c4b40825208843b9aca00820001846553f103846d696e65a046a6983554aaebed3a740d2fd47bd3104e24e6a4467638bebb88f46413ea01c288000000000000000b"
+
+// truncatedHeader returns sealedHeader with the RLP field at index lacking its
+// entries, as received from an untrusted peer.
+func truncatedHeader(t *testing.T, index int) *types.Header {
+	t.Helper()
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(common.FromHex(sealedHeader), &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields[index] = rlp.RawValue{0xC0}
+	var enc bytes.Buffer
+	if err := rlp.Encode(&enc, fields); err != nil {
+		t.Fatal(err)
+	}
+	malformed := new(types.Header)
+	if err := rlp.DecodeBytes(enc.Bytes(), malformed); err != nil {
+		t.Fatal(err)
+	}
+	return malformed
+}
+
+// Orders and entropies of malformed headers are errors, not panics.
+func TestOrderMalformedHeader(t *testing.T) {
+	engine, err := New(Config{PowMode: ModeTest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The entropy fields are not sealed, so headers lacking them still verify
+	const (
+		parentEntropy = 10
+		parentDeltaS  = 11
+		number        = 12
+	)
+	if _, err := engine.VerifySeal(truncatedHeader(t, parentDeltaS)); err != nil {
+		t.Fatalf("header without parent deltas: %v", err)
+	}
+	if !engine.IsBlockOf(decodeSealed(t), common.ZoneCtx) {
+		t.Fatal("sealed header not a zone block")
+	}
+	for _, index := range []int{parentDeltaS, number} {
+		header := truncatedHeader(t, index)
+		if _, order, err := engine.CalcOrder(header); err == nil || order != -1 {
+			t.Errorf("field %d: CalcOrder order %d, error %v", index, order, err)
+		}
+		if order, err := engine.WorkShareOrder(header); err == nil || order != -1 {
+			t.Errorf("field %d: WorkShareOrder order %d, error %v", index, order, err)
+		}
+		if order := engine.OrderOf(header, common.Hash{}); order != -1 {
+			t.Errorf("field %d: OrderOf order %d", index, order)
+		}
+		if engine.IsBlockOf(header, common.ZoneCtx) {
+			t.Errorf("field %d: zone block", index)
+		}
+	}
+	for _, index := range []int{parentEntropy, parentDeltaS, number} {
+		if _, err := engine.TotalLogS(truncatedHeader(t, index)); !errors.Is(err, types.ErrMalformedHeader) {
+			t.Errorf("field %d: TotalLogS error %v, want %v", index, err, types.ErrMalformedHeader)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package progpow_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow/progpowtest"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// template is an RLP encoded zone header at difficulty 16 with an empty seal.
+const template = "0xf901f7f863a00000000000000000000000000000000000000000000000000000000000000011a00000000000000000000000000000000000000000000000000000000000000022a00000000000000000000000000000000000000000000000000000000000000033a00000000000000000000000000000000000000000000000000000000000000077940000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000088a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000a00000000000000000000000000000000000000000000000000000000000000000f863a00000000000000000000000000000000000000000000000000000000000000044a00000000000000000000000000000000000000000000000000000000000000055a00000000000000000000000000000000000000000000000000000000000000066a0000000000000000000000000000000000000000000000000000000000000000010c58203e8640ac3800507c3010203834c4b40825208843b9aca00820001846553f103846d696e65a00000000000000000000000000000000000000000000000000000000000000000880000000000000000"
+
+// This example mines a devnet header with a test-mode engine and verifies its
+// seal, walking through the compute and verify steps a miner and a verifier
+// take on a live network.
+func ExampleProgpow_VerifySeal() {
+	// Test mode uses a 1 KiB verification cache, so the example runs instantly
+	engine, err := progpow.New(progpow.Config{PowMode: progpow.ModeTest})
+	if err != nil {
+		panic(err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(template), header); err != nil {
+		panic(err)
+	}
+	// Search for a nonce whose proof-of-work meets the difficulty, sealing the
+	// header with the matching mixHash
+	var powHash common.Hash
+	for nonce := uint64(0); ; nonce++ {
+		header.SetNonce(types.EncodeNonce(nonce))
+		mixHash, _ := engine.ComputePowLight(header)
+		header.SetMixHash(mixHash)
+
+		if powHash, err = engine.VerifySeal(header); err == nil {
+			break
+		}
+	}
+	fmt.Println("nonce:  ", header.NonceU64())
+	fmt.Println("powHash:", powHash.Hex())
+
+	// Tampering with any sealed field invalidates the seal
+	header.SetGasUsed(header.GasUsed() + 1)
+	_, err = engine.VerifySeal(header)
+	fmt.Println("tampered:", err)
+	// Output:
+	// nonce:   11
+	// powHash: 0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792
+	// tampered: invalid mixHash
+}
+
+// This example verifies a segment of consecutive headers, such as a devnet
+// export: every seal, and that each header builds on its predecessor.
+func ExampleProgpow_VerifyHeaderChain() {
+	engine, err := progpow.New(progpow.Config{PowMode: progpow.ModeTest})
+	if err != nil {
+		panic(err)
+	}
+	chain := progpowtest.GenerateChain(4, engine, nil)
+	fmt.Println("valid chain:", engine.VerifyHeaderChain(chain))
+
+	// Leaving out a header breaks the links of the segment
+	gapped := append([]*types.Header{chain[0]}, chain[2:]...)
+	err = engine.VerifyHeaderChain(gapped)
+	fmt.Println("gap detected:", errors.Is(err, progpow.ErrUnknownParent))
+	// Output:
+	// valid chain: <nil>
+	// gap detected: true
+}
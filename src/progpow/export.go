@@ -0,0 +1,183 @@
+package progpow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"lukechampine.com/blake3"
+)
+
+var (
+	// exportMagic prefixes every exported verification cache.
+	exportMagic = [4]byte{'p', 'p', 'w', 'c'}
+	// exportVersion is the layout version of the export envelope.
+	exportVersion = uint32(1)
+)
+
+var ErrInvalidCacheExport = errors.New("invalid cache export")
+
+// Byte orders of the cache words of an export.
+const (
+	exportLittleEndian = uint32(0)
+	exportBigEndian    = uint32(1)
+)
+
+// exportHeader is the fixed size envelope preceding the cache words of an
+// export. Its integers are little endian; the words follow in the byte order
+// it declares, that of the exporting machine, and a 32 byte blake3 checksum
+// of everything before it terminates the export.
+type exportHeader struct {
+	Magic     [4]byte
+	Version   uint32
+	Revision  uint32
+	ByteOrder uint32
+	Epoch     uint64
+	Words     uint64
+}
+
+// export serializes the verification cache in the export envelope. The cDag
+// is left out, it is quick to derive from the cache on import.
+func (c *cache) export() []byte {
+	header := exportHeader{
+		Magic:     exportMagic,
+		Version:   exportVersion,
+		Revision:  uint32(algorithmRevision),
+		ByteOrder: exportLittleEndian,
+		Epoch:     c.epoch,
+		Words:     uint64(len(c.cache)),
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if !isLittleEndian() {
+		header.ByteOrder, order = exportBigEndian, binary.BigEndian
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, binary.Size(header)+len(c.cache)*4+32))
+	binary.Write(buf, binary.LittleEndian, &header)
+	binary.Write(buf, order, c.cache)
+
+	sum := blake3.Sum256(buf.Bytes())
+	buf.Write(sum[:])
+	return buf.Bytes()
+}
+
+// ExportCache returns the verification cache of an epoch in a self-describing
+// envelope, generating the cache if needed, so a server can generate it once
+// and ship it to clients which install it with ImportCache instead of
+// generating it themselves, such as browser tabs. Exports of test mode
+// engines only import into test mode engines.
+func (progpow *Progpow) ExportCache(epoch uint64) ([]byte, error) {
+	cache, err := progpow.admitted(epoch*epochLength, func(block uint64) (*cache, error) {
+		return progpow.cache(block), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cache.export(), nil
+}
+
+// ImportCache installs the verification cache of an epoch exported by
+// ExportCache, on any platform, making the epoch verifiable without
+// generating its cache. Exports of another epoch, algorithm revision or cache
+// size, and corrupted ones, are rejected. The cache words are spot checked
+// against the cache algorithm, see cacheTailValid, while test mode caches are
+// small enough to be regenerated and compared in full. Only regenerating a
+// full size cache proves it belongs to the epoch, so exports should come from
+// a trusted source. Like generated caches, imported ones are subject to the
+// MemoryGuard.
+func (progpow *Progpow) ImportCache(epoch uint64, data []byte) error {
+	var header exportHeader
+	if len(data) < binary.Size(header) {
+		return fmt.Errorf("%w: %d bytes", ErrInvalidCacheExport, len(data))
+	}
+	binary.Read(bytes.NewReader(data), binary.LittleEndian, &header)
+	order, err := progpow.checkExportHeader(&header, epoch)
+	if err != nil {
+		return err
+	}
+	if uint64(len(data)) != exportLen(header.Words) {
+		return fmt.Errorf("%w: %d cache words in %d bytes", ErrInvalidCacheExport, header.Words, len(data))
+	}
+	release, err := progpow.admit(epoch)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return progpow.importCache(epoch, data, order)
+}
+
+// exportLen returns the length of the export of a cache of the given number
+// of words.
+func exportLen(words uint64) uint64 {
+	return uint64(binary.Size(exportHeader{})) + words*4 + 32
+}
+
+// checkExportHeader checks the envelope of an export against the engine and
+// the epoch the export is expected to hold, bounding the length of the export
+// before any of it is allocated, and returns the byte order of its words.
+func (progpow *Progpow) checkExportHeader(header *exportHeader, epoch uint64) (binary.ByteOrder, error) {
+	if header.Magic != exportMagic || header.Version != exportVersion {
+		return nil, ErrInvalidCacheExport
+	}
+	if header.Revision != uint32(algorithmRevision) {
+		return nil, fmt.Errorf("%w: algorithm revision %d, want %d", ErrInvalidCacheExport, header.Revision, algorithmRevision)
+	}
+	if header.Epoch != epoch {
+		return nil, fmt.Errorf("%w: cache of epoch %d, want %d", ErrInvalidCacheExport, header.Epoch, epoch)
+	}
+	wantWords := cacheSize(epoch*epochLength+1) / 4
+	if progpow.config.PowMode == ModeTest {
+		wantWords = 1024 / 4
+	}
+	if header.Words != wantWords {
+		return nil, fmt.Errorf("%w: %d cache words, want %d", ErrInvalidCacheExport, header.Words, wantWords)
+	}
+	switch header.ByteOrder {
+	case exportLittleEndian:
+		return binary.LittleEndian, nil
+	case exportBigEndian:
+		return binary.BigEndian, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown byte order %d", ErrInvalidCacheExport, header.ByteOrder)
+	}
+}
+
+// importCache verifies an export whose envelope checked out, derives the cDag
+// of its cache and installs the cache. The memory guard must have admitted
+// the epoch, and hold the room reserved for it until the cache is installed.
+func (progpow *Progpow) importCache(epoch uint64, data []byte, order binary.ByteOrder) error {
+	body := data[:len(data)-32]
+	if sum := blake3.Sum256(body); !bytes.Equal(sum[:], data[len(body):]) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidCacheExport)
+	}
+	words := body[binary.Size(exportHeader{}):]
+	c := &cache{
+		epoch: epoch,
+		cache: make([]uint32, len(words)/4),
+		cDag:  make([]uint32, progpowCacheWords),
+		done:  make(chan struct{}),
+	}
+	for i := range c.cache {
+		c.cache[i] = order.Uint32(words[i*4:])
+	}
+	if progpow.config.PowMode == ModeTest {
+		want := make([]uint32, len(c.cache))
+		generateCache(want, epoch, seedHash(epoch*epochLength+1), log.Log, nil, yieldSettings{})
+		for i := range want {
+			if c.cache[i] != want[i] {
+				return fmt.Errorf("%w: cache word %d differs from the generated cache", ErrInvalidCacheExport, i)
+			}
+		}
+	} else if !cacheTailValid(c.cache) {
+		return fmt.Errorf("%w: cache rows not derived by the cache algorithm", ErrInvalidCacheExport)
+	}
+	if !generateCDag(c.cDag, c.cache, epoch, log.Log, progpow.config.yieldSettings()) {
+		return errCacheAborted
+	}
+
+	// Mark the cache as generated so it is never regenerated
+	c.once.Do(func() { close(c.done) })
+	progpow.caches.add(epoch, c)
+	return nil
+}
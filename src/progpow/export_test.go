@@ -0,0 +1,83 @@
+package progpow
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+	"lukechampine.com/blake3"
+)
+
+// resum recomputes the checksum of an export after tampering with it.
+func resum(data []byte) {
+	sum := blake3.Sum256(data[:len(data)-32])
+	copy(data[len(data)-32:], sum[:])
+}
+
+func TestImportCache(t *testing.T) {
+	exporter, err := New(Config{PowMode: ModeTest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := exporter.ExportCache(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := binary.Size(exportHeader{})
+
+	// Exports are checked against the regenerated test mode cache
+	tampered := append([]byte(nil), data...)
+	tampered[words+8] ^= 1
+	resum(tampered)
+	engine, err := New(Config{PowMode: ModeTest, NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.ImportCache(0, tampered); !errors.Is(err, ErrInvalidCacheExport) {
+		t.Fatalf("tampered import: %v, want %v", err, ErrInvalidCacheExport)
+	}
+	// Imports are refused by a guard without room for them
+	guarded, err := New(Config{PowMode: ModeTest, MemoryGuard: NewMemoryGuard(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := guarded.ImportCache(0, data); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("guarded import: %v, want %v", err, ErrOverloaded)
+	}
+	// Imported caches verify seals right away, without generating the cache
+	if err := engine.ImportCache(0, data); err != nil {
+		t.Fatal(err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(sealedHeader), header); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.VerifySeal(header); err != nil {
+		t.Fatalf("seal verification with imported cache: %v", err)
+	}
+}
+
+func TestCacheTailValid(t *testing.T) {
+	cache := make([]uint32, 4096*hashBytes/4)
+	generateCache(cache, 0, seedHash(1), log.Log, nil, yieldSettings{})
+	if !cacheTailValid(cache) {
+		t.Fatal("generated cache rejected")
+	}
+	swapped := make([]uint32, len(cache))
+	for i, word := range cache {
+		swapped[i] = bits.ReverseBytes32(word)
+	}
+	if cacheTailValid(swapped) {
+		t.Fatal("cache in the wrong byte order accepted")
+	}
+	cache[len(cache)-1] ^= 1
+	cache[len(cache)-1-hashBytes/4] ^= 1
+	if cacheTailValid(cache) {
+		t.Fatal("corrupt cache accepted")
+	}
+}
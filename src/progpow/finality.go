@@ -0,0 +1,93 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var ErrInvalidTips = errors.New("invalid tips")
+
+// Finality estimates how settled a header is in one context of the hierarchy.
+type Finality struct {
+	Context int `json:"context"`
+	// Depth is the number of blocks of the context the tip is numbered above
+	// the header.
+	Depth uint64 `json:"depth"`
+	// DeltaS is the entropy, in the fixed point log format of IntrinsicLogS,
+	// the tip accumulated on top of the header in the context.
+	DeltaS *big.Int `json:"deltaS"`
+	// Confirmations is DeltaS in units of the entropy of a block of the
+	// context at the difficulty of the header: the blocks an attacker would
+	// have to outmine to displace the header.
+	Confirmations float64 `json:"confirmations"`
+	// Confidence is 1 - 2^-Confirmations, halving the remaining doubt with
+	// every block worth of entropy confirming the header.
+	Confidence float64 `json:"confidence"`
+}
+
+// FinalityEstimate scores how settled header is in every context a current
+// tip is given for, currentTips[ctx] being the latest block of the chain of
+// context ctx at the location of the header, nil to skip the context. The
+// score is grounded in the entropy of the verified seals rather than block
+// counts, so a few heavy blocks settle a header as much as many light ones.
+//
+// The seals of the header and of every tip are verified, their errors are
+// returned wrapped with their position. The estimate cannot check that the
+// tips descend from the header, which the caller must ensure; tips holding
+// no more entropy than the header score zero.
+func (progpow *Progpow) FinalityEstimate(header *types.Header, currentTips []*types.Header) ([]Finality, error) {
+	if len(currentTips) > common.HierarchyDepth {
+		return nil, fmt.Errorf("%w: %d tips for %d contexts", ErrInvalidTips, len(currentTips), common.HierarchyDepth)
+	}
+	intrinsicS, _, err := progpow.CalcOrder(header)
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	var (
+		estimates  = make([]Finality, 0, len(currentTips))
+		thresholdS = zoneThresholdLogS(header.Difficulty())
+		timeFactor = new(big.Int).Mul(progpow.timeFactorAt(header.Location()), big.NewInt(common.HierarchyDepth))
+	)
+	for ctx, tip := range currentTips {
+		if tip == nil {
+			continue
+		}
+		tipS, _, err := progpow.CalcOrder(tip)
+		if err != nil {
+			return nil, fmt.Errorf("tip %d: %w", ctx, err)
+		}
+		estimate := Finality{Context: ctx, DeltaS: new(big.Int)}
+		if number, tipNumber := header.NumberU64In(common.Context(ctx)), tip.NumberU64In(common.Context(ctx)); tipNumber > number {
+			estimate.Depth = tipNumber - number
+		}
+		totalS, err := entropyIn(header, common.Context(ctx), intrinsicS)
+		if err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+		tipTotalS, err := entropyIn(tip, common.Context(ctx), tipS)
+		if err != nil {
+			return nil, fmt.Errorf("tip %d: %w", ctx, err)
+		}
+		estimate.DeltaS.Sub(tipTotalS, totalS)
+		if estimate.DeltaS.Sign() < 0 {
+			estimate.DeltaS.SetUint64(0)
+		}
+		// A block of a dominant context takes timeFactor times the entropy
+		// of a block of the context below it
+		blockS := new(big.Int).Set(thresholdS)
+		for c := ctx; c < common.HierarchyDepth-1; c++ {
+			blockS.Mul(blockS, timeFactor)
+		}
+		if blockS.Sign() > 0 {
+			estimate.Confirmations, _ = new(big.Float).Quo(new(big.Float).SetInt(estimate.DeltaS), new(big.Float).SetInt(blockS)).Float64()
+		}
+		estimate.Confidence = 1 - math.Exp2(-estimate.Confirmations)
+		estimates = append(estimates, estimate)
+	}
+	return estimates, nil
+}
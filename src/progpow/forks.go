@@ -0,0 +1,111 @@
+package progpow
+
+import (
+	"fmt"
+	"sync"
+)
+
+// KernelGenesis is the version of the kernel Quai runs since genesis, which
+// blocks before the first fork of a ChainConfig are verified with.
+const KernelGenesis = 1
+
+// KernelParams are the tunables of the ProgPoW kernel a version of the
+// algorithm hashes with. The cache and dataset sizing is shared by all
+// versions, as verification caches are keyed by epoch alone.
+type KernelParams struct {
+	PeriodLength uint64 `json:"periodLength"` // Blocks a random program is kept for
+	CntDag       uint32 `json:"cntDag"`       // Rounds of DAG accesses per hash
+	CntCache     uint32 `json:"cntCache"`     // Cache accesses per round
+	CntMath      uint32 `json:"cntMath"`      // Math operations per round
+}
+
+// kernels is the registry of kernel versions forks can switch to.
+var kernels = struct {
+	lock   sync.RWMutex
+	params map[int]KernelParams
+}{
+	params: map[int]KernelParams{
+		KernelGenesis: {
+			PeriodLength: progpowPeriodLength,
+			CntDag:       progpowCntDag,
+			CntCache:     progpowCntCache,
+			CntMath:      progpowCntMath,
+		},
+	},
+}
+
+// RegisterKernel adds a version of the kernel forks can switch to. Versions
+// are never replaced, so headers verified under one keep verifying.
+func RegisterKernel(version int, params KernelParams) error {
+	if params.PeriodLength == 0 || params.CntDag == 0 || params.CntMath == 0 {
+		return fmt.Errorf("%w: zero kernel parameter in version %d", ErrInvalidConfig, version)
+	}
+	if params.CntCache > params.CntMath {
+		return fmt.Errorf("%w: %d cache accesses exceed %d math operations in version %d", ErrInvalidConfig, params.CntCache, params.CntMath, version)
+	}
+	kernels.lock.Lock()
+	defer kernels.lock.Unlock()
+
+	if _, ok := kernels.params[version]; ok {
+		return fmt.Errorf("%w: kernel version %d already registered", ErrInvalidConfig, version)
+	}
+	kernels.params[version] = params
+	return nil
+}
+
+// Kernel returns the parameters of a registered kernel version.
+func Kernel(version int) (KernelParams, bool) {
+	kernels.lock.RLock()
+	defer kernels.lock.RUnlock()
+
+	params, ok := kernels.params[version]
+	return params, ok
+}
+
+// Fork switches the kernel blocks are hashed with from a zone block number on.
+type Fork struct {
+	Block  uint64 `json:"block"`
+	Kernel int    `json:"kernel"`
+}
+
+// ChainConfig schedules the kernel versions along the zone block numbers of a
+// network. Blocks before the first fork, and all blocks of an empty schedule,
+// use KernelGenesis.
+type ChainConfig struct {
+	Forks []Fork `json:"forks"`
+}
+
+// validate rejects schedules forking to unregistered kernels or not listing
+// the forks in ascending block order.
+func (c *ChainConfig) validate() error {
+	for i, fork := range c.Forks {
+		if _, ok := Kernel(fork.Kernel); !ok {
+			return fmt.Errorf("%w: unknown kernel %d forked to at block %d", ErrInvalidConfig, fork.Kernel, fork.Block)
+		}
+		if i > 0 && c.Forks[i-1].Block >= fork.Block {
+			return fmt.Errorf("%w: fork at block %d follows fork at block %d", ErrInvalidConfig, fork.Block, c.Forks[i-1].Block)
+		}
+	}
+	return nil
+}
+
+// KernelAt returns the kernel version zone block number is hashed with.
+func (c *ChainConfig) KernelAt(number uint64) int {
+	version := KernelGenesis
+	if c == nil {
+		return version
+	}
+	for _, fork := range c.Forks {
+		if fork.Block > number {
+			break
+		}
+		version = fork.Kernel
+	}
+	return version
+}
+
+// kernelAt returns the kernel parameters zone block number is hashed with.
+func (c *ChainConfig) kernelAt(number uint64) KernelParams {
+	params, _ := Kernel(c.KernelAt(number))
+	return params
+}
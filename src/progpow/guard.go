@@ -0,0 +1,225 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+)
+
+// ErrOverloaded is returned by seal verification when the verification caches
+// it needs do not fit under the memory ceiling of the engine's MemoryGuard,
+// even after evicting every cache not in use.
+var ErrOverloaded = errors.New("memory ceiling reached")
+
+// MemoryGuard caps the memory held by the engines sharing it and by any other
+// consumers tracked with it, such as header stores or result memos. Before a
+// verification needs a cache that is not tracked yet, the guard checks the
+// cache still fits under the ceiling. If it does not, the guard evicts the
+// least recently used caches of its engines, other than those the verification
+// needs, and rejects the verification with ErrOverloaded if that is not
+// enough. Shedding load this way keeps a WASM module, whose linear memory
+// cannot grow past its ceiling, from aborting on an allocation failure.
+//
+// Caches count from the moment they are admitted: the guard reserves room for
+// them until their engine tracks them, and counts them from then on, before
+// they are generated, so concurrent admissions and generations still in
+// flight are accounted for. Memory held by consumers is only known to the
+// guard through their usage reports.
+type MemoryGuard struct {
+	ceiling uint64
+
+	lock      sync.Mutex
+	engines   []*Progpow
+	consumers map[string]func() uint64
+	reserved  map[*Progpow]map[uint64]int // Admitted epochs by engine, with the number of admissions holding them
+}
+
+// NewMemoryGuard creates a guard holding the engines and consumers sharing it
+// to at most ceiling bytes.
+func NewMemoryGuard(ceiling uint64) *MemoryGuard {
+	return &MemoryGuard{
+		ceiling:   ceiling,
+		consumers: make(map[string]func() uint64),
+		reserved:  make(map[*Progpow]map[uint64]int),
+	}
+}
+
+// Ceiling returns the number of bytes the guard caps memory at.
+func (g *MemoryGuard) Ceiling() uint64 {
+	return g.ceiling
+}
+
+// Track counts the bytes reported by usage against the ceiling, replacing any
+// consumer tracked under the same name. Usage is called with the guard locked,
+// so it must not call back into the guard.
+func (g *MemoryGuard) Track(name string, usage func() uint64) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.consumers[name] = usage
+}
+
+// Untrack stops counting the consumer tracked under name.
+func (g *MemoryGuard) Untrack(name string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	delete(g.consumers, name)
+}
+
+// Usage returns the bytes currently counted against the ceiling.
+func (g *MemoryGuard) Usage() uint64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.usage()
+}
+
+// usage sums the bytes of all engines and consumers, along with the room
+// reserved for the caches admitted but not tracked yet. Admitted epochs are
+// counted on their own, along with the future cache their lookup would
+// prepare, erring on the side of overcounting. The caller must hold g.lock.
+func (g *MemoryGuard) usage() uint64 {
+	var total uint64
+	for _, engine := range g.engines {
+		total += engine.caches.bytes()
+		for epoch := range g.reserved[engine] {
+			total += engine.caches.demand([]uint64{epoch})
+		}
+	}
+	for _, usage := range g.consumers {
+		total += usage()
+	}
+	return total
+}
+
+// register counts the caches of engine against the ceiling. Engines stay
+// registered for the lifetime of the guard.
+func (g *MemoryGuard) register(engine *Progpow) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.engines = append(g.engines, engine)
+}
+
+// admit reserves room for the caches of epochs engine is about to look up,
+// evicting the coldest caches of the registered engines, except those engine
+// is about to use, until they fit under the ceiling. It returns an error
+// wrapping ErrOverloaded, without reserving or evicting anything, if they
+// cannot be made to fit. Otherwise the room stays reserved until release is
+// called, which the caller must do once engine tracks the caches.
+func (g *MemoryGuard) admit(engine *Progpow, epochs []uint64) (release func(), err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	before := g.usage()
+	g.reserve(engine, epochs, 1)
+	usage := g.usage()
+	if usage > g.ceiling {
+		var reclaimable uint64
+		for _, e := range g.engines {
+			reclaimable += e.caches.reclaimable(g.spare(e, engine, epochs))
+		}
+		if usage-reclaimable > g.ceiling {
+			g.reserve(engine, epochs, -1)
+			return nil, fmt.Errorf("%w: need %d bytes, %d of %d in use", ErrOverloaded, usage-before, before, g.ceiling)
+		}
+		for usage > g.ceiling && g.shed(engine, epochs) {
+			usage = g.usage()
+		}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.lock.Lock()
+			defer g.lock.Unlock()
+
+			g.reserve(engine, epochs, -1)
+		})
+	}, nil
+}
+
+// reserve adds delta admissions holding epochs of engine. The caller must
+// hold g.lock.
+func (g *MemoryGuard) reserve(engine *Progpow, epochs []uint64, delta int) {
+	reserved := g.reserved[engine]
+	if reserved == nil {
+		reserved = make(map[uint64]int)
+		g.reserved[engine] = reserved
+	}
+	for _, epoch := range epochs {
+		if reserved[epoch] += delta; reserved[epoch] <= 0 {
+			delete(reserved, epoch)
+		}
+	}
+	if len(reserved) == 0 {
+		delete(g.reserved, engine)
+	}
+}
+
+// spare returns the epochs of e that must not be evicted to admit epochs to
+// engine: the epochs themselves and the future one their lookup prepares.
+func (g *MemoryGuard) spare(e, engine *Progpow, epochs []uint64) func(epoch uint64) bool {
+	if e != engine || len(epochs) == 0 {
+		return func(uint64) bool { return false }
+	}
+	return func(epoch uint64) bool {
+		if epoch == epochs[len(epochs)-1]+1 {
+			return true
+		}
+		i := sort.Search(len(epochs), func(i int) bool { return epochs[i] >= epoch })
+		return i < len(epochs) && epochs[i] == epoch
+	}
+}
+
+// shed evicts the coldest cache among the registered engines, sparing those
+// engine is about to use, and reports whether there was one to evict. The
+// caller must hold g.lock.
+func (g *MemoryGuard) shed(engine *Progpow, epochs []uint64) bool {
+	var (
+		victim *Progpow
+		epoch  uint64
+		used   uint64
+	)
+	for _, e := range g.engines {
+		if ep, u, ok := e.caches.coldest(g.spare(e, engine, epochs)); ok && (victim == nil || u < used) {
+			victim, epoch, used = e, ep, u
+		}
+	}
+	if victim == nil {
+		return false
+	}
+	victim.caches.evict(epoch)
+	log.Debug("Evicted ethash cache to stay under memory ceiling", "epoch", epoch, "ceiling", g.ceiling)
+	return true
+}
+
+// admit reserves room under the memory ceiling for the verification caches of
+// the given epochs that are not tracked yet, until release is called once
+// they are looked up. Engines without a MemoryGuard admit everything.
+func (progpow *Progpow) admit(epochs ...uint64) (release func(), err error) {
+	guard := progpow.config.MemoryGuard
+	if guard == nil || progpow.config.PowMode == ModeFake || progpow.config.PowMode == ModeFullFake {
+		return func() {}, nil
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	if progpow.caches.demand(epochs) == 0 {
+		return func() {}, nil
+	}
+	return guard.admit(progpow, epochs)
+}
+
+// admitted looks up the verification cache for a block number through lookup
+// once the memory guard admits it, holding the room reserved for the cache
+// until lookup tracks it.
+func (progpow *Progpow) admitted(block uint64, lookup func(block uint64) (*cache, error)) (*cache, error) {
+	release, err := progpow.admit(block / epochLength)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return lookup(block)
+}
@@ -0,0 +1,69 @@
+package progpow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// Admissions reserve room until their caches are tracked, so concurrent ones
+// cannot overshoot the ceiling together.
+func TestMemoryGuardReserves(t *testing.T) {
+	// Every lookup tracks its cache and prepares the one of the next epoch
+	guard := NewMemoryGuard(3 * cacheBytes(0, true))
+	engine, err := New(Config{PowMode: ModeTest, MemoryGuard: guard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := engine.admit(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.admit(5); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("admission beyond a reservation: %v, want %v", err, ErrOverloaded)
+	}
+	release()
+	release() // Releasing again is a no-op
+	if usage := guard.Usage(); usage != 0 {
+		t.Fatalf("usage %d after release, want 0", usage)
+	}
+	release, err = engine.admit(5)
+	if err != nil {
+		t.Fatalf("admission after release: %v", err)
+	}
+	release()
+
+	// Tracked caches are counted instead, and shed for later admissions
+	if _, err := engine.admittedCache(0); err != nil {
+		t.Fatal(err)
+	}
+	if usage := guard.Usage(); usage != 2*cacheBytes(0, true) {
+		t.Fatalf("usage %d with a tracked cache, want %d", usage, 2*cacheBytes(0, true))
+	}
+	if _, err := engine.admittedCache(5 * epochLength); err != nil {
+		t.Fatalf("admission shedding a tracked cache: %v", err)
+	}
+}
+
+// Proof-of-work computations go through the memory guard like verifications.
+func TestComputePowLightGuarded(t *testing.T) {
+	engine, err := New(Config{PowMode: ModeTest, MemoryGuard: NewMemoryGuard(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := decodeSealed(t)
+	if _, _, err := engine.ComputePowLightCtx(context.Background(), header); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("ComputePowLightCtx: %v, want %v", err, ErrOverloaded)
+	}
+	if mixHash, powHash := engine.ComputePowLight(header); mixHash != (common.Hash{}) || powHash != (common.Hash{}) {
+		t.Errorf("ComputePowLight computed %x, %x beyond the ceiling", mixHash, powHash)
+	}
+	if _, err := engine.CacheReady(header.NumberU64()); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("CacheReady: %v, want %v", err, ErrOverloaded)
+	}
+	if stats := engine.CacheStats(); len(stats.Caches) != 0 {
+		t.Errorf("caches %v tracked beyond the ceiling", stats.Caches)
+	}
+}
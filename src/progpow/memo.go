@@ -0,0 +1,113 @@
+package progpow
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// memoKey identifies a seal verification by the sealed content and the nonce
+// sealing it.
+type memoKey struct {
+	sealHash common.Hash
+	nonce    uint64
+}
+
+// memoEntry is the outcome of a seal verification.
+type memoEntry struct {
+	number  uint64
+	powHash common.Hash
+	mixHash common.Hash
+	err     error // Nil if the seal is valid
+	time    time.Time
+}
+
+// verifiedMemo remembers the outcomes of the most recent seal verifications.
+type verifiedMemo struct {
+	lock    sync.Mutex
+	entries *lrucache.LRU[memoKey, memoEntry]
+}
+
+// newVerifiedMemo creates a memo of the given number of verifications, or nil
+// if size is zero.
+func newVerifiedMemo(size int) *verifiedMemo {
+	if size == 0 {
+		return nil
+	}
+	return &verifiedMemo{entries: lrucache.New[memoKey, memoEntry](size, 0, nil)}
+}
+
+// record remembers the outcome of verifying the seal of header.
+func (m *verifiedMemo) record(header *types.Header, powHash, mixHash common.Hash, err error, now time.Time) {
+	if m == nil {
+		return
+	}
+	key := memoKey{sealHash: header.SealHash(), nonce: header.NonceU64()}
+	entry := memoEntry{number: header.NumberU64(), powHash: powHash, mixHash: mixHash, err: err, time: now}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.entries.Add(key, entry, 1)
+}
+
+// verifiedRecord is the NDJSON representation of a memoized verification.
+type verifiedRecord struct {
+	SealHash common.Hash `json:"sealHash"`
+	Nonce    uint64      `json:"nonce"`
+	Number   uint64      `json:"number"`
+	PowHash  common.Hash `json:"powHash"`
+	Verdict  string      `json:"verdict"`
+	Error    string      `json:"error,omitempty"`
+	Time     time.Time   `json:"time"`
+}
+
+// DumpVerified writes the memoized seal verification results to w as
+// newline-delimited JSON, one object per verification from the oldest to the
+// most recent, carrying the seal hash, nonce, block number, powHash, verdict
+// ("valid" or "invalid", with the reason in error) and time of verification.
+// Only verifications that computed the proof-of-work are memoized; seals
+// rejected before, such as for a missing cache, are not. Nothing is written
+// unless Config.VerifiedMemo is set.
+func (progpow *Progpow) DumpVerified(w io.Writer) error {
+	// If we're running a shared PoW, dump its results instead
+	if progpow.shared != nil {
+		return progpow.shared.DumpVerified(w)
+	}
+	memo := progpow.verified
+	if memo == nil {
+		return nil
+	}
+	// Snapshot the records, so verifications are not stalled by slow writers
+	memo.lock.Lock()
+	keys := memo.entries.Keys()
+	records := make([]verifiedRecord, 0, len(keys))
+	for _, key := range keys {
+		entry, _ := memo.entries.Peek(key)
+		record := verifiedRecord{
+			SealHash: key.sealHash,
+			Nonce:    key.nonce,
+			Number:   entry.number,
+			PowHash:  entry.powHash,
+			Verdict:  "valid",
+			Time:     entry.time,
+		}
+		if entry.err != nil {
+			record.Verdict, record.Error = "invalid", entry.err.Error()
+		}
+		records = append(records, record)
+	}
+	memo.lock.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package progpow
+
+import "runtime"
+
+// CacheMemory describes the memory held by the verification cache of an epoch.
+type CacheMemory struct {
+	Epoch      uint64 `json:"epoch"`
+	Generated  bool   `json:"generated"`  // Cache content is generated, sizes are zero otherwise
+	Future     bool   `json:"future"`     // Cache was prepared ahead of the epoch being requested
+	Mapped     bool   `json:"mapped"`     // Cache words are memory mapped from CacheDir
+	CacheBytes uint64 `json:"cacheBytes"` // Size of the cache words
+	CDagBytes  uint64 `json:"cDagBytes"`  // Size of the progpow cDag, always on the heap
+}
+
+// MemoryReport enumerates the memory held by an engine.
+type MemoryReport struct {
+	Caches      []CacheMemory `json:"caches"`      // Tracked caches, most recently used first
+	HeapBytes   uint64        `json:"heapBytes"`   // Cache and cDag bytes allocated on the heap
+	MappedBytes uint64        `json:"mappedBytes"` // Cache bytes backed by memory mapped files
+}
+
+// MemoryReport enumerates the bytes held by every tracked epoch cache and its
+// cDag. Caches still being generated are listed without sizes.
+func (progpow *Progpow) MemoryReport() MemoryReport {
+	caches := progpow.caches.resident()
+	resident := len(caches)
+	if future, ok := progpow.caches.upcoming(); ok {
+		caches = append(caches, future)
+	}
+	report := MemoryReport{Caches: make([]CacheMemory, 0, len(caches))}
+	for i, c := range caches {
+		mem := CacheMemory{Epoch: c.epoch, Generated: c.ready(), Future: i >= resident}
+		if mem.Generated {
+			mem.Mapped = c.mmap != nil
+			mem.CacheBytes = uint64(len(c.cache)) * 4
+			mem.CDagBytes = uint64(len(c.cDag)) * 4
+
+			if mem.Mapped {
+				report.MappedBytes += mem.CacheBytes
+				report.HeapBytes += mem.CDagBytes
+			} else {
+				report.HeapBytes += mem.CacheBytes + mem.CDagBytes
+			}
+		}
+		report.Caches = append(report.Caches, mem)
+	}
+	return report
+}
+
+// CacheGenerationBytes returns the heap the verification cache of an epoch
+// occupies at the peak of its generation: the cache words, the cDag and, if
+// caches are persisted to a CacheStore, the serialized copy exchanged with it.
+// Memory mapped caches in CacheDir are not counted, they live off the heap.
+func (progpow *Progpow) CacheGenerationBytes(epoch uint64) uint64 {
+	size := cacheBytes(epoch, progpow.config.PowMode == ModeTest)
+	if progpow.config.CacheStore != nil {
+		size += size - progpowCacheBytes
+	}
+	return size
+}
+
+// Preallocate grows the heap by the memory needed to generate the verification
+// caches of an epoch and of the following one, which the engine prepares ahead
+// of time, and releases it again, so generation reuses the memory instead of
+// growing the heap piecemeal. On WASM, where the linear memory grows in steps
+// that each pause the module and never shrinks, this moves the growth pauses
+// out of the first verification. Elsewhere it is harmless but rarely useful.
+func (progpow *Progpow) Preallocate(epoch uint64) {
+	reserve := make([]byte, progpow.CacheGenerationBytes(epoch)+progpow.CacheGenerationBytes(epoch+1))
+	runtime.KeepAlive(reserve)
+
+	// Collect the reservation right away, so its pages are free for reuse
+	// before any cache allocation can grow the heap further
+	runtime.GC()
+}
+
+// EpochCacheSize is the memory an epoch's verification cache is counted with.
+type EpochCacheSize struct {
+	Epoch  uint64 `json:"epoch"`
+	Bytes  uint64 `json:"bytes"`
+	Future bool   `json:"future"` // Cache was prepared ahead of the epoch being requested
+}
+
+// CacheStats describes the in-memory verification caches of an engine and
+// how well they serve its verifications.
+type CacheStats struct {
+	Caches     []EpochCacheSize `json:"caches"`     // Tracked caches, most recently used first
+	TotalBytes uint64           `json:"totalBytes"` // Sum of the sizes of the tracked caches
+	MaxItems   int              `json:"maxItems"`   // Bound on the number of caches, zero if unbounded
+	MaxBytes   uint64           `json:"maxBytes"`   // Bound on their total size, zero if unbounded
+
+	Hits      uint64 `json:"hits"`      // Lookups served by a tracked cache
+	Misses    uint64 `json:"misses"`    // Lookups needing a new cache
+	Evictions uint64 `json:"evictions"` // Caches dropped to stay within the bounds or the memory ceiling
+}
+
+// CacheStats returns the sizes of the tracked verification caches, counted
+// whether generated yet or not, along with the lookup and eviction counters
+// of the engine since its creation.
+func (progpow *Progpow) CacheStats() CacheStats {
+	stats := progpow.caches.stats()
+	stats.MaxItems = progpow.config.CachesInMem
+	stats.MaxBytes = progpow.config.MaxCacheBytes
+	return stats
+}
@@ -0,0 +1,163 @@
+package progpow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// Metrics creates the instruments an engine reports its measurements through,
+// so embedders can feed them into their telemetry stack of choice. Packages
+// metrics/prometheus and metrics/statsd adapt the common ones; the Prometheus
+// client types satisfy the instrument interfaces as they are. An instrument
+// may be requested more than once, implementations should hand out the same
+// one for the same name and labels. Instruments are updated from any
+// goroutine, so they must be safe for concurrent use.
+//
+// Counters may be partitioned by labels, as Prometheus does; a name is always
+// requested with the same label names, and the totals across label values
+// are left to the monitoring system to sum.
+//
+// The engine reports
+//
+//	seals_verified_total       counter, seals checked, valid or not
+//	seal_failures_total        counter, seals failing verification, labelled by
+//	                           reason: malformed, difficulty, mixhash, pow or other
+//	seal_result_hits_total     counter, seals verified with a remembered proof-of-work
+//	seal_result_misses_total   counter, seals hashed with Config.SealResults set
+//	cache_hits_total           counter, seals hashed with their epoch's cache generated
+//	cache_misses_total         counter, seals hashed after waiting for their epoch's cache
+//	cache_generation_seconds   histogram, time an epoch's cache took to generate or load
+//
+// Ratios, such as the cache hit ratio, are left to the monitoring system to
+// compute from the counters, e.g. in PromQL
+//
+//	rate(cache_hits_total[5m]) / (rate(cache_hits_total[5m]) + rate(cache_misses_total[5m]))
+type Metrics interface {
+	Counter(name string, labels ...Label) Counter
+	Gauge(name string) Gauge
+	Histogram(name string) Histogram
+}
+
+// Label is the value of a dimension an instrument is partitioned by.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Counter is a monotonically increasing value.
+type Counter interface {
+	Add(delta float64)
+}
+
+// Gauge is a value which may go up and down.
+type Gauge interface {
+	Set(value float64)
+}
+
+// Histogram samples the distribution of observed values.
+type Histogram interface {
+	Observe(value float64)
+}
+
+// NoopMetrics discards all measurements. It is the default of engines created
+// without Config.Metrics.
+type NoopMetrics struct{}
+
+func (NoopMetrics) Counter(name string, labels ...Label) Counter { return noopInstrument{} }
+func (NoopMetrics) Gauge(name string) Gauge                      { return noopInstrument{} }
+func (NoopMetrics) Histogram(name string) Histogram              { return noopInstrument{} }
+
+type noopInstrument struct{}
+
+func (noopInstrument) Add(float64)     {}
+func (noopInstrument) Set(float64)     {}
+func (noopInstrument) Observe(float64) {}
+
+// Reasons seals fail verification for, the values of the reason label of
+// seal_failures_total.
+var sealFailureReasons = []string{"malformed", "difficulty", "mixhash", "pow", "other"}
+
+// engineMetrics holds the instruments updated for every verified seal, looked
+// up once when the engine is created.
+type engineMetrics struct {
+	sealsVerified    Counter
+	sealFailures     map[string]Counter // Failures by reason
+	sealResultHits   Counter
+	sealResultMisses Counter
+	cacheHits        Counter
+	cacheMisses      Counter
+}
+
+func newEngineMetrics(m Metrics) *engineMetrics {
+	reasons := make(map[string]Counter, len(sealFailureReasons))
+	for _, reason := range sealFailureReasons {
+		reasons[reason] = m.Counter("seal_failures_total", Label{Name: "reason", Value: reason})
+	}
+	return &engineMetrics{
+		sealsVerified:    m.Counter("seals_verified_total"),
+		sealFailures:     reasons,
+		sealResultHits:   m.Counter("seal_result_hits_total"),
+		sealResultMisses: m.Counter("seal_result_misses_total"),
+		cacheHits:        m.Counter("cache_hits_total"),
+		cacheMisses:      m.Counter("cache_misses_total"),
+	}
+}
+
+// observeSeal records the outcome of a seal verification. Verifications which
+// bailed out for lack of a verification cache, or of memory to generate it, or
+// were given up by their caller, checked nothing and are skipped.
+func (m *engineMetrics) observeSeal(err error) {
+	if errors.Is(err, ErrCacheNotReady) || errors.Is(err, ErrOverloaded) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	m.sealsVerified.Add(1)
+	if err != nil {
+		m.sealFailures[sealFailureReason(err)].Add(1)
+	}
+}
+
+// sealFailureReason classifies the error of a failed seal verification.
+func sealFailureReason(err error) string {
+	switch {
+	case errors.Is(err, types.ErrMalformedHeader):
+		return "malformed"
+	case errors.Is(err, errInvalidDifficulty):
+		return "difficulty"
+	case errors.Is(err, errInvalidMixHash):
+		return "mixhash"
+	case errors.Is(err, errInvalidPoW):
+		return "pow"
+	default:
+		return "other"
+	}
+}
+
+// observeSealResult records whether a seal was verified with a remembered
+// proof-of-work, the hit rate being the share of hits among both counters.
+func (m *engineMetrics) observeSealResult(hit bool) {
+	if hit {
+		m.sealResultHits.Add(1)
+	} else {
+		m.sealResultMisses.Add(1)
+	}
+}
+
+// observeCache records whether a seal was hashed with the verification cache
+// of its epoch generated already, the hit rate being the share of hits among
+// both counters.
+func (m *engineMetrics) observeCache(hit bool) {
+	if hit {
+		m.cacheHits.Add(1)
+	} else {
+		m.cacheMisses.Add(1)
+	}
+}
+
+// observeCacheGeneration records the time since start in the cache generation
+// histogram of m.
+func observeCacheGeneration(m Metrics, start time.Time) {
+	m.Histogram("cache_generation_seconds").Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,68 @@
+package progpow
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// recordingMetrics keeps the counts of its counters by name and labels.
+type recordingMetrics struct {
+	NoopMetrics
+	lock   sync.Mutex
+	counts map[string]float64
+}
+
+func (m *recordingMetrics) Counter(name string, labels ...Label) Counter {
+	for _, label := range labels {
+		name += "{" + label.Name + "=" + label.Value + "}"
+	}
+	return recordingCounter{m, name}
+}
+
+type recordingCounter struct {
+	metrics *recordingMetrics
+	name    string
+}
+
+func (c recordingCounter) Add(delta float64) {
+	c.metrics.lock.Lock()
+	defer c.metrics.lock.Unlock()
+	c.metrics.counts[c.name] += delta
+}
+
+// Seal failures are counted in a single counter labelled by reason.
+func TestSealFailureMetrics(t *testing.T) {
+	metrics := &recordingMetrics{counts: make(map[string]float64)}
+	engine, err := New(Config{PowMode: ModeTest, Metrics: metrics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(sealedHeader), header); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.VerifySeal(header); err != nil {
+		t.Fatal(err)
+	}
+	header.SetGasUsed(header.GasUsed() + 1)
+	if _, err := engine.VerifySeal(header); err == nil {
+		t.Fatal("tampered header verified")
+	}
+	want := map[string]float64{
+		"seals_verified_total":                  2,
+		"seal_failures_total{reason=mixhash}":   1,
+		"seal_failures_total{reason=malformed}": 0,
+	}
+	for name, count := range want {
+		if metrics.counts[name] != count {
+			t.Errorf("%s: got %v, want %v", name, metrics.counts[name], count)
+		}
+	}
+	if _, ok := metrics.counts["seal_failures_total"]; ok {
+		t.Error("unlabelled seal_failures_total reported")
+	}
+}
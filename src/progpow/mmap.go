@@ -0,0 +1,143 @@
+//go:build !js
+// +build !js
+
+package progpow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	mmap "github.com/edsrzf/mmap-go"
+	"lukechampine.com/blake3"
+)
+
+// diskSupported reports whether caches can be persisted to and memory mapped
+// from disk on this platform.
+const diskSupported = true
+
+// mappedMemory is a memory mapped region of a cache file.
+type mappedMemory = mmap.MMap
+
+// dumpHeaderBytes is the length of the header preceding the contents of a
+// dump: dumpMagic followed by the checksum of the contents.
+var dumpHeaderBytes = (len(dumpMagic) + dumpChecksumWords) * 4
+
+// memoryMap tries to memory map a file of uint32s for read only access. The
+// contents are checked against the checksum in the dump header, so truncated
+// or corrupted files are rejected rather than hashed with.
+func memoryMap(path string, lock bool) (*os.File, mappedMemory, []uint32, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mem, buffer, err := memoryMapFile(file, false)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	if n := len(mem); n < dumpHeaderBytes {
+		mem.Unmap()
+		file.Close()
+		return nil, nil, nil, fmt.Errorf("%w: %s holds %d bytes, header needs %d", ErrDumpChecksum, path, n, dumpHeaderBytes)
+	}
+	for i, magic := range dumpMagic {
+		if buffer[i] != magic {
+			mem.Unmap()
+			file.Close()
+			return nil, nil, nil, ErrInvalidDumpMagic
+		}
+	}
+	if sum := dumpChecksum(mem); !bytes.Equal(sum[:], mem[len(dumpMagic)*4:dumpHeaderBytes]) {
+		mem.Unmap()
+		file.Close()
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrDumpChecksum, path)
+	}
+	if lock {
+		if err := mem.Lock(); err != nil {
+			mem.Unmap()
+			file.Close()
+			return nil, nil, nil, err
+		}
+	}
+	return file, mem, buffer[dumpHeaderBytes/4:], err
+}
+
+// dumpChecksum returns the checksum of the contents of a mapped dump, as
+// stored in its header.
+func dumpChecksum(mem mmap.MMap) [32]byte {
+	return blake3.Sum256(mem[dumpHeaderBytes:])
+}
+
+// memoryMapFile tries to memory map an already opened file descriptor.
+func memoryMapFile(file *os.File, write bool) (mmap.MMap, []uint32, error) {
+	// Try to memory map the file
+	flag := mmap.RDONLY
+	if write {
+		flag = mmap.RDWR
+	}
+	mem, err := mmap.Map(file, flag, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Yay, we managed to memory map the file, here be dragons
+	header := *(*reflect.SliceHeader)(unsafe.Pointer(&mem))
+	header.Len /= 4
+	header.Cap /= 4
+
+	return mem, *(*[]uint32)(unsafe.Pointer(&header)), nil
+}
+
+// memoryMapAndGenerate tries to memory map a temporary file of uint32s for write
+// access, fill it with the data from a generator and then move it into the final
+// path requested. If the generator reports it was aborted, the temporary file
+// is removed and errCacheAborted returned.
+func memoryMapAndGenerate(path string, size uint64, lock bool, randInt func() int, generator func(buffer []uint32) bool) (*os.File, mappedMemory, []uint32, error) {
+	// Ensure the data folder exists
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, nil, err
+	}
+	// Create a huge temporary empty file to fill with data
+	temp := path + "." + strconv.Itoa(randInt())
+
+	dump, err := os.Create(temp)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = dump.Truncate(int64(dumpHeaderBytes) + int64(size)); err != nil {
+		return nil, nil, nil, err
+	}
+	// Memory map the file for writing and fill it with the generator
+	mem, buffer, err := memoryMapFile(dump, true)
+	if err != nil {
+		dump.Close()
+		return nil, nil, nil, err
+	}
+	copy(buffer, dumpMagic)
+
+	data := buffer[dumpHeaderBytes/4:]
+	if !generator(data) {
+		mem.Unmap()
+		dump.Close()
+		os.Remove(temp)
+		return nil, nil, nil, errCacheAborted
+	}
+
+	sum := dumpChecksum(mem)
+	copy(mem[len(dumpMagic)*4:], sum[:])
+
+	if err := mem.Unmap(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := dump.Close(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		return nil, nil, nil, err
+	}
+	return memoryMap(path, lock)
+}
@@ -0,0 +1,33 @@
+//go:build js
+// +build js
+
+package progpow
+
+import (
+	"errors"
+	"os"
+)
+
+// diskSupported reports whether caches can be persisted to and memory mapped
+// from disk on this platform. JavaScript hosts offer neither memory mapping nor
+// a real file system, so caches are kept in memory only.
+const diskSupported = false
+
+var errMmapUnsupported = errors.New("memory mapping not supported on js")
+
+// mappedMemory is a memory mapped region of a cache file. It is never
+// populated on js.
+type mappedMemory []byte
+
+// Unmap is a no-op, nothing is ever mapped on js.
+func (m mappedMemory) Unmap() error { return nil }
+
+// memoryMap is unsupported on js.
+func memoryMap(path string, lock bool) (*os.File, mappedMemory, []uint32, error) {
+	return nil, nil, nil, errMmapUnsupported
+}
+
+// memoryMapAndGenerate is unsupported on js.
+func memoryMapAndGenerate(path string, size uint64, lock bool, randInt func() int, generator func(buffer []uint32) bool) (*os.File, mappedMemory, []uint32, error) {
+	return nil, nil, nil, errMmapUnsupported
+}
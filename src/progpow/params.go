@@ -0,0 +1,95 @@
+package progpow
+
+import (
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+)
+
+// Params are the consensus parameters an engine verifies with, exported as a
+// flat key-value set so frontends do not need to hardcode values that drift
+// from the Go source.
+type Params struct {
+	PowMode           string `json:"powMode"`
+	AlgorithmRevision int    `json:"algorithmRevision"`
+
+	EpochLength        uint64 `json:"epochLength"`
+	PeriodLength       uint64 `json:"periodLength"`
+	CacheInitBytes     uint64 `json:"cacheInitBytes"`
+	CacheGrowthBytes   uint64 `json:"cacheGrowthBytes"`
+	DatasetInitBytes   uint64 `json:"datasetInitBytes"`
+	DatasetGrowthBytes uint64 `json:"datasetGrowthBytes"`
+
+	HierarchyDepth    int      `json:"hierarchyDepth"`
+	NumRegionsInPrime int      `json:"numRegionsInPrime"`
+	NumZonesInRegion  int      `json:"numZonesInRegion"`
+	NumChains         int      `json:"numChains"`
+	Contexts          []string `json:"contexts"`
+
+	MaxTarget     *hexutil.Big   `json:"maxTarget"`
+	MinDifficulty *hexutil.Big   `json:"minDifficulty,omitempty"`
+	DurationLimit *hexutil.Big   `json:"durationLimit,omitempty"`
+	GasCeil       hexutil.Uint64 `json:"gasCeil"`
+
+	// TimeFactor scales the order thresholds: a block is a region or prime
+	// block if its entropy clears thresholds derived from its difficulty and
+	// the time factor, see Progpow.TargetsFor.
+	TimeFactor    *hexutil.Big          `json:"timeFactor"`
+	ZoneOverrides map[string]ZoneParams `json:"zoneOverrides,omitempty"` // By zone name, see Config.ZoneOverrides
+
+	Forks []Fork `json:"forks,omitempty"` // Kernel versions scheduled beyond KernelGenesis
+}
+
+// ZoneParams are the consensus parameters a zone overrides, see ZoneOverride.
+// Unset ones take the engine wide values.
+type ZoneParams struct {
+	MinDifficulty *hexutil.Big `json:"minDifficulty,omitempty"`
+	TimeFactor    *hexutil.Big `json:"timeFactor,omitempty"`
+}
+
+// Params returns the consensus parameters the engine is configured with.
+func (progpow *Progpow) Params() Params {
+	hierarchy := common.Hierarchy()
+	params := Params{
+		PowMode:            progpow.config.PowMode.String(),
+		AlgorithmRevision:  algorithmRevision,
+		EpochLength:        epochLength,
+		PeriodLength:       progpowPeriodLength,
+		CacheInitBytes:     cacheInitBytes,
+		CacheGrowthBytes:   cacheGrowthBytes,
+		DatasetInitBytes:   datasetInitBytes,
+		DatasetGrowthBytes: datasetGrowthBytes,
+		HierarchyDepth:     common.HierarchyDepth,
+		NumRegionsInPrime:  hierarchy.Regions,
+		NumZonesInRegion:   hierarchy.Zones,
+		NumChains:          hierarchy.Chains(),
+		Contexts:           []string{"prime", "region", "zone"},
+		MaxTarget:          (*hexutil.Big)(new(big.Int).Sub(big2e256, common.Big1)),
+		GasCeil:            hexutil.Uint64(progpow.config.GasCeil),
+		TimeFactor:         (*hexutil.Big)(new(big.Int).Set(TimeFactor)),
+	}
+	if progpow.config.MinDifficulty != nil {
+		params.MinDifficulty = (*hexutil.Big)(new(big.Int).Set(progpow.config.MinDifficulty))
+	}
+	if progpow.config.ChainConfig != nil {
+		params.Forks = append([]Fork(nil), progpow.config.ChainConfig.Forks...)
+	}
+	if progpow.config.DurationLimit != nil {
+		params.DurationLimit = (*hexutil.Big)(new(big.Int).Set(progpow.config.DurationLimit))
+	}
+	if len(progpow.config.ZoneOverrides) > 0 {
+		params.ZoneOverrides = make(map[string]ZoneParams, len(progpow.config.ZoneOverrides))
+		for name, override := range progpow.config.ZoneOverrides {
+			var zone ZoneParams
+			if override.MinDifficulty != nil {
+				zone.MinDifficulty = (*hexutil.Big)(new(big.Int).Set(override.MinDifficulty))
+			}
+			if override.TimeFactor != nil {
+				zone.TimeFactor = (*hexutil.Big)(new(big.Int).Set(override.TimeFactor))
+			}
+			params.ZoneOverrides[name] = zone
+		}
+	}
+	return params
+}
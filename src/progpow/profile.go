@@ -0,0 +1,23 @@
+package progpow
+
+import (
+	"context"
+	"io"
+	"runtime/pprof"
+)
+
+// CPUProfileDuring writes a CPU profile covering the execution of fn to w, so
+// operators can capture where cache generation or batch verification spends
+// its time in production. Samples taken within fn are labelled with the
+// engine's verification mode. It fails if a CPU profile is already running.
+func (progpow *Progpow) CPUProfileDuring(w io.Writer, fn func()) error {
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+	defer pprof.StopCPUProfile()
+
+	pprof.Do(context.Background(), pprof.Labels("progpow.mode", progpow.config.PowMode.String()), func(context.Context) {
+		fn()
+	})
+	return nil
+}
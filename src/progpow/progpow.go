@@ -2,34 +2,48 @@ package progpow
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"math/rand"
 	"os"
 	"path/filepath"
-	"reflect"
 	"runtime"
-	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
 	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
 	"github.com/dominant-strategies/progpow-verification-wasm/log"
 	"github.com/dominant-strategies/progpow-verification-wasm/types"
-	mmap "github.com/edsrzf/mmap-go"
-	"github.com/hashicorp/golang-lru/simplelru"
 )
 
 var (
 	// algorithmRevision is the data structure version used for file naming.
-	algorithmRevision = 1
+	// Revision 2 added the checksums of cache files and stored caches.
+	algorithmRevision = 2
 	// dumpMagic is a dataset dump header to sanity check a data dump.
 	dumpMagic = []uint32{0xbaddcafe, 0xfee1dead}
 )
 
-var ErrInvalidDumpMagic = errors.New("invalid dump magic")
+// dumpChecksumWords is the length of the blake3 checksum of the dump contents
+// following dumpMagic in the dump header.
+const dumpChecksumWords = 8
+
+var (
+	ErrInvalidDumpMagic = errors.New("invalid dump magic")
+	ErrDumpChecksum     = errors.New("dump checksum mismatch")
+	// ErrCacheNotReady is returned by VerifySeal in non-blocking mode when the
+	// verification cache for the header's epoch is still being generated.
+	ErrCacheNotReady = errors.New("verification cache not ready")
+
+	// errCacheAborted reports a cache generation stopped before completion
+	// because every caller waiting for it gave up.
+	errCacheAborted = errors.New("cache generation aborted")
+)
 
 // Mode defines the type and amount of PoW verification a progpow engine makes.
 type Mode uint
@@ -42,34 +56,193 @@ const (
 	ModeFullFake
 )
 
+// String returns the name of the verification mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeNormal:
+		return "normal"
+	case ModeShared:
+		return "shared"
+	case ModeTest:
+		return "test"
+	case ModeFake:
+		return "fake"
+	case ModeFullFake:
+		return "fullfake"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint(m))
+	}
+}
+
 // lru tracks caches or datasets by their last use time, keeping at most N of them.
-type lru struct {
+type lru[T any] struct {
 	what string
-	new  func(epoch uint64) interface{}
+	new  func(epoch uint64) T
+	cost func(epoch uint64) uint64
 	mu   sync.Mutex
 	// Items are kept in a LRU cache, but there is a special case:
 	// We always keep an item for (highest seen epoch) + 1 as the 'future item'.
-	cache      *simplelru.LRU // imported from "github.com/hashicorp/golang-lru/simplelru"
+	cache      *lrucache.LRU[uint64, T]
 	future     uint64
-	futureItem interface{}
+	futureItem T
+
+	// Recently requested epochs, used to keep hot epochs resident when a mixed
+	// workload (e.g. a backfill job next to live verification) would otherwise
+	// thrash a pure LRU policy.
+	recent    [residencyWindow]uint64
+	recentPos int
+	recentLen int
+
+	// Last use of every tracked epoch on the clock shared by all lrus, so a
+	// MemoryGuard can find the coldest item across engines
+	used map[uint64]uint64
+
+	// Lookup and eviction counters since creation
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// lruClock orders item uses across all lrus.
+var lruClock atomic.Uint64
+
+// residencyWindow is the number of recent lookups considered when deciding
+// which epochs to keep resident.
+const residencyWindow = 64
+
+// newlru create a new least-recently-used cache for either the verification caches
+// or the mining datasets, holding at most maxItems items taking at most maxBytes
+// together. A zero bound is disabled, but one of them always applies, at least
+// a single item being kept. The cost function reports the number of bytes an
+// item for a given epoch occupies.
+func newlru[T any](what string, maxItems int, maxBytes uint64, new func(epoch uint64) T, cost func(epoch uint64) uint64) *lru[T] {
+	if maxItems <= 0 && maxBytes == 0 {
+		maxItems = 1
+	}
+	lru := &lru[T]{what: what, new: new, cost: cost, used: make(map[uint64]uint64)}
+	lru.cache = lrucache.New(maxItems, maxBytes, func(epoch uint64, item T) {
+		delete(lru.used, epoch)
+		lru.evictions++
+		log.Trace("Evicted ethash "+what, "epoch", epoch)
+	})
+	lru.cache.SetPinned(lru.pinned)
+	return lru
 }
 
 // Config are the configuration parameters of the progpow.
 type Config struct {
 	PowMode Mode
 
-	CacheDir       string
-	CachesInMem    int
-	CachesOnDisk   int
+	// CacheDir is the directory verification caches are persisted to. Leaving
+	// it empty disables disk storage entirely, in which case CachesOnDisk and
+	// CachesLockMmap must be left unset.
+	CacheDir string
+	// CachesInMem is the number of epoch caches kept in memory. Zero selects
+	// DefaultCachesInMem, or no limit on the count if MaxCacheBytes is set.
+	CachesInMem int
+	// MaxCacheBytes bounds the epoch caches kept in memory by their total
+	// size, evicting the least recently used ones to make room, rather than
+	// by their count alone. Memory constrained hosts such as browser tabs set
+	// it to their budget. A cache larger than the budget is still kept while
+	// it is the only one. Zero leaves the size unbounded.
+	MaxCacheBytes uint64
+	// CacheStore persists verification caches where memory mapped files in
+	// CacheDir are unavailable, such as IndexedDB in browsers. Caches are
+	// loaded from the store into memory instead of being regenerated, and
+	// stored after generation. It cannot be combined with CacheDir.
+	CacheStore CacheStore `toml:"-"`
+	// CachesOnDisk is the number of most recent epoch caches retained in
+	// CacheDir or CacheStore; older caches are deleted when a new one is
+	// generated. Zero selects DefaultCachesOnDisk if either is set.
+	CachesOnDisk int
+	// PruneEpochsBehind, if set, ties the retention of caches in CacheDir or
+	// CacheStore to the verified chain height instead of CachesOnDisk: the
+	// caches of epochs more than PruneEpochsBehind epochs behind the epoch of
+	// the highest block whose seal verified are deleted whenever that epoch
+	// advances, however many there are. Chain-following services keep the
+	// caches their backfills need while disk usage stays bounded.
+	PruneEpochsBehind int
+	// CachesLockMmap locks memory mapped caches into RAM.
 	CachesLockMmap bool
-	DurationLimit  *big.Int
-	GasCeil        uint64
-	MinDifficulty  *big.Int
+	// Preallocate reserves the heap needed by the verification cache of the
+	// first epoch when the engine is created, see Progpow.Preallocate.
+	Preallocate bool
+	// OnCacheProgress, if set, is called with the percentage of an epoch's
+	// cache generated so far, at most once per whole percent, and with 100
+	// once the cache is ready, including when it was loaded rather than
+	// generated. It runs on the generating goroutine, so it should return
+	// quickly.
+	OnCacheProgress func(epoch uint64, pct float64) `toml:"-"`
+	// Yield, if set, is called from cache generation and batch verification
+	// whenever they ran for YieldInterval without interruption, and blocks
+	// until the computation may resume. Single threaded hosts such as
+	// browsers map it to handing control back to their event loop, so the
+	// page stays responsive during multi-second work. Builds with the
+	// extension tag yield to the event loop even without a hook. The caches
+	// of the current and the next epoch are generated concurrently, so it may
+	// be called from several goroutines at once.
+	Yield func() `toml:"-"`
+	// YieldInterval is how long computations run between calls to Yield.
+	// Zero selects DefaultYieldInterval.
+	YieldInterval time.Duration
+	// Metrics receives the measurements of the engine, see Metrics. Nil
+	// discards them.
+	Metrics Metrics `toml:"-"`
+	// MemoryGuard, if set, caps the memory held by the verification caches of
+	// this and other engines sharing the guard. Seal verifications needing a
+	// cache beyond the ceiling evict the coldest caches, or fail with
+	// ErrOverloaded if that does not make room. Nil leaves memory uncapped.
+	MemoryGuard *MemoryGuard `toml:"-"`
+	// VerifiedMemo is the number of recent seal verification results
+	// remembered for offline analysis, see Progpow.DumpVerified. Zero
+	// remembers none.
+	VerifiedMemo int
+	// SealResults is the number of recently computed proofs-of-work remembered
+	// by seal hash and nonce, so verifying the same sealed header again, as
+	// when several peers gossip a block, skips hashing it. Zero remembers
+	// none.
+	SealResults int
+
+	// DurationLimit is the block time in seconds above which the difficulty
+	// adjustment lowers the difficulty. Nil selects DefaultDurationLimit.
+	DurationLimit *big.Int
+	GasCeil       uint64
+	// MinDifficulty is the minimum the difficulty adjustment may ever yield.
+	// Nil selects DefaultMinDifficulty.
+	MinDifficulty *big.Int
+	// ZoneOverrides replaces the difficulty floor and order thresholds within
+	// single zones, keyed by the zone name such as cyprus1.
+	ZoneOverrides map[string]ZoneOverride
+	// ChainConfig schedules the kernel versions headers are hashed with at
+	// forks. Nil hashes all headers with KernelGenesis.
+	ChainConfig *ChainConfig
 
 	// When set, notifications sent by the remote sealer will
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// When set, VerifySeal returns ErrCacheNotReady instead of blocking while
+	// the verification cache for the header's epoch is generated. Callers can
+	// wait on CacheReady to learn when the epoch becomes verifiable.
+	NonBlocking bool
+
+	// ColdStartBudget bounds how long VerifySeal waits for the verification
+	// cache of an epoch that is not generated yet. Once exceeded, it returns
+	// ErrCacheNotReady and generation continues in the background. Zero waits
+	// until the cache is complete. The cache cannot be consumed partially:
+	// every row is rewritten from pseudo-random earlier rows during the
+	// RandMemoHash rounds, and the cDag is derived from rows across the cache.
+	ColdStartBudget time.Duration
+
+	// Clock is the time source of timeouts and fake mode delays. Nil selects
+	// the system clock; tests can install a SimulatedClock instead.
+	Clock Clock `toml:"-"`
+
+	// Rand is the randomness source for temporary cache file names. Nil
+	// selects the process wide source of math/rand; tests can install a seeded
+	// source for reproducible names.
+	Rand rand.Source `toml:"-"`
+
 	Log *log.Logger `toml:"-"`
 }
 
@@ -77,41 +250,95 @@ type Config struct {
 type Progpow struct {
 	config Config
 
-	caches *lru // In memory caches to avoid regenerating too often
+	caches   *lru[*cache]   // In memory caches to avoid regenerating too often
+	metrics  *engineMetrics // Instruments of the seal verification path
+	verified *verifiedMemo  // Recent seal verification results, nil if disabled
+	results  *sealResults   // Recently computed proofs-of-work, nil if disabled
+	work     *pendingWork   // Work packages handed out to external miners
+
+	verifiedEpoch atomic.Uint64 // One above the epoch of the highest verified block, zero if none
+	pruneLock     sync.Mutex    // Serialises pruning of persisted caches
 
 	// The fields below are hooks for testing
 	shared    *Progpow      // Shared PoW verifier to avoid cache regeneration
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
 	fakeDelay time.Duration // Time delay to sleep for before returning from verify
+
+	randLock sync.Mutex // Serialises access to the configured Rand source
+}
+
+// New creates a full sized progpow PoW scheme. The options are applied on top
+// of config, after which the configuration is validated and unset fields are
+// filled with their defaults.
+func New(config Config, opts ...Option) (*Progpow, error) {
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.Sanitize(); err != nil {
+		return nil, err
+	}
+	if config.CacheDir != "" {
+		config.Log.Info("Disk storage enabled for ethash caches", "dir", config.CacheDir, "count", config.CachesOnDisk)
+	}
+	if config.CacheStore != nil {
+		config.Log.Info("Store enabled for ethash caches", "store", fmt.Sprintf("%T", config.CacheStore), "count", config.CachesOnDisk)
+	}
+	test := config.PowMode == ModeTest
+	progpow := &Progpow{
+		config:   config,
+		caches:   newlru("cache", config.CachesInMem, config.MaxCacheBytes, newCache, func(epoch uint64) uint64 { return cacheBytes(epoch, test) }),
+		metrics:  newEngineMetrics(config.Metrics),
+		verified: newVerifiedMemo(config.VerifiedMemo),
+		results:  newSealResults(config.SealResults),
+		work:     newPendingWork(),
+	}
+	if config.MemoryGuard != nil {
+		config.MemoryGuard.register(progpow)
+	}
+	if config.Preallocate {
+		progpow.Preallocate(0)
+	}
+	return progpow, nil
 }
 
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 type cache struct {
-	epoch uint64    // Epoch for which this cache is relevant
-	dump  *os.File  // File descriptor of the memory mapped cache
-	mmap  mmap.MMap // Memory map itself to unmap before releasing
-	cache []uint32  // The actual cache data content (may be memory mapped)
-	cDag  []uint32  // The cDag used by progpow. May be nil
-	once  sync.Once // Ensures the cache is generated only once
+	epoch uint64        // Epoch for which this cache is relevant
+	dump  *os.File      // File descriptor of the memory mapped cache
+	mmap  mappedMemory  // Memory map itself to unmap before releasing
+	cache []uint32      // The actual cache data content (may be memory mapped)
+	cDag  []uint32      // The cDag used by progpow. May be nil
+	once  sync.Once     // Ensures done is closed only once
+	done  chan struct{} // Closed once the cache content is generated
+
+	lock sync.Mutex // Protects the generation run below
+	run  *cacheRun  // Generation in progress or completed, nil if none started or it was aborted
 }
 
 // get retrieves or creates an item for the given epoch. The first return value is always
 // non-nil. The second return value is non-nil if lru thinks that an item will be useful in
 // the near future.
-func (lru *lru) get(epoch uint64) (item, future interface{}) {
+func (lru *lru[T]) get(epoch uint64) (item, future T) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 
+	lru.observe(epoch)
+	lru.used[epoch] = lruClock.Add(1)
+
 	// Get or create the item for the requested epoch.
 	item, ok := lru.cache.Get(epoch)
-	if !ok {
+	if ok {
+		lru.hits++
+	} else {
 		if lru.future > 0 && lru.future == epoch {
+			lru.hits++
 			item = lru.futureItem
 		} else {
+			lru.misses++
 			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch)
 			item = lru.new(epoch)
 		}
-		lru.cache.Add(epoch, item)
+		lru.cache.Add(epoch, item, lru.cost(epoch))
 	}
 	// Update the 'future item' if epoch is larger than previously seen.
 	if epoch < maxEpoch-1 && lru.future < epoch+1 {
@@ -123,62 +350,407 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 	return item, future
 }
 
-// generate ensures that the cache content is generated before use.
-func (c *cache) generate(dir string, limit int, lock bool, test bool) {
-	c.once.Do(func() {
-		size := cacheSize(c.epoch*epochLength + 1)
-		seed := seedHash(c.epoch*epochLength + 1)
-		if test {
-			size = 1024
+// peek returns the item tracked for the given epoch, if any, without creating
+// it or marking it used.
+func (lru *lru[T]) peek(epoch uint64) (item T, ok bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if item, ok = lru.cache.Peek(epoch); ok {
+		return item, true
+	}
+	if lru.future > 0 && lru.future == epoch {
+		return lru.futureItem, true
+	}
+	return item, false
+}
+
+// add inserts an externally constructed item for the given epoch, replacing
+// any item already tracked for it.
+func (lru *lru[T]) add(epoch uint64, item T) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if lru.future == epoch {
+		lru.futureItem = item
+	}
+	lru.used[epoch] = lruClock.Add(1)
+	lru.cache.Add(epoch, item, lru.cost(epoch))
+}
+
+// bytes returns the cost of all items held, including the future item.
+func (lru *lru[T]) bytes() uint64 {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	total := lru.cache.Cost()
+	if lru.future > 0 && !lru.cache.Contains(lru.future) {
+		total += lru.cost(lru.future)
+	}
+	return total
+}
+
+// demand returns the cost of the items a get of every epoch, given in
+// ascending order, would add, including the future item it would prepare.
+func (lru *lru[T]) demand(epochs []uint64) uint64 {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	var need uint64
+	for _, epoch := range epochs {
+		if !lru.cache.Contains(epoch) && (lru.future == 0 || lru.future != epoch) {
+			need += lru.cost(epoch)
+		}
+	}
+	if n := len(epochs); n > 0 && epochs[n-1] < maxEpoch-1 && lru.future < epochs[n-1]+1 {
+		need += lru.cost(epochs[n-1] + 1)
+	}
+	return need
+}
+
+// coldest returns the least recently used item not spared by keep, along with
+// the time of its last use on lruClock. A future item nobody requested yet is
+// the coldest of all.
+func (lru *lru[T]) coldest(keep func(epoch uint64) bool) (epoch uint64, used uint64, ok bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if lru.future > 0 && !lru.cache.Contains(lru.future) && !keep(lru.future) {
+		return lru.future, 0, true
+	}
+	for _, epoch := range lru.cache.Keys() {
+		if !keep(epoch) {
+			return epoch, lru.used[epoch], true
 		}
-		// If we don't store anything on disk, generate and return.
-		if dir == "" {
-			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
-			c.cDag = make([]uint32, progpowCacheWords)
-			generateCDag(c.cDag, c.cache, c.epoch)
-			return
+	}
+	return 0, 0, false
+}
+
+// reclaimable returns the cost of all items not spared by keep.
+func (lru *lru[T]) reclaimable(keep func(epoch uint64) bool) uint64 {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	var total uint64
+	if lru.future > 0 && !lru.cache.Contains(lru.future) && !keep(lru.future) {
+		total += lru.cost(lru.future)
+	}
+	for _, epoch := range lru.cache.Keys() {
+		if !keep(epoch) {
+			total += lru.cost(epoch)
 		}
-		// Disk storage is needed, this will get fancy
-		var endian string
-		if !isLittleEndian() {
-			endian = ".be"
+	}
+	return total
+}
+
+// evict stops tracking the item of an epoch, which may be the future item.
+// Users still holding the item keep it alive until they are done with it.
+func (lru *lru[T]) evict(epoch uint64) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if lru.future == epoch && !lru.cache.Contains(epoch) {
+		var none T
+		lru.future, lru.futureItem = 0, none
+		log.Trace("Dropped future ethash "+lru.what, "epoch", epoch)
+		return
+	}
+	if lru.cache.Remove(epoch) {
+		delete(lru.used, epoch)
+		lru.evictions++
+		log.Trace("Evicted ethash "+lru.what, "epoch", epoch)
+	}
+}
+
+// stats returns the sizes of the tracked items, most recently used first and
+// the future item last, and the lookup and eviction counters.
+func (lru *lru[T]) stats() CacheStats {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	keys := lru.cache.Keys()
+	stats := CacheStats{
+		Caches:    make([]EpochCacheSize, 0, len(keys)+1),
+		Hits:      lru.hits,
+		Misses:    lru.misses,
+		Evictions: lru.evictions,
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		size, _ := lru.cache.ItemCost(keys[i])
+		stats.Caches = append(stats.Caches, EpochCacheSize{Epoch: keys[i], Bytes: size})
+		stats.TotalBytes += size
+	}
+	if lru.future > 0 && !lru.cache.Contains(lru.future) {
+		size := lru.cost(lru.future)
+		stats.Caches = append(stats.Caches, EpochCacheSize{Epoch: lru.future, Bytes: size, Future: true})
+		stats.TotalBytes += size
+	}
+	return stats
+}
+
+// resident returns the items currently held in memory, most recently used
+// first, without affecting their recency.
+func (lru *lru[T]) resident() []T {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	keys := lru.cache.Keys()
+	items := make([]T, 0, len(keys))
+	for i := len(keys) - 1; i >= 0; i-- {
+		if item, ok := lru.cache.Peek(keys[i]); ok {
+			items = append(items, item)
 		}
-		path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch")
-
-		// We're about to mmap the file, ensure that the mapping is cleaned up when the
-		// cache becomes unused.
-		runtime.SetFinalizer(c, (*cache).finalizer)
-
-		// Try to load the file from disk and memory map it
-		var err error
-		c.dump, c.mmap, c.cache, err = memoryMap(path, lock)
-		if err == nil {
-			logger.Debug("Loaded old ethash cache from disk")
-			c.cDag = make([]uint32, progpowCacheWords)
-			generateCDag(c.cDag, c.cache, c.epoch)
-			return
+	}
+	return items
+}
+
+// upcoming returns the item prepared for the epoch after the highest one seen,
+// if it is not tracked by the cache yet.
+func (lru *lru[T]) upcoming() (item T, ok bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	if lru.future == 0 || lru.cache.Contains(lru.future) {
+		return item, false
+	}
+	return lru.futureItem, true
+}
+
+// observe records a lookup of epoch in the residency window.
+func (lru *lru[T]) observe(epoch uint64) {
+	lru.recent[lru.recentPos] = epoch
+	lru.recentPos = (lru.recentPos + 1) % residencyWindow
+	if lru.recentLen < residencyWindow {
+		lru.recentLen++
+	}
+}
+
+// pinned reports whether epoch should be kept resident. The highest recently
+// requested epoch (the live chain head) is always pinned, as is any epoch that
+// accounts for at least a quarter of the recent lookups.
+func (lru *lru[T]) pinned(epoch uint64) bool {
+	var highest uint64
+	hits := 0
+	for i := 0; i < lru.recentLen; i++ {
+		if lru.recent[i] > highest {
+			highest = lru.recent[i]
 		}
-		logger.Debug("Failed to load old ethash cache", "err", err)
+		if lru.recent[i] == epoch {
+			hits++
+		}
+	}
+	return epoch == highest || hits*4 >= lru.recentLen
+}
+
+// newCache creates a new ethash verification cache.
+func newCache(epoch uint64) *cache {
+	return &cache{epoch: epoch, done: make(chan struct{})}
+}
+
+// cacheBytes returns the number of bytes the verification cache and cDag of
+// an epoch occupy.
+func cacheBytes(epoch uint64, test bool) uint64 {
+	if test {
+		return 1024 + progpowCacheBytes
+	}
+	return cacheSize(epoch*epochLength+1) + progpowCacheBytes
+}
+
+// cacheRun is a run generating the content of a cache. Callers waiting for
+// it through cache.wait may abandon it, which aborts the run once none of them
+// is left, unless some caller waits through cache.generate.
+type cacheRun struct {
+	ended   chan struct{} // Closed once the run completed or aborted
+	abort   chan struct{} // Closed to abort the run
+	waiters int           // Callers waiting for the run which may abandon it
+	kept    bool          // Whether a caller waits for the run until it ends
+	logger  log.Logger    // Logger of the caller which started the run
+}
+
+// join returns the generation run of the cache, starting one if none runs or
+// the last one was aborted, with start set if the caller has to run it. A run
+// logs through the logger of the caller starting it. Kept runs are never
+// aborted.
+func (c *cache) join(keep bool, logger log.Logger) (run *cacheRun, start bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.run == nil {
+		c.run = &cacheRun{ended: make(chan struct{}), abort: make(chan struct{}), logger: logger}
+		start = true
+	}
+	if keep {
+		c.run.kept = true
+	} else {
+		c.run.waiters++
+	}
+	return c.run, start
+}
+
+// generate ensures that the cache content is generated before use, logging
+// through logger if it has to start the generation.
+func (c *cache) generate(config *Config, randInt func() int, logger log.Logger) {
+	for !c.ready() {
+		run, start := c.join(true, logger)
+		if start {
+			c.execute(run, config, randInt)
+		}
+		<-run.ended
+	}
+}
+
+// execute performs a generation run, recording its end. An aborted run leaves
+// the cache empty for a later run to generate.
+func (c *cache) execute(run *cacheRun, config *Config, randInt func() int) {
+	generated := c.build(config, randInt, run)
 
-		// No previous cache available, create a new cache file to fill
-		c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, lock, func(buffer []uint32) { generateCache(buffer, c.epoch, seed) })
-		if err != nil {
-			logger.Error("Failed to generate mapped ethash cache", "err", err)
+	c.lock.Lock()
+	if generated {
+		c.once.Do(func() { close(c.done) })
+	} else {
+		// Release whatever the run mapped, a later run maps it anew
+		runtime.SetFinalizer(c, nil)
+		c.finalizer()
+		c.cache, c.cDag, c.run = nil, nil, nil
+	}
+	c.lock.Unlock()
+	close(run.ended)
+}
+
+// build generates the cache content, or loads it from disk or the cache store,
+// stopping early with false if the run is aborted.
+func (c *cache) build(config *Config, randInt func() int, run *cacheRun) (generated bool) {
+	var (
+		dir, store = config.CacheDir, config.CacheStore
+		limit      = config.CachesOnDisk
+		pruned     = config.PruneEpochsBehind > 0 // Retention follows the verified height instead
+		lock       = config.CachesLockMmap
+		test       = config.PowMode == ModeTest
+		yield      = config.yieldSettings()
+		start      = time.Now()
+	)
+	yield.abort = run.abort
+
+	// Report generation progress, and completion unless aborted, so progress
+	// displays are closed when the cache is loaded rather than generated
+	progress := func(pct float64) {}
+	if config.OnCacheProgress != nil {
+		progress = func(pct float64) { config.OnCacheProgress(c.epoch, pct) }
+	}
+	defer func() {
+		if generated {
+			progress(100)
+			observeCacheGeneration(config.Metrics, start)
+		}
+	}()
 
-			c.cache = make([]uint32, size/4)
-			generateCache(c.cache, c.epoch, seed)
+	size := cacheSize(c.epoch*epochLength + 1)
+	seed := seedHash(c.epoch*epochLength + 1)
+	if test {
+		size = 1024
+	}
+	generate := func(buffer []uint32) bool {
+		return generateCache(buffer, c.epoch, seed, run.logger, progress, yield)
+	}
+	// If caches are persisted to a store, load or generate in memory
+	if store != nil {
+		if pruned {
+			limit = 0
+		}
+		if c.cache = loadOrGenerate(store, c.epoch, size, limit, test, run.logger, generate); c.cache == nil {
+			return false
+		}
+		c.cDag = make([]uint32, progpowCacheWords)
+		return generateCDag(c.cDag, c.cache, c.epoch, run.logger, yield)
+	}
+	// If we don't store anything on disk, generate and return.
+	if dir == "" {
+		c.cache = make([]uint32, size/4)
+		if !generate(c.cache) {
+			return false
 		}
 		c.cDag = make([]uint32, progpowCacheWords)
-		generateCDag(c.cDag, c.cache, c.epoch)
-		// Iterate over all previous instances and delete old ones
+		return generateCDag(c.cDag, c.cache, c.epoch, run.logger, yield)
+	}
+	// Disk storage is needed, this will get fancy
+	path := cachePath(dir, c.epoch)
+	logger := run.logger.With("epoch", c.epoch)
+
+	// We're about to mmap the file, ensure that the mapping is cleaned up when the
+	// cache becomes unused.
+	runtime.SetFinalizer(c, (*cache).finalizer)
+
+	// Try to load the file from disk and memory map it
+	var err error
+	c.dump, c.mmap, c.cache, err = memoryMap(path, lock)
+	if err == nil {
+		logger.Debug("Loaded old ethash cache from disk")
+		c.cDag = make([]uint32, progpowCacheWords)
+		return generateCDag(c.cDag, c.cache, c.epoch, run.logger, yield)
+	}
+	if errors.Is(err, ErrDumpChecksum) {
+		logger.Warn("Regenerating corrupt ethash cache", "path", path, "err", err)
+	} else {
+		logger.Debug("Failed to load old ethash cache", "err", err)
+	}
+	// No previous cache available, create a new cache file to fill
+	c.dump, c.mmap, c.cache, err = memoryMapAndGenerate(path, size, lock, randInt, generate)
+	if errors.Is(err, errCacheAborted) {
+		return false
+	}
+	if err != nil {
+		logger.Error("Failed to generate mapped ethash cache", "err", err)
+
+		c.cache = make([]uint32, size/4)
+		if !generate(c.cache) {
+			return false
+		}
+	}
+	c.cDag = make([]uint32, progpowCacheWords)
+	if !generateCDag(c.cDag, c.cache, c.epoch, run.logger, yield) {
+		return false
+	}
+	// Iterate over all previous instances and delete old ones
+	if !pruned {
 		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
+			os.Remove(cachePath(dir, uint64(ep)))
+		}
+	}
+	removeOldRevisions(dir)
+	return true
+}
+
+// removeOldRevisions deletes the cache files of earlier algorithm revisions
+// from the cache directory, which are never loaded again.
+func removeOldRevisions(dir string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "cache-R*-*"))
+	for _, path := range paths {
+		var revision int
+		if _, err := fmt.Sscanf(filepath.Base(path), "cache-R%d-", &revision); err == nil && revision < algorithmRevision {
 			os.Remove(path)
 		}
-	})
+	}
+}
+
+// cachePath returns the path of the verification cache file of an epoch within
+// the cache directory.
+func cachePath(dir string, epoch uint64) string {
+	var endian string
+	if !isLittleEndian() {
+		endian = ".be"
+	}
+	seed := seedHash(epoch*epochLength + 1)
+	return filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
+}
+
+// ready reports whether the cache content has been generated.
+func (c *cache) ready() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // finalizer unmaps the memory and closes the file.
@@ -195,106 +767,85 @@ func (c *cache) finalizer() {
 // stored on disk, and finally generating one if none can be found.
 func (progpow *Progpow) cache(block uint64) *cache {
 	epoch := block / epochLength
-	currentI, futureI := progpow.caches.get(epoch)
-	current := currentI.(*cache)
-
-	// Wait for generation finish.
-	current.generate(progpow.config.CacheDir, progpow.config.CachesOnDisk, progpow.config.CachesLockMmap, progpow.config.PowMode == ModeTest)
+	current, future := progpow.caches.get(epoch)
 
-	// If we need a new future cache, now's a good time to regenerate it.
-	if futureI != nil {
-		future := futureI.(*cache)
-		go future.generate(progpow.config.CacheDir, progpow.config.CachesOnDisk, progpow.config.CachesLockMmap, progpow.config.PowMode == ModeTest)
+	// If we need a new future cache, now's a good time to regenerate it,
+	// alongside the current one rather than after it.
+	if future != nil {
+		go future.generate(&progpow.config, progpow.randInt, log.Log)
 	}
+	// Wait for generation finish.
+	current.generate(&progpow.config, progpow.randInt, log.Log)
 	return current
 }
 
-// memoryMap tries to memory map a file of uint32s for read only access.
-func memoryMap(path string, lock bool) (*os.File, mmap.MMap, []uint32, error) {
-	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	mem, buffer, err := memoryMapFile(file, false)
-	if err != nil {
-		file.Close()
-		return nil, nil, nil, err
-	}
-	for i, magic := range dumpMagic {
-		if buffer[i] != magic {
-			mem.Unmap()
-			file.Close()
-			return nil, nil, nil, ErrInvalidDumpMagic
-		}
-	}
-	if lock {
-		if err := mem.Lock(); err != nil {
-			mem.Unmap()
-			file.Close()
-			return nil, nil, nil, err
-		}
-	}
-	return file, mem, buffer[len(dumpMagic):], err
+// cacheReady reports whether the verification cache for the specified block
+// number is generated, so hashing with it does not wait for generation.
+func (progpow *Progpow) cacheReady(block uint64) bool {
+	cache, ok := progpow.caches.peek(block / epochLength)
+	return ok && cache.ready()
 }
 
-// memoryMapFile tries to memory map an already opened file descriptor.
-func memoryMapFile(file *os.File, write bool) (mmap.MMap, []uint32, error) {
-	// Try to memory map the file
-	flag := mmap.RDONLY
-	if write {
-		flag = mmap.RDWR
+// cacheWithin retrieves the verification cache for the specified block number,
+// waiting at most budget for it to be generated. If the cache is not ready in
+// time, or ctx is done first, generation carries on in the background and
+// ErrCacheNotReady, or ctx.Err(), is returned. Generation started here logs
+// through the logger of ctx.
+func (progpow *Progpow) cacheWithin(ctx context.Context, block uint64, budget time.Duration) (*cache, error) {
+	epoch := block / epochLength
+	current, future := progpow.caches.get(epoch)
+
+	logger := log.FromContext(ctx)
+	if future != nil {
+		go future.generate(&progpow.config, progpow.randInt, logger)
 	}
-	mem, err := mmap.Map(file, flag, 0)
-	if err != nil {
-		return nil, nil, err
+	if current.ready() {
+		return current, nil
 	}
-	// Yay, we managed to memory map the file, here be dragons
-	header := *(*reflect.SliceHeader)(unsafe.Pointer(&mem))
-	header.Len /= 4
-	header.Cap /= 4
-
-	return mem, *(*[]uint32)(unsafe.Pointer(&header)), nil
-}
-
-// memoryMapAndGenerate tries to memory map a temporary file of uint32s for write
-// access, fill it with the data from a generator and then move it into the final
-// path requested.
-func memoryMapAndGenerate(path string, size uint64, lock bool, generator func(buffer []uint32)) (*os.File, mmap.MMap, []uint32, error) {
-	// Ensure the data folder exists
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return nil, nil, nil, err
+	go current.generate(&progpow.config, progpow.randInt, logger)
+	if budget <= 0 {
+		return nil, ErrCacheNotReady
 	}
-	// Create a huge temporary empty file to fill with data
-	temp := path + "." + strconv.Itoa(rand.Int())
+	timer := progpow.config.Clock.NewTimer(budget)
+	defer timer.Stop()
 
-	dump, err := os.Create(temp)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	if err = dump.Truncate(int64(len(dumpMagic))*4 + int64(size)); err != nil {
-		return nil, nil, nil, err
+	select {
+	case <-current.done:
+		return current, nil
+	case <-timer.C():
+		return nil, ErrCacheNotReady
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	// Memory map the file for writing and fill it with the generator
-	mem, buffer, err := memoryMapFile(dump, true)
-	if err != nil {
-		dump.Close()
-		return nil, nil, nil, err
+}
+
+// randInt returns a non-negative pseudo-random int from the configured source.
+func (progpow *Progpow) randInt() int {
+	if progpow.config.Rand == nil {
+		return rand.Int()
 	}
-	copy(buffer, dumpMagic)
+	progpow.randLock.Lock()
+	defer progpow.randLock.Unlock()
 
-	data := buffer[len(dumpMagic):]
-	generator(data)
+	return int(progpow.config.Rand.Int63())
+}
 
-	if err := mem.Unmap(); err != nil {
-		return nil, nil, nil, err
-	}
-	if err := dump.Close(); err != nil {
-		return nil, nil, nil, err
+// CacheReady returns a channel that is closed once the verification cache for
+// the specified block number is generated. If generation has not started yet,
+// it is kicked off in the background, unless the MemoryGuard has no room for
+// the cache, which fails with ErrOverloaded.
+func (progpow *Progpow) CacheReady(block uint64) (<-chan struct{}, error) {
+	current, err := progpow.admitted(block, func(block uint64) (*cache, error) {
+		current, _ := progpow.caches.get(block / epochLength)
+		return current, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if err := os.Rename(temp, path); err != nil {
-		return nil, nil, nil, err
+	if !current.ready() {
+		go current.generate(&progpow.config, progpow.randInt, log.Log)
 	}
-	return memoryMap(path, lock)
+	return current.done, nil
 }
 
 // isLittleEndian returns whether the local system is running in little or big
@@ -317,32 +868,45 @@ var (
 	errInvalidPoW        = errors.New("invalid proof-of-work")
 )
 
+// ComputePowLight computes the mixHash and powHash of a header, waiting for the
+// verification cache of its epoch to be generated. Engines whose MemoryGuard
+// has no room for the cache return zero hashes; ComputePowLightCtx reports the
+// error instead.
 func (progpow *Progpow) ComputePowLight(header *types.Header) (mixHash, powHash common.Hash) {
-	powLight := func(size uint64, cache []uint32, hash []byte, nonce uint64, blockNumber uint64) ([]byte, []byte) {
-		ethashCache := progpow.cache(blockNumber)
-		if ethashCache.cDag == nil {
-			cDag := make([]uint32, progpowCacheWords)
-			generateCDag(cDag, ethashCache.cache, blockNumber/epochLength)
-			ethashCache.cDag = cDag
-		}
-		return progpowLight(size, cache, hash, nonce, blockNumber, ethashCache.cDag)
+	cache, err := progpow.admitted(header.NumberU64(), func(block uint64) (*cache, error) {
+		return progpow.cache(block), nil
+	})
+	if err != nil {
+		return common.Hash{}, common.Hash{}
 	}
-	cache := progpow.cache(header.NumberU64())
-	size := datasetSize(header.NumberU64())
-	digest, result := powLight(size, cache.cache, header.SealHash().Bytes(), header.NonceU64(), header.NumberU64(common.ZONE_CTX))
-	mixHash = common.BytesToHash(digest)
-	powHash = common.BytesToHash(result)
+	return progpow.computePowLight(header, cache)
+}
+
+// computePowLight computes the mixHash and powHash of a header using the given
+// verification cache, caching the results in the header.
+func (progpow *Progpow) computePowLight(header *types.Header, cache *cache) (mixHash, powHash common.Hash) {
+	mixHash, powHash = progpow.hashLight(cache, header.SealHash(), header.NonceU64(), header.NumberU64(), header.NumberU64In(common.ZoneCtx))
 	header.PowDigest.Store(mixHash)
 	header.PowHash.Store(powHash)
+	return mixHash, powHash
+}
+
+// hashLight computes the mixHash and powHash of a seal hash and nonce using
+// the verification cache of the block number, the number in the context of
+// the node, while the kernel is picked by the number in the zone context.
+func (progpow *Progpow) hashLight(cache *cache, sealHash common.Hash, nonce, number, zoneNumber uint64) (mixHash, powHash common.Hash) {
+	digest, result := progpowLight(datasetSize(number), cache.cache, sealHash.Bytes(), nonce, zoneNumber, cache.cDag, progpow.config.ChainConfig.kernelAt(zoneNumber))
 
 	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
 	// until after the call to hashimotoLight so it's not unmapped while being used.
 	runtime.KeepAlive(cache)
 
-	return mixHash, powHash
+	return common.BytesToHash(digest), common.BytesToHash(result)
 }
 
-// VerifySeal returns the PowHash and the verifySeal output
+// VerifySeal returns the PowHash and the verifySeal output. Headers lacking
+// per context entries the seal covers fail with a types.FieldError wrapping
+// types.ErrMalformedHeader.
 func (progpow *Progpow) VerifySeal(header *types.Header) (common.Hash, error) {
 	return progpow.verifySeal(header)
 }
@@ -351,10 +915,49 @@ func (progpow *Progpow) VerifySeal(header *types.Header) (common.Hash, error) {
 // either using the usual progpow cache for it, or alternatively using a full DAG
 // to make remote mining fast.
 func (progpow *Progpow) verifySeal(header *types.Header) (common.Hash, error) {
+	powHash, err := progpow.verifySealWith(header, progpow.admittedCache)
+	progpow.metrics.observeSeal(err)
+	return powHash, err
+}
+
+// sealCache returns the verification cache for a block number, bailing out
+// rather than stalling on cache generation if the configuration requests so.
+func (progpow *Progpow) sealCache(block uint64) (*cache, error) {
+	if budget, ok := progpow.coldStartBudget(); ok {
+		return progpow.cacheWithin(context.Background(), block, budget)
+	}
+	return progpow.cache(block), nil
+}
+
+// coldStartBudget returns how long seal verifications wait for a verification
+// cache to be generated, if the configuration bounds the wait.
+func (progpow *Progpow) coldStartBudget() (time.Duration, bool) {
+	if progpow.config.NonBlocking {
+		return 0, true
+	}
+	return progpow.config.ColdStartBudget, progpow.config.ColdStartBudget > 0
+}
+
+// admittedCache returns the verification cache for a block number like
+// sealCache, once the memory guard admits it.
+func (progpow *Progpow) admittedCache(block uint64) (*cache, error) {
+	return progpow.admitted(block, progpow.sealCache)
+}
+
+// verifySealWith checks the seal of a header like verifySeal, retrieving the
+// verification cache through lookup if the proof-of-work is not cached in the
+// header yet.
+func (progpow *Progpow) verifySealWith(header *types.Header, lookup func(block uint64) (*cache, error)) (common.Hash, error) {
+	// Malformed headers from untrusted sources must fail verification, not
+	// crash the process on the fields hashed below
+	number, err := sealNumber(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
 	// If we're running a fake PoW, accept any seal as valid
 	if progpow.config.PowMode == ModeFake || progpow.config.PowMode == ModeFullFake {
-		time.Sleep(progpow.fakeDelay)
-		if progpow.fakeFail == header.Number().Uint64() {
+		progpow.config.Clock.Sleep(progpow.fakeDelay)
+		if progpow.fakeFail == number {
 			return common.Hash{}, errInvalidPoW
 		}
 		return common.Hash{}, nil
@@ -364,22 +967,64 @@ func (progpow *Progpow) verifySeal(header *types.Header) (common.Hash, error) {
 		return progpow.shared.verifySeal(header)
 	}
 	// Ensure that we have a valid difficulty for the block
-	if header.Difficulty().Sign() <= 0 {
+	if header.Difficulty() == nil || header.Difficulty().Sign() <= 0 {
 		return common.Hash{}, errInvalidDifficulty
 	}
 	// Check progpow
 	mixHash := header.PowDigest.Load()
 	powHash := header.PowHash.Load()
 	if powHash == nil || mixHash == nil {
-		mixHash, powHash = progpow.ComputePowLight(header)
+		if result, ok := progpow.results.get(header); ok {
+			progpow.metrics.observeSealResult(true)
+			header.PowDigest.Store(result.mixHash)
+			header.PowHash.Store(result.powHash)
+			mixHash, powHash = result.mixHash, result.powHash
+		} else {
+			hit := progpow.cacheReady(number)
+			cache, err := lookup(number)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			progpow.metrics.observeCache(hit)
+			mixHash, powHash = progpow.computePowLight(header, cache)
+			if progpow.results != nil {
+				progpow.metrics.observeSealResult(false)
+				progpow.results.add(header, mixHash.(common.Hash), powHash.(common.Hash))
+			}
+		}
 	}
 	// Verify the calculated values against the ones provided in the header
 	if !bytes.Equal(header.MixHash().Bytes(), mixHash.(common.Hash).Bytes()) {
+		progpow.verified.record(header, powHash.(common.Hash), mixHash.(common.Hash), errInvalidMixHash, progpow.config.Clock.Now())
 		return common.Hash{}, errInvalidMixHash
 	}
-	target := new(big.Int).Div(big2e256, header.Difficulty())
-	if new(big.Int).SetBytes(powHash.(common.Hash).Bytes()).Cmp(target) > 0 {
-		return powHash.(common.Hash), errInvalidPoW
+	err = CheckTarget(powHash.(common.Hash), header.Difficulty())
+	progpow.verified.record(header, powHash.(common.Hash), mixHash.(common.Hash), err, progpow.config.Clock.Now())
+	if err != nil {
+		return powHash.(common.Hash), err
 	}
+	progpow.noteVerified(number)
 	return powHash.(common.Hash), nil
 }
+
+// sealNumber returns the block number a header is sealed at, in the context
+// of the node, after checking through the checked accessors that the header
+// carries every per context field its seal hash covers.
+func sealNumber(header *types.Header) (uint64, error) {
+	for _, ctx := range common.Contexts {
+		if _, err := header.ParentHashSafe(ctx); err != nil {
+			return 0, err
+		}
+		if _, err := header.ManifestHashSafe(ctx); err != nil {
+			return 0, err
+		}
+		if _, err := header.NumberSafe(ctx); err != nil {
+			return 0, err
+		}
+	}
+	number, err := header.NumberSafe(common.NodeLocation.Ctx())
+	if err != nil {
+		return 0, err
+	}
+	return number.Uint64(), nil
+}
@@ -18,6 +18,7 @@ package progpow
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/bits"
 
 	"golang.org/x/crypto/sha3"
@@ -36,13 +37,33 @@ const (
 	progpowMixBytes     = 256
 )
 
+// HashimotoLight runs the ProgPoW kernel Quai hashes with since genesis on a
+// verification cache, returning the mixHash (digest) and PoW hash (result) of
+// a seal hash and nonce. It is the kernel behind seal verification, exposed
+// for fuzzers and differential testers against other implementations: size is
+// the dataset size of the epoch, see DatasetSize, and cache and cDag are the
+// words of the verification cache of the epoch and of the cDag derived from
+// it, e.g. as read from an ExportCache envelope. It panics if sealHash is not 32 bytes or
+// cDag not 16KB, and hashes garbage if cache or size mismatch the epoch, as
+// the kernel cannot tell.
+func HashimotoLight(size uint64, cache []uint32, sealHash []byte, nonce, blockNumber uint64, cDag []uint32) (digest, result []byte) {
+	if len(sealHash) != 32 {
+		panic(fmt.Sprintf("progpow: seal hash of %d bytes, want 32", len(sealHash)))
+	}
+	if len(cDag) != progpowCacheWords {
+		panic(fmt.Sprintf("progpow: cDag of %d words, want %d", len(cDag), progpowCacheWords))
+	}
+	kernel, _ := Kernel(KernelGenesis)
+	return progpowLight(size, cache, sealHash, nonce, blockNumber, cDag, kernel)
+}
+
 func progpowLight(size uint64, cache []uint32, hash []byte, nonce uint64,
-	blockNumber uint64, cDag []uint32) ([]byte, []byte) {
+	blockNumber uint64, cDag []uint32, kernel KernelParams) ([]byte, []byte) {
 	keccak512 := makeHasher(sha3.NewLegacyKeccak512())
 	lookup := func(index uint32) []byte {
 		return generateDatasetItem(cache, index/16, keccak512)
 	}
-	return progpow(hash, nonce, size, blockNumber, cDag, lookup)
+	return progpow(hash, nonce, size, blockNumber, cDag, lookup, kernel)
 }
 
 func rotl32(x uint32, n uint32) uint32 {
@@ -287,7 +308,7 @@ func progpowMath(a uint32, b uint32, r uint32) uint32 {
 
 func progpowLoop(seed uint64, loop uint32, mix *[progpowLanes][progpowRegs]uint32,
 	lookup func(index uint32) []byte,
-	cDag []uint32, datasetSize uint32) {
+	cDag []uint32, datasetSize uint32, kernel KernelParams) {
 	// All lanes share a base address for the global load
 	// Global offset uses mix[0] to guarantee it depends on the load result
 	gOffset := mix[loop%progpowLanes][0] % (64 * datasetSize / (progpowLanes * progpowDagLoads))
@@ -318,8 +339,8 @@ func progpowLoop(seed uint64, loop uint32, mix *[progpowLanes][progpowRegs]uint3
 		srcCounter = uint32(0)
 		dstCounter = uint32(0)
 
-		for i := uint32(0); i < progpowCntMath; i++ {
-			if i < progpowCntCache {
+		for i := uint32(0); i < kernel.CntMath; i++ {
+			if i < kernel.CntCache {
 				// Cached memory access
 				// lanes access random location
 
@@ -368,7 +389,7 @@ func progpowLoop(seed uint64, loop uint32, mix *[progpowLanes][progpowRegs]uint3
 }
 
 func progpow(hash []byte, nonce uint64, size uint64, blockNumber uint64, cDag []uint32,
-	lookup func(index uint32) []byte) ([]byte, []byte) {
+	lookup func(index uint32) []byte, kernel KernelParams) ([]byte, []byte) {
 	var (
 		mix         [progpowLanes][progpowRegs]uint32
 		laneResults [progpowLanes]uint32
@@ -378,9 +399,9 @@ func progpow(hash []byte, nonce uint64, size uint64, blockNumber uint64, cDag []
 	for lane := uint32(0); lane < progpowLanes; lane++ {
 		mix[lane] = fillMix(seed, lane)
 	}
-	period := (blockNumber / progpowPeriodLength)
-	for l := uint32(0); l < progpowCntDag; l++ {
-		progpowLoop(period, l, &mix, lookup, cDag, uint32(size/progpowMixBytes))
+	period := (blockNumber / kernel.PeriodLength)
+	for l := uint32(0); l < kernel.CntDag; l++ {
+		progpowLoop(period, l, &mix, lookup, cDag, uint32(size/progpowMixBytes), kernel)
 	}
 
 	// Reduce mix data to a single per-lane result
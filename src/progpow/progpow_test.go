@@ -0,0 +1,82 @@
+package progpow
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// Hashing and verifying one header from many goroutines is safe, as is
+// modifying a private copy of it meanwhile. Run with -race.
+func TestConcurrentHeaderUse(t *testing.T) {
+	engine, err := New(Config{PowMode: ModeTest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(sealedHeader), header); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		sealHash = header.SealHash()
+		hash     = header.Hash()
+		pend     sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+			for j := 0; j < 20; j++ {
+				if h := header.SealHash(); h != sealHash {
+					t.Errorf("seal hash %x, want %x", h, sealHash)
+				}
+				if h := header.Hash(); h != hash {
+					t.Errorf("hash %x, want %x", h, hash)
+				}
+				if _, err := engine.VerifySeal(header); err != nil {
+					t.Errorf("seal verification failed: %v", err)
+				}
+			}
+		}()
+	}
+	pend.Add(1)
+	go func() {
+		defer pend.Done()
+		cpy := header.Copy()
+		for j := 0; j < 20; j++ {
+			cpy.SetNonce(types.EncodeNonce(uint64(j)))
+			cpy.SealHash()
+			engine.VerifySeal(cpy)
+		}
+	}()
+	pend.Wait()
+}
+
+// The cDag is the same however many goroutines derive it.
+func TestGenerateCDagWorkers(t *testing.T) {
+	cache := make([]uint32, 1024/4)
+	generateCache(cache, 0, seedHash(1), log.Log, nil, yieldSettings{})
+
+	derive := func(procs int) []uint32 {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		cDag := make([]uint32, progpowCacheWords)
+		if !generateCDag(cDag, cache, 0, log.Log, yieldSettings{}) {
+			t.Fatal("cDag generation aborted")
+		}
+		return cDag
+	}
+	want := derive(1)
+	for _, procs := range []int{2, 3, 8} {
+		got := derive(procs)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%d goroutines: cDag word %d is %#x, want %#x", procs, i, got[i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package progpowtest generates sealed header chains for tests of code built
+// on top of the verifier, such as header sync or entropy bookkeeping.
+package progpowtest
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var (
+	// TestDifficulty is the difficulty of generated headers unless changed
+	// through BlockGen.SetDifficulty. It keeps sealing a header at a few dozen
+	// hashes on average.
+	TestDifficulty = big.NewInt(16)
+
+	// TestLocation is the zone the generated chain belongs to.
+	TestLocation = common.Location{0, 0}
+)
+
+// BlockTime is the number of seconds between generated headers, unless changed
+// through BlockGen.OffsetTime.
+const BlockTime = 10
+
+// BlockGen creates a header of a test chain. See GenerateChain.
+type BlockGen struct {
+	i      int
+	chain  []*types.Header
+	parent *types.Header
+	header *types.Header
+}
+
+// Number returns the number of the header being generated in the context of
+// the chain.
+func (b *BlockGen) Number() *big.Int {
+	return b.header.NumberIn(b.header.Location().Ctx())
+}
+
+// Parent returns the parent of the header being generated.
+func (b *BlockGen) Parent() *types.Header {
+	return b.parent
+}
+
+// PrevBlock returns a previously generated header by its index, or the parent
+// if index is -1. It panics if the index is out of range.
+func (b *BlockGen) PrevBlock(index int) *types.Header {
+	if index >= b.i {
+		panic(fmt.Errorf("block index %d out of range (%d,%d)", index, -1, b.i))
+	}
+	if index == -1 {
+		return b.parent
+	}
+	return b.chain[index]
+}
+
+// Header returns the unsealed header being generated, for modifications
+// without a dedicated BlockGen method. The header is sealed after the
+// generator function returns, so seal fields set here are overwritten.
+func (b *BlockGen) Header() *types.Header {
+	return b.header
+}
+
+// SetCoinbase sets the coinbase of the header.
+func (b *BlockGen) SetCoinbase(addr common.Address) {
+	b.header.SetCoinbase(addr)
+}
+
+// SetExtra sets the extra data field of the header.
+func (b *BlockGen) SetExtra(data []byte) {
+	b.header.SetExtra(data)
+}
+
+// SetDifficulty sets the difficulty the header is sealed at. Sealing takes
+// difficulty hashes on average, so keep it low.
+func (b *BlockGen) SetDifficulty(difficulty *big.Int) {
+	b.header.SetDifficulty(difficulty)
+}
+
+// OffsetTime moves the timestamp of the header by the given number of seconds
+// relative to the default of BlockTime seconds after the parent.
+func (b *BlockGen) OffsetTime(seconds int64) {
+	time := int64(b.header.Time()) + seconds
+	if time <= int64(b.parent.Time()) {
+		panic("header time not after parent")
+	}
+	b.header.SetTime(uint64(time))
+}
+
+// Genesis returns the sealed genesis header of the chains generated by engine.
+func Genesis(engine *progpow.Progpow) *types.Header {
+	header := types.NewEmptyHeader()
+	header.SetLocation(TestLocation)
+	header.SetDifficulty(TestDifficulty)
+	seal(engine, header)
+	return header
+}
+
+// GenerateChain creates a chain of n headers on top of Genesis(engine). Each
+// header is linked to its parent in every context the parent is a block of,
+// carries the entropy accumulated by its ancestors and is sealed by engine,
+// so the chain verifies like one mined on a live network. The engine should
+// run in test mode, which keeps sealing cheap.
+//
+// The generator function is called with a BlockGen for every header before it
+// is sealed, and may modify it. The first argument is the index of the header
+// being generated. It may be nil.
+func GenerateChain(n int, engine *progpow.Progpow, gen func(int, *BlockGen)) []*types.Header {
+	return GenerateChainFrom(Genesis(engine), n, engine, gen)
+}
+
+// GenerateChainFrom creates a chain of n headers on top of parent, like
+// GenerateChain.
+func GenerateChainFrom(parent *types.Header, n int, engine *progpow.Progpow, gen func(int, *BlockGen)) []*types.Header {
+	chain := make([]*types.Header, 0, n)
+	for i := 0; i < n; i++ {
+		b := &BlockGen{i: i, chain: chain, parent: parent, header: makeHeader(engine, parent)}
+		if gen != nil {
+			gen(i, b)
+		}
+		seal(engine, b.header)
+		chain = append(chain, b.header)
+		parent = b.header
+	}
+	return chain
+}
+
+// makeHeader creates the unsealed child of parent. In every context at or
+// below the order of the parent, the parent is the latest block, so the child
+// links to it and continues its numbering and entropy; in the contexts above
+// it inherits the links of the parent.
+func makeHeader(engine *progpow.Progpow, parent *types.Header) *types.Header {
+	_, order, err := engine.CalcOrder(parent)
+	if err != nil {
+		panic(fmt.Errorf("invalid parent: %w", err))
+	}
+	totalS, err := engine.TotalLogS(parent)
+	if err != nil {
+		panic(err)
+	}
+	deltaS, err := engine.DeltaLogS(parent)
+	if err != nil {
+		panic(err)
+	}
+	header := types.NewEmptyHeader()
+	for _, ctx := range common.Contexts {
+		if int(ctx) < order {
+			header.SetParentHashIn(parent.ParentHashIn(ctx), ctx)
+			header.SetNumberIn(parent.NumberIn(ctx), ctx)
+			header.SetParentEntropyIn(parent.ParentEntropyIn(ctx), ctx)
+			header.SetParentDeltaSIn(parent.ParentDeltaSIn(ctx), ctx)
+			continue
+		}
+		header.SetParentHashIn(parent.Hash(), ctx)
+		header.SetNumberIn(new(big.Int).Add(parent.NumberIn(ctx), common.Big1), ctx)
+		header.SetParentEntropyIn(totalS, ctx)
+		if int(ctx) == order {
+			header.SetParentDeltaSIn(deltaS, ctx)
+		} else {
+			// A dominant block starts a new subordinate accumulation
+			header.SetParentDeltaSIn(common.Big0, ctx)
+		}
+	}
+	header.SetLocation(parent.Location())
+	header.SetDifficulty(parent.Difficulty())
+	header.SetGasLimit(parent.GasLimit())
+	header.SetBaseFee(parent.BaseFee())
+	header.SetTime(parent.Time() + BlockTime)
+	return header
+}
+
+// seal searches for the nonce sealing header at its difficulty.
+func seal(engine *progpow.Progpow, header *types.Header) {
+	for nonce := uint64(0); ; nonce++ {
+		header.SetNonce(types.EncodeNonce(nonce))
+		mixHash, _ := engine.ComputePowLight(header)
+		header.SetMixHash(mixHash)
+
+		if _, err := engine.VerifySeal(header); err == nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package progpowtest
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/crypto"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// DevnetBlocksPerZone is the number of headers mined in every zone of the
+// embedded devnet.
+const DevnetBlocksPerZone = 30
+
+// DevnetTimeFactor is the time factor of the zones of the embedded devnet. It
+// is well below progpow.TimeFactor, so region and prime blocks are frequent
+// enough for a few hundred headers to contain several of each.
+var DevnetTimeFactor = big.NewInt(3)
+
+// devnetJSON is the devnet returned by LoadDevnet, generated in test mode for
+// the default hierarchy.
+//
+//go:generate go run ../../cmd/gendevnet -out devnet.json
+//go:embed devnet.json
+var devnetJSON []byte
+
+// DevnetBlock is a sealed header of a devnet along with what its dominant
+// chains record about it.
+type DevnetBlock struct {
+	Header *types.Header
+	Order  int // Most dominant context the header is a block of
+
+	// SubManifest lists the blocks of the subordinate chain since the last
+	// block of the chain of Order, oldest first: the region blocks of the
+	// region for prime blocks, the zone blocks of the zone for region blocks.
+	// The header commits to it in ManifestHash(Order). It is nil for zone
+	// blocks.
+	SubManifest types.BlockManifest
+
+	// Termini are the termini in the chain of Order once the header is added:
+	// the dom termini are the tips of the dominant chains indexed by context,
+	// the sub termini the latest blocks of the chain of Order coincident in
+	// each of its subordinate chains, or its genesis if there is none.
+	Termini types.Termini
+}
+
+// Devnet is a hierarchical test network: the genesis of each of its chains and
+// headers mined in all of its zones, linked in every context and carrying the
+// entropy and manifest hashes of their dominant chains, so features spanning
+// the hierarchy, like block ordering, manifests or fork choice, can be tested
+// on realistic data without a running network.
+//
+// Manifest hashes are the Keccak-256 hash of the RLP encoded manifest, see
+// ManifestHash, standing in for the trie root go-quai derives.
+type Devnet struct {
+	Hierarchy  common.HierarchyParams
+	TimeFactor *big.Int        // Time factor of all zones, see Config
+	Genesis    []*types.Header // Prime first, then every region followed by its zones
+	Blocks     []*DevnetBlock  // In the order they were mined, the zones taking turns
+}
+
+// LoadDevnet decodes the embedded devnet: DevnetBlocksPerZone headers mined at
+// TestDifficulty in every zone of the default hierarchy, with a time factor of
+// DevnetTimeFactor. Every call returns fresh headers, which tests may modify.
+func LoadDevnet() (*Devnet, error) {
+	devnet := new(Devnet)
+	if err := json.Unmarshal(devnetJSON, devnet); err != nil {
+		return nil, fmt.Errorf("corrupt devnet: %w", err)
+	}
+	return devnet, nil
+}
+
+// Config returns the configuration of the engine the devnet was mined with.
+// Engines verifying its headers need the same zone overrides, or the order of
+// the headers differs.
+func (d *Devnet) Config() progpow.Config {
+	return devnetConfig(d.Hierarchy, d.TimeFactor)
+}
+
+// devnetConfig returns the test mode configuration overriding the time factor
+// of every zone of hierarchy.
+func devnetConfig(hierarchy common.HierarchyParams, timeFactor *big.Int) progpow.Config {
+	config := progpow.Config{PowMode: progpow.ModeTest, ZoneOverrides: make(map[string]progpow.ZoneOverride)}
+	for region := 0; region < hierarchy.Regions; region++ {
+		for zone := 0; zone < hierarchy.Zones; zone++ {
+			name := common.Location{byte(region), byte(zone)}.Name()
+			config.ZoneOverrides[name] = progpow.ZoneOverride{TimeFactor: timeFactor}
+		}
+	}
+	return config
+}
+
+// GenesisOf returns the genesis of the chain at loc, or nil if the devnet has
+// no such chain.
+func (d *Devnet) GenesisOf(loc common.Location) *types.Header {
+	for _, genesis := range d.Genesis {
+		if bytes.Equal(genesis.Location(), loc) {
+			return genesis
+		}
+	}
+	return nil
+}
+
+// Chain returns the chain at loc, oldest first: its genesis followed by the
+// blocks mined below loc whose order makes them blocks of the chain. It
+// returns nil if the devnet has no such chain.
+//
+// The dominant chains advance with the blocks of all zones below them, so the
+// numbers of consecutive zone blocks may advance by more than one in the
+// dominant contexts, which VerifyHeaderChain, checking segments of a single
+// zone network, rejects.
+func (d *Devnet) Chain(loc common.Location) []*types.Header {
+	genesis := d.GenesisOf(loc)
+	if genesis == nil {
+		return nil
+	}
+	chain := []*types.Header{genesis}
+	for _, block := range d.Blocks {
+		if block.Order <= len(loc) && bytes.HasPrefix(block.Header.Location(), loc) {
+			chain = append(chain, block.Header)
+		}
+	}
+	return chain
+}
+
+// Block returns the mined block of the given hash, or nil if there is none.
+func (d *Devnet) Block(hash common.Hash) *DevnetBlock {
+	for _, block := range d.Blocks {
+		if block.Header.Hash() == hash {
+			return block
+		}
+	}
+	return nil
+}
+
+// ManifestHash returns the hash of a manifest committed to by the headers of
+// a devnet.
+func ManifestHash(manifest types.BlockManifest) common.Hash {
+	var buf bytes.Buffer
+	if err := rlp.Encode(&buf, manifest); err != nil {
+		panic(err)
+	}
+	return common.BytesToHash(crypto.Keccak256(buf.Bytes()))
+}
+
+// GenerateDevnet mines a devnet of blocksPerZone headers in every zone of the
+// hierarchy set by common.SetHierarchy, the zones taking turns, with an engine
+// in test mode and the given time factor. Every header links to the tips of
+// its zone, region and prime chain as of its mining, so blocks coincident in
+// one zone are seen by the others. Mining is deterministic.
+func GenerateDevnet(blocksPerZone int, timeFactor *big.Int) (*Devnet, error) {
+	hierarchy := common.Hierarchy()
+	engine, err := progpow.New(devnetConfig(hierarchy, timeFactor))
+	if err != nil {
+		return nil, err
+	}
+	g := newDevnetGen(engine, hierarchy)
+	g.devnet.TimeFactor = new(big.Int).Set(timeFactor)
+	for round := 0; round < blocksPerZone; round++ {
+		for region := 0; region < g.devnet.Hierarchy.Regions; region++ {
+			for zone := 0; zone < g.devnet.Hierarchy.Zones; zone++ {
+				g.mine(region, zone, uint64(round+1)*BlockTime)
+			}
+		}
+	}
+	return g.devnet, nil
+}
+
+// devnetGen tracks the tips of the chains of a devnet being mined.
+type devnetGen struct {
+	engine *progpow.Progpow
+	devnet *Devnet
+
+	primeTip  *types.Header
+	regionTip []*types.Header   // By region
+	zoneTip   [][]*types.Header // By region and zone
+
+	primeIn  []common.Hash   // Latest prime block coincident in every region
+	regionIn [][]common.Hash // Latest region block coincident in every zone
+
+	regionSince []types.BlockManifest   // Region blocks since the last prime block
+	zoneSince   [][]types.BlockManifest // Zone blocks since the last region block
+}
+
+func newDevnetGen(engine *progpow.Progpow, hierarchy common.HierarchyParams) *devnetGen {
+	g := &devnetGen{
+		engine:      engine,
+		devnet:      &Devnet{Hierarchy: hierarchy},
+		regionTip:   make([]*types.Header, hierarchy.Regions),
+		zoneTip:     make([][]*types.Header, hierarchy.Regions),
+		primeIn:     make([]common.Hash, hierarchy.Regions),
+		regionIn:    make([][]common.Hash, hierarchy.Regions),
+		regionSince: make([]types.BlockManifest, hierarchy.Regions),
+		zoneSince:   make([][]types.BlockManifest, hierarchy.Regions),
+	}
+	g.primeTip = g.genesis(common.Location{})
+	for region := 0; region < hierarchy.Regions; region++ {
+		g.regionTip[region] = g.genesis(common.Location{byte(region)})
+		g.primeIn[region] = g.primeTip.Hash()
+
+		g.zoneTip[region] = make([]*types.Header, hierarchy.Zones)
+		g.regionIn[region] = make([]common.Hash, hierarchy.Zones)
+		g.zoneSince[region] = make([]types.BlockManifest, hierarchy.Zones)
+		for zone := 0; zone < hierarchy.Zones; zone++ {
+			g.zoneTip[region][zone] = g.genesis(common.Location{byte(region), byte(zone)})
+			g.regionIn[region][zone] = g.regionTip[region].Hash()
+		}
+	}
+	return g
+}
+
+// genesis creates and records the sealed genesis of the chain at loc.
+func (g *devnetGen) genesis(loc common.Location) *types.Header {
+	header := types.NewEmptyHeader()
+	header.SetLocation(loc)
+	header.SetDifficulty(TestDifficulty)
+	seal(g.engine, header)
+	g.devnet.Genesis = append(g.devnet.Genesis, header)
+	return header
+}
+
+// mine seals the next header of a zone at the given time and advances the tips
+// of the chains it turns out to be a block of.
+func (g *devnetGen) mine(region, zone int, time uint64) {
+	tips := []*types.Header{g.primeTip, g.regionTip[region], g.zoneTip[region][zone]}
+	manifests := []types.BlockManifest{g.regionSince[region], g.zoneSince[region][zone]}
+
+	header := types.NewEmptyHeader()
+	for i, tip := range tips {
+		totalS, err := g.engine.TotalLogS(tip)
+		if err != nil {
+			panic(fmt.Errorf("invalid tip: %w", err))
+		}
+		ctx := common.Contexts[i]
+		header.SetParentHashIn(tip.Hash(), ctx)
+		header.SetNumberIn(new(big.Int).Add(tip.NumberIn(ctx), common.Big1), ctx)
+		header.SetParentEntropyIn(totalS, ctx)
+		header.SetParentDeltaSIn(g.deltaS(tip, ctx), ctx)
+		if ctx < common.ZoneCtx {
+			header.SetManifestHashIn(ManifestHash(manifests[ctx]), ctx)
+		}
+	}
+	header.SetLocation(common.Location{byte(region), byte(zone)})
+	header.SetDifficulty(TestDifficulty)
+	header.SetTime(time)
+	seal(g.engine, header)
+
+	_, order, err := g.engine.CalcOrder(header)
+	if err != nil {
+		panic(err)
+	}
+	var (
+		hash  = header.Hash()
+		block = &DevnetBlock{Header: header, Order: order}
+	)
+	g.zoneTip[region][zone] = header
+	switch order {
+	case common.PRIME_CTX:
+		block.SubManifest = manifests[common.PRIME_CTX]
+		g.primeTip, g.regionTip[region] = header, header
+		g.primeIn[region], g.regionIn[region][zone] = hash, hash
+		g.regionSince[region], g.zoneSince[region][zone] = nil, nil
+		block.Termini = types.NewTermini(nil, g.primeIn)
+	case common.REGION_CTX:
+		block.SubManifest = manifests[common.REGION_CTX]
+		g.regionTip[region] = header
+		g.regionIn[region][zone] = hash
+		g.regionSince[region] = append(g.regionSince[region], hash)
+		g.zoneSince[region][zone] = nil
+		block.Termini = types.NewTermini([]common.Hash{g.primeTip.Hash()}, g.regionIn[region])
+	default:
+		g.zoneSince[region][zone] = append(g.zoneSince[region][zone], hash)
+		block.Termini = types.NewTermini([]common.Hash{g.primeTip.Hash(), g.regionTip[region].Hash()}, nil)
+	}
+	g.devnet.Blocks = append(g.devnet.Blocks, block)
+}
+
+// deltaS returns the entropy a child of tip inherits as accumulated in context
+// ctx since the last block of the dominant context: the delta of tip if it is
+// a block of exactly ctx, none if tip is a dominant block and starts a new
+// accumulation.
+func (g *devnetGen) deltaS(tip *types.Header, ctx common.Context) *big.Int {
+	_, order, err := g.engine.CalcOrder(tip)
+	if err != nil {
+		panic(err)
+	}
+	if order < int(ctx) {
+		return new(big.Int)
+	}
+	deltaS, err := g.engine.DeltaLogS(tip)
+	if err != nil {
+		panic(err)
+	}
+	return deltaS
+}
+
+// devnetEnc is the JSON encoding of a devnet, headers being RLP encoded.
+type devnetEnc struct {
+	Regions    int               `json:"regions"`
+	Zones      int               `json:"zones"`
+	TimeFactor *hexutil.Big      `json:"timeFactor"`
+	Genesis    []hexutil.Bytes   `json:"genesis"`
+	Blocks     []*devnetBlockEnc `json:"blocks"`
+}
+
+type devnetBlockEnc struct {
+	Header      hexutil.Bytes       `json:"header"`
+	Order       int                 `json:"order"`
+	SubManifest types.BlockManifest `json:"subManifest,omitempty"`
+	DomTermini  []common.Hash       `json:"domTermini,omitempty"`
+	SubTermini  []common.Hash       `json:"subTermini,omitempty"`
+}
+
+// MarshalJSON encodes the devnet in the format of the embedded one.
+func (d *Devnet) MarshalJSON() ([]byte, error) {
+	enc := devnetEnc{Regions: d.Hierarchy.Regions, Zones: d.Hierarchy.Zones, TimeFactor: (*hexutil.Big)(d.TimeFactor)}
+	for _, genesis := range d.Genesis {
+		var raw bytes.Buffer
+		if err := rlp.Encode(&raw, genesis); err != nil {
+			return nil, err
+		}
+		enc.Genesis = append(enc.Genesis, raw.Bytes())
+	}
+	for _, block := range d.Blocks {
+		var raw bytes.Buffer
+		if err := rlp.Encode(&raw, block.Header); err != nil {
+			return nil, err
+		}
+		enc.Blocks = append(enc.Blocks, &devnetBlockEnc{
+			Header:      raw.Bytes(),
+			Order:       block.Order,
+			SubManifest: block.SubManifest,
+			DomTermini:  block.Termini.DomTermini(),
+			SubTermini:  block.Termini.SubTermini(),
+		})
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a devnet encoded by MarshalJSON.
+func (d *Devnet) UnmarshalJSON(input []byte) error {
+	var dec devnetEnc
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.TimeFactor == nil {
+		return errors.New("missing time factor")
+	}
+	devnet := Devnet{
+		Hierarchy:  common.HierarchyParams{Regions: dec.Regions, Zones: dec.Zones},
+		TimeFactor: (*big.Int)(dec.TimeFactor),
+	}
+	for i, raw := range dec.Genesis {
+		header, err := types.DecodeHeaderRLP(raw)
+		if err != nil {
+			return fmt.Errorf("genesis %d: %w", i, err)
+		}
+		devnet.Genesis = append(devnet.Genesis, header)
+	}
+	for i, enc := range dec.Blocks {
+		header, err := types.DecodeHeaderRLP(enc.Header)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+		devnet.Blocks = append(devnet.Blocks, &DevnetBlock{
+			Header:      header,
+			Order:       enc.Order,
+			SubManifest: enc.SubManifest,
+			Termini:     types.NewTermini(enc.DomTermini, enc.SubTermini),
+		})
+	}
+	*d = devnet
+	return nil
+}
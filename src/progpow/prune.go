@@ -0,0 +1,56 @@
+package progpow
+
+import "os"
+
+// noteVerified records the number of a block whose seal verified. Once its
+// epoch is beyond any verified before, the persisted caches falling more than
+// PruneEpochsBehind epochs behind it are deleted in the background.
+func (progpow *Progpow) noteVerified(block uint64) {
+	if progpow.config.PruneEpochsBehind == 0 {
+		return
+	}
+	epoch := block / epochLength
+	for {
+		seen := progpow.verifiedEpoch.Load()
+		if seen > epoch {
+			return
+		}
+		if progpow.verifiedEpoch.CompareAndSwap(seen, epoch+1) {
+			break
+		}
+	}
+	go progpow.pruneBehind(epoch)
+}
+
+// pruneBehind deletes the persisted caches of the epochs more than
+// PruneEpochsBehind epochs behind epoch.
+func (progpow *Progpow) pruneBehind(epoch uint64) {
+	behind := uint64(progpow.config.PruneEpochsBehind)
+	if epoch <= behind {
+		return
+	}
+	progpow.pruneLock.Lock()
+	defer progpow.pruneLock.Unlock()
+
+	// Another verification may have advanced the height meanwhile, prune
+	// behind the highest one
+	if highest := progpow.verifiedEpoch.Load() - 1; highest > epoch {
+		epoch = highest
+	}
+	var (
+		dir, store = progpow.config.CacheDir, progpow.config.CacheStore
+		test       = progpow.config.PowMode == ModeTest
+	)
+	for ep := epoch - behind - 1; ; ep-- {
+		switch {
+		case store != nil:
+			store.Delete(storeKey(ep, test))
+		case dir != "":
+			os.Remove(cachePath(dir, ep))
+		}
+		if ep == 0 {
+			break
+		}
+	}
+	progpow.config.Log.Debug("Pruned ethash caches behind verified height", "epoch", epoch, "behind", behind)
+}
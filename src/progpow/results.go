@@ -0,0 +1,106 @@
+package progpow
+
+import (
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// sealResult is the proof-of-work computed for a sealed header, along with the
+// block numbers it was computed at, in the context of the node and the zone.
+type sealResult struct {
+	mixHash    common.Hash
+	powHash    common.Hash
+	number     uint64
+	zoneNumber uint64
+}
+
+// sealResults remembers the proof-of-work of recently verified seals by seal
+// hash and nonce. The proof-of-work depends on nothing else, as the seal hash
+// covers the block number picking the epoch and kernel, so a header gossiped
+// by several peers is hashed once. Only the hashes are kept: the verdict is
+// checked against the mixHash and difficulty of every header anew.
+type sealResults struct {
+	lock    sync.Mutex
+	entries *lrucache.LRU[memoKey, sealResult]
+	hits    uint64
+	misses  uint64
+}
+
+// newSealResults creates a cache of the given number of results, or nil if
+// size is zero.
+func newSealResults(size int) *sealResults {
+	if size == 0 {
+		return nil
+	}
+	return &sealResults{entries: lrucache.New[memoKey, sealResult](size, 0, nil)}
+}
+
+// get returns the proof-of-work remembered for the seal of header.
+func (r *sealResults) get(header *types.Header) (sealResult, bool) {
+	if r == nil {
+		return sealResult{}, false
+	}
+	key := memoKey{sealHash: header.SealHash(), nonce: header.NonceU64()}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result, ok := r.entries.Get(key)
+	if ok {
+		r.hits++
+	} else {
+		r.misses++
+	}
+	return result, ok
+}
+
+// add remembers the proof-of-work computed for the seal of header.
+func (r *sealResults) add(header *types.Header, mixHash, powHash common.Hash) {
+	if r == nil {
+		return
+	}
+	key := memoKey{sealHash: header.SealHash(), nonce: header.NonceU64()}
+	r.addResult(key, sealResult{mixHash: mixHash, powHash: powHash, number: header.NumberU64(), zoneNumber: header.NumberU64In(common.ZoneCtx)})
+}
+
+// addResult remembers the proof-of-work computed for a seal.
+func (r *sealResults) addResult(key memoKey, result sealResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries.Add(key, result, 1)
+}
+
+// SealResultStats describes how well the remembered proofs-of-work of an
+// engine serve its seal verifications.
+type SealResultStats struct {
+	Results  int    `json:"results"`  // Proofs-of-work remembered
+	MaxItems int    `json:"maxItems"` // Bound on the remembered proofs-of-work
+	Hits     uint64 `json:"hits"`     // Verifications served by a remembered proof-of-work
+	Misses   uint64 `json:"misses"`   // Verifications computing the proof-of-work
+}
+
+// SealResultStats returns the number of proofs-of-work remembered under
+// Config.SealResults and the lookup counters since the creation of the
+// engine, all zero if none are remembered.
+func (progpow *Progpow) SealResultStats() SealResultStats {
+	// If we're running a shared PoW, report its results instead
+	if progpow.shared != nil {
+		return progpow.shared.SealResultStats()
+	}
+	r := progpow.results
+	if r == nil {
+		return SealResultStats{}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return SealResultStats{
+		Results:  r.entries.Len(),
+		MaxItems: progpow.config.SealResults,
+		Hits:     r.hits,
+		Misses:   r.misses,
+	}
+}
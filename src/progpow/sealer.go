@@ -0,0 +1,137 @@
+package progpow
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// Seal searches for a nonce sealing header at its difficulty, iterating nonces
+// on one goroutine per available CPU with the light verification path, and
+// sends a copy of the header carrying the found nonce and mixHash to results.
+// The search runs in the background until a seal is found or stop is closed;
+// the header itself is not modified. If results is not ready to receive the
+// sealed header, it is dropped.
+//
+// The light path recomputes dataset items from the cache for every nonce, so
+// sealing is only practical at the tiny difficulties of testnet demos and
+// integration tests. Engines in a fake mode seal right away with a zero nonce.
+// Errors preparing the verification cache are returned before the search
+// starts.
+func (progpow *Progpow) Seal(header *types.Header, stop <-chan struct{}, results chan<- *types.Header) error {
+	// If we're running a fake PoW, simply return a 0 nonce immediately
+	if progpow.config.PowMode == ModeFake || progpow.config.PowMode == ModeFullFake {
+		sealed := sealedCopy(header, types.BlockNonce{}, common.Hash{})
+		select {
+		case results <- sealed:
+		default:
+			progpow.config.Log.Warn("Sealing result is not read by miner", "mode", "fake", "sealhash", header.SealHash())
+		}
+		return nil
+	}
+	// If we're running a shared PoW, delegate sealing to it
+	if progpow.shared != nil {
+		return progpow.shared.Seal(header, stop, results)
+	}
+	if header.Difficulty() == nil || header.Difficulty().Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	cache, err := progpow.admittedCache(header.NumberU64())
+	if err != nil {
+		return err
+	}
+	var (
+		abort   = make(chan struct{})
+		found   = make(chan *types.Header)
+		threads = runtime.GOMAXPROCS(0)
+		pend    sync.WaitGroup
+	)
+	pend.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func(nonce uint64) {
+			defer pend.Done()
+			progpow.mine(header, cache, nonce, abort, found)
+		}(uint64(progpow.randInt()))
+	}
+	go func() {
+		select {
+		case <-stop:
+		case sealed := <-found:
+			select {
+			case results <- sealed:
+			default:
+				progpow.config.Log.Warn("Sealing result is not read by miner", "mode", "local", "sealhash", header.SealHash())
+			}
+		}
+		close(abort)
+		pend.Wait()
+	}()
+	return nil
+}
+
+// mine iterates nonces from seed until one seals header with the given cache,
+// sending the sealed copy to found, or until abort is closed.
+func (progpow *Progpow) mine(header *types.Header, cache *cache, seed uint64, abort <-chan struct{}, found chan<- *types.Header) {
+	var (
+		sealHash   = header.SealHash().Bytes()
+		number     = header.NumberU64In(common.ZoneCtx)
+		kernel     = progpow.config.ChainConfig.kernelAt(number)
+		size       = datasetSize(header.NumberU64())
+		difficulty = header.Difficulty()
+		yield      = progpow.config.yieldSettings().newYielder(16)
+	)
+	// Caches are unmapped in a finalizer. Ensure that the cache stays alive
+	// until the search is over so it's not unmapped while being used.
+	defer runtime.KeepAlive(cache)
+
+	for nonce := seed; ; nonce++ {
+		select {
+		case <-abort:
+			return
+		default:
+		}
+		digest, result := progpowLight(size, cache.cache, sealHash, nonce, number, cache.cDag, kernel)
+		if CheckTarget(common.BytesToHash(result), difficulty) == nil {
+			select {
+			case found <- sealedCopy(header, types.EncodeNonce(nonce), common.BytesToHash(digest)):
+			case <-abort:
+			}
+			return
+		}
+		yield.step()
+	}
+}
+
+// SealCandidate returns a copy of header carrying the given nonce along with
+// the mixHash computed for it, and the resulting PoW hash. The copy verifies
+// exactly if the PoW hash meets its difficulty, see CheckTarget, so test
+// harnesses iterating nonces themselves, rather than through Seal, cannot pair
+// a nonce with a stale mixHash or proof-of-work cached for another candidate.
+// Engines in a fake mode return the copy with a zero mixHash and PoW hash.
+func (progpow *Progpow) SealCandidate(header *types.Header, nonce types.BlockNonce) (*types.Header, common.Hash, error) {
+	// If we're running a fake PoW, any seal is as good as another
+	if progpow.config.PowMode == ModeFake || progpow.config.PowMode == ModeFullFake {
+		return sealedCopy(header, nonce, common.Hash{}), common.Hash{}, nil
+	}
+	// If we're running a shared PoW, delegate to it
+	if progpow.shared != nil {
+		return progpow.shared.SealCandidate(header, nonce)
+	}
+	cache, err := progpow.admittedCache(header.NumberU64())
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	candidate := header.WithNonce(nonce)
+	mixHash, powHash := progpow.computePowLight(candidate, cache)
+	candidate.SetMixHash(mixHash)
+	return candidate, powHash, nil
+}
+
+// sealedCopy returns a copy of header carrying the given seal.
+func sealedCopy(header *types.Header, nonce types.BlockNonce, mixHash common.Hash) *types.Header {
+	sealed := header.WithNonce(nonce)
+	sealed.SetMixHash(mixHash)
+	return sealed
+}
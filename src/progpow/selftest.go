@@ -0,0 +1,106 @@
+package progpow
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// selfTestJSON holds golden vectors recorded with this implementation when the
+// self test was added: headers with their progpow outputs for the test-mode and
+// the full epoch 0 verification caches, and a low difficulty header to mine.
+// Being self-generated, they catch builds and platforms computing differently
+// from the one they were recorded on, not errors in the algorithm itself, which
+// they would reproduce. They are no evidence of agreement with go-quai.
+//
+//go:embed selftest.json
+var selfTestJSON []byte
+
+// selfTestVector is an RLP encoded header and its expected progpow output.
+type selfTestVector struct {
+	Mode    string `json:"mode"`
+	Header  string `json:"header"`
+	MixHash string `json:"mixHash"`
+	PowHash string `json:"powHash"`
+}
+
+// selfTestMaxNonces bounds the nonce search of the self test. The mining header
+// has difficulty 16, so exhausting this is practically impossible.
+const selfTestMaxNonces = 1 << 12
+
+var ErrSelfTest = errors.New("self test failed")
+
+// SelfTest checks that the local build computes the recorded progpow outputs
+// for the embedded golden vectors, and that a header mined at test difficulty
+// verifies. It uses dedicated in-memory engines, so the caches of progpow are
+// left untouched, and returns an error wrapping ErrSelfTest on any mismatch.
+func (progpow *Progpow) SelfTest() error {
+	var vectors struct {
+		Vectors []selfTestVector `json:"vectors"`
+		Mining  string           `json:"mining"`
+	}
+	if err := json.Unmarshal(selfTestJSON, &vectors); err != nil {
+		return fmt.Errorf("%w: corrupt vectors: %v", ErrSelfTest, err)
+	}
+	fullEngine, err := New(Config{Log: progpow.config.Log})
+	if err != nil {
+		return err
+	}
+	testEngine, err := New(Config{PowMode: ModeTest, Log: progpow.config.Log})
+	if err != nil {
+		return err
+	}
+	engines := map[string]*Progpow{"normal": fullEngine, "test": testEngine}
+
+	for i, vector := range vectors.Vectors {
+		engine, ok := engines[vector.Mode]
+		if !ok {
+			return fmt.Errorf("%w: vector %d: unknown mode %q", ErrSelfTest, i, vector.Mode)
+		}
+		header, err := decodeSelfTestHeader(vector.Header)
+		if err != nil {
+			return fmt.Errorf("%w: vector %d: %v", ErrSelfTest, i, err)
+		}
+		mixHash, powHash := engine.ComputePowLight(header)
+		if mixHash.Hex() != vector.MixHash || powHash.Hex() != vector.PowHash {
+			return fmt.Errorf("%w: vector %d (%s): have mixHash %s powHash %s, want %s %s", ErrSelfTest, i, vector.Mode, mixHash.Hex(), powHash.Hex(), vector.MixHash, vector.PowHash)
+		}
+	}
+	progpow.config.Log.Debug("Progpow golden vectors verified", "count", len(vectors.Vectors))
+
+	// Mine the low difficulty header and check the result verifies
+	header, err := decodeSelfTestHeader(vectors.Mining)
+	if err != nil {
+		return fmt.Errorf("%w: mining header: %v", ErrSelfTest, err)
+	}
+	for nonce := uint64(0); ; nonce++ {
+		if nonce == selfTestMaxNonces {
+			return fmt.Errorf("%w: no seal found in %d nonces", ErrSelfTest, selfTestMaxNonces)
+		}
+		header.SetNonce(types.EncodeNonce(nonce))
+		mixHash, powHash := testEngine.ComputePowLight(header)
+		if CheckTarget(powHash, header.Difficulty()) == nil {
+			header.SetMixHash(mixHash)
+			break
+		}
+	}
+	if _, err := testEngine.VerifySeal(header); err != nil {
+		return fmt.Errorf("%w: mined header rejected: %v", ErrSelfTest, err)
+	}
+	progpow.config.Log.Debug("Progpow mine-and-verify succeeded", "nonce", header.NonceU64())
+	return nil
+}
+
+// decodeSelfTestHeader decodes a hex encoded RLP header.
+func decodeSelfTestHeader(input string) (*types.Header, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(input), header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
+)
+
+// responseKey identifies a verification result. The header hash does not
+// commit to the mixHash, so it is part of the key as well.
+type responseKey struct {
+	hash    common.Hash
+	mixHash common.Hash
+}
+
+// etag returns the entity tag of the verification result for the key.
+func (k responseKey) etag() string {
+	return fmt.Sprintf(`"%x-%x"`, k.hash, k.mixHash)
+}
+
+// responseCache is a concurrency safe LRU of encoded verification responses.
+type responseCache struct {
+	mu    sync.Mutex
+	cache *lrucache.LRU[responseKey, []byte]
+}
+
+// newResponseCache creates a response cache holding at most size responses.
+func newResponseCache(size int) *responseCache {
+	return &responseCache{cache: lrucache.New[responseKey, []byte](size, 0, nil)}
+}
+
+// get retrieves the encoded response stored for key.
+func (c *responseCache) get(key responseKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cache.Get(key)
+}
+
+// add stores the encoded response for key.
+func (c *responseCache) add(key responseKey, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.Add(key, body, uint64(len(body)))
+}
@@ -0,0 +1,400 @@
+// Package server exposes a progpow verification engine over HTTP, allowing it
+// to run as a sidecar verifier instead of being embedded.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/chainstats"
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+const (
+	// DefaultCacheSize is the number of verification responses kept by default.
+	DefaultCacheSize = 4096
+
+	// maxRequestBytes caps the size of request bodies.
+	maxRequestBytes = 1 << 20
+
+	// traceHeader carries the trace ID of a request, see ServeHTTP.
+	traceHeader = "X-Trace-Id"
+
+	// maxTraceIDLength caps the length of trace IDs supplied by clients.
+	maxTraceIDLength = 64
+)
+
+// Config are the configuration parameters of the HTTP service.
+type Config struct {
+	// CacheSize is the number of verification responses to keep for repeated
+	// queries. Zero selects DefaultCacheSize, a negative value disables caching.
+	CacheSize int
+
+	// StatsWindow is the number of recent headers per chain the statistics
+	// served under /stats are computed over. Zero selects the default.
+	StatsWindow int
+
+	// Pprof mounts the net/http/pprof handlers under /debug/pprof/.
+	Pprof bool
+}
+
+var (
+	ErrInvalidNetwork = errors.New("invalid network name")
+	ErrNetworkExists  = errors.New("network already registered")
+)
+
+// Server is an http.Handler serving verification requests from shared,
+// long-lived engines and their epoch caches. Besides the default engine served
+// at the root, several networks (e.g. garden, orchard) can be hosted side by
+// side under /<network>/, each with its own engine, cache directory and
+// parameters.
+type Server struct {
+	config Config
+	mux    *http.ServeMux
+
+	lock     sync.Mutex
+	networks map[string]*network
+}
+
+// network is a single engine hosted by the service.
+type network struct {
+	engine *progpow.Progpow
+	cache  *responseCache // nil if response caching is disabled
+	stats  *chainstats.Stats
+}
+
+// New creates an HTTP service verifying headers with engine at the root paths.
+func New(engine *progpow.Progpow, config Config) *Server {
+	s := &Server{
+		config:   config,
+		mux:      http.NewServeMux(),
+		networks: make(map[string]*network),
+	}
+	n := s.newNetwork(engine)
+	s.mux.HandleFunc("/verify", n.handleVerify)
+	s.mux.HandleFunc("/compute", n.handleCompute)
+	s.mux.HandleFunc("/params", n.handleParams)
+	s.mux.HandleFunc("/memory", n.handleMemory)
+	s.mux.HandleFunc("/stats", n.handleStats)
+	s.mux.HandleFunc("/verified", n.handleVerified)
+
+	if config.Pprof {
+		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return s
+}
+
+// AddNetwork hosts engine under /<name>/, next to the default engine.
+func (s *Server) AddNetwork(name string, engine *progpow.Progpow) error {
+	if name == "" || strings.ContainsAny(name, "/?#") {
+		return fmt.Errorf("%w: %q", ErrInvalidNetwork, name)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.networks[name]; ok {
+		return fmt.Errorf("%w: %q", ErrNetworkExists, name)
+	}
+	n := s.newNetwork(engine)
+	s.networks[name] = n
+	s.mux.HandleFunc("/"+name+"/verify", n.handleVerify)
+	s.mux.HandleFunc("/"+name+"/compute", n.handleCompute)
+	s.mux.HandleFunc("/"+name+"/params", n.handleParams)
+	s.mux.HandleFunc("/"+name+"/memory", n.handleMemory)
+	s.mux.HandleFunc("/"+name+"/stats", n.handleStats)
+	s.mux.HandleFunc("/"+name+"/verified", n.handleVerified)
+	return nil
+}
+
+// Networks returns the names of the networks hosted next to the default engine.
+func (s *Server) Networks() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0, len(s.networks))
+	for name := range s.networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newNetwork wraps an engine with a response cache sized per the config.
+func (s *Server) newNetwork(engine *progpow.Progpow) *network {
+	n := &network{engine: engine, stats: chainstats.New(s.config.StatsWindow)}
+	switch {
+	case s.config.CacheSize == 0:
+		n.cache = newResponseCache(DefaultCacheSize)
+	case s.config.CacheSize > 0:
+		n.cache = newResponseCache(s.config.CacheSize)
+	}
+	return n
+}
+
+// ServeHTTP implements http.Handler. Every request is tagged with a trace ID,
+// taken from its X-Trace-Id header or generated, which is echoed in the
+// response and attached to the log lines of the request, so a caller's report
+// can be matched with the server logs.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(traceHeader)
+	if !validTraceID(id) {
+		id = log.NewTraceID()
+	}
+	w.Header().Set(traceHeader, id)
+	s.mux.ServeHTTP(w, r.WithContext(log.WithTraceID(r.Context(), id)))
+}
+
+// validTraceID reports whether a trace ID supplied by a client is safe to log:
+// non-empty, bounded and made of letters, digits, dashes and underscores.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// headerRequest is the JSON body accepted by POST /verify and POST /compute.
+type headerRequest struct {
+	Header string `json:"header"`
+}
+
+// verifyResponse is the JSON result of a verification.
+type verifyResponse struct {
+	Hash    string `json:"hash"`
+	MixHash string `json:"mixHash"`
+	PowHash string `json:"powHash,omitempty"`
+	Valid   bool   `json:"valid"`
+	Error   string `json:"error,omitempty"`
+}
+
+// computeResponse is the JSON result of a proof-of-work computation.
+type computeResponse struct {
+	Hash     string `json:"hash"`
+	SealHash string `json:"sealHash"`
+	MixHash  string `json:"mixHash"`
+	PowHash  string `json:"powHash"`
+}
+
+// readHeader decodes the header of a request, given as RLP-hex either in the
+// "header" query parameter of a GET request or in the JSON body of a POST. On
+// failure it writes the error response and returns nil.
+func readHeader(w http.ResponseWriter, r *http.Request) *types.Header {
+	var input string
+	switch r.Method {
+	case http.MethodGet:
+		input = r.URL.Query().Get("header")
+	case http.MethodPost:
+		var req headerRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return nil
+		}
+		input = req.Header
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return nil
+	}
+	header, err := types.DecodeHeaderRLP(common.FromHex(input))
+	if err != nil {
+		log.FromContext(r.Context()).Debug("Rejected undecodable header", "err", err)
+		writeError(w, http.StatusBadRequest, "invalid header RLP: "+err.Error())
+		return nil
+	}
+	if err := header.SanityCheck(); err != nil {
+		log.FromContext(r.Context()).Debug("Rejected malformed header", "err", err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	return header
+}
+
+// handleCompute computes the mixHash and powHash of a header, see readHeader,
+// for nodes and miners cross-checking their own computation. The nonce and the
+// fields covered by the seal hash determine the result; the mixHash carried by
+// the header is ignored.
+func (n *network) handleCompute(w http.ResponseWriter, r *http.Request) {
+	header := readHeader(w, r)
+	if header == nil {
+		return
+	}
+	mixHash, powHash, err := n.engine.ComputePowLightCtx(r.Context(), header)
+	if err != nil {
+		log.FromContext(r.Context()).Debug("Failed to compute proof-of-work", "hash", header.Hash(), "err", err)
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	log.FromContext(r.Context()).Debug("Computed proof-of-work", "hash", header.Hash(), "powHash", powHash)
+	body, err := json.Marshal(computeResponse{
+		Hash:     header.Hash().Hex(),
+		SealHash: header.SealHash().Hex(),
+		MixHash:  mixHash.Hex(),
+		PowHash:  powHash.Hex(),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeBody(w, http.StatusOK, body)
+}
+
+// handleVerify verifies the seal of a header, see readHeader. Results are
+// deterministic for a given header, so definitive ones, valid or invalid, are
+// tagged with an ETag derived from it and served from cache on repeated
+// queries. Transient failures, such as a verification cache still being
+// generated, are neither tagged nor cached, so clients retrying get the result
+// once it is known. Clients disconnecting abort the generation of a cache no
+// other request waits for.
+func (n *network) handleVerify(w http.ResponseWriter, r *http.Request) {
+	header := readHeader(w, r)
+	if header == nil {
+		return
+	}
+	key := responseKey{hash: header.Hash(), mixHash: header.MixHash()}
+	if n.cache != nil {
+		if body, ok := n.cache.get(key); ok {
+			log.FromContext(r.Context()).Debug("Served cached seal verification", "hash", key.hash)
+			writeTagged(w, r, key.etag(), body)
+			return
+		}
+	}
+	res := verifyResponse{Hash: key.hash.Hex(), MixHash: key.mixHash.Hex()}
+	powHash, verifyErr := n.engine.VerifySealCtx(r.Context(), header)
+	log.FromContext(r.Context()).Debug("Verified seal", "hash", key.hash, "valid", verifyErr == nil, "err", verifyErr)
+	if verifyErr != nil {
+		res.Error = verifyErr.Error()
+	} else {
+		res.PowHash, res.Valid = powHash.Hex(), true
+		n.stats.Add(header, n.engine.OrderOf(header, powHash))
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// A cold cache is a transient condition, not a property of the header
+	if transientError(verifyErr) {
+		w.Header().Set("Cache-Control", "no-store")
+		writeBody(w, http.StatusOK, body)
+		return
+	}
+	if n.cache != nil {
+		n.cache.add(key, body)
+	}
+	writeTagged(w, r, key.etag(), body)
+}
+
+// transientError reports whether a verification failed for a condition of the
+// engine, such as its verification cache not being generated yet or its memory
+// ceiling being reached, or was given up with the request, rather than for a
+// property of the header.
+func transientError(err error) bool {
+	return errors.Is(err, progpow.ErrCacheNotReady) || errors.Is(err, progpow.ErrOverloaded) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// writeTagged writes a definitive JSON response tagged with etag, or a 304 if
+// the request already holds it.
+func writeTagged(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeBody(w, http.StatusOK, body)
+}
+
+// handleParams returns the consensus parameters of the network's engine.
+func (n *network) handleParams(w http.ResponseWriter, r *http.Request) {
+	writeGet(w, r, n.engine.Params())
+}
+
+// handleMemory returns the memory report of the network's engine.
+func (n *network) handleMemory(w http.ResponseWriter, r *http.Request) {
+	writeGet(w, r, n.engine.MemoryReport())
+}
+
+// handleStats returns the statistics of the headers verified on the network.
+func (n *network) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeGet(w, r, n.stats.Summary())
+}
+
+// handleVerified serves the memoized seal verification results of the engine
+// as newline-delimited JSON, see progpow.Progpow.DumpVerified.
+func (n *network) handleVerified(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var buf bytes.Buffer
+	if err := n.engine.DumpVerified(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// writeGet serves v as the JSON response to a GET request.
+func writeGet(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeBody(w, http.StatusOK, body)
+}
+
+// matchesETag reports whether an If-None-Match header value matches etag.
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBody writes a JSON encoded response body.
+func writeBody(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeError writes a JSON encoded error response.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	body, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{msg})
+	writeBody(w, status, body)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// verify sends a verification request for header, revalidating etag if set.
+func verify(t *testing.T, s *Server, header string, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/verify?header="+header, nil)
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestVerifyETagOnlyForDefinitiveResults(t *testing.T) {
+	engine, err := progpow.New(progpow.Config{PowMode: progpow.ModeTest, NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(engine, Config{})
+
+	header := types.NewEmptyHeader()
+	header.SetDifficulty(big.NewInt(1))
+	var enc bytes.Buffer
+	if err := rlp.Encode(&enc, header); err != nil {
+		t.Fatal(err)
+	}
+	input := hexutil.Encode(enc.Bytes())
+
+	// The verification cache is cold, so the result is transient
+	w := verify(t, s, input, "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(progpow.ErrCacheNotReady.Error())) {
+		t.Fatalf("cold verification: status %d, body %s", w.Code, w.Body)
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Fatalf("transient result tagged with %s", etag)
+	}
+	// Revalidating a guessed tag must not turn the transient result into a 304
+	key := responseKey{hash: header.Hash(), mixHash: header.MixHash()}
+	if w := verify(t, s, input, "*"); w.Code == http.StatusNotModified {
+		t.Fatal("transient result answered with 304")
+	}
+	// Once the cache is generated, the result is definitive and tagged
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		w = verify(t, s, input, "")
+		if w.Header().Get("ETag") != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("verification cache not generated in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if etag := w.Header().Get("ETag"); etag != key.etag() {
+		t.Fatalf("etag %s, want %s", etag, key.etag())
+	}
+	if w := verify(t, s, input, key.etag()); w.Code != http.StatusNotModified {
+		t.Fatalf("revalidation: status %d, want 304", w.Code)
+	}
+}
@@ -0,0 +1,82 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+)
+
+var ErrInvalidSimulation = errors.New("invalid difficulty simulation")
+
+// SimulationParams configure a difficulty simulation.
+type SimulationParams struct {
+	DifficultyParams
+
+	InitialDifficulty *big.Int // Difficulty of the first simulated block
+	Seed              int64    // Seed of the pseudo-random block times, equal seeds give equal trajectories
+}
+
+// SimulatedBlock is a block of a simulated chain.
+type SimulatedBlock struct {
+	Number     uint64   `json:"number"`
+	Time       uint64   `json:"time"`       // Timestamp in seconds since the start of the simulation
+	BlockTime  uint64   `json:"blockTime"`  // Seconds since the previous block
+	Difficulty *big.Int `json:"difficulty"` // Difficulty the block was mined at
+	Hashrate   float64  `json:"hashrate"`   // Hashes per second mining the block
+}
+
+// SimulateDifficulty mines a simulated chain with one block per entry of
+// hashrates, the network hashrate in hashes per second while that block is
+// mined, and returns the resulting trajectory of block times and difficulties.
+// Difficulties follow the same adjustment algorithm as CalcDifficulty, so the
+// effect of parameter changes can be evaluated against the production code.
+//
+// Finding a seal is memoryless, so every block time is drawn from an
+// exponential distribution with a mean of difficulty / hashrate seconds, and
+// truncated to whole seconds like header timestamps. Uncles are not simulated.
+func SimulateDifficulty(params SimulationParams, hashrates []float64) ([]SimulatedBlock, error) {
+	if params.DurationLimit == nil || params.DurationLimit.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: duration limit must be positive", ErrInvalidSimulation)
+	}
+	if params.MinDifficulty == nil || params.BoundDivisor == nil || params.BoundDivisor.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: minimum difficulty and positive bound divisor required", ErrInvalidSimulation)
+	}
+	if params.InitialDifficulty == nil || params.InitialDifficulty.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: initial difficulty must be positive", ErrInvalidSimulation)
+	}
+	for _, hashrate := range hashrates {
+		if !(hashrate > 0) || math.IsInf(hashrate, 0) {
+			return nil, fmt.Errorf("%w: hashrates must be positive and finite", ErrInvalidSimulation)
+		}
+	}
+	var (
+		rng    = rand.New(rand.NewSource(params.Seed))
+		blocks = make([]SimulatedBlock, 0, len(hashrates))
+		now    uint64
+	)
+	for i, hashrate := range hashrates {
+		// The first two blocks have no grandparent, so the difficulty of the
+		// parent carries over unchanged
+		difficulty := new(big.Int).Set(params.InitialDifficulty)
+		if i >= 2 {
+			parent := blocks[i-1]
+			difficulty = calcDifficulty(params.DifficultyParams, parent.Difficulty, parent.BlockTime, false)
+		} else if i == 1 {
+			difficulty.Set(blocks[0].Difficulty)
+		}
+		mean, _ := new(big.Float).Quo(new(big.Float).SetInt(difficulty), big.NewFloat(hashrate)).Float64()
+		elapsed := uint64(rng.ExpFloat64() * mean)
+
+		now += elapsed
+		blocks = append(blocks, SimulatedBlock{
+			Number:     uint64(i),
+			Time:       now,
+			BlockTime:  elapsed,
+			Difficulty: difficulty,
+			Hashrate:   hashrate,
+		})
+	}
+	return blocks, nil
+}
@@ -0,0 +1,115 @@
+package progpow
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// EpochLength is the number of blocks in a single ethash epoch.
+const EpochLength = epochLength
+
+// MaxTableEpoch is the number of epochs covered by the built-in size tables.
+const MaxTableEpoch = maxEpoch
+
+var ErrInvalidSchedule = errors.New("invalid size schedule")
+
+// SizeSchedule describes how the verification cache and the mining dataset
+// grow over epochs. The tables cover the leading epochs, the calculators are
+// consulted for any epoch beyond them.
+type SizeSchedule struct {
+	CacheSizes   []uint64 // Cache sizes in bytes of the leading epochs
+	DatasetSizes []uint64 // Dataset sizes in bytes of the leading epochs
+
+	CalcCacheSize   func(epoch int) uint64 // Cache size fallback (nil = CalcCacheSize)
+	CalcDatasetSize func(epoch int) uint64 // Dataset size fallback (nil = CalcDatasetSize)
+}
+
+// activeSchedule is the size schedule installed via SetSizeSchedule, or nil if
+// the built-in tables are in use.
+var activeSchedule atomic.Pointer[SizeSchedule]
+
+// CacheSizes returns a copy of the built-in verification cache size table.
+func CacheSizes() []uint64 {
+	return append([]uint64(nil), cacheSizes[:]...)
+}
+
+// DatasetSizes returns a copy of the built-in mining dataset size table.
+func DatasetSizes() []uint64 {
+	return append([]uint64(nil), datasetSizes[:]...)
+}
+
+// CalcCacheSize calculates the cache size of an epoch from the growth schedule,
+// taking the highest prime row count below the linear threshold. It is the
+// fallback used beyond the end of the size table.
+func CalcCacheSize(epoch int) uint64 {
+	return calcCacheSize(epoch)
+}
+
+// CalcDatasetSize calculates the dataset size of an epoch from the growth
+// schedule, taking the highest prime row count below the linear threshold. It
+// is the fallback used beyond the end of the size table.
+func CalcDatasetSize(epoch int) uint64 {
+	return calcDatasetSize(epoch)
+}
+
+// DefaultSizeSchedule returns the built-in size schedule.
+func DefaultSizeSchedule() SizeSchedule {
+	return SizeSchedule{
+		CacheSizes:      CacheSizes(),
+		DatasetSizes:    DatasetSizes(),
+		CalcCacheSize:   calcCacheSize,
+		CalcDatasetSize: calcDatasetSize,
+	}
+}
+
+// SetSizeSchedule replaces the size schedule used by every engine in the
+// process, allowing forks with a different growth schedule to supply their own
+// tables. It must be called before any engine is created, as caches already
+// generated keep the size they were built with. Passing nil restores the
+// built-in tables.
+func SetSizeSchedule(s *SizeSchedule) error {
+	if s == nil {
+		activeSchedule.Store(nil)
+		return nil
+	}
+	for _, size := range s.CacheSizes {
+		if size == 0 || size%hashBytes != 0 {
+			return ErrInvalidSchedule
+		}
+	}
+	for _, size := range s.DatasetSizes {
+		if size == 0 || size%mixBytes != 0 {
+			return ErrInvalidSchedule
+		}
+	}
+	schedule := SizeSchedule{
+		CacheSizes:      append([]uint64(nil), s.CacheSizes...),
+		DatasetSizes:    append([]uint64(nil), s.DatasetSizes...),
+		CalcCacheSize:   s.CalcCacheSize,
+		CalcDatasetSize: s.CalcDatasetSize,
+	}
+	if schedule.CalcCacheSize == nil {
+		schedule.CalcCacheSize = calcCacheSize
+	}
+	if schedule.CalcDatasetSize == nil {
+		schedule.CalcDatasetSize = calcDatasetSize
+	}
+	activeSchedule.Store(&schedule)
+	return nil
+}
+
+// cacheSize returns the cache size of an epoch according to the schedule.
+func (s *SizeSchedule) cacheSize(epoch int) uint64 {
+	if epoch < len(s.CacheSizes) {
+		return s.CacheSizes[epoch]
+	}
+	return s.CalcCacheSize(epoch)
+}
+
+// datasetSize returns the dataset size of an epoch according to the schedule.
+func (s *SizeSchedule) datasetSize(epoch int) uint64 {
+	if epoch < len(s.DatasetSizes) {
+		return s.DatasetSizes[epoch]
+	}
+	return s.CalcDatasetSize(epoch)
+}
@@ -0,0 +1,156 @@
+package progpow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+var ErrNoWarmCache = errors.New("no generated verification cache to snapshot")
+
+// snapshotResult is the little endian layout of a remembered proof-of-work in
+// a snapshot.
+type snapshotResult struct {
+	SealHash   common.Hash
+	Nonce      uint64
+	Number     uint64
+	ZoneNumber uint64
+	MixHash    common.Hash
+	PowHash    common.Hash
+}
+
+// Snapshot writes the warm state of the engine to w, so that a freshly
+// started engine (e.g. a new WASM instance) can load it with LoadSnapshot
+// instead of regenerating it: the most recently used generated verification
+// cache in the envelope of ExportCache, followed by the number of remembered
+// proofs-of-work of its epoch, see Config.SealResults, as a little endian
+// uint32 and the proofs-of-work themselves, oldest first. The cDag is left
+// out, it is quick to derive from the cache on load.
+func (progpow *Progpow) Snapshot(w io.Writer) error {
+	// If we're running a shared PoW, snapshot its state instead
+	if progpow.shared != nil {
+		return progpow.shared.Snapshot(w)
+	}
+	var current *cache
+	for _, c := range progpow.caches.resident() {
+		if c.ready() {
+			current = c
+			break
+		}
+	}
+	if current == nil {
+		return ErrNoWarmCache
+	}
+	results := progpow.results.of(current.epoch)
+
+	buf := bytes.NewBuffer(current.export())
+	binary.Write(buf, binary.LittleEndian, uint32(len(results)))
+	binary.Write(buf, binary.LittleEndian, results)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// of returns the remembered proofs-of-work computed with the verification
+// cache of epoch, oldest first.
+func (r *sealResults) of(epoch uint64) []snapshotResult {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var results []snapshotResult
+	for _, key := range r.entries.Keys() {
+		result, _ := r.entries.Peek(key)
+		if result.number/epochLength != epoch {
+			continue
+		}
+		results = append(results, snapshotResult{
+			SealHash:   key.sealHash,
+			Nonce:      key.nonce,
+			Number:     result.number,
+			ZoneNumber: result.zoneNumber,
+			MixHash:    result.mixHash,
+			PowHash:    result.powHash,
+		})
+	}
+	return results
+}
+
+// LoadSnapshot reads a snapshot written by Snapshot holding the warm state of
+// epoch, and installs the contained verification cache like ImportCache,
+// making the epoch immediately verifiable. The envelope is checked against
+// the epoch, and the cache admitted by the MemoryGuard, before the cache is
+// read. Snapshots are untrusted input: the proofs-of-work they carry are
+// hashed anew with the installed cache and only remembered if they match, up
+// to the most recent Config.SealResults of them.
+func (progpow *Progpow) LoadSnapshot(epoch uint64, r io.Reader) error {
+	// If we're running a shared PoW, load the state into it instead
+	if progpow.shared != nil {
+		return progpow.shared.LoadSnapshot(epoch, r)
+	}
+	var header exportHeader
+	head := make([]byte, binary.Size(header))
+	if _, err := io.ReadFull(r, head); err != nil {
+		return err
+	}
+	binary.Read(bytes.NewReader(head), binary.LittleEndian, &header)
+	order, err := progpow.checkExportHeader(&header, epoch)
+	if err != nil {
+		return err
+	}
+	release, err := progpow.admit(epoch)
+	if err != nil {
+		return err
+	}
+	defer release()
+	data := make([]byte, exportLen(header.Words))
+	copy(data, head)
+	if _, err := io.ReadFull(r, data[len(head):]); err != nil {
+		return err
+	}
+	if err := progpow.importCache(epoch, data, order); err != nil {
+		return err
+	}
+	return progpow.loadResults(epoch, r)
+}
+
+// loadResults reads the proofs-of-work of a snapshot following its cache, and
+// remembers the most recent ones which hash the same with the cache of epoch.
+func (progpow *Progpow) loadResults(epoch uint64, r io.Reader) error {
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+	if progpow.results == nil {
+		return nil
+	}
+	c, ok := progpow.caches.peek(epoch)
+	if !ok {
+		return nil
+	}
+	skip := int(count) - progpow.config.SealResults
+	for i := 0; i < int(count); i++ {
+		var result snapshotResult
+		if err := binary.Read(r, binary.LittleEndian, &result); err != nil {
+			return err
+		}
+		if i < skip || result.Number/epochLength != epoch {
+			continue
+		}
+		mixHash, powHash := progpow.hashLight(c, result.SealHash, result.Nonce, result.Number, result.ZoneNumber)
+		if mixHash != result.MixHash || powHash != result.PowHash {
+			continue
+		}
+		progpow.results.addResult(memoKey{sealHash: result.SealHash, nonce: result.Nonce}, sealResult{
+			mixHash:    mixHash,
+			powHash:    powHash,
+			number:     result.Number,
+			zoneNumber: result.ZoneNumber,
+		})
+	}
+	return nil
+}
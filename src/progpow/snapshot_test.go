@@ -0,0 +1,94 @@
+package progpow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// decodeSealed returns a fresh copy of the sealed test header, without the
+// proof-of-work cached in it.
+func decodeSealed(t *testing.T) *types.Header {
+	t.Helper()
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(common.FromHex(sealedHeader), header); err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+func TestSnapshot(t *testing.T) {
+	source, err := New(Config{PowMode: ModeTest, SealResults: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Snapshot(new(bytes.Buffer)); !errors.Is(err, ErrNoWarmCache) {
+		t.Fatalf("cold snapshot: %v, want %v", err, ErrNoWarmCache)
+	}
+	if _, err := source.VerifySeal(decodeSealed(t)); err != nil {
+		t.Fatal(err)
+	}
+	var snapshot bytes.Buffer
+	if err := source.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshots of another epoch are rejected
+	engine, err := New(Config{PowMode: ModeTest, SealResults: 8, NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadSnapshot(1, bytes.NewReader(snapshot.Bytes())); !errors.Is(err, ErrInvalidCacheExport) {
+		t.Fatalf("snapshot of another epoch: %v, want %v", err, ErrInvalidCacheExport)
+	}
+	// Loaded snapshots verify right away, without hashing remembered seals
+	if err := engine.LoadSnapshot(0, bytes.NewReader(snapshot.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.VerifySeal(decodeSealed(t)); err != nil {
+		t.Fatalf("seal verification with loaded snapshot: %v", err)
+	}
+	if stats := engine.SealResultStats(); stats.Results != 1 || stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("seal results %+v, want one remembered and hit", stats)
+	}
+	// Remembered proofs-of-work which do not hash the same are dropped
+	forged := append([]byte(nil), snapshot.Bytes()...)
+	forged[len(forged)-1] ^= 1
+	engine, err = New(Config{PowMode: ModeTest, SealResults: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.LoadSnapshot(0, bytes.NewReader(forged)); err != nil {
+		t.Fatal(err)
+	}
+	if stats := engine.SealResultStats(); stats.Results != 0 {
+		t.Errorf("%d forged seal results remembered", stats.Results)
+	}
+}
+
+// Snapshots of caches beyond the memory ceiling are refused before the cache
+// is read.
+func TestLoadSnapshotAdmission(t *testing.T) {
+	engine, err := New(Config{MemoryGuard: NewMemoryGuard(1 << 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const epoch = 100
+	header := exportHeader{
+		Magic:    exportMagic,
+		Version:  exportVersion,
+		Revision: uint32(algorithmRevision),
+		Epoch:    epoch,
+		Words:    cacheSize(epoch*epochLength+1) / 4,
+	}
+	var snapshot bytes.Buffer
+	binary.Write(&snapshot, binary.LittleEndian, &header)
+	if err := engine.LoadSnapshot(epoch, &snapshot); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("oversized snapshot: %v, want %v", err, ErrOverloaded)
+	}
+}
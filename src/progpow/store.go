@@ -0,0 +1,181 @@
+package progpow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+	"lukechampine.com/blake3"
+)
+
+// ErrCacheNotStored is returned by a CacheStore if it holds no cache under the
+// requested key.
+var ErrCacheNotStored = errors.New("verification cache not stored")
+
+// CacheStore persists generated verification caches across engine restarts.
+// Caches are addressed by an opaque key identifying the algorithm revision,
+// epoch and cache size, and held as little endian words followed by their 32
+// byte blake3 checksum, so stored caches are portable between platforms and
+// corrupt ones are regenerated. Implementations must be safe for concurrent
+// use.
+type CacheStore interface {
+	// Load returns the cache stored under key, or ErrCacheNotStored.
+	Load(key string) ([]byte, error)
+
+	// Store saves a cache under key, replacing any previous one.
+	Store(key string, data []byte) error
+
+	// Delete removes the cache stored under key. Deleting a missing key is not
+	// an error.
+	Delete(key string) error
+}
+
+// storeChecksumSize is the length of the blake3 checksum following the words
+// of a stored cache.
+const storeChecksumSize = 32
+
+// storeKey returns the key the verification cache of an epoch is stored under.
+// Test mode caches are much smaller than regular ones and are kept apart.
+func storeKey(epoch uint64, test bool) string {
+	return storeKeyAt(algorithmRevision, epoch, test)
+}
+
+// storeKeyAt returns the key the verification cache of an epoch is stored
+// under by the given algorithm revision.
+func storeKeyAt(revision int, epoch uint64, test bool) string {
+	var mode string
+	if test {
+		mode = "-test"
+	}
+	seed := seedHash(epoch*epochLength + 1)
+	return fmt.Sprintf("cache-R%d-%x%s", revision, seed[:8], mode)
+}
+
+// loadOrGenerate returns the verification cache of an epoch from the store, or
+// generates it with generator and stores it if it is missing or corrupt. The
+// caches of epochs older than the retention limit are deleted after a new one
+// is stored, unless the limit is zero. If the generator reports it was aborted,
+// nothing is stored and nil is returned. Storing a cache also deletes the one
+// stored for the epoch by earlier algorithm revisions. Progress is logged
+// through logger.
+func loadOrGenerate(store CacheStore, epoch uint64, size uint64, limit int, test bool, logger log.Logger, generator func(buffer []uint32) bool) []uint32 {
+	key := storeKey(epoch, test)
+	logger = logger.With("epoch", epoch)
+
+	data, err := store.Load(key)
+	if err == nil {
+		if data, err = checkStoredCache(data, size); err == nil {
+			logger.Debug("Loaded old ethash cache from store")
+			return decodeWords(data)
+		}
+		logger.Warn("Regenerating corrupt ethash cache", "key", key, "err", err)
+	} else {
+		logger.Debug("Failed to load old ethash cache", "err", err)
+	}
+	cache := make([]uint32, size/4)
+	if !generator(cache) {
+		return nil
+	}
+	if err := store.Store(key, encodeWords(cache)); err != nil {
+		logger.Warn("Failed to store ethash cache", "err", err)
+		return cache
+	}
+	for revision := 1; revision < algorithmRevision; revision++ {
+		store.Delete(storeKeyAt(revision, epoch, test))
+	}
+	// Iterate over all previous instances and delete old ones
+	for ep := int(epoch) - limit; limit > 0 && ep >= 0; ep-- {
+		store.Delete(storeKey(uint64(ep), test))
+	}
+	return cache
+}
+
+// checkStoredCache checks the size and checksum of a stored cache of size
+// bytes, returning its words without the checksum.
+func checkStoredCache(data []byte, size uint64) ([]byte, error) {
+	if uint64(len(data)) != size+storeChecksumSize {
+		return nil, fmt.Errorf("%w: size mismatch: have %d, want %d", ErrDumpChecksum, len(data), size+storeChecksumSize)
+	}
+	words := data[:size]
+	if sum := blake3.Sum256(words); !bytes.Equal(sum[:], data[size:]) {
+		return nil, ErrDumpChecksum
+	}
+	return words, nil
+}
+
+// encodeWords serializes cache words in little endian order, followed by their
+// checksum.
+func encodeWords(words []uint32) []byte {
+	data := make([]byte, len(words)*4, len(words)*4+storeChecksumSize)
+	for i, word := range words {
+		binary.LittleEndian.PutUint32(data[i*4:], word)
+	}
+	sum := blake3.Sum256(data)
+	return append(data, sum[:]...)
+}
+
+// decodeWords deserializes little endian cache words.
+func decodeWords(data []byte) []uint32 {
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return words
+}
+
+// FileCacheStore is a CacheStore keeping every cache in a file of its own
+// within a directory. Unlike CacheDir, the files are read into memory rather
+// than memory mapped, so it works wherever a file system is available.
+type FileCacheStore struct {
+	dir string
+}
+
+// NewFileCacheStore creates a store persisting caches in dir, creating the
+// directory if needed.
+func NewFileCacheStore(dir string) (*FileCacheStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileCacheStore{dir: dir}, nil
+}
+
+// Load implements CacheStore.
+func (s *FileCacheStore) Load(key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrCacheNotStored
+	}
+	return data, err
+}
+
+// Store implements CacheStore. The cache is written to a temporary file first
+// and renamed into place, so concurrent readers never see a partial cache.
+func (s *FileCacheStore) Store(key string, data []byte) error {
+	f, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(s.dir, key))
+}
+
+// Delete implements CacheStore.
+func (s *FileCacheStore) Delete(key string) error {
+	err := os.Remove(filepath.Join(s.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,118 @@
+//go:build js && wasm
+// +build js,wasm
+
+package progpow
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+// indexedDBObjectStore is the name of the object store holding the caches.
+const indexedDBObjectStore = "caches"
+
+var errIndexedDBUnavailable = errors.New("indexedDB is not available")
+
+// IndexedDBCacheStore is a CacheStore persisting caches in the IndexedDB of
+// the browser, so a page load does not have to regenerate the verification
+// cache of every epoch it verifies. Its methods block the calling goroutine
+// until the database completes the request, so they must not be called from
+// the JavaScript event loop itself, e.g. directly inside a js.FuncOf callback.
+type IndexedDBCacheStore struct {
+	db js.Value
+}
+
+// NewIndexedDBCacheStore opens (or creates) the IndexedDB database name to
+// persist caches in. It fails if IndexedDB is not available, as in Node.js.
+func NewIndexedDBCacheStore(name string) (*IndexedDBCacheStore, error) {
+	factory := js.Global().Get("indexedDB")
+	if factory.IsUndefined() || factory.IsNull() {
+		return nil, errIndexedDBUnavailable
+	}
+	req := factory.Call("open", name, 1)
+
+	upgrade := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		db := req.Get("result")
+		if !db.Get("objectStoreNames").Call("contains", indexedDBObjectStore).Bool() {
+			db.Call("createObjectStore", indexedDBObjectStore)
+		}
+		return nil
+	})
+	defer upgrade.Release()
+	req.Set("onupgradeneeded", upgrade)
+
+	db, err := awaitRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexedDBCacheStore{db: db}, nil
+}
+
+// Load implements CacheStore.
+func (s *IndexedDBCacheStore) Load(key string) ([]byte, error) {
+	result, err := awaitRequest(s.objectStore("readonly").Call("get", key))
+	if err != nil {
+		return nil, err
+	}
+	if result.IsUndefined() || result.IsNull() {
+		return nil, ErrCacheNotStored
+	}
+	if !result.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, fmt.Errorf("indexedDB: unexpected cache value %s", result.Type())
+	}
+	data := make([]byte, result.Get("byteLength").Int())
+	js.CopyBytesToGo(data, result)
+	return data, nil
+}
+
+// Store implements CacheStore.
+func (s *IndexedDBCacheStore) Store(key string, data []byte) error {
+	value := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(value, data)
+
+	_, err := awaitRequest(s.objectStore("readwrite").Call("put", value, key))
+	return err
+}
+
+// Delete implements CacheStore.
+func (s *IndexedDBCacheStore) Delete(key string) error {
+	_, err := awaitRequest(s.objectStore("readwrite").Call("delete", key))
+	return err
+}
+
+// objectStore opens the cache object store in a new transaction of the given
+// mode.
+func (s *IndexedDBCacheStore) objectStore(mode string) js.Value {
+	return s.db.Call("transaction", indexedDBObjectStore, mode).Call("objectStore", indexedDBObjectStore)
+}
+
+// awaitRequest blocks until an IndexedDB request succeeds or fails and returns
+// its result.
+func awaitRequest(req js.Value) (js.Value, error) {
+	done := make(chan error, 1)
+
+	success := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- nil
+		return nil
+	})
+	defer success.Release()
+
+	failure := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		msg := "unknown error"
+		if err := req.Get("error"); !err.IsNull() && !err.IsUndefined() {
+			msg = err.Get("message").String()
+		}
+		done <- fmt.Errorf("indexedDB: %s", msg)
+		return nil
+	})
+	defer failure.Release()
+
+	req.Set("onsuccess", success)
+	req.Set("onerror", failure)
+
+	if err := <-done; err != nil {
+		return js.Undefined(), err
+	}
+	return req.Get("result"), nil
+}
@@ -0,0 +1,80 @@
+package progpow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/log"
+)
+
+// Stored caches are checked on load and regenerated if corrupt, and caches
+// of earlier revisions are deleted.
+func TestLoadOrGenerateChecksum(t *testing.T) {
+	store, err := NewFileCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := storeKeyAt(algorithmRevision-1, 0, true)
+	if err := store.Store(oldKey, make([]byte, 1024)); err != nil {
+		t.Fatal(err)
+	}
+	generated := 0
+	generate := func(buffer []uint32) bool {
+		generated++
+		for i := range buffer {
+			buffer[i] = uint32(i)
+		}
+		return true
+	}
+	load := func() {
+		t.Helper()
+		cache := loadOrGenerate(store, 0, 1024, 0, true, log.Log, generate)
+		if len(cache) != 256 || cache[255] != 255 {
+			t.Fatalf("cache of %d words ending in %d", len(cache), cache[len(cache)-1])
+		}
+	}
+	load()
+	if _, err := store.Load(oldKey); err != ErrCacheNotStored {
+		t.Fatalf("cache of revision %d left in store: %v", algorithmRevision-1, err)
+	}
+	load()
+	if generated != 1 {
+		t.Fatalf("stored cache generated %d times, want once", generated)
+	}
+	// Corrupt and truncated caches are regenerated
+	data, err := store.Load(storeKey(0, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[100] ^= 1
+	store.Store(storeKey(0, true), data)
+	load()
+	store.Store(storeKey(0, true), data[:1024])
+	load()
+	if generated != 3 {
+		t.Fatalf("corrupt caches generated %d times, want 3", generated)
+	}
+}
+
+func TestRemoveOldRevisions(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"cache-R1-0123456789abcdef",
+		"cache-R1-0123456789abcdef.be",
+		"cache-R2-0123456789abcdef",
+		"unrelated",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removeOldRevisions(dir)
+	for i, name := range names {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if removed := os.IsNotExist(err); removed != (i < 2) {
+			t.Errorf("%s removed: %v", name, removed)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package progpow
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// bigPool recycles the scratch integers of target and entropy calculations,
+// which run for every verified header.
+var bigPool = sync.Pool{New: func() interface{} { return new(big.Int) }}
+
+// DifficultyToTarget returns the target a proof-of-work hash must not exceed
+// to meet the given difficulty, 2^256 / difficulty. The difficulty must be
+// positive.
+func DifficultyToTarget(difficulty *big.Int) *big.Int {
+	return new(big.Int).Div(big2e256, difficulty)
+}
+
+// TargetToDifficulty returns the difficulty matching a target, 2^256 / target,
+// the inverse of DifficultyToTarget. The target must be positive.
+func TargetToDifficulty(target *big.Int) *big.Int {
+	return new(big.Int).Div(big2e256, target)
+}
+
+// CheckTarget checks that a proof-of-work hash meets the given difficulty,
+// independently of the difficulty of the header it was computed for. Pools can
+// use it to accept shares below the block difficulty.
+func CheckTarget(powHash common.Hash, difficulty *big.Int) error {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return errInvalidDifficulty
+	}
+	// powHash <= 2^256 / difficulty holds exactly if powHash * difficulty <= 2^256,
+	// which spares the division
+	hash := u256FromHash(powHash)
+	diff, ok := u256FromBig(difficulty)
+	if !ok {
+		// Difficulties from 2^256 on leave the target at one for exactly 2^256
+		// and at zero beyond
+		if hash.isZero() || (hash == u256{1} && difficulty.Cmp(big2e256) == 0) {
+			return nil
+		}
+		return errInvalidPoW
+	}
+	if !hash.mulAtMost2e256(&diff) {
+		return errInvalidPoW
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package progpow
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// checkTargetBig is the reference CheckTarget, comparing the hash against the
+// target 2^256 / difficulty.
+func checkTargetBig(powHash common.Hash, difficulty *big.Int) bool {
+	return new(big.Int).SetBytes(powHash[:]).Cmp(new(big.Int).Div(big2e256, difficulty)) <= 0
+}
+
+// bigHash returns x as a hash, which must fit.
+func bigHash(x *big.Int) common.Hash {
+	var h common.Hash
+	x.FillBytes(h[:])
+	return h
+}
+
+func TestCheckTarget(t *testing.T) {
+	var (
+		one      = big.NewInt(1)
+		maxHash  = new(big.Int).Sub(big2e256, one)
+		above256 = new(big.Int).Add(big2e256, one)
+	)
+	tests := []struct {
+		name       string
+		hash       *big.Int
+		difficulty *big.Int
+		valid      bool
+	}{
+		{"difficulty 1, zero hash", new(big.Int), one, true},
+		{"difficulty 1, max hash", maxHash, one, true},
+		{"difficulty 2, hash==target", new(big.Int).Lsh(one, 255), big.NewInt(2), true},
+		{"difficulty 2, hash==target+1", new(big.Int).Add(new(big.Int).Lsh(one, 255), one), big.NewInt(2), false},
+		{"difficulty 3, hash==target", new(big.Int).Div(big2e256, big.NewInt(3)), big.NewInt(3), true},
+		{"difficulty 3, hash==target+1", new(big.Int).Add(new(big.Int).Div(big2e256, big.NewInt(3)), one), big.NewInt(3), false},
+		{"difficulty 2^256-1, hash==target", one, maxHash, true},
+		{"difficulty 2^256-1, hash==target+1", big.NewInt(2), maxHash, false},
+		{"difficulty 2^256, zero hash", new(big.Int), big2e256, true},
+		{"difficulty 2^256, hash==target", one, big2e256, true},
+		{"difficulty 2^256, hash==target+1", big.NewInt(2), big2e256, false},
+		{"difficulty 2^256+1, zero hash", new(big.Int), above256, true},
+		{"difficulty 2^256+1, hash 1", one, above256, false},
+		{"difficulty 2^300, max hash", maxHash, new(big.Int).Lsh(one, 300), false},
+	}
+	for _, tt := range tests {
+		hash := bigHash(tt.hash)
+		if ref := checkTargetBig(hash, tt.difficulty); ref != tt.valid {
+			t.Fatalf("%s: reference reports %v, want %v", tt.name, ref, tt.valid)
+		}
+		err := CheckTarget(hash, tt.difficulty)
+		if tt.valid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, errInvalidPoW) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, errInvalidPoW)
+		}
+	}
+	for _, difficulty := range []*big.Int{nil, new(big.Int), big.NewInt(-1)} {
+		if err := CheckTarget(common.Hash{}, difficulty); !errors.Is(err, errInvalidDifficulty) {
+			t.Errorf("difficulty %v: error %v, want %v", difficulty, err, errInvalidDifficulty)
+		}
+	}
+}
+
+// The target check agrees with the division around the targets of random
+// difficulties of all sizes.
+func TestCheckTargetRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		difficulty := new(big.Int).Rand(rng, new(big.Int).Lsh(common.Big1, uint(1+rng.Intn(257))))
+		difficulty.Add(difficulty, common.Big1)
+
+		target := new(big.Int).Div(big2e256, difficulty)
+		for _, delta := range []int64{-1, 0, 1} {
+			hash := new(big.Int).Add(target, big.NewInt(delta))
+			if hash.Sign() < 0 || hash.BitLen() > 256 {
+				continue
+			}
+			want := checkTargetBig(bigHash(hash), difficulty)
+			if got := CheckTarget(bigHash(hash), difficulty) == nil; got != want {
+				t.Fatalf("difficulty %v, hash target%+d: valid %v, want %v", difficulty, delta, got, want)
+			}
+		}
+	}
+}
+
+func TestMulAtMost2e256(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() *big.Int {
+		return new(big.Int).Rand(rng, new(big.Int).Lsh(common.Big1, uint(rng.Intn(257))+1))
+	}
+	var (
+		two128 = new(big.Int).Lsh(common.Big1, 128)
+		edges  = [][2]*big.Int{
+			{two128, two128},
+			{two128, new(big.Int).Add(two128, common.Big1)},
+			{new(big.Int).Lsh(common.Big1, 255), big.NewInt(2)},
+			{new(big.Int).Sub(big2e256, common.Big1), common.Big1},
+			{new(big.Int).Sub(big2e256, common.Big1), big.NewInt(2)},
+		}
+	)
+	for i := 0; i < 2000+len(edges); i++ {
+		var x, y *big.Int
+		if i < len(edges) {
+			x, y = edges[i][0], edges[i][1]
+		} else {
+			x, y = random(), random()
+		}
+		if x.BitLen() > 256 || y.BitLen() > 256 {
+			continue
+		}
+		ux, _ := u256FromBig(x)
+		uy, _ := u256FromBig(y)
+		want := new(big.Int).Mul(x, y).Cmp(big2e256) <= 0
+		if got := ux.mulAtMost2e256(&uy); got != want {
+			t.Fatalf("%v * %v <= 2^256: %v, want %v", x, y, got, want)
+		}
+	}
+}
+
+func BenchmarkCheckTarget(b *testing.B) {
+	powHash := common.HexToHash("0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792")
+	difficulty := big.NewInt(16)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := CheckTarget(powHash, difficulty); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheckTargetBig(b *testing.B) {
+	powHash := common.HexToHash("0x0690015ecb9b2348397cdb502af3c9e8b06e4f2aa4d752a96116165f6bd38792")
+	difficulty := big.NewInt(16)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !checkTargetBig(powHash, difficulty) {
+			b.Fatal("hash above target")
+		}
+	}
+}
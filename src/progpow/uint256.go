@@ -0,0 +1,76 @@
+package progpow
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// u256 is an unsigned 256 bit integer of four 64 bit limbs, least significant
+// first. It keeps the target check of every verified seal on the stack,
+// without the heap integers and pool round trips of big.Int, which weigh on
+// WASM builds verifying thousands of headers.
+type u256 [4]uint64
+
+// u256FromHash interprets a hash as a big endian integer.
+func u256FromHash(h common.Hash) u256 {
+	return u256{
+		binary.BigEndian.Uint64(h[24:32]),
+		binary.BigEndian.Uint64(h[16:24]),
+		binary.BigEndian.Uint64(h[8:16]),
+		binary.BigEndian.Uint64(h[0:8]),
+	}
+}
+
+// u256FromBig converts a non-negative integer, reporting false if it does not
+// fit into 256 bits.
+func u256FromBig(x *big.Int) (z u256, ok bool) {
+	if x.BitLen() > 256 {
+		return z, false
+	}
+	words := x.Bits()
+	if bits.UintSize == 64 {
+		for i, w := range words {
+			z[i] = uint64(w)
+		}
+		return z, true
+	}
+	for i, w := range words {
+		z[i/2] |= uint64(w) << (32 * (i % 2))
+	}
+	return z, true
+}
+
+// isZero reports whether x is zero.
+func (x *u256) isZero() bool {
+	return x[0]|x[1]|x[2]|x[3] == 0
+}
+
+// mulAtMost2e256 reports whether x*y <= 2^256, from their full 512 bit
+// product.
+func (x *u256) mulAtMost2e256(y *u256) bool {
+	var p [8]uint64
+	for i := 0; i < 4; i++ {
+		var carry uint64
+		for j := 0; j < 4; j++ {
+			hi, lo := bits.Mul64(x[i], y[j])
+			var c uint64
+			lo, c = bits.Add64(lo, p[i+j], 0)
+			hi += c
+			lo, c = bits.Add64(lo, carry, 0)
+			hi += c
+			p[i+j], carry = lo, hi
+		}
+		p[i+4] = carry
+	}
+	switch {
+	case p[7]|p[6]|p[5] != 0 || p[4] > 1:
+		return false
+	case p[4] == 1:
+		return p[3]|p[2]|p[1]|p[0] == 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,102 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// Uncle rules of the Quai consensus.
+const (
+	MaxWorkShareCount = 16 // Maximum number of uncles, workshares included, in a single block, as in go-quai
+	maxUncleDepth     = 7  // Maximum number of generations an uncle's parent may lie behind the block
+)
+
+var (
+	ErrTooManyUncles   = errors.New("too many uncles")
+	ErrDuplicateUncle  = errors.New("duplicate uncle")
+	ErrUncleIsAncestor = errors.New("uncle is ancestor")
+	ErrDanglingUncle   = errors.New("uncle's parent is not ancestor")
+)
+
+// VerifyUncles checks the uncles of a block against the consensus rules: at
+// most MaxWorkShareCount of them, no duplicates, none being the block or one of its ancestors, each building on
+// one of the seven ancestors preceding the block other than its parent, and
+// each carrying a valid seal. Building on an ancestor is checked like in
+// VerifyHeaderChain.
+//
+// The uncles of a Quai block are its workshares, so their seals need not meet
+// the zone difficulty: like in WorkShareOrder, hashes falling short of it
+// count as workshares, and are accepted if they meet the difficulty relaxed
+// by WorkShareThresholdBits.
+//
+// ancestors holds the headers of the recent ancestors of the block by hash.
+// The chain is followed from the parent of the block through parent hashes in
+// the context of its location, so headers off the chain or too far back are
+// ignored, and a chain cut short leaves the uncles building beyond the cut
+// dangling. Uncles which ancestors of the block already included cannot be
+// told from headers and are left to the caller.
+//
+// Rule violations are returned wrapped with the index of the uncle, the
+// first one found in block order.
+func (progpow *Progpow) VerifyUncles(block *types.Block, ancestors map[common.Hash]*types.Header) error {
+	uncles := block.Uncles()
+	if len(uncles) > MaxWorkShareCount {
+		return fmt.Errorf("%w: %d uncles, limit %d", ErrTooManyUncles, len(uncles), MaxWorkShareCount)
+	}
+	if len(uncles) == 0 {
+		return nil
+	}
+	header := block.Header()
+	if err := header.SanityCheck(); err != nil {
+		return err
+	}
+	ctx := header.Location().Ctx()
+
+	// Gather the ancestors the uncles may build on, along with the block
+	recent := make(map[common.Hash]*types.Header, maxUncleDepth+1)
+	for parent, i := header.ParentHashIn(ctx), 0; i < maxUncleDepth; i++ {
+		ancestor := ancestors[parent]
+		if ancestor == nil {
+			break
+		}
+		recent[parent] = ancestor
+		if parent, _ = ancestor.ParentHashSafe(ctx); parent == (common.Hash{}) {
+			break
+		}
+	}
+	hash := header.Hash()
+	recent[hash] = header
+	seen := map[common.Hash]bool{hash: true}
+
+	for i, uncle := range uncles {
+		if err := uncle.SanityCheck(); err != nil {
+			return fmt.Errorf("uncle %d: %w", i, err)
+		}
+		// Make sure every uncle is included only once
+		hash := uncle.Hash()
+		if seen[hash] {
+			return fmt.Errorf("uncle %d: %w: %x", i, ErrDuplicateUncle, hash)
+		}
+		seen[hash] = true
+
+		// Make sure the uncle has a valid ancestry
+		if recent[hash] != nil {
+			return fmt.Errorf("uncle %d: %w: %x", i, ErrUncleIsAncestor, hash)
+		}
+		parentHash := uncle.ParentHashIn(ctx)
+		parent := recent[parentHash]
+		if parent == nil || parentHash == header.ParentHashIn(ctx) {
+			return fmt.Errorf("uncle %d: %w: parent %x", i, ErrDanglingUncle, parentHash)
+		}
+		if err := verifyParent(uncle, parent); err != nil {
+			return fmt.Errorf("uncle %d: %w", i, err)
+		}
+		if err := progpow.verifyWorkShare(uncle, WorkShareThresholdBits); err != nil {
+			return fmt.Errorf("uncle %d: %w", i, err)
+		}
+	}
+	return nil
+}
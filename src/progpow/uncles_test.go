@@ -0,0 +1,93 @@
+package progpow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow"
+	"github.com/dominant-strategies/progpow-verification-wasm/progpow/progpowtest"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// mineShare seals a copy of sibling with the given extra data for inclusion
+// in block, missing the difficulty but meeting the workshare threshold or not.
+func mineShare(t *testing.T, engine *progpow.Progpow, block, sibling *types.Header, extra byte, meets bool) *types.Header {
+	t.Helper()
+	share := sibling.Copy()
+	share.SetExtra([]byte{extra})
+	check := engine.WorkShareCheck(progpow.WorkShareThresholdBits)
+	for nonce := uint64(0); nonce < 1<<16; nonce++ {
+		share.SetNonce(types.EncodeNonce(nonce))
+		mixHash, _ := engine.ComputePowLight(share)
+		share.SetMixHash(mixHash)
+
+		if order, err := engine.WorkShareOrder(share); err != nil || order != common.HierarchyDepth {
+			continue
+		}
+		if (check(block, share) == nil) == meets {
+			return share
+		}
+	}
+	t.Fatal("no share found")
+	return nil
+}
+
+func TestVerifyUncles(t *testing.T) {
+	engine, err := progpow.New(progpow.Config{PowMode: progpow.ModeTest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := progpowtest.GenerateChain(4, engine, nil)
+	ancestors := make(map[common.Hash]*types.Header)
+	for _, header := range chain[:3] {
+		ancestors[header.Hash()] = header
+	}
+	var (
+		head   = chain[3]
+		shares []*types.Header
+	)
+	for i := 0; i < 4; i++ {
+		shares = append(shares, mineShare(t, engine, head, chain[2], byte(i), true))
+	}
+	weak := mineShare(t, engine, head, chain[2], 4, false)
+	sibling := head.Copy()
+	sibling.SetExtra([]byte{5})
+
+	tests := []struct {
+		name   string
+		uncles []*types.Header
+		want   error
+	}{
+		{"none", nil, nil},
+		{"workshares", shares, nil},
+		{"uncle block", chain[2:3], progpow.ErrUncleIsAncestor},
+		{"duplicate", []*types.Header{shares[0], shares[0]}, progpow.ErrDuplicateUncle},
+		{"below threshold", []*types.Header{shares[0], weak}, progpow.ErrInvalidWorkShare},
+		{"sibling", []*types.Header{sibling}, progpow.ErrDanglingUncle},
+	}
+	for _, tt := range tests {
+		block := types.NewBlockWithHeader(head).WithBody(nil, tt.uncles, nil, nil)
+		if err := engine.VerifyUncles(block, ancestors); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// The uncle count is a consensus rule, unaffected by the decode limits
+	limits := types.CurrentDecodeLimits()
+	defer types.SetDecodeLimits(limits)
+	tight := limits
+	tight.MaxUncles = 3
+	types.SetDecodeLimits(tight)
+	block := types.NewBlockWithHeader(head).WithBody(nil, shares, nil, nil)
+	if err := engine.VerifyUncles(block, ancestors); err != nil {
+		t.Errorf("workshares over the decode limit: %v", err)
+	}
+	for i := len(shares); i <= progpow.MaxWorkShareCount; i++ {
+		shares = append(shares, mineShare(t, engine, head, chain[2], byte(i+8), true))
+	}
+	block = types.NewBlockWithHeader(head).WithBody(nil, shares, nil, nil)
+	if err := engine.VerifyUncles(block, ancestors); !errors.Is(err, progpow.ErrTooManyUncles) {
+		t.Errorf("%d workshares: got %v, want %v", len(shares), err, progpow.ErrTooManyUncles)
+	}
+}
@@ -0,0 +1,102 @@
+package progpow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/crypto"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var ErrNoVectors = errors.New("cannot generate test vectors")
+
+// TestVector is a header along with the progpow output this implementation
+// computes for it, for other implementations to cross-check against.
+type TestVector struct {
+	Mode     string           `json:"mode"`
+	Epoch    uint64           `json:"epoch"`
+	Number   uint64           `json:"number"`
+	Header   hexutil.Bytes    `json:"header"` // RLP encoding, seal included
+	SealHash common.Hash      `json:"sealHash"`
+	Nonce    types.BlockNonce `json:"nonce"`
+	MixHash  common.Hash      `json:"mixHash"`
+	PowHash  common.Hash      `json:"powHash"`
+}
+
+// GenerateTestVectors returns count test vectors for the given epoch, computed
+// with the verification cache of the engine mode. The headers are derived from
+// the epoch and their index alone, so every call, on every platform, yields
+// the same vectors. Their numbers start at the first block of the epoch, and
+// their nonces and other fields are pseudo random, so the vectors exercise the
+// whole kernel. They are not expected to meet their difficulty.
+//
+// Engines in a fake mode compute no proof-of-work and return an error wrapping
+// ErrNoVectors.
+func (progpow *Progpow) GenerateTestVectors(epoch uint64, count int) ([]TestVector, error) {
+	mode := progpow.config.PowMode
+	if mode != ModeNormal && mode != ModeTest {
+		return nil, fmt.Errorf("%w: engine in %s mode", ErrNoVectors, mode)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("%w: negative count %d", ErrNoVectors, count)
+	}
+	if epoch > (math.MaxUint64-uint64(count))/epochLength {
+		return nil, fmt.Errorf("%w: epoch %d out of range", ErrNoVectors, epoch)
+	}
+	vectors := make([]TestVector, count)
+	for i := range vectors {
+		header := testVectorHeader(epoch, uint64(i))
+		mixHash, powHash := progpow.ComputePowLight(header)
+		header.SetMixHash(mixHash)
+
+		var enc bytes.Buffer
+		if err := rlp.Encode(&enc, header); err != nil {
+			return nil, err
+		}
+		vectors[i] = TestVector{
+			Mode:     mode.String(),
+			Epoch:    epoch,
+			Number:   header.NumberU64(),
+			Header:   enc.Bytes(),
+			SealHash: header.SealHash(),
+			Nonce:    header.Nonce(),
+			MixHash:  mixHash,
+			PowHash:  powHash,
+		}
+	}
+	return vectors, nil
+}
+
+// testVectorHeader derives the index-th test vector header of an epoch.
+func testVectorHeader(epoch, index uint64) *types.Header {
+	var seed [16]byte
+	binary.BigEndian.PutUint64(seed[:8], epoch)
+	binary.BigEndian.PutUint64(seed[8:], index)
+
+	// Every field gets its own hash of the seed, labeled by the field
+	field := func(label string) []byte {
+		return crypto.Keccak256([]byte("progpow test vector "+label), seed[:])
+	}
+	number := new(big.Int).SetUint64(epoch*epochLength + index)
+
+	header := types.NewEmptyHeader()
+	for _, ctx := range common.Contexts {
+		header.SetParentHashIn(common.BytesToHash(field(fmt.Sprintf("parent %d", int(ctx)))), ctx)
+		header.SetNumberIn(number, ctx)
+	}
+	header.SetLocation(common.Location{0, 0})
+	header.SetCoinbase(common.BytesToAddress(field("coinbase")))
+	header.SetRoot(common.BytesToHash(field("root")))
+	header.SetTxHash(common.BytesToHash(field("txhash")))
+	header.SetDifficulty(new(big.Int).SetBytes(field("difficulty")[:8]))
+	header.SetTime(binary.BigEndian.Uint64(field("time")) >> 32)
+	header.SetNonce(types.BlockNonce(field("nonce")[:8]))
+	return header
+}
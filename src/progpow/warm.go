@@ -0,0 +1,85 @@
+package progpow
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WarmEvent reports an epoch whose verification cache WarmCaches finished.
+type WarmEvent struct {
+	Epoch uint64 // Epoch whose cache was warmed
+	Done  int    // Number of epochs finished so far, this one included
+	Total int    // Number of epochs covered by the warmed range
+	Err   error  // Reason the cache could not be warmed, if any
+}
+
+// WarmCaches generates, or loads from disk or the CacheStore, the verification
+// caches of every epoch covered by the blocks from fromBlock to toBlock
+// inclusive, in the background and ahead of their first use, so bulk
+// verification does not stall on a cold cache at every epoch boundary. Up to
+// one cache per available CPU is prepared at a time.
+//
+// The returned channel receives an event per epoch as its cache becomes ready
+// and is closed once all are. It is buffered to hold every event, so callers
+// may ignore it. An empty range, or an engine in a fake mode, which needs no
+// caches, closes it right away.
+//
+// Only CachesInMem caches stay in memory, the most recently warmed ones,
+// unless they are pinned; warming a wider range is only worthwhile if caches
+// are persisted, so evicted ones are loaded rather than generated again, and
+// only up to CachesOnDisk epochs, as older persisted caches are deleted. A
+// MemoryGuard may reject epochs, reported with an error wrapping
+// ErrOverloaded.
+func (progpow *Progpow) WarmCaches(fromBlock, toBlock uint64) <-chan WarmEvent {
+	// If we're running a shared PoW, warm its caches instead
+	if progpow.shared != nil {
+		return progpow.shared.WarmCaches(fromBlock, toBlock)
+	}
+	var epochs []uint64
+	if fromBlock <= toBlock && progpow.config.PowMode != ModeFake && progpow.config.PowMode != ModeFullFake {
+		for epoch := fromBlock / epochLength; epoch <= toBlock/epochLength; epoch++ {
+			epochs = append(epochs, epoch)
+		}
+	}
+	events := make(chan WarmEvent, len(epochs))
+	if len(epochs) == 0 {
+		close(events)
+		return events
+	}
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(epochs) {
+		workers = len(epochs)
+	}
+	jobs := make(chan uint64, len(epochs))
+	for _, epoch := range epochs {
+		jobs <- epoch
+	}
+	close(jobs)
+
+	var (
+		lock sync.Mutex // Keeps Done counting up in channel order
+		done int
+		pend sync.WaitGroup
+	)
+	pend.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer pend.Done()
+
+			for epoch := range jobs {
+				_, err := progpow.admitted(epoch*epochLength, func(block uint64) (*cache, error) {
+					return progpow.cache(block), nil
+				})
+				lock.Lock()
+				done++
+				events <- WarmEvent{Epoch: epoch, Done: done, Total: len(epochs), Err: err}
+				lock.Unlock()
+			}
+		}()
+	}
+	go func() {
+		pend.Wait()
+		close(events)
+	}()
+	return events
+}
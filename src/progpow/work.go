@@ -0,0 +1,103 @@
+package progpow
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// maxPendingWork is the number of work packages handed out by GetWork that
+// SubmitWork accepts solutions for. Older packages are dropped, so miners
+// submitting for work long replaced are rejected.
+const maxPendingWork = 64
+
+// WorkPackage is the work an external miner seals a header with, the tuple
+// eth_getWork style protocols hand out.
+type WorkPackage struct {
+	SealHash    common.Hash // Hash of the header without its seal, see Header.SealHash
+	SeedHash    common.Hash // Seed of the verification cache of the block, see SeedHash
+	Target      common.Hash // Boundary the PoW hash must not exceed, see DifficultyToTarget
+	BlockNumber uint64      // Number of the block in the context of the node
+}
+
+// MarshalJSON encodes the package as the array of hex strings of an
+// eth_getWork response.
+func (w WorkPackage) MarshalJSON() ([]byte, error) {
+	return json.Marshal([4]string{w.SealHash.Hex(), w.SeedHash.Hex(), w.Target.Hex(), hexutil.EncodeUint64(w.BlockNumber)})
+}
+
+// pendingWork holds the headers of the work packages handed out, by seal hash.
+type pendingWork struct {
+	lock    sync.Mutex
+	headers *lrucache.LRU[common.Hash, *types.Header]
+}
+
+func newPendingWork() *pendingWork {
+	return &pendingWork{headers: lrucache.New[common.Hash, *types.Header](maxPendingWork, 0, nil)}
+}
+
+// GetWork returns the work package external miners seal header with, and
+// remembers the header so SubmitWork can check the solutions. The seal fields
+// of header are ignored. Headers lacking fields the seal covers, or with a
+// non-positive difficulty, are rejected.
+func (progpow *Progpow) GetWork(header *types.Header) (WorkPackage, error) {
+	number, err := sealNumber(header)
+	if err != nil {
+		return WorkPackage{}, err
+	}
+	if header.Difficulty() == nil || header.Difficulty().Sign() <= 0 {
+		return WorkPackage{}, errInvalidDifficulty
+	}
+	// Keep a copy, so the caller modifying the header does not change the
+	// work after the fact. The seal is ignored, clear the nonce along
+	pending := header.WithNonce(types.BlockNonce{})
+	work := WorkPackage{
+		SealHash:    pending.SealHash(),
+		SeedHash:    common.BytesToHash(SeedHash(number)),
+		Target:      targetHash(pending.Difficulty()),
+		BlockNumber: number,
+	}
+	progpow.work.lock.Lock()
+	progpow.work.headers.Add(work.SealHash, pending, 1)
+	progpow.work.lock.Unlock()
+
+	return work, nil
+}
+
+// targetHash returns the target of a positive difficulty as a hash. A
+// difficulty of one targets every hash, capping the target at 2^256 - 1.
+func targetHash(difficulty *big.Int) (target common.Hash) {
+	t := DifficultyToTarget(difficulty)
+	if t.Cmp(big2e256) == 0 {
+		t.Sub(t, common.Big1)
+	}
+	t.FillBytes(target[:])
+	return target
+}
+
+// SubmitWork reports whether nonce and mixDigest seal the header of a work
+// package handed out by GetWork, identified by its seal hash. Every package is
+// solved once: later submissions for it, and submissions for unknown or
+// dropped packages, are rejected.
+func (progpow *Progpow) SubmitWork(nonce types.BlockNonce, sealHash, mixDigest common.Hash) bool {
+	progpow.work.lock.Lock()
+	header, ok := progpow.work.headers.Peek(sealHash)
+	progpow.work.lock.Unlock()
+	if !ok {
+		return false
+	}
+	if _, err := progpow.verifySeal(sealedCopy(header, nonce, mixDigest)); err != nil {
+		return false
+	}
+	// Concurrent submissions for the same package may both verify, only the
+	// first to remove it is accepted
+	progpow.work.lock.Lock()
+	defer progpow.work.lock.Unlock()
+
+	return progpow.work.headers.Remove(sealHash)
+}
@@ -0,0 +1,61 @@
+package progpow
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+var ErrInvalidWorkShare = errors.New("invalid workshare")
+
+// WorkShareThresholdBits is the number of bits the difficulty of a workshare
+// may fall short of the difficulty of its header, as in go-quai's
+// WorkSharesThresholdDiff. VerifyUncles checks uncles against it.
+const WorkShareThresholdBits = 3
+
+// WorkShareCheck returns an uncle check for types.DecodeLimits.CheckUncle that
+// accepts the uncles of a block as workshares: headers numbered below the
+// block in the zone, whose seal meets their own difficulty relaxed by a factor
+// of 2^thresholdBits. A negative thresholdBits is taken as zero, accepting
+// only uncles that meet their full difficulty. Rejected uncles yield an error
+// wrapping ErrInvalidWorkShare.
+//
+// Seals are verified through the caches of the engine, under its MemoryGuard
+// if it has one, so decoding a block may stall on cache generation like
+// VerifySeal does.
+func (progpow *Progpow) WorkShareCheck(thresholdBits int) func(header, uncle *types.Header) error {
+	if thresholdBits < 0 {
+		thresholdBits = 0
+	}
+	return func(header, uncle *types.Header) error {
+		if uncle.NumberU64In(common.ZoneCtx) >= header.NumberU64In(common.ZoneCtx) {
+			return fmt.Errorf("%w: number %d not below block %d", ErrInvalidWorkShare, uncle.NumberU64In(common.ZoneCtx), header.NumberU64In(common.ZoneCtx))
+		}
+		return progpow.verifyWorkShare(uncle, thresholdBits)
+	}
+}
+
+// verifyWorkShare checks that the seal of a workshare meets its difficulty
+// relaxed by a factor of 2^thresholdBits.
+func (progpow *Progpow) verifyWorkShare(share *types.Header, thresholdBits int) error {
+	powHash, err := progpow.verifySealWith(share, progpow.admittedCache)
+	if err == nil {
+		return nil
+	}
+	// Shares failing the full difficulty may still meet the relaxed one, as
+	// long as their mixHash checked out. Fake modes compute no powHash.
+	if !errors.Is(err, errInvalidPoW) || powHash == (common.Hash{}) {
+		return fmt.Errorf("%w: %v", ErrInvalidWorkShare, err)
+	}
+	difficulty := new(big.Int).Rsh(share.Difficulty(), uint(thresholdBits))
+	if difficulty.Sign() == 0 {
+		difficulty.SetUint64(1)
+	}
+	if err := CheckTarget(powHash, difficulty); err != nil {
+		return fmt.Errorf("%w: below threshold of %d bits: %v", ErrInvalidWorkShare, thresholdBits, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package progpow
+
+import "time"
+
+// DefaultYieldInterval is how long computations run between yields when
+// yielding is enabled without an explicit Config.YieldInterval. It keeps every
+// stretch below the 50ms browsers consider a long task.
+const DefaultYieldInterval = 40 * time.Millisecond
+
+// yieldSettings selects how long computations yield to the host: by calling
+// fn every interval. The zero value follows the build profile. Computations
+// which can be abandoned also stop at their next yield point once abort is
+// closed.
+type yieldSettings struct {
+	fn       func()
+	interval time.Duration
+	abort    <-chan struct{}
+}
+
+// yieldSettings returns the yield settings of the configuration.
+func (c *Config) yieldSettings() yieldSettings {
+	return yieldSettings{fn: c.Yield, interval: c.YieldInterval}
+}
+
+// newYielder creates a yielder checking the clock every given number of steps,
+// keeping the clock reads off the per-step path.
+func (s yieldSettings) newYielder(every int) *yielder {
+	y := &yielder{fn: s.fn, interval: s.interval, abort: s.abort, every: every}
+	if y.fn == nil && yieldByDefault {
+		y.fn = sleepYield
+	}
+	if y.fn == nil {
+		return y
+	}
+	if y.interval == 0 {
+		y.interval = DefaultYieldInterval
+	}
+	y.last = time.Now()
+	return y
+}
+
+// yielder interrupts long computations at regular intervals so the host can
+// process events in between.
+type yielder struct {
+	fn       func() // Yield hook, nil if yielding is disabled
+	interval time.Duration
+	abort    <-chan struct{} // Closed to stop the computation, nil if it runs to completion
+	every    int             // Number of steps between clock reads
+	steps    int
+	last     time.Time
+}
+
+// step records a step of the computation and yields if it ran for the yield
+// interval since it last yielded. It returns false once the computation is
+// aborted, and is a no-op if yielding is disabled and it cannot be aborted.
+func (y *yielder) step() bool {
+	if y.fn == nil && y.abort == nil {
+		return true
+	}
+	if y.steps++; y.steps < y.every {
+		return true
+	}
+	y.steps = 0
+	select {
+	case <-y.abort:
+		return false
+	default:
+	}
+	if y.fn == nil || time.Since(y.last) < y.interval {
+		return true
+	}
+	y.fn()
+	y.last = time.Now()
+	return true
+}
+
+// sleepYield is the yield hook of build profiles yielding by default. On
+// js/wasm, sleeping parks the goroutine and hands control back to the
+// JavaScript event loop until the timer fires, unless other goroutines are
+// runnable.
+func sleepYield() {
+	time.Sleep(time.Millisecond)
+}
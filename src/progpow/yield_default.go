@@ -0,0 +1,8 @@
+//go:build !(js && wasm && extension)
+// +build !js !wasm !extension
+
+package progpow
+
+// yieldByDefault is false outside the extension profile: without a Config.Yield
+// hook, computations run to completion without yielding.
+const yieldByDefault = false
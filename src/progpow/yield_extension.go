@@ -0,0 +1,10 @@
+//go:build js && wasm && extension
+// +build js,wasm,extension
+
+package progpow
+
+// yieldByDefault enables yielding without a Config.Yield hook. Browser
+// extension service workers are stopped when an event handler blocks for too
+// long, so the extension profile keeps every task below the long task
+// threshold even if the embedder installs no hook.
+const yieldByDefault = true
@@ -0,0 +1,138 @@
+package progpow
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/math"
+	"github.com/dominant-strategies/progpow-verification-wasm/types"
+)
+
+// ZoneOverride replaces consensus parameters of the engine within a single
+// zone, for permissioned testnets running zones of unequal hash power. Unset
+// fields keep the engine wide values.
+type ZoneOverride struct {
+	// MinDifficulty is the minimum the difficulty adjustment may yield for
+	// blocks of the zone, in place of Config.MinDifficulty.
+	MinDifficulty *big.Int
+	// TimeFactor is the ratio of the block intervals of adjacent contexts
+	// the order thresholds of the zone's blocks scale with, in place of
+	// TimeFactor.
+	TimeFactor *big.Int
+}
+
+// zoneName returns the name of a zone location, such as cyprus1, or false if
+// loc is not a valid zone.
+func zoneName(loc common.Location) (string, bool) {
+	if len(loc) != common.ZONE_CTX || !loc.Valid() {
+		return "", false
+	}
+	return loc.Name(), true
+}
+
+// sanitizeZoneOverrides rejects overrides of unknown zones and of parameters
+// the engine could not honour.
+func sanitizeZoneOverrides(overrides map[string]ZoneOverride) error {
+	var (
+		zones  = make(map[string]bool)
+		params = common.Hierarchy()
+	)
+	for r := 0; r < params.Regions; r++ {
+		for z := 0; z < params.Zones; z++ {
+			name, _ := zoneName(common.Location{byte(r), byte(z)})
+			zones[name] = true
+		}
+	}
+	for name, override := range overrides {
+		if !zones[name] {
+			return fmt.Errorf("%w: ZoneOverrides for unknown zone %q", ErrInvalidConfig, name)
+		}
+		if override.MinDifficulty != nil && override.MinDifficulty.Sign() <= 0 {
+			return fmt.Errorf("%w: non-positive MinDifficulty %v for zone %s", ErrInvalidConfig, override.MinDifficulty, name)
+		}
+		if override.TimeFactor != nil && override.TimeFactor.Sign() <= 0 {
+			return fmt.Errorf("%w: non-positive TimeFactor %v for zone %s", ErrInvalidConfig, override.TimeFactor, name)
+		}
+	}
+	return nil
+}
+
+// zoneOverride returns the override configured for the zone at loc, zero if
+// there is none.
+func (progpow *Progpow) zoneOverride(loc common.Location) ZoneOverride {
+	name, ok := zoneName(loc)
+	if !ok {
+		return ZoneOverride{}
+	}
+	return progpow.config.ZoneOverrides[name]
+}
+
+// minDifficultyAt returns the difficulty floor of blocks at loc.
+func (progpow *Progpow) minDifficultyAt(loc common.Location) *big.Int {
+	if min := progpow.zoneOverride(loc).MinDifficulty; min != nil {
+		return min
+	}
+	return progpow.config.MinDifficulty
+}
+
+// timeFactorAt returns the ratio of the block intervals of adjacent contexts
+// the order thresholds of blocks at loc scale with.
+func (progpow *Progpow) timeFactorAt(loc common.Location) *big.Int {
+	if factor := progpow.zoneOverride(loc).TimeFactor; factor != nil {
+		return factor
+	}
+	return TimeFactor
+}
+
+// Targets are the thresholds the proof-of-work of a header is held against to
+// determine its order. Entropies are fixed point logarithms like those of
+// IntrinsicLogS.
+type Targets struct {
+	Zone *big.Int // Largest PoW hash meeting the zone difficulty
+
+	RegionBlockS *big.Int // Intrinsic entropy a region block must exceed
+	RegionS      *big.Int // Entropy a region block must exceed along with the zone's since the last region block
+	PrimeBlockS  *big.Int // Intrinsic entropy a prime block must exceed
+	PrimeS       *big.Int // Entropy a prime block must exceed along with the region's and zone's since the last prime block
+}
+
+// TargetsFor returns the thresholds the proof-of-work of header is held
+// against, at its difficulty and under the overrides of its zone. They are
+// the thresholds CalcOrder checks the header with.
+func (progpow *Progpow) TargetsFor(header *types.Header) (Targets, error) {
+	if header.Difficulty() == nil || header.Difficulty().Sign() <= 0 {
+		return Targets{}, errInvalidDifficulty
+	}
+	return targetsFor(header.Difficulty(), progpow.timeFactorAt(header.Location())), nil
+}
+
+// targetsFor returns the thresholds of a block of the given difficulty, whose
+// order thresholds scale with timeFactor.
+func targetsFor(difficulty, timeFactor *big.Int) Targets {
+	// The thresholds scale with the entropy of a block just meeting the zone
+	// difficulty
+	zoneThresholdS := zoneThresholdLogS(difficulty)
+	factor := new(big.Int).Mul(timeFactor, big.NewInt(common.HierarchyDepth))
+
+	// Prime case
+	primeEntropyThreshold := new(big.Int).Mul(factor, factor)
+	primeEntropyThreshold.Mul(primeEntropyThreshold, zoneThresholdS)
+	primeBlockThreshold := new(big.Int).Quo(primeEntropyThreshold, common.Big2)
+	primeEntropyThreshold.Sub(primeEntropyThreshold, primeBlockThreshold)
+	primeAdder, _ := math.BinaryLog(primeBlockThreshold, 8)
+
+	// Region case
+	regionEntropyThreshold := new(big.Int).Mul(factor, zoneThresholdS)
+	regionBlockThreshold := new(big.Int).Quo(regionEntropyThreshold, common.Big2)
+	regionEntropyThreshold.Sub(regionEntropyThreshold, regionBlockThreshold)
+	regionAdder, _ := math.BinaryLog(regionBlockThreshold, 8)
+
+	return Targets{
+		Zone:         DifficultyToTarget(difficulty),
+		RegionBlockS: new(big.Int).Add(zoneThresholdS, big.NewInt(int64(regionAdder))),
+		RegionS:      regionEntropyThreshold,
+		PrimeBlockS:  new(big.Int).Add(zoneThresholdS, big.NewInt(int64(primeAdder))),
+		PrimeS:       primeEntropyThreshold,
+	}
+}
@@ -23,18 +23,17 @@ import (
 	"io"
 	"math/big"
 	"reflect"
-	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
 	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
-	"lukechampine.com/blake3"
 )
 
 var (
-	hasher   = blake3.New(32, nil)
-	hasherMu sync.RWMutex
+	EmptyRootHash  = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	EmptyUncleHash = rlpHash([]*Header(nil))
 )
 
 // A BlockNonce is a 64-bit hash which proves (combined with the
@@ -42,14 +41,41 @@ var (
 // out on a block.
 type BlockNonce [8]byte
 
+// EncodeNonce converts the given integer to a block nonce.
+func EncodeNonce(i uint64) BlockNonce {
+	var n BlockNonce
+	binary.BigEndian.PutUint64(n[:], i)
+	return n
+}
+
+// Uint64 returns the integer value of a block nonce.
+func (n BlockNonce) Uint64() uint64 {
+	return binary.BigEndian.Uint64(n[:])
+}
+
 // Bytes() returns the raw bytes of the block nonce
 func (n BlockNonce) Bytes() []byte {
 	return n[:]
 }
 
-//go:generate gencodec -type Header -field-override headerMarshaling -out gen_header_json.go
+// MarshalText encodes n as a hex string with 0x prefix.
+func (n BlockNonce) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(n[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *BlockNonce) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("BlockNonce", input, n[:])
+}
 
 // Header represents a block header in the Quai blockchain.
+//
+// Headers are safe for concurrent reads: the accessors, hashing, encoding and
+// seal verification may run on the same header from any number of goroutines,
+// the proof-of-work values verification caches being stored atomically.
+// Modifying a header, through its Set methods or by decoding into it, must not
+// overlap with any other use of it. Headers shared between goroutines are to
+// be modified on a private Copy, which can be shared in turn once complete.
 type Header struct {
 	parentHash    []common.Hash   `json:"parentHash"           gencodec:"required"`
 	uncleHash     common.Hash     `json:"sha3Uncles"           gencodec:"required"`
@@ -111,6 +137,16 @@ func (h *Header) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eh); err != nil {
 		return err
 	}
+	h.setExt(&eh)
+
+	if decodeLimits.Load().CheckHeaders {
+		return h.SanityCheck()
+	}
+	return nil
+}
+
+// setExt sets the fields of h to those decoded into eh.
+func (h *Header) setExt(eh *extheader) {
 	h.parentHash = eh.ParentHash
 	h.uncleHash = eh.UncleHash
 	h.coinbase = eh.Coinbase
@@ -132,8 +168,6 @@ func (h *Header) DecodeRLP(s *rlp.Stream) error {
 	h.extra = eh.Extra
 	h.mixHash = eh.MixHash
 	h.nonce = eh.Nonce
-
-	return nil
 }
 
 // EncodeRLP serializes h into the Quai RLP block format.
@@ -164,12 +198,13 @@ func (h *Header) EncodeRLP(w io.Writer) error {
 }
 
 // Localized accessors
+
+// ParentHash returns the parent hash of the header in the context passed, or
+// else in the context of the node.
+//
+// Deprecated: use ParentHashIn.
 func (h *Header) ParentHash(args ...int) common.Hash {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.parentHash[nodeCtx]
+	return h.ParentHashIn(argsCtx(args))
 }
 func (h *Header) UncleHash() common.Hash {
 	return h.uncleHash
@@ -189,26 +224,29 @@ func (h *Header) EtxHash() common.Hash {
 func (h *Header) EtxRollupHash() common.Hash {
 	return h.etxRollupHash
 }
+
+// ParentEntropy returns the parent entropy of the header in the context passed,
+// or else in the context of the node.
+//
+// Deprecated: use ParentEntropyIn.
 func (h *Header) ParentEntropy(args ...int) *big.Int {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.parentEntropy[nodeCtx]
+	return h.ParentEntropyIn(argsCtx(args))
 }
+
+// ParentDeltaS returns the parent delta entropy of the header in the context
+// passed, or else in the context of the node.
+//
+// Deprecated: use ParentDeltaSIn.
 func (h *Header) ParentDeltaS(args ...int) *big.Int {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.parentDeltaS[nodeCtx]
+	return h.ParentDeltaSIn(argsCtx(args))
 }
+
+// ManifestHash returns the manifest hash of the header in the context passed,
+// or else in the context of the node.
+//
+// Deprecated: use ManifestHashIn.
 func (h *Header) ManifestHash(args ...int) common.Hash {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.manifestHash[nodeCtx]
+	return h.ManifestHashIn(argsCtx(args))
 }
 func (h *Header) ReceiptHash() common.Hash {
 	return h.receiptHash
@@ -216,19 +254,21 @@ func (h *Header) ReceiptHash() common.Hash {
 func (h *Header) Difficulty() *big.Int {
 	return h.difficulty
 }
+
+// Number returns the block number of the header in the context passed, or else
+// in the context of the node.
+//
+// Deprecated: use NumberIn.
 func (h *Header) Number(args ...int) *big.Int {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.number[nodeCtx]
+	return h.NumberIn(argsCtx(args))
 }
+
+// NumberU64 returns the block number of the header in the context passed, or
+// else in the context of the node.
+//
+// Deprecated: use NumberU64In.
 func (h *Header) NumberU64(args ...int) uint64 {
-	nodeCtx := common.NodeLocation.Context()
-	if len(args) > 0 {
-		nodeCtx = args[0]
-	}
-	return h.number[nodeCtx].Uint64()
+	return h.NumberU64In(argsCtx(args))
 }
 func (h *Header) GasLimit() uint64 {
 	return h.gasLimit
@@ -246,6 +286,253 @@ func (h *Header) MixHash() common.Hash      { return h.mixHash }
 func (h *Header) Nonce() BlockNonce         { return h.nonce }
 func (h *Header) NonceU64() uint64          { return binary.BigEndian.Uint64(h.nonce[:]) }
 
+// NewEmptyHeader returns a header with every per context field allocated for
+// the full hierarchy, zero numbers and difficulty, and empty roots, ready to be
+// filled in with the Set methods.
+func NewEmptyHeader() *Header {
+	h := &Header{}
+	h.parentHash = make([]common.Hash, common.HierarchyDepth)
+	h.manifestHash = make([]common.Hash, common.HierarchyDepth)
+	h.parentEntropy = make([]*big.Int, common.HierarchyDepth)
+	h.parentDeltaS = make([]*big.Int, common.HierarchyDepth)
+	h.number = make([]*big.Int, common.HierarchyDepth)
+	h.difficulty = big.NewInt(0)
+	h.root = EmptyRootHash
+	h.mixHash = EmptyRootHash
+	h.txHash = EmptyRootHash
+	h.etxHash = EmptyRootHash
+	h.etxRollupHash = EmptyRootHash
+	h.uncleHash = EmptyUncleHash
+	h.baseFee = big.NewInt(0)
+	h.extra = make([]byte, 0)
+	for i := 0; i < common.HierarchyDepth; i++ {
+		h.manifestHash[i] = EmptyRootHash
+		h.parentEntropy[i] = big.NewInt(0)
+		h.parentDeltaS[i] = big.NewInt(0)
+		h.number[i] = big.NewInt(0)
+	}
+	return h
+}
+
+// ParentHashes returns a copy of the parent hashes of all contexts, indexed by
+// context.
+func (h *Header) ParentHashes() []common.Hash {
+	return append([]common.Hash(nil), h.parentHash...)
+}
+
+// ManifestHashes returns a copy of the manifest hashes of all contexts, indexed
+// by context.
+func (h *Header) ManifestHashes() []common.Hash {
+	return append([]common.Hash(nil), h.manifestHash...)
+}
+
+// Numbers returns a copy of the block numbers of all contexts, indexed by
+// context.
+func (h *Header) Numbers() []*big.Int {
+	return copyBigs(h.number)
+}
+
+// ParentEntropies returns a copy of the parent entropies of all contexts,
+// indexed by context.
+func (h *Header) ParentEntropies() []*big.Int {
+	return copyBigs(h.parentEntropy)
+}
+
+// copyBigs returns a deep copy of a list of integers, keeping nil entries.
+func copyBigs(bigs []*big.Int) []*big.Int {
+	if bigs == nil {
+		return nil
+	}
+	cpy := make([]*big.Int, len(bigs))
+	for i, b := range bigs {
+		if b != nil {
+			cpy[i] = new(big.Int).Set(b)
+		}
+	}
+	return cpy
+}
+
+// Copy returns a deep copy of the header, sharing no memory with it, which can
+// be modified while h is in use by other goroutines. The copy starts without
+// cached proof-of-work values, like WithNonce.
+func (h *Header) Copy() *Header {
+	return copyHeader(h)
+}
+
+// copyHeader returns a deep copy of a header, without its cached hashes and
+// proof-of-work values, so the copy can be modified without affecting h.
+func copyHeader(h *Header) *Header {
+	cpy := &Header{
+		parentHash:    append([]common.Hash(nil), h.parentHash...),
+		uncleHash:     h.uncleHash,
+		coinbase:      h.coinbase,
+		root:          h.root,
+		txHash:        h.txHash,
+		etxHash:       h.etxHash,
+		etxRollupHash: h.etxRollupHash,
+		manifestHash:  append([]common.Hash(nil), h.manifestHash...),
+		receiptHash:   h.receiptHash,
+		parentEntropy: copyBigs(h.parentEntropy),
+		parentDeltaS:  copyBigs(h.parentDeltaS),
+		number:        copyBigs(h.number),
+		gasLimit:      h.gasLimit,
+		gasUsed:       h.gasUsed,
+		location:      append(common.Location(nil), h.location...),
+		time:          h.time,
+		extra:         common.CopyBytes(h.extra),
+		mixHash:       h.mixHash,
+		nonce:         h.nonce,
+	}
+	if h.difficulty != nil {
+		cpy.difficulty = new(big.Int).Set(h.difficulty)
+	}
+	if h.baseFee != nil {
+		cpy.baseFee = new(big.Int).Set(h.baseFee)
+	}
+	return cpy
+}
+
+// WithNonce returns a copy of the header carrying the given nonce. The copy
+// starts without cached hashes and proof-of-work values, so none computed for
+// h or an earlier candidate can be mistaken for its own. The mixHash is kept
+// and has to be replaced by the one computed for the new nonce.
+func (h *Header) WithNonce(nonce BlockNonce) *Header {
+	cpy := copyHeader(h)
+	cpy.nonce = nonce
+	return cpy
+}
+
+// WithTime returns a copy of the header carrying the given timestamp, without
+// cached hashes and proof-of-work values like WithNonce. The time is part of
+// the sealed content, so the copy needs a new seal.
+func (h *Header) WithTime(timestamp uint64) *Header {
+	cpy := copyHeader(h)
+	cpy.time = timestamp
+	return cpy
+}
+
+// SetNonce sets the nonce of the header. The cached proof-of-work values depend
+// on the nonce and are cleared.
+func (h *Header) SetNonce(val BlockNonce) {
+	h.hash = atomic.Value{} // clear hash cache, but NOT sealHash
+	h.PowHash = atomic.Value{}
+	h.PowDigest = atomic.Value{}
+	h.nonce = val
+}
+
+// SetMixHash sets the mix digest of the header.
+func (h *Header) SetMixHash(val common.Hash) {
+	h.hash = atomic.Value{} // clear hash cache, but NOT sealHash
+	h.mixHash = val
+}
+
+// clearSealCaches drops the cached hashes and proof-of-work values, which all
+// depend on the sealed fields of the header.
+func (h *Header) clearSealCaches() {
+	h.hash = atomic.Value{}
+	h.sealHash = atomic.Value{}
+	h.PowHash = atomic.Value{}
+	h.PowDigest = atomic.Value{}
+}
+
+// Localized setters
+
+// SetParentHash sets the parent hash of the header in the context passed, or
+// else in the context of the node.
+//
+// Deprecated: use SetParentHashIn.
+func (h *Header) SetParentHash(val common.Hash, args ...int) {
+	h.SetParentHashIn(val, argsCtx(args))
+}
+func (h *Header) SetUncleHash(val common.Hash) {
+	h.clearSealCaches()
+	h.uncleHash = val
+}
+func (h *Header) SetCoinbase(val common.Address) {
+	h.clearSealCaches()
+	h.coinbase = val
+}
+func (h *Header) SetRoot(val common.Hash) {
+	h.clearSealCaches()
+	h.root = val
+}
+func (h *Header) SetTxHash(val common.Hash) {
+	h.clearSealCaches()
+	h.txHash = val
+}
+func (h *Header) SetEtxHash(val common.Hash) {
+	h.clearSealCaches()
+	h.etxHash = val
+}
+func (h *Header) SetEtxRollupHash(val common.Hash) {
+	h.clearSealCaches()
+	h.etxRollupHash = val
+}
+
+// SetParentEntropy sets the parent entropy of a context. The entropy is not
+// sealed, so the cached hashes remain valid.
+//
+// Deprecated: use SetParentEntropyIn.
+func (h *Header) SetParentEntropy(val *big.Int, args ...int) {
+	h.SetParentEntropyIn(val, argsCtx(args))
+}
+
+// SetParentDeltaS sets the parent deltaS of a context. Like the entropy it is
+// not sealed.
+//
+// Deprecated: use SetParentDeltaSIn.
+func (h *Header) SetParentDeltaS(val *big.Int, args ...int) {
+	h.SetParentDeltaSIn(val, argsCtx(args))
+}
+
+// SetManifestHash sets the manifest hash of the header in the context passed,
+// or else in the context of the node.
+//
+// Deprecated: use SetManifestHashIn.
+func (h *Header) SetManifestHash(val common.Hash, args ...int) {
+	h.SetManifestHashIn(val, argsCtx(args))
+}
+func (h *Header) SetReceiptHash(val common.Hash) {
+	h.clearSealCaches()
+	h.receiptHash = val
+}
+func (h *Header) SetDifficulty(val *big.Int) {
+	h.clearSealCaches()
+	h.difficulty = new(big.Int).Set(val)
+}
+
+// SetNumber sets the block number of the header in the context passed, or else
+// in the context of the node.
+//
+// Deprecated: use SetNumberIn.
+func (h *Header) SetNumber(val *big.Int, args ...int) {
+	h.SetNumberIn(val, argsCtx(args))
+}
+func (h *Header) SetGasLimit(val uint64) {
+	h.clearSealCaches()
+	h.gasLimit = val
+}
+func (h *Header) SetGasUsed(val uint64) {
+	h.clearSealCaches()
+	h.gasUsed = val
+}
+func (h *Header) SetBaseFee(val *big.Int) {
+	h.clearSealCaches()
+	h.baseFee = new(big.Int).Set(val)
+}
+func (h *Header) SetLocation(val common.Location) {
+	h.clearSealCaches()
+	h.location = append(common.Location(nil), val...)
+}
+func (h *Header) SetTime(val uint64) {
+	h.clearSealCaches()
+	h.time = val
+}
+func (h *Header) SetExtra(val []byte) {
+	h.clearSealCaches()
+	h.extra = common.CopyBytes(val)
+}
+
 // headerData comprises all data fields of the header, excluding the nonce, so
 // that the nonce may be independently adjusted in the work algorithm.
 type sealData struct {
@@ -271,9 +558,8 @@ type sealData struct {
 
 // SealHash returns the hash of a block prior to it being sealed.
 func (h *Header) SealHash() (hash common.Hash) {
-	hasherMu.Lock()
-	defer hasherMu.Unlock()
-	hasher.Reset()
+	hasher, pool := getHeaderHasher()
+	defer pool.Put(hasher)
 	hdata := sealData{
 		ParentHash:    make([]common.Hash, common.HierarchyDepth),
 		UncleHash:     h.UncleHash(),
@@ -293,10 +579,10 @@ func (h *Header) SealHash() (hash common.Hash) {
 		Time:          h.Time(),
 		Extra:         h.Extra(),
 	}
-	for i := 0; i < common.HierarchyDepth; i++ {
-		hdata.ParentHash[i] = h.ParentHash(i)
-		hdata.ManifestHash[i] = h.ManifestHash(i)
-		hdata.Number[i] = h.Number(i)
+	for i, ctx := range common.Contexts {
+		hdata.ParentHash[i] = h.ParentHashIn(ctx)
+		hdata.ManifestHash[i] = h.ManifestHashIn(ctx)
+		hdata.Number[i] = h.NumberIn(ctx)
 	}
 	rlp.Encode(hasher, hdata)
 	hash.SetBytes(hasher.Sum(hash[:0]))
@@ -307,14 +593,13 @@ func (h *Header) SealHash() (hash common.Hash) {
 // SealHash suffixed with a nonce.
 func (h *Header) Hash() (hash common.Hash) {
 	sealHash := h.SealHash().Bytes()
-	hasherMu.Lock()
-	defer hasherMu.Unlock()
-	hasher.Reset()
+	hasher, pool := getHeaderHasher()
+	defer pool.Put(hasher)
 	var hData [40]byte
 	copy(hData[:], h.Nonce().Bytes())
 	copy(hData[len(h.nonce):], sealHash)
-	sum := blake3.Sum256(hData[:])
-	hash.SetBytes(sum[:])
+	hasher.Write(hData[:])
+	hash.SetBytes(hasher.Sum(hash[:0]))
 	return hash
 }
 
@@ -334,7 +619,9 @@ var headerSize = common.StorageSize(reflect.TypeOf(Header{}).Size())
 // Size returns the approximate memory used by all internal contents. It is used
 // to approximate and limit the memory consumption of various caches.
 func (h *Header) Size() common.StorageSize {
-	return headerSize + common.StorageSize(len(h.extra)+(h.difficulty.BitLen()+totalBitLen(h.number))/8)
+	hashes := (len(h.parentHash) + len(h.manifestHash)) * common.HashLength
+	bits := totalBitLen([]*big.Int{h.difficulty, h.baseFee}) + totalBitLen(h.parentEntropy) + totalBitLen(h.parentDeltaS) + totalBitLen(h.number)
+	return headerSize + common.StorageSize(len(h.extra)+len(h.location)+hashes+bits/8)
 }
 
 // Block represents an entire block in the Quai blockchain.
@@ -355,6 +642,81 @@ type Block struct {
 	ReceivedFrom interface{}
 }
 
+// NewBlock assembles a block from a header and a body. Unlike go-quai, which
+// derives the roots of the header from the body, the header is taken as given,
+// so the body can then be validated against it, for instance by comparing
+// CalcUncleHash of the uncles with the uncle hash of the header.
+//
+// The header, the uncles and the lists are copied, so later changes to them
+// do not affect the block; the transactions themselves are shared.
+func NewBlock(header *Header, txs []*Transaction, uncles []*Header, etxs []*Transaction, subManifest BlockManifest) *Block {
+	return NewBlockWithHeader(header).WithBody(txs, uncles, etxs, subManifest)
+}
+
+// NewBlockWithHeader creates a block with the given header and an empty body.
+// The header is copied, so later changes to it do not affect the block.
+func NewBlockWithHeader(header *Header) *Block {
+	return &Block{header: copyHeader(header)}
+}
+
+// WithSeal returns a new block with the data from b but the header replaced
+// with the sealed one.
+func (b *Block) WithSeal(header *Header) *Block {
+	return &Block{
+		header:          copyHeader(header),
+		uncles:          b.uncles,
+		transactions:    b.transactions,
+		extTransactions: b.extTransactions,
+		subManifest:     b.subManifest,
+	}
+}
+
+// WithBody returns a new block with the header of b and the given body. The
+// uncles and the lists are copied, the transactions themselves are shared.
+func (b *Block) WithBody(txs []*Transaction, uncles []*Header, etxs []*Transaction, subManifest BlockManifest) *Block {
+	block := &Block{
+		header:          b.header,
+		transactions:    make(Transactions, len(txs)),
+		uncles:          make([]*Header, len(uncles)),
+		extTransactions: make(Transactions, len(etxs)),
+		subManifest:     make(BlockManifest, len(subManifest)),
+	}
+	copy(block.transactions, txs)
+	for i := range uncles {
+		block.uncles[i] = copyHeader(uncles[i])
+	}
+	copy(block.extTransactions, etxs)
+	copy(block.subManifest, subManifest)
+	return block
+}
+
+// Header returns a copy of the header of the block.
+func (b *Block) Header() *Header { return copyHeader(b.header) }
+
+// Uncles returns the uncles of the block. They must not be modified.
+func (b *Block) Uncles() []*Header { return b.uncles }
+
+// Transactions returns the transactions of the block.
+func (b *Block) Transactions() Transactions { return b.transactions }
+
+// ExtTransactions returns the external transactions of the block.
+func (b *Block) ExtTransactions() Transactions { return b.extTransactions }
+
+// SubManifest returns the manifest of subordinate block hashes of the block.
+func (b *Block) SubManifest() BlockManifest { return b.subManifest }
+
+// Hash returns the hash of the header of the block.
+func (b *Block) Hash() common.Hash { return b.header.Hash() }
+
+// CalcUncleHash returns the uncle hash committing to a list of uncles, as
+// carried in the header of the block including them.
+func CalcUncleHash(uncles []*Header) common.Hash {
+	if len(uncles) == 0 {
+		return EmptyUncleHash
+	}
+	return rlpHash(uncles)
+}
+
 // "external" block encoding. used for eth protocol, etc.
 type extblock struct {
 	Header      *Header
@@ -364,15 +726,49 @@ type extblock struct {
 	SubManifest BlockManifest
 }
 
-// DecodeRLP decodes the Quai RLP encoding into b.
+// DecodeRLP decodes the Quai RLP encoding into b. The encoding is checked
+// against the current DecodeLimits before any of it is decoded. If a part
+// fails to decode, the error is a FieldError naming it, wrapping the
+// FieldError of the header or transaction at fault where there is one.
 func (b *Block) DecodeRLP(s *rlp.Stream) error {
+	limits := decodeLimits.Load()
+
+	_, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	if err := limits.checkBlockSize(size); err != nil {
+		return err
+	}
+	raw, err := s.Raw()
+	if err != nil {
+		return err
+	}
+	parts, err := SplitBlockRLP(raw)
+	if err != nil {
+		return err
+	}
+	if err := limits.checkTxList("txs", parts.Txs); err != nil {
+		return err
+	}
+	if err := limits.checkTxList("etxs", parts.Etxs); err != nil {
+		return err
+	}
+	if err := limits.checkUncleList(parts.Uncles); err != nil {
+		return err
+	}
 	var eb extblock
-	_, size, _ := s.Kind()
-	if err := s.Decode(&eb); err != nil {
+	if err := rlp.DecodeBytes(raw, &eb); err != nil {
+		if fieldErr := locateBlockError(parts); fieldErr != nil {
+			return fieldErr
+		}
+		return err
+	}
+	if err := limits.checkUncles(eb.Header, eb.Uncles); err != nil {
 		return err
 	}
 	b.header, b.uncles, b.transactions, b.extTransactions, b.subManifest = eb.Header, eb.Uncles, eb.Txs, eb.Etxs, eb.SubManifest
-	b.size.Store(common.StorageSize(rlp.ListSize(size)))
+	b.size.Store(common.StorageSize(len(raw)))
 	return nil
 }
 
@@ -388,28 +784,61 @@ func (b *Block) EncodeRLP(w io.Writer) error {
 }
 
 // Wrapped header accessors
-func (b *Block) ParentHash(args ...int) common.Hash   { return b.header.ParentHash(args...) }
-func (b *Block) UncleHash() common.Hash               { return b.header.UncleHash() }
-func (b *Block) Coinbase() common.Address             { return b.header.Coinbase() }
-func (b *Block) Root() common.Hash                    { return b.header.Root() }
-func (b *Block) TxHash() common.Hash                  { return b.header.TxHash() }
-func (b *Block) EtxHash() common.Hash                 { return b.header.EtxHash() }
-func (b *Block) EtxRollupHash() common.Hash           { return b.header.EtxRollupHash() }
+func (b *Block) UncleHash() common.Hash     { return b.header.UncleHash() }
+func (b *Block) Coinbase() common.Address   { return b.header.Coinbase() }
+func (b *Block) Root() common.Hash          { return b.header.Root() }
+func (b *Block) TxHash() common.Hash        { return b.header.TxHash() }
+func (b *Block) EtxHash() common.Hash       { return b.header.EtxHash() }
+func (b *Block) EtxRollupHash() common.Hash { return b.header.EtxRollupHash() }
+func (b *Block) ReceiptHash() common.Hash   { return b.header.ReceiptHash() }
+func (b *Block) GasLimit() uint64           { return b.header.GasLimit() }
+func (b *Block) GasUsed() uint64            { return b.header.GasUsed() }
+func (b *Block) BaseFee() *big.Int          { return b.header.BaseFee() }
+func (b *Block) Location() common.Location  { return b.header.Location() }
+func (b *Block) Time() uint64               { return b.header.Time() }
+func (b *Block) Extra() []byte              { return b.header.Extra() }
+func (b *Block) Nonce() BlockNonce          { return b.header.Nonce() }
+func (b *Block) NonceU64() uint64           { return b.header.NonceU64() }
+
+// Difficulty returns the difficulty of the header of the block. Headers carry
+// a single difficulty, so a context passed is ignored.
+func (b *Block) Difficulty(args ...int) *big.Int { return b.header.Difficulty() }
+
+// ParentHash returns the parent hash of the block in the context passed, or
+// else in the context of the node.
+//
+// Deprecated: use ParentHashIn.
+func (b *Block) ParentHash(args ...int) common.Hash { return b.header.ParentHash(args...) }
+
+// ManifestHash returns the manifest hash of the block in the context passed,
+// or else in the context of the node.
+//
+// Deprecated: use ManifestHashIn.
 func (b *Block) ManifestHash(args ...int) common.Hash { return b.header.ManifestHash(args...) }
-func (b *Block) ReceiptHash() common.Hash             { return b.header.ReceiptHash() }
-func (b *Block) Difficulty(args ...int) *big.Int      { return b.header.Difficulty() }
-func (b *Block) ParentEntropy(args ...int) *big.Int   { return b.header.ParentEntropy(args...) }
-func (b *Block) ParentDeltaS(args ...int) *big.Int    { return b.header.ParentDeltaS(args...) }
-func (b *Block) Number(args ...int) *big.Int          { return b.header.Number(args...) }
-func (b *Block) NumberU64(args ...int) uint64         { return b.header.NumberU64(args...) }
-func (b *Block) GasLimit() uint64                     { return b.header.GasLimit() }
-func (b *Block) GasUsed() uint64                      { return b.header.GasUsed() }
-func (b *Block) BaseFee() *big.Int                    { return b.header.BaseFee() }
-func (b *Block) Location() common.Location            { return b.header.Location() }
-func (b *Block) Time() uint64                         { return b.header.Time() }
-func (b *Block) Extra() []byte                        { return b.header.Extra() }
-func (b *Block) Nonce() BlockNonce                    { return b.header.Nonce() }
-func (b *Block) NonceU64() uint64                     { return b.header.NonceU64() }
+
+// ParentEntropy returns the parent entropy of the block in the context passed,
+// or else in the context of the node.
+//
+// Deprecated: use ParentEntropyIn.
+func (b *Block) ParentEntropy(args ...int) *big.Int { return b.header.ParentEntropy(args...) }
+
+// ParentDeltaS returns the parent delta entropy of the block in the context
+// passed, or else in the context of the node.
+//
+// Deprecated: use ParentDeltaSIn.
+func (b *Block) ParentDeltaS(args ...int) *big.Int { return b.header.ParentDeltaS(args...) }
+
+// Number returns the block number in the context passed, or else in the
+// context of the node.
+//
+// Deprecated: use NumberIn.
+func (b *Block) Number(args ...int) *big.Int { return b.header.Number(args...) }
+
+// NumberU64 returns the block number in the context passed, or else in the
+// context of the node.
+//
+// Deprecated: use NumberU64In.
+func (b *Block) NumberU64(args ...int) uint64 { return b.header.NumberU64(args...) }
 
 // PendingHeader stores the header and termini value associated with the header.
 type PendingHeader struct {
@@ -448,6 +877,24 @@ type Termini struct {
 	subTermini []common.Hash `json:"subTermini"`
 }
 
+// NewTermini creates the termini of the given dom and sub termini.
+func NewTermini(domTermini, subTermini []common.Hash) Termini {
+	return Termini{
+		domTermini: append([]common.Hash(nil), domTermini...),
+		subTermini: append([]common.Hash(nil), subTermini...),
+	}
+}
+
+// DomTermini returns a copy of the dom termini.
+func (t Termini) DomTermini() []common.Hash {
+	return append([]common.Hash(nil), t.domTermini...)
+}
+
+// SubTermini returns a copy of the sub termini.
+func (t Termini) SubTermini() []common.Hash {
+	return append([]common.Hash(nil), t.subTermini...)
+}
+
 // "external termini" pending header encoding. used for rlp
 type extTermini struct {
 	DomTermini []common.Hash
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// BlockRLP holds the raw encodings of the top-level parts of an RLP encoded
+// block, following the field order of extblock. Every part is a subslice of the
+// original input and can be decoded on its own with rlp.DecodeBytes.
+type BlockRLP struct {
+	Header      rlp.RawValue
+	Txs         rlp.RawValue
+	Uncles      rlp.RawValue
+	Etxs        rlp.RawValue
+	SubManifest rlp.RawValue
+}
+
+// SplitBlockRLP splits an RLP encoded block into the byte spans of its header,
+// transactions, uncles, external transactions and sub manifest, allowing the
+// header to be verified before any of the body is decoded. Only the framing of
+// the parts is checked, their contents are left for the caller to decode.
+func SplitBlockRLP(raw []byte) (*BlockRLP, error) {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		block = new(BlockRLP)
+		parts = []*rlp.RawValue{&block.Header, &block.Txs, &block.Uncles, &block.Etxs, &block.SubManifest}
+	)
+	for _, part := range parts {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		*part = content[:len(content)-len(rest)]
+		content = rest
+	}
+	return block, nil
+}
@@ -0,0 +1,175 @@
+//go:build goquai
+// +build goquai
+
+// Converters between this package's types and go-quai's core/types, allowing
+// node-adjacent services to hand headers and blocks across without an RLP
+// round-trip. They pull in go-quai and are only built with the goquai tag,
+// against the go-quai release pinned as GOQUAI_VERSION in the goquai workflow
+// of .github/workflows, which builds them on every change:
+//
+//	go get github.com/dominant-strategies/go-quai@$GOQUAI_VERSION
+//	go build -tags goquai ./...
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+
+	quaicommon "github.com/dominant-strategies/go-quai/common"
+	quaitypes "github.com/dominant-strategies/go-quai/core/types"
+	quairlp "github.com/dominant-strategies/go-quai/rlp"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// HeaderFromQuai converts a go-quai header into a header of this package.
+func HeaderFromQuai(qh *quaitypes.Header) *Header {
+	h := &Header{
+		parentHash:    make([]common.Hash, common.HierarchyDepth),
+		manifestHash:  make([]common.Hash, common.HierarchyDepth),
+		parentEntropy: make([]*big.Int, common.HierarchyDepth),
+		parentDeltaS:  make([]*big.Int, common.HierarchyDepth),
+		number:        make([]*big.Int, common.HierarchyDepth),
+	}
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		h.parentHash[ctx] = common.Hash(qh.ParentHash(ctx))
+		h.manifestHash[ctx] = common.Hash(qh.ManifestHash(ctx))
+		h.parentEntropy[ctx] = copyBig(qh.ParentEntropy(ctx))
+		h.parentDeltaS[ctx] = copyBig(qh.ParentDeltaS(ctx))
+		h.number[ctx] = copyBig(qh.Number(ctx))
+	}
+	h.uncleHash = common.Hash(qh.UncleHash())
+	h.coinbase = common.BytesToAddress(qh.Coinbase().Bytes())
+	h.root = common.Hash(qh.Root())
+	h.txHash = common.Hash(qh.TxHash())
+	h.etxHash = common.Hash(qh.EtxHash())
+	h.etxRollupHash = common.Hash(qh.EtxRollupHash())
+	h.receiptHash = common.Hash(qh.ReceiptHash())
+	h.difficulty = copyBig(qh.Difficulty())
+	h.gasLimit = qh.GasLimit()
+	h.gasUsed = qh.GasUsed()
+	h.baseFee = copyBig(qh.BaseFee())
+	h.location = common.Location(qh.Location())
+	h.time = qh.Time()
+	h.extra = common.CopyBytes(qh.Extra())
+	h.mixHash = common.Hash(qh.MixHash())
+	h.nonce = BlockNonce(qh.Nonce())
+	return h
+}
+
+// ToQuai converts the header into a go-quai header.
+func (h *Header) ToQuai() *quaitypes.Header {
+	qh := quaitypes.EmptyHeader()
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		qh.SetParentHash(quaicommon.Hash(h.parentHash[ctx]), ctx)
+		qh.SetManifestHash(quaicommon.Hash(h.manifestHash[ctx]), ctx)
+		qh.SetParentEntropy(copyBig(h.parentEntropy[ctx]), ctx)
+		qh.SetParentDeltaS(copyBig(h.parentDeltaS[ctx]), ctx)
+		qh.SetNumber(copyBig(h.number[ctx]), ctx)
+	}
+	qh.SetUncleHash(quaicommon.Hash(h.uncleHash))
+	qh.SetCoinbase(quaicommon.BytesToAddress(h.coinbase.Bytes()))
+	qh.SetRoot(quaicommon.Hash(h.root))
+	qh.SetTxHash(quaicommon.Hash(h.txHash))
+	qh.SetEtxHash(quaicommon.Hash(h.etxHash))
+	qh.SetEtxRollupHash(quaicommon.Hash(h.etxRollupHash))
+	qh.SetReceiptHash(quaicommon.Hash(h.receiptHash))
+	qh.SetDifficulty(copyBig(h.difficulty))
+	qh.SetGasLimit(h.gasLimit)
+	qh.SetGasUsed(h.gasUsed)
+	qh.SetBaseFee(copyBig(h.baseFee))
+	qh.SetLocation(quaicommon.Location(h.location))
+	qh.SetTime(h.time)
+	qh.SetExtra(common.CopyBytes(h.extra))
+	qh.SetMixHash(quaicommon.Hash(h.mixHash))
+	qh.SetNonce(quaitypes.BlockNonce(h.nonce))
+	return qh
+}
+
+// BlockFromQuai converts a go-quai block into a block of this package. Headers
+// are converted directly, transactions go through their canonical encoding as
+// the transaction payload types are not shared between the packages.
+func BlockFromQuai(qb *quaitypes.Block) (*Block, error) {
+	b := &Block{
+		header:      HeaderFromQuai(qb.Header()),
+		subManifest: make(BlockManifest, len(qb.SubManifest())),
+	}
+	for _, uncle := range qb.Uncles() {
+		b.uncles = append(b.uncles, HeaderFromQuai(uncle))
+	}
+	for i, hash := range qb.SubManifest() {
+		b.subManifest[i] = common.Hash(hash)
+	}
+	var err error
+	if b.transactions, err = txsFromQuai(qb.Transactions()); err != nil {
+		return nil, err
+	}
+	if b.extTransactions, err = txsFromQuai(qb.ExtTransactions()); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ToQuai converts the block into a go-quai block.
+func (b *Block) ToQuai() (*quaitypes.Block, error) {
+	uncles := make([]*quaitypes.Header, len(b.uncles))
+	for i, uncle := range b.uncles {
+		uncles[i] = uncle.ToQuai()
+	}
+	manifest := make(quaitypes.BlockManifest, len(b.subManifest))
+	for i, hash := range b.subManifest {
+		manifest[i] = quaicommon.Hash(hash)
+	}
+	txs, err := txsToQuai(b.transactions)
+	if err != nil {
+		return nil, err
+	}
+	etxs, err := txsToQuai(b.extTransactions)
+	if err != nil {
+		return nil, err
+	}
+	return quaitypes.NewBlockWithHeader(b.header.ToQuai()).WithBody(txs, uncles, etxs, manifest), nil
+}
+
+// txsFromQuai converts go-quai transactions through their canonical encoding.
+func txsFromQuai(qtxs quaitypes.Transactions) (Transactions, error) {
+	txs := make(Transactions, len(qtxs))
+	for i, qtx := range qtxs {
+		enc, err := quairlp.EncodeToBytes(qtx)
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = new(Transaction)
+		if err := rlp.DecodeBytes(enc, txs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return txs, nil
+}
+
+// txsToQuai converts transactions into go-quai ones through their canonical
+// encoding.
+func txsToQuai(txs Transactions) (quaitypes.Transactions, error) {
+	qtxs := make(quaitypes.Transactions, len(txs))
+	for i, tx := range txs {
+		var buf bytes.Buffer
+		if err := tx.EncodeRLP(&buf); err != nil {
+			return nil, err
+		}
+		qtxs[i] = new(quaitypes.Transaction)
+		if err := quairlp.DecodeBytes(buf.Bytes(), qtxs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return qtxs, nil
+}
+
+// copyBig returns a copy of b, preserving nil.
+func copyBig(b *big.Int) *big.Int {
+	if b == nil {
+		return nil
+	}
+	return new(big.Int).Set(b)
+}
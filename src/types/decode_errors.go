@@ -0,0 +1,164 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+var errMissingField = errors.New("missing field")
+
+// FieldError reports the field of an encoded header, block or transaction
+// which failed to decode, or which decoded but violates the structure the
+// rest of the code assumes, so the mismatch can be traced to the offending
+// field rather than left to the bare error of the decoder.
+type FieldError struct {
+	Type     string // Decoded type, such as Header, Block or InternalTx
+	Index    int    // Position of the field in the RLP list, -1 if unknown
+	Field    string // Name of the field in the JSON-RPC format
+	Expected string // What the field must hold, empty if Err tells alone
+	Got      string // What the field holds instead
+	Err      error  // Cause, such as an rlp error or ErrMalformedHeader
+}
+
+func (e *FieldError) Error() string {
+	if e.Expected != "" {
+		return fmt.Sprintf("%s: expected %s, got %s", e.Field, e.Expected, e.Got)
+	}
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// DecodeHeaderRLP decodes an RLP encoded header like rlp.DecodeBytes. If a
+// field fails to decode, the error is a FieldError naming it.
+func DecodeHeaderRLP(raw []byte) (*Header, error) {
+	header := new(Header)
+	err := rlp.DecodeBytes(raw, header)
+	if err == nil {
+		return header, nil
+	}
+	if fieldErr := locateFieldError("Header", reflect.TypeOf(extheader{}), raw, headerJSONName); fieldErr != nil {
+		return nil, fieldErr
+	}
+	return nil, err
+}
+
+// decodeTxData decodes the RLP encoded body of a transaction of the type named
+// name into inner. If a field fails to decode, the error is a FieldError
+// naming it.
+func decodeTxData(name string, raw []byte, inner TxData) error {
+	err := rlp.DecodeBytes(raw, inner)
+	if err == nil {
+		return nil
+	}
+	if fieldErr := locateFieldError(name, reflect.TypeOf(inner).Elem(), raw, txJSONName); fieldErr != nil {
+		return fieldErr
+	}
+	return err
+}
+
+// locateFieldError decodes the fields of the RLP list raw one by one into the
+// fields of the struct type typ, named name, and returns the error of the
+// first which fails, nil if none does or raw is not a list to begin with.
+// jsonName maps the Go names of the fields to their JSON-RPC names.
+func locateFieldError(name string, typ reflect.Type, raw []byte, jsonName func(string) string) *FieldError {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if len(content) == 0 {
+			return &FieldError{Type: name, Index: i, Field: jsonName(field.Name), Err: errMissingField}
+		}
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return &FieldError{Type: name, Index: i, Field: jsonName(field.Name), Err: err}
+		}
+		if err := rlp.DecodeBytes(content[:len(content)-len(rest)], reflect.New(field.Type).Interface()); err != nil {
+			return &FieldError{Type: name, Index: i, Field: jsonName(field.Name), Err: err}
+		}
+		content = rest
+	}
+	// Surplus fields are not attributable to any field, the error of the
+	// decoder is the best description
+	return nil
+}
+
+// locateBlockError decodes the parts of an RLP encoded block one by one and
+// returns the error of the first which fails, nil if none does.
+func locateBlockError(parts *BlockRLP) *FieldError {
+	if _, err := DecodeHeaderRLP(parts.Header); err != nil {
+		return &FieldError{Type: "Block", Index: 0, Field: "header", Err: err}
+	}
+	if fieldErr := locateTxListError(1, "transactions", parts.Txs); fieldErr != nil {
+		return fieldErr
+	}
+	uncles, _, err := rlp.SplitList(parts.Uncles)
+	if err != nil {
+		return &FieldError{Type: "Block", Index: 2, Field: "uncles", Err: err}
+	}
+	for i := 0; len(uncles) > 0; i++ {
+		_, _, rest, err := rlp.Split(uncles)
+		if err != nil {
+			return &FieldError{Type: "Block", Index: 2, Field: fmt.Sprintf("uncles[%d]", i), Err: err}
+		}
+		if _, err := DecodeHeaderRLP(uncles[:len(uncles)-len(rest)]); err != nil {
+			return &FieldError{Type: "Block", Index: 2, Field: fmt.Sprintf("uncles[%d]", i), Err: err}
+		}
+		uncles = rest
+	}
+	if fieldErr := locateTxListError(3, "extTransactions", parts.Etxs); fieldErr != nil {
+		return fieldErr
+	}
+	if err := rlp.DecodeBytes(parts.SubManifest, new(BlockManifest)); err != nil {
+		return &FieldError{Type: "Block", Index: 4, Field: "subManifest", Err: err}
+	}
+	return nil
+}
+
+// locateTxListError decodes the transactions of the list at position index of
+// a block one by one and returns the error of the first which fails.
+func locateTxListError(index int, field string, raw []byte) *FieldError {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return &FieldError{Type: "Block", Index: index, Field: field, Err: err}
+	}
+	for i := 0; len(content) > 0; i++ {
+		_, _, rest, err := rlp.Split(content)
+		if err == nil {
+			err = rlp.DecodeBytes(content[:len(content)-len(rest)], new(Transaction))
+		}
+		if err != nil {
+			return &FieldError{Type: "Block", Index: index, Field: fmt.Sprintf("%s[%d]", field, i), Err: err}
+		}
+		content = rest
+	}
+	return nil
+}
+
+// headerJSONName returns the JSON-RPC name of the header field of the given Go
+// name.
+func headerJSONName(name string) string {
+	return jsonName(reflect.TypeOf(headerJSON{}), name)
+}
+
+// txJSONName returns the JSON-RPC name of the transaction field of the given
+// Go name.
+func txJSONName(name string) string {
+	return jsonName(reflect.TypeOf(txJSON{}), name)
+}
+
+// jsonName returns the JSON name of the field of the given Go name in typ, or
+// the Go name if typ has no such field.
+func jsonName(typ reflect.Type, name string) string {
+	field, ok := typ.FieldByName(name)
+	if !ok {
+		return name
+	}
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
@@ -0,0 +1,169 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+var (
+	ErrBlockTooLarge = errors.New("block exceeds size limit")
+	ErrTooManyTxs    = errors.New("transaction list exceeds count limit")
+	ErrTxTooLarge    = errors.New("transaction exceeds size limit")
+	ErrTooManyUncles = errors.New("uncle list exceeds count limit")
+)
+
+// DecodeLimits bounds the resources spent decoding untrusted blocks and
+// transaction lists. The limits are checked on the raw encoding before any
+// transaction or uncle is decoded, so a hostile blob is rejected without
+// allocating the objects it describes. A zero field disables the corresponding
+// limit.
+type DecodeLimits struct {
+	MaxTxs       int // Maximum number of transactions in a list, checked for transactions and external transactions separately
+	MaxTxSize    int // Maximum encoded size of a single transaction
+	MaxBlockSize int // Maximum encoded size of a block, header included
+	MaxUncles    int // Maximum number of uncles in a block, workshares included
+
+	// CheckHeaders runs Header.SanityCheck on every header decoded from RLP,
+	// standalone or within a block, failing the decode if it does not pass.
+	CheckHeaders bool
+
+	// CheckUncle, if set, is called with the header and every uncle of a
+	// decoded block once the block is decoded, failing the decode if it
+	// returns an error. It lets streaming pipelines reject blocks carrying
+	// workshares below their threshold at parse time; progpow provides checks
+	// for it.
+	CheckUncle func(header, uncle *Header) error
+}
+
+// DefaultDecodeLimits are the limits in force unless changed by SetDecodeLimits.
+// They are well above anything a valid block carries.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxTxs:       65536,
+	MaxTxSize:    128 * 1024,
+	MaxBlockSize: 16 * 1024 * 1024,
+	MaxUncles:    1024,
+}
+
+var decodeLimits atomic.Pointer[DecodeLimits]
+
+func init() {
+	limits := DefaultDecodeLimits
+	decodeLimits.Store(&limits)
+}
+
+// SetDecodeLimits replaces the limits enforced while decoding blocks and
+// transaction lists.
+func SetDecodeLimits(limits DecodeLimits) {
+	decodeLimits.Store(&limits)
+}
+
+// CurrentDecodeLimits returns the limits enforced while decoding blocks and
+// transaction lists.
+func CurrentDecodeLimits() DecodeLimits {
+	return *decodeLimits.Load()
+}
+
+// checkBlockSize checks the content size of an encoded block, as announced by
+// its list header, against the limits.
+func (limits *DecodeLimits) checkBlockSize(size uint64) error {
+	return limits.checkBlockBytes(rlp.ListSize(size))
+}
+
+// checkBlockBytes checks the full size of an encoded block against the limits.
+func (limits *DecodeLimits) checkBlockBytes(size uint64) error {
+	if limits.MaxBlockSize > 0 && size > uint64(limits.MaxBlockSize) {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrBlockTooLarge, size, limits.MaxBlockSize)
+	}
+	return nil
+}
+
+// isLimitError reports whether err reports a violation of the decode limits.
+func isLimitError(err error) bool {
+	return errors.Is(err, ErrBlockTooLarge) || errors.Is(err, ErrTooManyTxs) || errors.Is(err, ErrTxTooLarge) || errors.Is(err, ErrTooManyUncles)
+}
+
+// checkTxCount checks the number of transactions in a list against the limits.
+func (limits *DecodeLimits) checkTxCount(list string, count int) error {
+	if limits.MaxTxs > 0 && count > limits.MaxTxs {
+		return fmt.Errorf("%w: %d %s, limit %d", ErrTooManyTxs, count, list, limits.MaxTxs)
+	}
+	return nil
+}
+
+// checkTxSize checks the encoded size of a transaction against the limits.
+func (limits *DecodeLimits) checkTxSize(list string, index, size int) error {
+	if limits.MaxTxSize > 0 && size > limits.MaxTxSize {
+		return fmt.Errorf("%w: %s[%d] is %d bytes, limit %d", ErrTxTooLarge, list, index, size, limits.MaxTxSize)
+	}
+	return nil
+}
+
+// checkTxList checks an RLP list of transactions in the envelope encoding
+// against the limits, without decoding any of them. The list is named in
+// errors.
+func (limits *DecodeLimits) checkTxList(list string, raw []byte) error {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	count, err := rlp.CountValues(content)
+	if err != nil {
+		return err
+	}
+	if err := limits.checkTxCount(list, count); err != nil {
+		return err
+	}
+	for i := 0; len(content) > 0; i++ {
+		_, _, rest, err := rlp.Split(content)
+		if err != nil {
+			return err
+		}
+		if err := limits.checkTxSize(list, i, len(content)-len(rest)); err != nil {
+			return err
+		}
+		content = rest
+	}
+	return nil
+}
+
+// checkUncleList checks an RLP list of uncles against the limits, without
+// decoding any of them.
+func (limits *DecodeLimits) checkUncleList(raw []byte) error {
+	if limits.MaxUncles <= 0 {
+		return nil
+	}
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	count, err := rlp.CountValues(content)
+	if err != nil {
+		return err
+	}
+	return limits.checkUncleCount(count)
+}
+
+// checkUncleCount checks the number of uncles of a block against the limits.
+func (limits *DecodeLimits) checkUncleCount(count int) error {
+	if limits.MaxUncles > 0 && count > limits.MaxUncles {
+		return fmt.Errorf("%w: %d uncles, limit %d", ErrTooManyUncles, count, limits.MaxUncles)
+	}
+	return nil
+}
+
+// checkUncles runs the uncle check of the limits, if any, on every uncle of a
+// decoded block.
+func (limits *DecodeLimits) checkUncles(header *Header, uncles []*Header) error {
+	if limits.CheckUncle == nil {
+		return nil
+	}
+	for i, uncle := range uncles {
+		if err := limits.CheckUncle(header, uncle); err != nil {
+			return fmt.Errorf("uncle %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// Encoded sizes of the fixed size fields of headers.
+const (
+	hashEncodedSize    = 1 + common.HashLength    // String header and hash
+	addressEncodedSize = 1 + common.AddressLength // String header and address
+	nonceEncodedSize   = 1 + 8                    // String header and nonce
+)
+
+// EncodedSize returns the length of the RLP encoding of the header, computed
+// from its fields without encoding it.
+func (h *Header) EncodedSize() uint64 {
+	size := hashListEncodedSize(h.parentHash) +
+		4*hashEncodedSize + // uncleHash, root, txHash, etxHash
+		hashEncodedSize + // etxRollupHash
+		addressEncodedSize +
+		hashListEncodedSize(h.manifestHash) +
+		hashEncodedSize + // receiptHash
+		uint64(rlp.BigIntSize(h.difficulty)) +
+		bigListEncodedSize(h.parentEntropy) +
+		bigListEncodedSize(h.parentDeltaS) +
+		bigListEncodedSize(h.number) +
+		uint64(rlp.IntSize(h.gasLimit)) +
+		uint64(rlp.IntSize(h.gasUsed)) +
+		uint64(rlp.BigIntSize(h.baseFee)) +
+		uint64(rlp.BytesSize(h.location)) +
+		uint64(rlp.IntSize(h.time)) +
+		uint64(rlp.BytesSize(h.extra)) +
+		hashEncodedSize + // mixHash
+		nonceEncodedSize
+	return rlp.ListSize(size)
+}
+
+// EncodedSize returns the length of the RLP encoding of the block, computed
+// from its parts without encoding them. Only transactions which were not
+// decoded are encoded, once, to learn their size.
+func (b *Block) EncodedSize() uint64 {
+	uncles := uint64(0)
+	for _, uncle := range b.uncles {
+		uncles += uncle.EncodedSize()
+	}
+	size := b.header.EncodedSize() +
+		txListEncodedSize(b.transactions) +
+		rlp.ListSize(uncles) +
+		txListEncodedSize(b.extTransactions) +
+		hashListEncodedSize(b.subManifest)
+	return rlp.ListSize(size)
+}
+
+// hashListEncodedSize returns the encoded size of a list of hashes.
+func hashListEncodedSize(hashes []common.Hash) uint64 {
+	return rlp.ListSize(uint64(len(hashes)) * hashEncodedSize)
+}
+
+// bigListEncodedSize returns the encoded size of a list of integers.
+func bigListEncodedSize(ints []*big.Int) uint64 {
+	size := uint64(0)
+	for _, i := range ints {
+		size += uint64(rlp.BigIntSize(i))
+	}
+	return rlp.ListSize(size)
+}
+
+// txListEncodedSize returns the encoded size of a list of transactions, each
+// a string holding its typed encoding.
+func txListEncodedSize(txs Transactions) uint64 {
+	size := uint64(0)
+	for _, tx := range txs {
+		typed := uint64(tx.Size())
+		size += rlp.ListSize(typed) // String headers are sized like list headers
+	}
+	return rlp.ListSize(size)
+}
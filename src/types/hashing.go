@@ -18,11 +18,56 @@ package types
 
 import (
 	"bytes"
+	"io"
 	"sync"
+	"sync/atomic"
 
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/crypto"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
 	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
 )
 
+// Hasher is a hash function computing the seal hash and hash of headers. The
+// 32 byte Blake3 hash is used unless another backend is installed with
+// SetHasher.
+type Hasher interface {
+	io.Writer
+	// Sum appends the hash of the data written since the last Reset to b.
+	Sum(b []byte) []byte
+	// Reset discards the data written so far.
+	Reset()
+}
+
+// headerHashers pools the hashers of the installed header hash backend, so
+// concurrent callers hash in parallel without allocating a hasher per call.
+var headerHashers atomic.Pointer[sync.Pool]
+
+func init() {
+	SetHasher(nil)
+}
+
+// SetHasher installs the constructor of the hashers used by Header.SealHash and
+// Header.Hash, such as an instrumented or deterministic fake hash in tests. Nil
+// restores Blake3. Hashes computed before the call are not affected, so the
+// backend should be installed before any header is hashed.
+func SetHasher(newHasher func() Hasher) {
+	if newHasher == nil {
+		newHasher = func() Hasher { return blake3.New(32, nil) }
+	}
+	headerHashers.Store(&sync.Pool{New: func() interface{} { return newHasher() }})
+}
+
+// getHeaderHasher returns a reset hasher of the installed header hash backend,
+// along with the pool it is to be returned to once done.
+func getHeaderHasher() (Hasher, *sync.Pool) {
+	pool := headerHashers.Load()
+	hasher := pool.Get().(Hasher)
+	hasher.Reset()
+	return hasher, pool
+}
+
 // hasherPool holds LegacyKeccak256 hashers for rlpHash.
 var hasherPool = sync.Pool{
 	New: func() interface{} { return sha3.NewLegacyKeccak256() },
@@ -32,3 +77,25 @@ var hasherPool = sync.Pool{
 var encodeBufferPool = sync.Pool{
 	New: func() interface{} { return new(bytes.Buffer) },
 }
+
+// rlpHash encodes x and hashes the encoded bytes.
+func rlpHash(x interface{}) (h common.Hash) {
+	sha := hasherPool.Get().(crypto.KeccakState)
+	defer hasherPool.Put(sha)
+	sha.Reset()
+	rlp.Encode(sha, x)
+	sha.Read(h[:])
+	return h
+}
+
+// prefixedRlpHash writes the prefix into the hasher before rlp-encoding x.
+// It's used for typed transactions.
+func prefixedRlpHash(prefix byte, x interface{}) (h common.Hash) {
+	sha := hasherPool.Get().(crypto.KeccakState)
+	defer hasherPool.Put(sha)
+	sha.Reset()
+	sha.Write([]byte{prefix})
+	rlp.Encode(sha, x)
+	sha.Read(h[:])
+	return h
+}
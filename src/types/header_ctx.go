@@ -0,0 +1,106 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// The accessors below read and write the per context fields of a header in an
+// explicit, typed context. They replace passing the context as the optional
+// int argument of ParentHash, Number and the like, where leaving it out
+// silently picks the context of the node. A context outside the hierarchy
+// panics with ErrInvalidContext; the checked accessors report it as an error
+// instead.
+
+// ctxIndex returns the index of the entries of context ctx in the per context
+// fields.
+func ctxIndex(ctx common.Context) int {
+	if !ctx.Valid() {
+		panic(fmt.Sprintf("%v: %d, want 0 to %d", ErrInvalidContext, int(ctx), common.HierarchyDepth-1))
+	}
+	return int(ctx)
+}
+
+// argsCtx returns the context passed as the optional argument of the untyped
+// accessors, defaulting to the context of the node.
+func argsCtx(args []int) common.Context {
+	if len(args) > 0 {
+		return common.Context(args[0])
+	}
+	return common.NodeLocation.Ctx()
+}
+
+// ParentHashIn returns the parent hash of the header in context ctx.
+func (h *Header) ParentHashIn(ctx common.Context) common.Hash {
+	return h.parentHash[ctxIndex(ctx)]
+}
+
+// ManifestHashIn returns the manifest hash of the header in context ctx.
+func (h *Header) ManifestHashIn(ctx common.Context) common.Hash {
+	return h.manifestHash[ctxIndex(ctx)]
+}
+
+// ParentEntropyIn returns the parent entropy of the header in context ctx.
+func (h *Header) ParentEntropyIn(ctx common.Context) *big.Int {
+	return h.parentEntropy[ctxIndex(ctx)]
+}
+
+// ParentDeltaSIn returns the parent delta entropy of the header in context
+// ctx.
+func (h *Header) ParentDeltaSIn(ctx common.Context) *big.Int {
+	return h.parentDeltaS[ctxIndex(ctx)]
+}
+
+// NumberIn returns the block number of the header in context ctx.
+func (h *Header) NumberIn(ctx common.Context) *big.Int {
+	return h.number[ctxIndex(ctx)]
+}
+
+// NumberU64In returns the block number of the header in context ctx as a
+// uint64.
+func (h *Header) NumberU64In(ctx common.Context) uint64 {
+	return h.number[ctxIndex(ctx)].Uint64()
+}
+
+// SetParentHashIn sets the parent hash of the header in context ctx.
+func (h *Header) SetParentHashIn(val common.Hash, ctx common.Context) {
+	i := ctxIndex(ctx)
+	h.clearSealCaches()
+	h.parentHash[i] = val
+}
+
+// SetManifestHashIn sets the manifest hash of the header in context ctx.
+func (h *Header) SetManifestHashIn(val common.Hash, ctx common.Context) {
+	i := ctxIndex(ctx)
+	h.clearSealCaches()
+	h.manifestHash[i] = val
+}
+
+// SetParentEntropyIn sets the parent entropy of the header in context ctx.
+// The entropy is not sealed, so the cached hashes remain valid.
+func (h *Header) SetParentEntropyIn(val *big.Int, ctx common.Context) {
+	h.parentEntropy[ctxIndex(ctx)] = val
+}
+
+// SetParentDeltaSIn sets the parent delta entropy of the header in context
+// ctx. Like the entropy it is not sealed.
+func (h *Header) SetParentDeltaSIn(val *big.Int, ctx common.Context) {
+	h.parentDeltaS[ctxIndex(ctx)] = val
+}
+
+// SetNumberIn sets the block number of the header in context ctx.
+func (h *Header) SetNumberIn(val *big.Int, ctx common.Context) {
+	i := ctxIndex(ctx)
+	h.clearSealCaches()
+	h.number[i] = new(big.Int).Set(val)
+}
+
+// Wrapped typed context header accessors
+func (b *Block) ParentHashIn(ctx common.Context) common.Hash   { return b.header.ParentHashIn(ctx) }
+func (b *Block) ManifestHashIn(ctx common.Context) common.Hash { return b.header.ManifestHashIn(ctx) }
+func (b *Block) ParentEntropyIn(ctx common.Context) *big.Int   { return b.header.ParentEntropyIn(ctx) }
+func (b *Block) ParentDeltaSIn(ctx common.Context) *big.Int    { return b.header.ParentDeltaSIn(ctx) }
+func (b *Block) NumberIn(ctx common.Context) *big.Int          { return b.header.NumberIn(ctx) }
+func (b *Block) NumberU64In(ctx common.Context) uint64         { return b.header.NumberU64In(ctx) }
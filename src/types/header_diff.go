@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+)
+
+// diffMissing stands for the value of an entry a per context field lacks.
+const diffMissing = "missing"
+
+// FieldDiff is a difference between two headers in one field, or in one entry
+// of a per context field. Values are given as encoded in the JSON-RPC format,
+// with null for missing integers and "missing" for entries a header lacks.
+type FieldDiff struct {
+	Field  string `json:"field"`  // Name of the field in the JSON-RPC format
+	Index  int    `json:"index"`  // Context of the entry of per context fields, -1 for others
+	Sealed bool   `json:"sealed"` // Whether the seal hash covers the field
+	A      string `json:"a"`      // Value in the first header
+	B      string `json:"b"`      // Value in the second header
+}
+
+// String implements fmt.Stringer.
+func (d FieldDiff) String() string {
+	field := d.Field
+	if d.Index >= 0 {
+		field = fmt.Sprintf("%s[%d]", d.Field, d.Index)
+	}
+	if !d.Sealed {
+		field += " (unsealed)"
+	}
+	return fmt.Sprintf("%s: %s != %s", field, d.A, d.B)
+}
+
+// DiffHeaders reports the fields in which two headers differ, in the order of
+// the RLP encoding, with one difference per differing entry of per context
+// fields. Headers differing in sealed fields only have different seal hashes,
+// the others change only the header hash or the entropy. Malformed headers
+// are compared as they are, so it also tells which entries one of them lacks.
+func DiffHeaders(a, b *Header) []FieldDiff {
+	var diffs []FieldDiff
+	diff := func(field string, sealed bool, va, vb string) {
+		if va != vb {
+			diffs = append(diffs, FieldDiff{Field: field, Index: -1, Sealed: sealed, A: va, B: vb})
+		}
+	}
+	diffCtx := func(field string, sealed bool, la, lb int, value func(h *Header, i int) string) {
+		for i := 0; i < la || i < lb; i++ {
+			va, vb := diffMissing, diffMissing
+			if i < la {
+				va = value(a, i)
+			}
+			if i < lb {
+				vb = value(b, i)
+			}
+			if va != vb {
+				diffs = append(diffs, FieldDiff{Field: field, Index: i, Sealed: sealed, A: va, B: vb})
+			}
+		}
+	}
+	diffCtx("parentHash", true, len(a.parentHash), len(b.parentHash), func(h *Header, i int) string { return h.parentHash[i].Hex() })
+	diff("sha3Uncles", true, a.uncleHash.Hex(), b.uncleHash.Hex())
+	diff("miner", true, diffAddress(a.coinbase), diffAddress(b.coinbase))
+	diff("stateRoot", true, a.root.Hex(), b.root.Hex())
+	diff("transactionsRoot", true, a.txHash.Hex(), b.txHash.Hex())
+	diff("extTransactionsRoot", true, a.etxHash.Hex(), b.etxHash.Hex())
+	diff("extRollupRoot", true, a.etxRollupHash.Hex(), b.etxRollupHash.Hex())
+	diffCtx("manifestHash", true, len(a.manifestHash), len(b.manifestHash), func(h *Header, i int) string { return h.manifestHash[i].Hex() })
+	diff("receiptsRoot", true, a.receiptHash.Hex(), b.receiptHash.Hex())
+	diff("difficulty", true, diffBig(a.difficulty), diffBig(b.difficulty))
+	diffCtx("parentEntropy", false, len(a.parentEntropy), len(b.parentEntropy), func(h *Header, i int) string { return diffBig(h.parentEntropy[i]) })
+	diffCtx("parentDeltaS", false, len(a.parentDeltaS), len(b.parentDeltaS), func(h *Header, i int) string { return diffBig(h.parentDeltaS[i]) })
+	diffCtx("number", true, len(a.number), len(b.number), func(h *Header, i int) string { return diffBig(h.number[i]) })
+	diff("gasLimit", true, hexutil.EncodeUint64(a.gasLimit), hexutil.EncodeUint64(b.gasLimit))
+	diff("gasUsed", true, hexutil.EncodeUint64(a.gasUsed), hexutil.EncodeUint64(b.gasUsed))
+	diff("baseFeePerGas", true, diffBig(a.baseFee), diffBig(b.baseFee))
+	diff("location", true, hexutil.Encode(a.location), hexutil.Encode(b.location))
+	diff("timestamp", true, hexutil.EncodeUint64(a.time), hexutil.EncodeUint64(b.time))
+	diff("extraData", true, hexutil.Encode(a.extra), hexutil.Encode(b.extra))
+	diff("mixHash", false, a.mixHash.Hex(), b.mixHash.Hex())
+	diff("nonce", false, hexutil.Encode(a.nonce[:]), hexutil.Encode(b.nonce[:]))
+	return diffs
+}
+
+// diffBig formats an integer header field for DiffHeaders.
+func diffBig(value *big.Int) string {
+	if value == nil {
+		return "null"
+	}
+	return hexutil.EncodeBig(value)
+}
+
+// diffAddress formats an address header field for DiffHeaders, the unset
+// address being encoded as zero like in RLP.
+func diffAddress(address common.Address) string {
+	b := address.Bytes20()
+	return hexutil.Encode(b[:])
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// Positions of the extracted fields within the RLP encoded header, following
+// the field order of extheader.
+const (
+	headerDifficultyIndex = 9
+	headerNumberIndex     = 12
+	headerMixHashIndex    = 19
+	headerNonceIndex      = 20
+)
+
+var ErrHeaderFieldSize = errors.New("header field has invalid size")
+
+// HeaderFields contains the subset of header fields needed to pre-filter
+// headers before paying for a full decode.
+type HeaderFields struct {
+	Number     []*big.Int
+	Difficulty *big.Int
+	MixHash    common.Hash
+	Nonce      BlockNonce
+}
+
+// HeaderFieldsFromRLP extracts the number, difficulty, nonce and mixHash of an
+// RLP encoded header by splitting the raw encoding, without reflection and
+// without allocating a full Header. The number must have one entry per
+// context, like Header.SanityCheck requires, otherwise a FieldError wrapping
+// ErrMalformedHeader is returned. The remaining fields are skipped and not
+// validated.
+func HeaderFieldsFromRLP(raw []byte) (*HeaderFields, error) {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil, err
+	}
+	fields := new(HeaderFields)
+	for i := 0; i <= headerNonceIndex; i++ {
+		kind, value, rest, err := rlp.Split(content)
+		if err != nil {
+			return nil, err
+		}
+		content = rest
+
+		switch i {
+		case headerDifficultyIndex:
+			if kind == rlp.List {
+				return nil, rlp.ErrExpectedString
+			}
+			if fields.Difficulty, err = bigFromRLP(value); err != nil {
+				return nil, err
+			}
+		case headerNumberIndex:
+			if kind != rlp.List {
+				return nil, rlp.ErrExpectedList
+			}
+			for len(value) > 0 {
+				num, rest, err := rlp.SplitString(value)
+				if err != nil {
+					return nil, err
+				}
+				n, err := bigFromRLP(num)
+				if err != nil {
+					return nil, err
+				}
+				if len(fields.Number) == common.HierarchyDepth {
+					return nil, malformedField("Number", -1, fmt.Sprintf("%d elements", common.HierarchyDepth), fmt.Sprintf("more than %d", common.HierarchyDepth))
+				}
+				fields.Number = append(fields.Number, n)
+				value = rest
+			}
+			if len(fields.Number) != common.HierarchyDepth {
+				return nil, malformedField("Number", -1, fmt.Sprintf("%d elements", common.HierarchyDepth), fmt.Sprint(len(fields.Number)))
+			}
+		case headerMixHashIndex:
+			if kind == rlp.List {
+				return nil, rlp.ErrExpectedString
+			}
+			if len(value) != common.HashLength {
+				return nil, ErrHeaderFieldSize
+			}
+			copy(fields.MixHash[:], value)
+		case headerNonceIndex:
+			if kind == rlp.List {
+				return nil, rlp.ErrExpectedString
+			}
+			if len(value) != len(fields.Nonce) {
+				return nil, ErrHeaderFieldSize
+			}
+			copy(fields.Nonce[:], value)
+		}
+	}
+	return fields, nil
+}
+
+// NumberU64 returns the block number in the given context, defaulting to the
+// context of the running node.
+//
+// Deprecated: use NumberU64In.
+func (f *HeaderFields) NumberU64(args ...int) uint64 {
+	return f.NumberU64In(argsCtx(args))
+}
+
+// NumberU64In returns the block number in context ctx.
+func (f *HeaderFields) NumberU64In(ctx common.Context) uint64 {
+	return f.Number[ctxIndex(ctx)].Uint64()
+}
+
+// bigFromRLP interprets the content of an RLP string as a canonical big endian
+// unsigned integer.
+func bigFromRLP(b []byte) (*big.Int, error) {
+	if len(b) > 0 && b[0] == 0 {
+		return nil, rlp.ErrCanonInt
+	}
+	return new(big.Int).SetBytes(b), nil
+}
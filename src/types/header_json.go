@@ -0,0 +1,253 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+)
+
+// headerJSON is the JSON-RPC representation of a header, as served by go-quai
+// nodes. Numbers are hex encoded and the per context fields are arrays indexed
+// by context.
+type headerJSON struct {
+	ParentHash    []common.Hash   `json:"parentHash"           gencodec:"required"`
+	UncleHash     *common.Hash    `json:"sha3Uncles"           gencodec:"required"`
+	Coinbase      *common.Address `json:"miner"                gencodec:"required"`
+	Root          *common.Hash    `json:"stateRoot"            gencodec:"required"`
+	TxHash        *common.Hash    `json:"transactionsRoot"     gencodec:"required"`
+	EtxHash       *common.Hash    `json:"extTransactionsRoot"  gencodec:"required"`
+	EtxRollupHash *common.Hash    `json:"extRollupRoot"        gencodec:"required"`
+	ManifestHash  []common.Hash   `json:"manifestHash"         gencodec:"required"`
+	ReceiptHash   *common.Hash    `json:"receiptsRoot"         gencodec:"required"`
+	Difficulty    *hexutil.Big    `json:"difficulty"           gencodec:"required"`
+	ParentEntropy []*hexutil.Big  `json:"parentEntropy"        gencodec:"required"`
+	ParentDeltaS  []*hexutil.Big  `json:"parentDeltaS"         gencodec:"required"`
+	Number        []*hexutil.Big  `json:"number"               gencodec:"required"`
+	GasLimit      *hexutil.Uint64 `json:"gasLimit"             gencodec:"required"`
+	GasUsed       *hexutil.Uint64 `json:"gasUsed"              gencodec:"required"`
+	BaseFee       *hexutil.Big    `json:"baseFeePerGas"        gencodec:"required"`
+	Location      *hexutil.Bytes  `json:"location"             gencodec:"required"`
+	Time          *hexutil.Uint64 `json:"timestamp"            gencodec:"required"`
+	Extra         *hexutil.Bytes  `json:"extraData"            gencodec:"required"`
+	MixHash       *common.Hash    `json:"mixHash"              gencodec:"required"`
+	Nonce         *BlockNonce     `json:"nonce"`
+	Hash          *common.Hash    `json:"hash"`
+}
+
+// MarshalJSON marshals the header in the go-quai JSON-RPC format, including the
+// header hash.
+func (h *Header) MarshalJSON() ([]byte, error) {
+	var (
+		location = hexutil.Bytes(h.location)
+		extra    = hexutil.Bytes(h.extra)
+		gasLimit = hexutil.Uint64(h.gasLimit)
+		gasUsed  = hexutil.Uint64(h.gasUsed)
+		time     = hexutil.Uint64(h.time)
+		hash     = h.Hash()
+	)
+	return json.Marshal(&headerJSON{
+		ParentHash:    h.parentHash,
+		UncleHash:     &h.uncleHash,
+		Coinbase:      &h.coinbase,
+		Root:          &h.root,
+		TxHash:        &h.txHash,
+		EtxHash:       &h.etxHash,
+		EtxRollupHash: &h.etxRollupHash,
+		ManifestHash:  h.manifestHash,
+		ReceiptHash:   &h.receiptHash,
+		Difficulty:    (*hexutil.Big)(h.difficulty),
+		ParentEntropy: bigsToHex(h.parentEntropy),
+		ParentDeltaS:  bigsToHex(h.parentDeltaS),
+		Number:        bigsToHex(h.number),
+		GasLimit:      &gasLimit,
+		GasUsed:       &gasUsed,
+		BaseFee:       (*hexutil.Big)(h.baseFee),
+		Location:      &location,
+		Time:          &time,
+		Extra:         &extra,
+		MixHash:       &h.mixHash,
+		Nonce:         &h.nonce,
+		Hash:          &hash,
+	})
+}
+
+// UnmarshalJSON decodes a header from the go-quai JSON-RPC format. The hash
+// field, if present, is ignored; it is recomputed from the decoded fields.
+func (h *Header) UnmarshalJSON(input []byte) error {
+	return h.unmarshalJSON(input, true)
+}
+
+// UnmarshalUnsealedJSON decodes a header like UnmarshalJSON, but accepts a
+// header lacking its seal, the mixHash and nonce, such as a pending header a
+// work package is built from. Absent seal fields are left zero.
+func (h *Header) UnmarshalUnsealedJSON(input []byte) error {
+	return h.unmarshalJSON(input, false)
+}
+
+// unmarshalJSON decodes a header from the go-quai JSON-RPC format, requiring
+// the mixHash only if sealed is set.
+func (h *Header) unmarshalJSON(input []byte, sealed bool) error {
+	var dec headerJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.ParentHash == nil {
+		return errors.New("missing required field 'parentHash' for Header")
+	}
+	if dec.UncleHash == nil {
+		return errors.New("missing required field 'sha3Uncles' for Header")
+	}
+	if dec.Coinbase == nil {
+		return errors.New("missing required field 'miner' for Header")
+	}
+	if dec.Root == nil {
+		return errors.New("missing required field 'stateRoot' for Header")
+	}
+	if dec.TxHash == nil {
+		return errors.New("missing required field 'transactionsRoot' for Header")
+	}
+	if dec.EtxHash == nil {
+		return errors.New("missing required field 'extTransactionsRoot' for Header")
+	}
+	if dec.EtxRollupHash == nil {
+		return errors.New("missing required field 'extRollupRoot' for Header")
+	}
+	if dec.ManifestHash == nil {
+		return errors.New("missing required field 'manifestHash' for Header")
+	}
+	if dec.ReceiptHash == nil {
+		return errors.New("missing required field 'receiptsRoot' for Header")
+	}
+	if dec.Difficulty == nil {
+		return errors.New("missing required field 'difficulty' for Header")
+	}
+	if dec.ParentEntropy == nil {
+		return errors.New("missing required field 'parentEntropy' for Header")
+	}
+	if dec.ParentDeltaS == nil {
+		return errors.New("missing required field 'parentDeltaS' for Header")
+	}
+	if dec.Number == nil {
+		return errors.New("missing required field 'number' for Header")
+	}
+	if dec.GasLimit == nil {
+		return errors.New("missing required field 'gasLimit' for Header")
+	}
+	if dec.GasUsed == nil {
+		return errors.New("missing required field 'gasUsed' for Header")
+	}
+	if dec.BaseFee == nil {
+		return errors.New("missing required field 'baseFeePerGas' for Header")
+	}
+	if dec.Location == nil {
+		return errors.New("missing required field 'location' for Header")
+	}
+	if dec.Time == nil {
+		return errors.New("missing required field 'timestamp' for Header")
+	}
+	if dec.Extra == nil {
+		return errors.New("missing required field 'extraData' for Header")
+	}
+	if dec.MixHash == nil {
+		if sealed {
+			return errors.New("missing required field 'mixHash' for Header")
+		}
+		dec.MixHash = new(common.Hash)
+	}
+	// The per context fields are indexed by context, make sure they cover all
+	// of them so the accessors cannot run out of bounds
+	for _, field := range []struct {
+		name string
+		size int
+	}{
+		{"parentHash", len(dec.ParentHash)},
+		{"manifestHash", len(dec.ManifestHash)},
+		{"parentEntropy", len(dec.ParentEntropy)},
+		{"parentDeltaS", len(dec.ParentDeltaS)},
+		{"number", len(dec.Number)},
+	} {
+		if field.size != common.HierarchyDepth {
+			return &FieldError{
+				Type:     "Header",
+				Index:    -1,
+				Field:    field.name,
+				Expected: fmt.Sprintf("%d elements", common.HierarchyDepth),
+				Got:      fmt.Sprint(field.size),
+				Err:      ErrMalformedHeader,
+			}
+		}
+	}
+	parentEntropy, err := bigsFromHex("parentEntropy", dec.ParentEntropy)
+	if err != nil {
+		return err
+	}
+	parentDeltaS, err := bigsFromHex("parentDeltaS", dec.ParentDeltaS)
+	if err != nil {
+		return err
+	}
+	number, err := bigsFromHex("number", dec.Number)
+	if err != nil {
+		return err
+	}
+	h.parentHash = dec.ParentHash
+	h.uncleHash = *dec.UncleHash
+	h.coinbase = *dec.Coinbase
+	h.root = *dec.Root
+	h.txHash = *dec.TxHash
+	h.etxHash = *dec.EtxHash
+	h.etxRollupHash = *dec.EtxRollupHash
+	h.manifestHash = dec.ManifestHash
+	h.receiptHash = *dec.ReceiptHash
+	h.difficulty = (*big.Int)(dec.Difficulty)
+	h.parentEntropy = parentEntropy
+	h.parentDeltaS = parentDeltaS
+	h.number = number
+	h.gasLimit = uint64(*dec.GasLimit)
+	h.gasUsed = uint64(*dec.GasUsed)
+	h.baseFee = (*big.Int)(dec.BaseFee)
+	h.location = common.Location(*dec.Location)
+	h.time = uint64(*dec.Time)
+	h.extra = *dec.Extra
+	h.mixHash = *dec.MixHash
+	h.nonce = BlockNonce{}
+	if dec.Nonce != nil {
+		h.nonce = *dec.Nonce
+	}
+	// Drop anything cached for previous contents of the header
+	h.hash = atomic.Value{}
+	h.sealHash = atomic.Value{}
+	h.PowHash = atomic.Value{}
+	h.PowDigest = atomic.Value{}
+
+	return nil
+}
+
+// bigsToHex converts a per context list of integers to its JSON form.
+func bigsToHex(bigs []*big.Int) []*hexutil.Big {
+	if bigs == nil {
+		return nil
+	}
+	out := make([]*hexutil.Big, len(bigs))
+	for i, b := range bigs {
+		out[i] = (*hexutil.Big)(b)
+	}
+	return out
+}
+
+// bigsFromHex converts a per context list of integers from its JSON form,
+// rejecting null entries.
+func bigsFromHex(name string, bigs []*hexutil.Big) ([]*big.Int, error) {
+	out := make([]*big.Int, len(bigs))
+	for i, b := range bigs {
+		if b == nil {
+			return nil, fmt.Errorf("missing value %d of field '%s' for Header", i, name)
+		}
+		out[i] = (*big.Int)(b)
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"sync"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	lrucache "github.com/dominant-strategies/progpow-verification-wasm/internal/cache"
+)
+
+// HeaderLRU is a least-recently-used cache of headers by hash, bounded by the
+// number of headers and/or the memory they take as estimated by Header.Size,
+// for light clients keeping recently fetched or verified headers around. It
+// is safe for concurrent use.
+type HeaderLRU struct {
+	lock    sync.Mutex
+	headers *lrucache.LRU[common.Hash, *Header]
+}
+
+// NewHeaderLRU creates a cache holding at most maxItems headers taking at most
+// maxSize. A zero limit disables that bound.
+func NewHeaderLRU(maxItems int, maxSize common.StorageSize) *HeaderLRU {
+	return &HeaderLRU{headers: lrucache.New[common.Hash, *Header](maxItems, uint64(maxSize), nil)}
+}
+
+// Add inserts header under its hash, evicting the least recently used
+// headers if it does not fit otherwise, and returns the hash. Headers must
+// not be modified once added.
+func (c *HeaderLRU) Add(header *Header) common.Hash {
+	hash := header.Hash()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.headers.Add(hash, header, uint64(header.Size()))
+	return hash
+}
+
+// Get returns the header of the given hash and marks it as most recently
+// used.
+func (c *HeaderLRU) Get(hash common.Hash) (*Header, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headers.Get(hash)
+}
+
+// Contains reports whether the header of the given hash is cached, without
+// updating its recency.
+func (c *HeaderLRU) Contains(hash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headers.Contains(hash)
+}
+
+// Remove deletes the header of the given hash, reporting whether it was
+// cached.
+func (c *HeaderLRU) Remove(hash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headers.Remove(hash)
+}
+
+// Len returns the number of cached headers.
+func (c *HeaderLRU) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headers.Len()
+}
+
+// Size returns the memory the cached headers take, as estimated by
+// Header.Size.
+func (c *HeaderLRU) Size() common.StorageSize {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return common.StorageSize(c.headers.Cost())
+}
+
+// Purge removes all headers.
+func (c *HeaderLRU) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.headers.Purge()
+}
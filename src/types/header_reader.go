@@ -0,0 +1,54 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// HeaderReader decodes headers one at a time from a stream of concatenated RLP
+// encoded headers, such as a header export or a shipped log, without holding
+// more than the header being decoded in memory.
+type HeaderReader struct {
+	stream *rlp.Stream
+	count  int // Number of headers decoded so far
+}
+
+// NewHeaderReader creates a reader decoding the headers of r. Readers without
+// a ReadByte method are buffered internally.
+func NewHeaderReader(r io.Reader) *HeaderReader {
+	return &HeaderReader{stream: rlp.NewStream(r, 0)}
+}
+
+// Next decodes the next header of the stream. It returns io.EOF once the
+// stream ends cleanly between headers; a stream ending within a header yields
+// io.ErrUnexpectedEOF. A header announcing a size beyond the block size limit
+// of the decode limits is rejected before it is read.
+func (r *HeaderReader) Next() (*Header, error) {
+	_, size, err := r.stream.Kind()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("header %d: %w", r.count, err)
+	}
+	if err := decodeLimits.Load().checkBlockSize(size); err != nil {
+		return nil, fmt.Errorf("header %d: %w", r.count, err)
+	}
+	header := new(Header)
+	if err := r.stream.Decode(header); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("header %d: %w", r.count, err)
+	}
+	r.count++
+	return header, nil
+}
+
+// Count returns the number of headers decoded so far.
+func (r *HeaderReader) Count() int {
+	return r.count
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// ErrInvalidContext is returned by the checked accessors of per context header
+// fields for contexts outside the hierarchy.
+var ErrInvalidContext = errors.New("invalid context")
+
+// The checked accessors below return the per context fields of a header like
+// their unchecked counterparts, but report contexts outside the hierarchy, and
+// entries a malformed header lacks, with an error instead of panicking. Use
+// them on headers which did not pass SanityCheck, or with contexts which are
+// not constants.
+
+// ParentHashSafe returns the parent hash of the header in context ctx.
+func (h *Header) ParentHashSafe(ctx common.Context) (common.Hash, error) {
+	if err := checkEntry("ParentHash", ctx, len(h.parentHash)); err != nil {
+		return common.Hash{}, err
+	}
+	return h.parentHash[ctx], nil
+}
+
+// ManifestHashSafe returns the manifest hash of the header in context ctx.
+func (h *Header) ManifestHashSafe(ctx common.Context) (common.Hash, error) {
+	if err := checkEntry("ManifestHash", ctx, len(h.manifestHash)); err != nil {
+		return common.Hash{}, err
+	}
+	return h.manifestHash[ctx], nil
+}
+
+// NumberSafe returns the block number of the header in context ctx.
+func (h *Header) NumberSafe(ctx common.Context) (*big.Int, error) {
+	return bigEntry("Number", ctx, h.number)
+}
+
+// ParentEntropySafe returns the parent entropy of the header in context ctx.
+func (h *Header) ParentEntropySafe(ctx common.Context) (*big.Int, error) {
+	return bigEntry("ParentEntropy", ctx, h.parentEntropy)
+}
+
+// ParentDeltaSSafe returns the parent delta entropy of the header in context
+// ctx.
+func (h *Header) ParentDeltaSSafe(ctx common.Context) (*big.Int, error) {
+	return bigEntry("ParentDeltaS", ctx, h.parentDeltaS)
+}
+
+// bigEntry returns the entry for context ctx of the integer field of the given
+// Go name, failing if it is missing.
+func bigEntry(name string, ctx common.Context, values []*big.Int) (*big.Int, error) {
+	if err := checkEntry(name, ctx, len(values)); err != nil {
+		return nil, err
+	}
+	if values[ctx] == nil {
+		return nil, malformedField(name, int(ctx), "an integer", "none")
+	}
+	return values[ctx], nil
+}
+
+// checkEntry checks that a per context field of the given Go name, holding
+// entries, has an entry for context ctx.
+func checkEntry(name string, ctx common.Context, entries int) error {
+	if !ctx.Valid() {
+		return fmt.Errorf("%w: %d, want 0 to %d", ErrInvalidContext, int(ctx), common.HierarchyDepth-1)
+	}
+	if int(ctx) >= entries {
+		return malformedField(name, -1, fmt.Sprintf("%d elements", common.HierarchyDepth), fmt.Sprint(entries))
+	}
+	return nil
+}
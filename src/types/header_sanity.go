@@ -0,0 +1,107 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// Bounds enforced by Header.SanityCheck. They are far above anything a valid
+// header carries, but keep hostile ones from making later processing
+// allocate or compute without limit.
+const (
+	MaxExtraSize      = 100 * 1024 // Maximum length of the extra data field
+	maxNumberBits     = 64         // Block numbers are used as uint64
+	maxDifficultyBits = 80         // Difficulty never came close to 2^80
+	maxBigFieldBits   = 256        // Base fee and entropy values
+)
+
+var ErrMalformedHeader = errors.New("malformed header")
+
+// SanityCheck checks a decoded header for the structural invariants the rest
+// of the code assumes: one entry per context in every per context field, a
+// location within the hierarchy set by common.SetHierarchy, no missing
+// integers, numbers fitting uint64 and bounded difficulty, base fee, entropy
+// and extra data. Decoding does not enforce them, so headers from
+// untrusted sources should be checked before use, either explicitly or by
+// enabling DecodeLimits.CheckHeaders. A violation is reported with a
+// FieldError wrapping ErrMalformedHeader, where it would otherwise surface as
+// a panic, e.g. in SealHash.
+func (h *Header) SanityCheck() error {
+	lengths := []struct {
+		name string
+		len  int
+	}{
+		{"ParentHash", len(h.parentHash)},
+		{"ManifestHash", len(h.manifestHash)},
+		{"ParentEntropy", len(h.parentEntropy)},
+		{"ParentDeltaS", len(h.parentDeltaS)},
+		{"Number", len(h.number)},
+	}
+	for _, field := range lengths {
+		if field.len != common.HierarchyDepth {
+			return malformedField(field.name, -1, fmt.Sprintf("%d elements", common.HierarchyDepth), fmt.Sprint(field.len))
+		}
+	}
+	if len(h.location) >= common.HierarchyDepth {
+		return malformedField("Location", -1, fmt.Sprintf("at most %d bytes", common.HierarchyDepth-1), fmt.Sprintf("%d bytes", len(h.location)))
+	}
+	if !h.location.Valid() {
+		hierarchy := common.Hierarchy()
+		return malformedField("Location", -1, fmt.Sprintf("a chain of %d regions of %d zones", hierarchy.Regions, hierarchy.Zones), fmt.Sprint(h.location))
+	}
+	for ctx := 0; ctx < common.HierarchyDepth; ctx++ {
+		if err := checkBig("Number", ctx, h.number[ctx], maxNumberBits); err != nil {
+			return err
+		}
+		if err := checkBig("ParentEntropy", ctx, h.parentEntropy[ctx], maxBigFieldBits); err != nil {
+			return err
+		}
+		if err := checkBig("ParentDeltaS", ctx, h.parentDeltaS[ctx], maxBigFieldBits); err != nil {
+			return err
+		}
+	}
+	if err := checkBig("Difficulty", -1, h.difficulty, maxDifficultyBits); err != nil {
+		return err
+	}
+	if err := checkBig("BaseFee", -1, h.baseFee, maxBigFieldBits); err != nil {
+		return err
+	}
+	if len(h.extra) > MaxExtraSize {
+		return malformedField("Extra", -1, fmt.Sprintf("at most %d bytes", MaxExtraSize), fmt.Sprintf("%d bytes", len(h.extra)))
+	}
+	return nil
+}
+
+// checkBig checks that an integer header field is present, non-negative and
+// at most bits long. Per context fields are checked per context, ctx is
+// negative for the others.
+func checkBig(name string, ctx int, value *big.Int, bits int) error {
+	switch {
+	case value == nil:
+		return malformedField(name, ctx, "an integer", "none")
+	case value.Sign() < 0:
+		return malformedField(name, ctx, "a non-negative integer", value.String())
+	case value.BitLen() > bits:
+		return malformedField(name, ctx, fmt.Sprintf("at most %d bits", bits), fmt.Sprintf("%d bits", value.BitLen()))
+	}
+	return nil
+}
+
+// malformedField returns the FieldError of a header field, given by its Go
+// name in extheader, violating a structural invariant. The entries of per
+// context fields are named along with their context, ctx is negative for the
+// others.
+func malformedField(name string, ctx int, expected, got string) *FieldError {
+	err := &FieldError{Type: "Header", Index: -1, Field: headerJSONName(name), Expected: expected, Got: got, Err: ErrMalformedHeader}
+	if field, ok := reflect.TypeOf(extheader{}).FieldByName(name); ok {
+		err.Index = field.Index[0]
+	}
+	if ctx >= 0 {
+		err.Field = fmt.Sprintf("%s[%d]", err.Field, ctx)
+	}
+	return err
+}
@@ -0,0 +1,606 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/internal/protowire"
+)
+
+// The protobuf encoding of headers, blocks and transactions follows the schema
+// in proto_block.proto, the layout of go-quai's protobuf messages. It is an
+// alternative to RLP for services which moved their internal transport to
+// protobuf; the hashes of the decoded values are the same either way.
+
+var ErrInvalidProto = errors.New("invalid protobuf encoding")
+
+// MarshalProto encodes the header as a ProtoHeader message.
+func (h *Header) MarshalProto() ([]byte, error) {
+	return h.appendProto(nil), nil
+}
+
+func (h *Header) appendProto(b []byte) []byte {
+	for _, hash := range h.parentHash {
+		b = appendProtoHash(b, 1, hash)
+	}
+	b = appendProtoHash(b, 2, h.uncleHash)
+	b = appendProtoValue(b, 3, protoAddressBytes(h.coinbase))
+	b = appendProtoHash(b, 4, h.root)
+	b = appendProtoHash(b, 5, h.txHash)
+	b = appendProtoHash(b, 6, h.etxHash)
+	b = appendProtoHash(b, 7, h.etxRollupHash)
+	for _, hash := range h.manifestHash {
+		b = appendProtoHash(b, 8, hash)
+	}
+	b = appendProtoHash(b, 9, h.receiptHash)
+	b = appendProtoBig(b, 10, h.difficulty)
+	for _, list := range []struct {
+		num    int
+		values []*big.Int
+	}{{11, h.parentEntropy}, {12, h.parentDeltaS}, {13, h.number}} {
+		for _, value := range list.values {
+			// Repeated fields cannot skip entries, missing ones encode as zero
+			b = protowire.AppendBytes(b, list.num, protoBigBytes(value))
+		}
+	}
+	b = protowire.AppendVarint(b, 14, h.gasLimit)
+	b = protowire.AppendVarint(b, 15, h.gasUsed)
+	b = appendProtoBig(b, 16, h.baseFee)
+	b = appendProtoValue(b, 17, h.location)
+	b = protowire.AppendVarint(b, 18, h.time)
+	b = protowire.AppendBytes(b, 19, h.extra)
+	b = appendProtoHash(b, 20, h.mixHash)
+	return protowire.AppendVarint(b, 21, h.nonce.Uint64())
+}
+
+// UnmarshalProto decodes a ProtoHeader message into h. Like RLP decoding, it
+// leaves the structure of the header to SanityCheck unless
+// DecodeLimits.CheckHeaders is set.
+func (h *Header) UnmarshalProto(input []byte) error {
+	var eh extheader
+	err := protowire.Range(input, func(f protowire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			err = appendProtoHashField(&eh.ParentHash, f)
+		case 2:
+			eh.UncleHash, err = protoHashField(f)
+		case 3:
+			var value []byte
+			if value, err = protoValueField(f); err == nil {
+				eh.Coinbase = common.BytesToAddress(value)
+			}
+		case 4:
+			eh.Root, err = protoHashField(f)
+		case 5:
+			eh.TxHash, err = protoHashField(f)
+		case 6:
+			eh.EtxHash, err = protoHashField(f)
+		case 7:
+			eh.EtxRollupHash, err = protoHashField(f)
+		case 8:
+			err = appendProtoHashField(&eh.ManifestHash, f)
+		case 9:
+			eh.ReceiptHash, err = protoHashField(f)
+		case 10:
+			eh.Difficulty, err = protoBigField(f)
+		case 11:
+			err = appendProtoBigField(&eh.ParentEntropy, f)
+		case 12:
+			err = appendProtoBigField(&eh.ParentDeltaS, f)
+		case 13:
+			err = appendProtoBigField(&eh.Number, f)
+		case 14:
+			eh.GasLimit, err = protoVarintField(f)
+		case 15:
+			eh.GasUsed, err = protoVarintField(f)
+		case 16:
+			eh.BaseFee, err = protoBigField(f)
+		case 17:
+			var value []byte
+			if value, err = protoValueField(f); err == nil {
+				eh.Location = common.Location(value)
+			}
+		case 18:
+			eh.Time, err = protoVarintField(f)
+		case 19:
+			eh.Extra, err = protoBytesField(f)
+		case 20:
+			eh.MixHash, err = protoHashField(f)
+		case 21:
+			var nonce uint64
+			if nonce, err = protoVarintField(f); err == nil {
+				eh.Nonce = EncodeNonce(nonce)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w: header: %v", ErrInvalidProto, err)
+	}
+	h.setExt(&eh)
+
+	if decodeLimits.Load().CheckHeaders {
+		return h.SanityCheck()
+	}
+	return nil
+}
+
+// MarshalProto encodes the transaction as a ProtoTransaction message. Only the
+// built-in transaction types have a protobuf encoding.
+func (tx *Transaction) MarshalProto() ([]byte, error) {
+	return tx.appendProto(nil)
+}
+
+func (tx *Transaction) appendProto(b []byte) ([]byte, error) {
+	appendCommon := func(b []byte, chainID *big.Int, nonce uint64, tipCap, feeCap *big.Int, gas uint64, to *common.Address, value *big.Int, data []byte, accessList AccessList) []byte {
+		b = protowire.AppendVarint(b, 1, uint64(tx.Type()))
+		if to != nil {
+			b = protowire.AppendBytes(b, 2, protoAddressBytes(*to))
+		}
+		b = protowire.AppendVarint(b, 3, nonce)
+		b = appendProtoBig(b, 4, value)
+		b = protowire.AppendVarint(b, 5, gas)
+		b = protowire.AppendBytes(b, 6, data)
+		b = appendProtoBig(b, 7, chainID)
+		b = appendProtoBig(b, 8, feeCap)
+		b = appendProtoBig(b, 9, tipCap)
+		return protowire.AppendBytes(b, 10, appendProtoAccessList(nil, accessList))
+	}
+	appendSignature := func(b []byte, v, r, s *big.Int) []byte {
+		b = appendProtoBig(b, 11, v)
+		b = appendProtoBig(b, 12, r)
+		return appendProtoBig(b, 13, s)
+	}
+	switch inner := tx.inner.(type) {
+	case *InternalTx:
+		b = appendCommon(b, inner.ChainID, inner.Nonce, inner.GasTipCap, inner.GasFeeCap, inner.Gas, inner.To, inner.Value, inner.Data, inner.AccessList)
+		return appendSignature(b, inner.V, inner.R, inner.S), nil
+	case *ExternalTx:
+		b = appendCommon(b, inner.ChainID, inner.Nonce, inner.GasTipCap, inner.GasFeeCap, inner.Gas, inner.To, inner.Value, inner.Data, inner.AccessList)
+		return protowire.AppendBytes(b, 19, protoAddressBytes(inner.Sender)), nil
+	case *InternalToExternalTx:
+		b = appendCommon(b, inner.ChainID, inner.Nonce, inner.GasTipCap, inner.GasFeeCap, inner.Gas, inner.To, inner.Value, inner.Data, inner.AccessList)
+		b = appendSignature(b, inner.V, inner.R, inner.S)
+		b = protowire.AppendVarint(b, 14, inner.ETXGasLimit)
+		b = appendProtoBig(b, 15, inner.ETXGasPrice)
+		b = appendProtoBig(b, 16, inner.ETXGasTip)
+		b = protowire.AppendBytes(b, 17, inner.ETXData)
+		return protowire.AppendBytes(b, 18, appendProtoAccessList(nil, inner.ETXAccessList)), nil
+	default:
+		return nil, fmt.Errorf("%w: 0x%02x has no protobuf encoding", ErrTxTypeNotSupported, tx.Type())
+	}
+}
+
+// protoTx holds the fields of a ProtoTransaction message.
+type protoTx struct {
+	typ                             uint64
+	to                              *common.Address
+	nonce, gas, etxGasLimit         uint64
+	value, chainID, feeCap, tipCap  *big.Int
+	v, r, s, etxGasPrice, etxGasTip *big.Int
+	data, etxData                   []byte
+	accessList, etxAccessList       AccessList
+	sender                          common.Address
+}
+
+// UnmarshalProto decodes a ProtoTransaction message into tx.
+func (tx *Transaction) UnmarshalProto(input []byte) error {
+	var dec protoTx
+	err := protowire.Range(input, func(f protowire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			dec.typ, err = protoVarintField(f)
+		case 2:
+			var to []byte
+			if to, err = protoBytesField(f); err == nil {
+				addr := common.BytesToAddress(to)
+				dec.to = &addr
+			}
+		case 3:
+			dec.nonce, err = protoVarintField(f)
+		case 4:
+			dec.value, err = protoBigField(f)
+		case 5:
+			dec.gas, err = protoVarintField(f)
+		case 6:
+			dec.data, err = protoBytesField(f)
+		case 7:
+			dec.chainID, err = protoBigField(f)
+		case 8:
+			dec.feeCap, err = protoBigField(f)
+		case 9:
+			dec.tipCap, err = protoBigField(f)
+		case 10:
+			dec.accessList, err = protoAccessListField(f)
+		case 11:
+			dec.v, err = protoBigField(f)
+		case 12:
+			dec.r, err = protoBigField(f)
+		case 13:
+			dec.s, err = protoBigField(f)
+		case 14:
+			dec.etxGasLimit, err = protoVarintField(f)
+		case 15:
+			dec.etxGasPrice, err = protoBigField(f)
+		case 16:
+			dec.etxGasTip, err = protoBigField(f)
+		case 17:
+			dec.etxData, err = protoBytesField(f)
+		case 18:
+			dec.etxAccessList, err = protoAccessListField(f)
+		case 19:
+			var sender []byte
+			if sender, err = protoBytesField(f); err == nil {
+				dec.sender = common.BytesToAddress(sender)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w: transaction: %v", ErrInvalidProto, err)
+	}
+	var inner TxData
+	switch dec.typ {
+	case InternalTxType:
+		inner = &InternalTx{
+			ChainID: dec.chainID, Nonce: dec.nonce, GasTipCap: dec.tipCap, GasFeeCap: dec.feeCap, Gas: dec.gas,
+			To: dec.to, Value: dec.value, Data: dec.data, AccessList: dec.accessList,
+			V: dec.v, R: dec.r, S: dec.s,
+		}
+	case ExternalTxType:
+		inner = &ExternalTx{
+			ChainID: dec.chainID, Nonce: dec.nonce, GasTipCap: dec.tipCap, GasFeeCap: dec.feeCap, Gas: dec.gas,
+			To: dec.to, Value: dec.value, Data: dec.data, AccessList: dec.accessList,
+			Sender: dec.sender,
+		}
+	case InternalToExternalTxType:
+		inner = &InternalToExternalTx{
+			ChainID: dec.chainID, Nonce: dec.nonce, GasTipCap: dec.tipCap, GasFeeCap: dec.feeCap, Gas: dec.gas,
+			To: dec.to, Value: dec.value, Data: dec.data, AccessList: dec.accessList,
+			ETXGasLimit: dec.etxGasLimit, ETXGasPrice: dec.etxGasPrice, ETXGasTip: dec.etxGasTip,
+			ETXData: dec.etxData, ETXAccessList: dec.etxAccessList,
+			V: dec.v, R: dec.r, S: dec.s,
+		}
+	default:
+		return fmt.Errorf("%w: 0x%02x has no protobuf encoding", ErrTxTypeNotSupported, dec.typ)
+	}
+	tx.setDecoded(inner, 0)
+	return nil
+}
+
+// MarshalProto encodes the block as a ProtoBlock message.
+func (b *Block) MarshalProto() ([]byte, error) {
+	var (
+		body []byte
+		err  error
+	)
+	if body, err = appendProtoTxs(body, 1, b.transactions); err != nil {
+		return nil, err
+	}
+	var uncles []byte
+	for _, uncle := range b.uncles {
+		uncles = protowire.AppendBytes(uncles, 1, uncle.appendProto(nil))
+	}
+	body = protowire.AppendBytes(body, 2, uncles)
+	if body, err = appendProtoTxs(body, 3, b.extTransactions); err != nil {
+		return nil, err
+	}
+	var manifest []byte
+	for _, hash := range b.subManifest {
+		manifest = appendProtoHash(manifest, 1, hash)
+	}
+	body = protowire.AppendBytes(body, 4, manifest)
+
+	enc := protowire.AppendBytes(nil, 1, b.header.appendProto(nil))
+	return protowire.AppendBytes(enc, 2, body), nil
+}
+
+// appendProtoTxs appends the field num holding txs as a ProtoTransactions
+// message.
+func appendProtoTxs(b []byte, num int, txs Transactions) ([]byte, error) {
+	var list []byte
+	for i, tx := range txs {
+		enc, err := tx.appendProto(nil)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		list = protowire.AppendBytes(list, 1, enc)
+	}
+	return protowire.AppendBytes(b, num, list), nil
+}
+
+// UnmarshalProto decodes a ProtoBlock message into b. Like DecodeRLP, it checks
+// the message against the current DecodeLimits, the size of the block and of
+// every transaction list and the number of uncles before decoding any of them,
+// and runs the uncle check of the limits on the decoded block.
+func (b *Block) UnmarshalProto(input []byte) error {
+	limits := decodeLimits.Load()
+	if err := limits.checkBlockBytes(uint64(len(input))); err != nil {
+		return err
+	}
+	var (
+		header   *Header
+		body     []byte
+		bodySeen bool
+	)
+	err := protowire.Range(input, func(f protowire.Field) error {
+		var err error
+		switch f.Num {
+		case 1:
+			var enc []byte
+			if enc, err = protoBytesField(f); err == nil {
+				header = new(Header)
+				err = header.UnmarshalProto(enc)
+			}
+		case 2:
+			body, err = protoBytesField(f)
+			bodySeen = true
+		}
+		return err
+	})
+	if err != nil {
+		return protoBlockError("", err)
+	}
+	if header == nil {
+		return fmt.Errorf("%w: block without header", ErrInvalidProto)
+	}
+	var (
+		txs, etxs Transactions
+		uncles    []*Header
+		manifest  BlockManifest
+	)
+	if bodySeen {
+		err = protowire.Range(body, func(f protowire.Field) error {
+			enc, err := protoBytesField(f)
+			if err != nil {
+				return err
+			}
+			switch f.Num {
+			case 1:
+				txs, err = protoTxsField(limits, "txs", enc)
+			case 2:
+				if err := limits.checkUncleCount(protoCount(enc, 1)); err != nil {
+					return err
+				}
+				err = protowire.Range(enc, func(f protowire.Field) error {
+					enc, err := protoBytesField(f)
+					if err != nil || f.Num != 1 {
+						return err
+					}
+					uncle := new(Header)
+					if err := uncle.UnmarshalProto(enc); err != nil {
+						return fmt.Errorf("uncle %d: %w", len(uncles), err)
+					}
+					uncles = append(uncles, uncle)
+					return nil
+				})
+			case 3:
+				etxs, err = protoTxsField(limits, "etxs", enc)
+			case 4:
+				err = protowire.Range(enc, func(f protowire.Field) error {
+					if f.Num != 1 {
+						return nil
+					}
+					return appendProtoHashField((*[]common.Hash)(&manifest), f)
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return protoBlockError("body: ", err)
+		}
+	}
+	if err := limits.checkUncles(header, uncles); err != nil {
+		return err
+	}
+	b.header, b.uncles, b.transactions, b.extTransactions, b.subManifest = header, uncles, txs, etxs, manifest
+	return nil
+}
+
+// protoBlockError wraps an error decoding a block in ErrInvalidProto, unless
+// it comes from decoding a header or transaction which already reported it.
+func protoBlockError(prefix string, err error) error {
+	if errors.Is(err, ErrInvalidProto) || errors.Is(err, ErrTxTypeNotSupported) || isLimitError(err) {
+		return fmt.Errorf("%s%w", prefix, err)
+	}
+	return fmt.Errorf("%w: %s%v", ErrInvalidProto, prefix, err)
+}
+
+// protoTxsField decodes a ProtoTransactions message, the transaction list
+// named list, after checking the number and sizes of its transactions against
+// the limits.
+func protoTxsField(limits *DecodeLimits, list string, enc []byte) (Transactions, error) {
+	if err := limits.checkTxCount(list, protoCount(enc, 1)); err != nil {
+		return nil, err
+	}
+	var txs Transactions
+	err := protowire.Range(enc, func(f protowire.Field) error {
+		enc, err := protoBytesField(f)
+		if err != nil || f.Num != 1 {
+			return err
+		}
+		if err := limits.checkTxSize(list, len(txs), len(enc)); err != nil {
+			return err
+		}
+		tx := new(Transaction)
+		if err := tx.UnmarshalProto(enc); err != nil {
+			return fmt.Errorf("transaction %d: %w", len(txs), err)
+		}
+		txs = append(txs, tx)
+		return nil
+	})
+	return txs, err
+}
+
+// protoCount returns the number of fields num in a message, without decoding
+// them. Malformed messages are counted up to the first error, which decoding
+// reports.
+func protoCount(enc []byte, num int) int {
+	count := 0
+	protowire.Range(enc, func(f protowire.Field) error {
+		if f.Num == num {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// appendProtoValue appends the field num holding a message wrapping value,
+// such as ProtoAddress or ProtoLocation.
+func appendProtoValue(b []byte, num int, value []byte) []byte {
+	return protowire.AppendBytes(b, num, protowire.AppendBytes(nil, 1, value))
+}
+
+// appendProtoHash appends the field num holding hash as a ProtoHash message.
+func appendProtoHash(b []byte, num int, hash common.Hash) []byte {
+	return appendProtoValue(b, num, hash[:])
+}
+
+// appendProtoBig appends the field num holding value as big-endian bytes,
+// omitting nil values.
+func appendProtoBig(b []byte, num int, value *big.Int) []byte {
+	if value == nil {
+		return b
+	}
+	return protowire.AppendBytes(b, num, value.Bytes())
+}
+
+// protoBigBytes returns the big-endian bytes of value, none for nil.
+func protoBigBytes(value *big.Int) []byte {
+	if value == nil {
+		return nil
+	}
+	return value.Bytes()
+}
+
+// protoAddressBytes returns the 20 bytes of address, zero for an address
+// without a scope like RLP encodes it.
+func protoAddressBytes(address common.Address) []byte {
+	b := address.Bytes20()
+	return b[:]
+}
+
+// appendProtoAccessList appends the fields of a ProtoAccessList message.
+func appendProtoAccessList(b []byte, list AccessList) []byte {
+	for _, tuple := range list {
+		b = protowire.AppendBytes(b, 1, protowire.AppendBytes(nil, 1, protoAddressBytes(tuple.Address)))
+	}
+	return b
+}
+
+// protoBytesField returns a copy of the content of a length-delimited field.
+func protoBytesField(f protowire.Field) ([]byte, error) {
+	if f.Type != protowire.BytesType {
+		return nil, fmt.Errorf("field %d: wire type %d, want bytes", f.Num, f.Type)
+	}
+	return common.CopyBytes(f.Bytes), nil
+}
+
+// protoVarintField returns the value of a varint field.
+func protoVarintField(f protowire.Field) (uint64, error) {
+	if f.Type != protowire.VarintType {
+		return 0, fmt.Errorf("field %d: wire type %d, want varint", f.Num, f.Type)
+	}
+	return f.Varint, nil
+}
+
+// protoBigField returns the integer held by a field as big-endian bytes.
+func protoBigField(f protowire.Field) (*big.Int, error) {
+	if f.Type != protowire.BytesType {
+		return nil, fmt.Errorf("field %d: wire type %d, want bytes", f.Num, f.Type)
+	}
+	return new(big.Int).SetBytes(f.Bytes), nil
+}
+
+// appendProtoBigField appends the integer held by a field to list.
+func appendProtoBigField(list *[]*big.Int, f protowire.Field) error {
+	value, err := protoBigField(f)
+	if err != nil {
+		return err
+	}
+	*list = append(*list, value)
+	return nil
+}
+
+// protoValueField returns the value of the wrapper message held by a field,
+// such as ProtoHash or ProtoLocation.
+func protoValueField(f protowire.Field) ([]byte, error) {
+	msg, err := protoBytesField(f)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	err = protowire.Range(msg, func(inner protowire.Field) error {
+		if inner.Num == 1 {
+			value, err = protoBytesField(inner)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("field %d: %v", f.Num, err)
+	}
+	return value, nil
+}
+
+// protoHashField returns the hash of the ProtoHash message held by a field.
+func protoHashField(f protowire.Field) (common.Hash, error) {
+	value, err := protoValueField(f)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(value) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("field %d: hash of %d bytes, want %d", f.Num, len(value), common.HashLength)
+	}
+	return common.BytesToHash(value), nil
+}
+
+// appendProtoHashField appends the hash of the ProtoHash message held by a
+// field to list.
+func appendProtoHashField(list *[]common.Hash, f protowire.Field) error {
+	hash, err := protoHashField(f)
+	if err != nil {
+		return err
+	}
+	*list = append(*list, hash)
+	return nil
+}
+
+// protoAccessListField decodes the ProtoAccessList message held by a field.
+func protoAccessListField(f protowire.Field) (AccessList, error) {
+	msg, err := protoBytesField(f)
+	if err != nil {
+		return nil, err
+	}
+	list := AccessList{}
+	err = protowire.Range(msg, func(tuple protowire.Field) error {
+		if tuple.Num != 1 {
+			return nil
+		}
+		enc, err := protoBytesField(tuple)
+		if err != nil {
+			return err
+		}
+		var address []byte
+		err = protowire.Range(enc, func(inner protowire.Field) error {
+			var err error
+			if inner.Num == 1 {
+				address, err = protoBytesField(inner)
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		list = append(list, AccessTuple{Address: common.BytesToAddress(address)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("field %d: %v", f.Num, err)
+	}
+	return list, nil
+}
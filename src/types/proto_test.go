@@ -0,0 +1,257 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+// Protobuf fixtures encoded by protobuf-go, the runtime of go-quai's generated
+// code, from the schema in proto_block.proto: a header and a block holding
+// fixtureHeader(common.HierarchyDepth) and fixtureTxs, and a header lacking the
+// zone entries of its per context fields.
+const (
+	protoHeaderFixture      = "0a220a2001010101010101010101010101010101010101010101010101010101010101010a220a2002020202020202020202020202020202020202020202020202020202020202020a220a20030303030303030303030303030303030303030303030303030303030303030312220a2004040404040404040404040404040404040404040404040404040404040404041a160a140a1b2c3d4e5f60718293a4b5c6d7e8f90123456722220a2005050505050505050505050505050505050505050505050505050505050505052a220a20060606060606060606060606060606060606060606060606060606060606060632220a2007070707070707070707070707070707070707070707070707070707070707073a220a20080808080808080808080808080808080808080808080808080808080808080842220a20090909090909090909090909090909090909090909090909090909090909090942220a200a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a42220a200b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b4a220a200c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c52030f42405a01105a01115a01126201206201216201226a0201006a0201016a02010270c096b1027888a4018201043b9aca008a01040a020001900180e2cfaa069a010471756169a201220a200d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0da801b424"
+	protoShortHeaderFixture = "0a220a2001010101010101010101010101010101010101010101010101010101010101010a220a20020202020202020202020202020202020202020202020202020202020202020212220a2004040404040404040404040404040404040404040404040404040404040404041a160a140a1b2c3d4e5f60718293a4b5c6d7e8f90123456722220a2005050505050505050505050505050505050505050505050505050505050505052a220a20060606060606060606060606060606060606060606060606060606060606060632220a2007070707070707070707070707070707070707070707070707070707070707073a220a20080808080808080808080808080808080808080808080808080808080808080842220a20090909090909090909090909090909090909090909090909090909090909090942220a200a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a4a220a200c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c52030f42405a01105a01116201206201216a0201006a02010170c096b1027888a4018201043b9aca008a01040a020001900180e2cfaa069a010471756169a201220a200d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0da801b424"
+	protoBlockFixture       = "0ab9040a220a2001010101010101010101010101010101010101010101010101010101010101010a220a2002020202020202020202020202020202020202020202020202020202020202020a220a20030303030303030303030303030303030303030303030303030303030303030312220a2004040404040404040404040404040404040404040404040404040404040404041a160a140a1b2c3d4e5f60718293a4b5c6d7e8f90123456722220a2005050505050505050505050505050505050505050505050505050505050505052a220a20060606060606060606060606060606060606060606060606060606060606060632220a2007070707070707070707070707070707070707070707070707070707070707073a220a20080808080808080808080808080808080808080808080808080808080808080842220a20090909090909090909090909090909090909090909090909090909090909090942220a200a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a42220a200b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b4a220a200c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c52030f42405a01105a01115a01126201206201216201226a0201006a0201016a02010270c096b1027888a4018201043b9aca008a01040a020001900180e2cfaa069a010471756169a201220a200d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0da801b424129c080ad0020a8f010800121400112233445566778899aabbccddeeff0011223318012201012888a4013202dead3a0223284201024a010152180a160a1400112233445566778899aabbccddeeff001122335a0062201e9ce8a2d57265d0f1d8c07221b8cce2c109a91dc9d27b96a1c5b97a7f55ef4f6a2074deaa4ef410d645523da23d047e418cd4109f64104994e9c9403824fcd26ab30abb010802121400112233445566778899aabbccddeeff0011223318012201012888a4013202dead3a0223284201024a010152180a160a1400112233445566778899aabbccddeeff001122335a010162201e9ce8a2d57265d0f1d8c07221b8cce2c109a91dc9d27b96a1c5b97a7f55ef4f6a2074deaa4ef410d645523da23d047e418cd4109f64104994e9c9403824fcd26ab370a08d067a0103820101028a0102beef9201180a160a14fedcba9876543210fedcba9876543210fedcba9812bc040ab9040a220a2001010101010101010101010101010101010101010101010101010101010101010a220a2002020202020202020202020202020202020202020202020202020202020202020a220a20030303030303030303030303030303030303030303030303030303030303030312220a2004040404040404040404040404040404040404040404040404040404040404041a160a140a1b2c3d4e5f60718293a4b5c6d7e8f90123456722220a2005050505050505050505050505050505050505050505050505050505050505052a220a20060606060606060606060606060606060606060606060606060606060606060632220a2007070707070707070707070707070707070707070707070707070707070707073a220a20080808080808080808080808080808080808080808080808080808080808080842220a20090909090909090909090909090909090909090909090909090909090909090942220a200a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a0a42220a200b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b4a220a200c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c0c52030f42405a01105a01115a01126201206201216201226a0201006a0201016a02010270c096b1027888a4018201043b9aca008a01040a020001900180e2cfaa069a010471756169a201220a200d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0d0da801b4241a620a600801121400112233445566778899aabbccddeeff0011223318012201012888a4013202dead3a0223284201024a010152180a160a1400112233445566778899aabbccddeeff001122339a0114fedcba9876543210fedcba9876543210fedcba9822240a220a200e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e0e"
+)
+
+var (
+	fixtureTo     = common.BytesToAddress(common.FromHex("00112233445566778899aabbccddeeff00112233"))
+	fixtureSender = common.BytesToAddress(common.FromHex("fedcba9876543210fedcba9876543210fedcba98"))
+)
+
+// fixtureHeader returns the header of the protobuf fixtures, with the given
+// number of entries in its per context fields.
+func fixtureHeader(entries int) *Header {
+	h := &Header{
+		uncleHash:     common.BytesToHash(bytes.Repeat([]byte{0x04}, 32)),
+		coinbase:      common.BytesToAddress(common.FromHex("0a1b2c3d4e5f60718293a4b5c6d7e8f901234567")),
+		root:          common.BytesToHash(bytes.Repeat([]byte{0x05}, 32)),
+		txHash:        common.BytesToHash(bytes.Repeat([]byte{0x06}, 32)),
+		etxHash:       common.BytesToHash(bytes.Repeat([]byte{0x07}, 32)),
+		etxRollupHash: common.BytesToHash(bytes.Repeat([]byte{0x08}, 32)),
+		receiptHash:   common.BytesToHash(bytes.Repeat([]byte{0x0c}, 32)),
+		difficulty:    big.NewInt(1000000),
+		gasLimit:      5000000,
+		gasUsed:       21000,
+		baseFee:       big.NewInt(1000000000),
+		location:      common.Location{0, 1},
+		time:          1700000000,
+		extra:         []byte("quai"),
+		mixHash:       common.BytesToHash(bytes.Repeat([]byte{0x0d}, 32)),
+		nonce:         EncodeNonce(0x1234),
+	}
+	for i := 0; i < entries; i++ {
+		h.parentHash = append(h.parentHash, common.BytesToHash(bytes.Repeat([]byte{byte(0x01 + i)}, 32)))
+		h.manifestHash = append(h.manifestHash, common.BytesToHash(bytes.Repeat([]byte{byte(0x09 + i)}, 32)))
+		h.parentEntropy = append(h.parentEntropy, big.NewInt(int64(0x10+i)))
+		h.parentDeltaS = append(h.parentDeltaS, big.NewInt(int64(0x20+i)))
+		h.number = append(h.number, big.NewInt(int64(0x100+i)))
+	}
+	return h
+}
+
+// fixtureTxs returns the transactions and external transactions of the block
+// fixture, one of every type with a protobuf encoding.
+func fixtureTxs() (txs, etxs Transactions) {
+	r, _ := new(big.Int).SetString("1e9ce8a2d57265d0f1d8c07221b8cce2c109a91dc9d27b96a1c5b97a7f55ef4f", 16)
+	s, _ := new(big.Int).SetString("74deaa4ef410d645523da23d047e418cd4109f64104994e9c9403824fcd26ab3", 16)
+	to := fixtureTo
+	txs = Transactions{
+		&Transaction{inner: &InternalTx{
+			ChainID: big.NewInt(9000), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000,
+			To: &to, Value: big.NewInt(1), Data: []byte{0xde, 0xad}, AccessList: AccessList{{Address: fixtureTo}},
+			V: new(big.Int), R: r, S: s,
+		}},
+		&Transaction{inner: &InternalToExternalTx{
+			ChainID: big.NewInt(9000), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000,
+			To: &to, Value: big.NewInt(1), Data: []byte{0xde, 0xad}, AccessList: AccessList{{Address: fixtureTo}},
+			ETXGasLimit: 100000, ETXGasPrice: big.NewInt(3), ETXGasTip: big.NewInt(2),
+			ETXData: []byte{0xbe, 0xef}, ETXAccessList: AccessList{{Address: fixtureSender}},
+			V: big.NewInt(1), R: r, S: s,
+		}},
+	}
+	etxs = Transactions{
+		&Transaction{inner: &ExternalTx{
+			ChainID: big.NewInt(9000), Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2), Gas: 21000,
+			To: &to, Value: big.NewInt(1), Data: []byte{0xde, 0xad}, AccessList: AccessList{{Address: fixtureTo}},
+			Sender: fixtureSender,
+		}},
+	}
+	return txs, etxs
+}
+
+// fixtureBlock returns the block of the protobuf fixture.
+func fixtureBlock() *Block {
+	txs, etxs := fixtureTxs()
+	manifest := BlockManifest{common.BytesToHash(bytes.Repeat([]byte{0x0e}, 32))}
+	return &Block{
+		header:          fixtureHeader(common.HierarchyDepth),
+		uncles:          []*Header{fixtureHeader(common.HierarchyDepth)},
+		transactions:    txs,
+		extTransactions: etxs,
+		subManifest:     manifest,
+	}
+}
+
+// Headers, transactions and blocks hash the same after a protobuf round trip.
+func TestProtoRoundTrip(t *testing.T) {
+	header := fixtureHeader(common.HierarchyDepth)
+	enc, err := header.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(Header)
+	if err := dec.UnmarshalProto(enc); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Hash() != header.Hash() {
+		t.Errorf("header hash %x after round trip, want %x", dec.Hash(), header.Hash())
+	}
+
+	txs, etxs := fixtureTxs()
+	for _, tx := range append(txs, etxs...) {
+		enc, err := tx.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec := new(Transaction)
+		if err := dec.UnmarshalProto(enc); err != nil {
+			t.Fatalf("transaction of type %d: %v", tx.Type(), err)
+		}
+		if dec.Hash() != tx.Hash() {
+			t.Errorf("transaction of type %d: hash %x after round trip, want %x", tx.Type(), dec.Hash(), tx.Hash())
+		}
+	}
+
+	block := fixtureBlock()
+	if enc, err = block.MarshalProto(); err != nil {
+		t.Fatal(err)
+	}
+	decBlock := new(Block)
+	if err := decBlock.UnmarshalProto(enc); err != nil {
+		t.Fatal(err)
+	}
+	checkBlock(t, decBlock, block)
+}
+
+// checkBlock compares the hashes of the header and body of a decoded block
+// with the ones of the block it encodes.
+func checkBlock(t *testing.T, have, want *Block) {
+	t.Helper()
+	if have.Hash() != want.Hash() {
+		t.Errorf("block hash %x, want %x", have.Hash(), want.Hash())
+	}
+	for _, list := range []struct {
+		name       string
+		have, want Transactions
+	}{{"txs", have.Transactions(), want.Transactions()}, {"etxs", have.ExtTransactions(), want.ExtTransactions()}} {
+		if len(list.have) != len(list.want) {
+			t.Errorf("%d %s, want %d", len(list.have), list.name, len(list.want))
+			continue
+		}
+		for i := range list.want {
+			if list.have[i].Hash() != list.want[i].Hash() {
+				t.Errorf("%s %d: hash %x, want %x", list.name, i, list.have[i].Hash(), list.want[i].Hash())
+			}
+		}
+	}
+	if len(have.Uncles()) != len(want.Uncles()) || have.Uncles()[0].Hash() != want.Uncles()[0].Hash() {
+		t.Errorf("uncles %v, want %v", have.Uncles(), want.Uncles())
+	}
+	if len(have.SubManifest()) != len(want.SubManifest()) || have.SubManifest()[0] != want.SubManifest()[0] {
+		t.Errorf("manifest %v, want %v", have.SubManifest(), want.SubManifest())
+	}
+}
+
+// Messages encoded by protobuf-go after go-quai's schema decode to the values
+// they hold, and encode back to the same bytes.
+func TestProtoFixtures(t *testing.T) {
+	header := new(Header)
+	if err := header.UnmarshalProto(common.FromHex(protoHeaderFixture)); err != nil {
+		t.Fatal(err)
+	}
+	if want := fixtureHeader(common.HierarchyDepth); header.Hash() != want.Hash() {
+		t.Errorf("header hash %x, want %x", header.Hash(), want.Hash())
+	}
+	if enc, _ := header.MarshalProto(); !bytes.Equal(enc, common.FromHex(protoHeaderFixture)) {
+		t.Errorf("header encoding %x, want %s", enc, protoHeaderFixture)
+	}
+
+	block := new(Block)
+	if err := block.UnmarshalProto(common.FromHex(protoBlockFixture)); err != nil {
+		t.Fatal(err)
+	}
+	checkBlock(t, block, fixtureBlock())
+	if enc, _ := block.MarshalProto(); !bytes.Equal(enc, common.FromHex(protoBlockFixture)) {
+		t.Errorf("block encoding %x, want %s", enc, protoBlockFixture)
+	}
+}
+
+// Headers missing per context entries decode, leaving them to SanityCheck like
+// RLP decoding, unless the decode limits check headers.
+func TestProtoShortHeader(t *testing.T) {
+	defer SetDecodeLimits(CurrentDecodeLimits())
+
+	header := new(Header)
+	if err := header.UnmarshalProto(common.FromHex(protoShortHeaderFixture)); err != nil {
+		t.Fatal(err)
+	}
+	if number, err := header.NumberSafe(common.RegionCtx); err != nil || number.Int64() != 0x101 {
+		t.Errorf("region number %v, %v, want %d", number, err, 0x101)
+	}
+	if _, err := header.NumberSafe(common.ZoneCtx); !errors.Is(err, ErrMalformedHeader) {
+		t.Errorf("zone number error %v, want %v", err, ErrMalformedHeader)
+	}
+	if err := header.SanityCheck(); !errors.Is(err, ErrMalformedHeader) {
+		t.Errorf("sanity check %v, want %v", err, ErrMalformedHeader)
+	}
+	SetDecodeLimits(DecodeLimits{CheckHeaders: true})
+	if err := new(Header).UnmarshalProto(common.FromHex(protoShortHeaderFixture)); !errors.Is(err, ErrMalformedHeader) {
+		t.Errorf("checked decoding %v, want %v", err, ErrMalformedHeader)
+	}
+}
+
+// Protobuf blocks are held to the decode limits like RLP blocks.
+func TestBlockDecodeLimits(t *testing.T) {
+	defer SetDecodeLimits(CurrentDecodeLimits())
+
+	uncle := NewEmptyHeader()
+	block := NewBlock(NewEmptyHeader(), Transactions{signedTx(), signedTx(), signedTx()}, []*Header{uncle, uncle}, nil, nil)
+	protoEnc, err := block.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rlpEnc bytes.Buffer
+	if err := rlp.Encode(&rlpEnc, block); err != nil {
+		t.Fatal(err)
+	}
+	errUncle := errors.New("uncle rejected")
+	tests := []struct {
+		name   string
+		limits DecodeLimits
+		err    error
+	}{
+		{"no limits", DecodeLimits{}, nil},
+		{"default limits", DefaultDecodeLimits, nil},
+		{"block size", DecodeLimits{MaxBlockSize: 100}, ErrBlockTooLarge},
+		{"transaction count", DecodeLimits{MaxTxs: 2}, ErrTooManyTxs},
+		{"transaction size", DecodeLimits{MaxTxSize: 10}, ErrTxTooLarge},
+		{"uncle count", DecodeLimits{MaxUncles: 1}, ErrTooManyUncles},
+		{"uncle check", DecodeLimits{CheckUncle: func(header, uncle *Header) error { return errUncle }}, errUncle},
+	}
+	for _, tt := range tests {
+		SetDecodeLimits(tt.limits)
+		if err := new(Block).UnmarshalProto(protoEnc); !errors.Is(err, tt.err) {
+			t.Errorf("%s: protobuf error %v, want %v", tt.name, err, tt.err)
+		}
+		if err := rlp.DecodeBytes(rlpEnc.Bytes(), new(Block)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: RLP error %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
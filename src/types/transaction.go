@@ -122,6 +122,25 @@ func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
 	}
 }
 
+// MarshalBinary returns the canonical encoding of the transaction: the type
+// byte followed by the RLP list of its fields, as stored in the trie whose
+// root is txHash.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	err := tx.encodeTyped(&buf)
+	return buf.Bytes(), err
+}
+
+// UnmarshalBinary decodes the canonical encoding of transactions.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	inner, err := tx.decodeTyped(b)
+	if err != nil {
+		return err
+	}
+	tx.setDecoded(inner, len(b))
+	return nil
+}
+
 // decodeTyped decodes a typed transaction from the canonical format.
 func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 	if len(b) == 0 {
@@ -130,18 +149,18 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 	switch b[0] {
 	case InternalTxType:
 		var inner InternalTx
-		err := rlp.DecodeBytes(b[1:], &inner)
+		err := decodeTxData("InternalTx", b[1:], &inner)
 		return &inner, err
 	case ExternalTxType:
 		var inner ExternalTx
-		err := rlp.DecodeBytes(b[1:], &inner)
+		err := decodeTxData("ExternalTx", b[1:], &inner)
 		return &inner, err
 	case InternalToExternalTxType:
 		var inner InternalToExternalTx
-		err := rlp.DecodeBytes(b[1:], &inner)
+		err := decodeTxData("InternalToExternalTx", b[1:], &inner)
 		return &inner, err
 	default:
-		return nil, ErrTxTypeNotSupported
+		return decodeExtraTyped(b[0], b[1:])
 	}
 }
 
@@ -154,11 +173,113 @@ func (tx *Transaction) setDecoded(inner TxData, size int) {
 	}
 }
 
+// Size returns the length of the typed encoding of the transaction, which its
+// RLP encoding wraps in a string. Transactions which were not decoded are
+// encoded once to find out; those which cannot be encoded have size zero.
+func (tx *Transaction) Size() common.StorageSize {
+	if size := tx.size.Load(); size != nil {
+		return size.(common.StorageSize)
+	}
+	buf := encodeBufferPool.Get().(*bytes.Buffer)
+	defer encodeBufferPool.Put(buf)
+	buf.Reset()
+	if err := tx.encodeTyped(buf); err != nil {
+		return 0
+	}
+	size := common.StorageSize(buf.Len())
+	tx.size.Store(size)
+	return size
+}
+
 // Type returns the transaction type.
 func (tx *Transaction) Type() uint8 {
 	return tx.inner.txType()
 }
 
+// To returns the recipient address of the transaction, or nil for contract
+// creation.
+func (tx *Transaction) To() *common.Address {
+	to := tx.inner.to()
+	if to == nil {
+		return nil
+	}
+	cpy := *to
+	return &cpy
+}
+
+// ToChain returns the location of the chain the transaction is destined for,
+// resolved from the address space of its recipient, or nil if the recipient
+// lies outside every known address space. Contract creations execute in the
+// chain they originate from, so their destination is FromChain.
+func (tx *Transaction) ToChain() common.Location {
+	if loc := tx.toChain.Load(); loc != nil {
+		return loc.(common.Location)
+	}
+	var loc common.Location
+	if to := tx.inner.to(); to == nil {
+		loc = tx.FromChain()
+	} else if l := to.Location(); l != nil {
+		loc = *l
+	}
+	if loc != nil {
+		tx.toChain.Store(loc)
+	}
+	return loc
+}
+
+// FromChain returns the location of the chain the transaction originates from,
+// resolved from the address space of its sender. External transactions carry
+// their sender; for signed transactions the sender has to be recovered through
+// Sender first, otherwise nil is returned.
+func (tx *Transaction) FromChain() common.Location {
+	if loc := tx.fromChain.Load(); loc != nil {
+		return loc.(common.Location)
+	}
+	var from common.Address
+	switch inner := tx.inner.(type) {
+	case *ExternalTx:
+		from = inner.Sender
+	default:
+		cached, ok := tx.from.Load().(sigCache)
+		if !ok {
+			return nil
+		}
+		from = cached.from
+	}
+	loc := from.Location()
+	if loc == nil {
+		return nil
+	}
+	tx.fromChain.Store(*loc)
+	return *loc
+}
+
+// ConfirmationCtx returns the context of the chain dominating both the origin
+// and the destination of the transaction, at which an ETX emitted by it may be
+// confirmed. It returns -1 if either location is unknown.
+func (tx *Transaction) ConfirmationCtx() int {
+	if ctx := tx.confirmCtx.Load(); ctx != nil {
+		return ctx.(int)
+	}
+	from, to := tx.FromChain(), tx.ToChain()
+	if from == nil || to == nil {
+		return -1
+	}
+	ctx := to.CommonDom(from).Context()
+	tx.confirmCtx.Store(ctx)
+	return ctx
+}
+
+// Hash returns the transaction hash, the keccak256 of its canonical encoding.
+func (tx *Transaction) Hash() common.Hash {
+	if hash := tx.hash.Load(); hash != nil {
+		return hash.(common.Hash)
+	}
+	h := prefixedRlpHash(tx.Type(), tx.inner)
+	tx.hash.Store(h)
+	return h
+}
+
 // Transactions implements DerivableList for transactions.
 type Transactions []*Transaction
 
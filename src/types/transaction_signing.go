@@ -0,0 +1,248 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/crypto"
+)
+
+var (
+	ErrInvalidChainId  = errors.New("invalid chain id for signer")
+	ErrSenderNotInZone = errors.New("sender not in the zone of the signer")
+	errInvalidPubkey   = errors.New("invalid public key")
+)
+
+// sigCache is used to cache the derived sender and contains
+// the signer used to derive it.
+type sigCache struct {
+	signer Signer
+	from   common.Address
+}
+
+// Sender returns the address derived from the signature (V, R, S) using secp256k1
+// elliptic curve and an error if it failed deriving or upon an incorrect
+// signature.
+//
+// Sender may cache the address, allowing it to be used regardless of
+// signing method. The cache is invalidated if the cached signer does
+// not match the signer used in the current call.
+func Sender(signer Signer, tx *Transaction) (common.Address, error) {
+	if sc := tx.from.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		// If the signer used to derive from in a previous
+		// call is not the same as used current, invalidate
+		// the cache.
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+
+	addr, err := signer.Sender(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.from.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
+// Signer encapsulates transaction signature handling. The name of this type is slightly
+// misleading because Signers don't actually sign, they're just for validating and
+// processing of signatures.
+//
+// Note that this interface is not a stable API and may change at any time to accommodate
+// new protocol rules.
+type Signer interface {
+	// Sender returns the sender address of the transaction.
+	Sender(tx *Transaction) (common.Address, error)
+
+	// ChainID returns the chain id the signer accepts transactions of.
+	ChainID() *big.Int
+
+	// Location returns the zone whose transactions the signer handles.
+	Location() common.Location
+
+	// Hash returns 'signature hash', i.e. the transaction hash that is signed by the
+	// private key. This hash does not uniquely identify the transaction.
+	Hash(tx *Transaction) common.Hash
+
+	// Equal returns true if the given signer is the same as the receiver.
+	Equal(Signer) bool
+}
+
+// SignerV1 recovers the senders of the transactions of a zone chain. Signed
+// transactions carry a recovery id of 0 or 1 as V and commit to the chain id,
+// so they cannot be replayed on other networks, and must be sent from an
+// address of the zone, as told by the address prefix ranges. External
+// transactions are not signed, their sender is the one they carry.
+type SignerV1 struct {
+	chainId  *big.Int
+	location common.Location
+}
+
+// NewSigner returns a signer for the transactions of the zone at location on
+// the chain with the given id.
+func NewSigner(chainId *big.Int, location common.Location) Signer {
+	if chainId == nil {
+		chainId = new(big.Int)
+	}
+	return SignerV1{
+		chainId:  chainId,
+		location: location,
+	}
+}
+
+func (s SignerV1) Sender(tx *Transaction) (common.Address, error) {
+	if inner, ok := tx.inner.(*ExternalTx); ok {
+		return inner.Sender, nil
+	}
+	if tx.inner.chainID() == nil || tx.inner.chainID().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V, R, S := tx.inner.rawSignatureValues()
+	if V == nil || R == nil || S == nil {
+		return common.Address{}, ErrInvalidSig
+	}
+	addr, err := recoverPlain(s.Hash(tx), R, S, V)
+	if err != nil {
+		return common.Address{}, err
+	}
+	inZone, err := s.location.ContainsAddress(addr)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if !inZone {
+		return common.Address{}, ErrSenderNotInZone
+	}
+	return addr, nil
+}
+
+func (s SignerV1) ChainID() *big.Int {
+	return s.chainId
+}
+
+func (s SignerV1) Location() common.Location {
+	return s.location
+}
+
+func (s SignerV1) Equal(s2 Signer) bool {
+	x, ok := s2.(SignerV1)
+	return ok && x.chainId.Cmp(s.chainId) == 0 && bytes.Equal(x.location, s.location)
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s SignerV1) Hash(tx *Transaction) common.Hash {
+	switch inner := tx.inner.(type) {
+	case *InternalTx:
+		return prefixedRlpHash(tx.Type(), &internalTxSigData{
+			ChainID:    s.chainId,
+			Nonce:      inner.Nonce,
+			GasTipCap:  inner.GasTipCap,
+			GasFeeCap:  inner.GasFeeCap,
+			Gas:        inner.Gas,
+			To:         inner.To,
+			Value:      inner.Value,
+			Data:       inner.Data,
+			AccessList: inner.AccessList,
+		})
+	case *InternalToExternalTx:
+		return prefixedRlpHash(tx.Type(), &internalToExternalTxSigData{
+			ChainID:       s.chainId,
+			Nonce:         inner.Nonce,
+			GasTipCap:     inner.GasTipCap,
+			GasFeeCap:     inner.GasFeeCap,
+			Gas:           inner.Gas,
+			To:            inner.To,
+			Value:         inner.Value,
+			Data:          inner.Data,
+			AccessList:    inner.AccessList,
+			ETXGasLimit:   inner.ETXGasLimit,
+			ETXGasPrice:   inner.ETXGasPrice,
+			ETXGasTip:     inner.ETXGasTip,
+			ETXData:       inner.ETXData,
+			ETXAccessList: inner.ETXAccessList,
+		})
+	default:
+		// External transactions are not signed
+		return common.Hash{}
+	}
+}
+
+// internalTxSigData is the signed part of an internal transaction, its fields
+// without the signature values.
+type internalTxSigData struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nilString"`
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+}
+
+// internalToExternalTxSigData is the signed part of an internal to external
+// transaction, its fields without the signature values.
+type internalToExternalTxSigData struct {
+	ChainID       *big.Int
+	Nonce         uint64
+	GasTipCap     *big.Int
+	GasFeeCap     *big.Int
+	Gas           uint64
+	To            *common.Address `rlp:"nilString"`
+	Value         *big.Int
+	Data          []byte
+	AccessList    AccessList
+	ETXGasLimit   uint64
+	ETXGasPrice   *big.Int
+	ETXGasTip     *big.Int
+	ETXData       []byte
+	ETXAccessList AccessList
+}
+
+// recoverPlain recovers the address which signed sighash with the signature
+// values R, S and the recovery id V.
+func recoverPlain(sighash common.Hash, R, S, Vb *big.Int) (common.Address, error) {
+	if Vb.BitLen() > 8 {
+		return common.Address{}, ErrInvalidSig
+	}
+	V := byte(Vb.Uint64())
+	if !crypto.ValidateSignatureValues(V, R, S) {
+		return common.Address{}, ErrInvalidSig
+	}
+	// encode the signature in uncompressed format
+	r, s := R.Bytes(), S.Bytes()
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(s):64], s)
+	sig[64] = V
+	// recover the public key from the signature
+	pub, err := crypto.Ecrecover(sighash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(pub) == 0 || pub[0] != 4 {
+		return common.Address{}, errInvalidPubkey
+	}
+	return common.BytesToAddress(crypto.Keccak256(pub[1:])[12:]), nil
+}
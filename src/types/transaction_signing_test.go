@@ -0,0 +1,48 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// signedTx is an internal transaction of chain 9000 signed with the test key
+// of the go-ethereum crypto package, whose address 0x970e81... lies in paxos3.
+func signedTx() *Transaction {
+	r, _ := new(big.Int).SetString("1e9ce8a2d57265d0f1d8c07221b8cce2c109a91dc9d27b96a1c5b97a7f55ef4f", 16)
+	s, _ := new(big.Int).SetString("74deaa4ef410d645523da23d047e418cd4109f64104994e9c9403824fcd26ab3", 16)
+	return &Transaction{inner: &InternalTx{
+		ChainID:   big.NewInt(9000),
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       21000,
+		Value:     big.NewInt(1),
+		V:         new(big.Int),
+		R:         r,
+		S:         s,
+	}}
+}
+
+func TestSignerV1Sender(t *testing.T) {
+	paxos3 := common.Location{1, 2}
+	want := common.HexToAddress("0x970e8128ab834e8eac17ab8e3812f010678cf791")
+
+	from, err := NewSigner(big.NewInt(9000), paxos3).Sender(signedTx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !from.Equal(want) {
+		t.Fatalf("sender %v, want %v", from, want)
+	}
+	// The same signature does not recover a sender of another zone
+	if _, err := NewSigner(big.NewInt(9000), common.Location{0, 0}).Sender(signedTx()); !errors.Is(err, ErrSenderNotInZone) {
+		t.Fatalf("sender in cyprus1: %v, want %v", err, ErrSenderNotInZone)
+	}
+	// Nor on another chain
+	if _, err := NewSigner(big.NewInt(1), paxos3).Sender(signedTx()); !errors.Is(err, ErrInvalidChainId) {
+		t.Fatalf("sender on chain 1: %v, want %v", err, ErrInvalidChainId)
+	}
+}
@@ -0,0 +1,318 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/common/hexutil"
+)
+
+// txJSON is the JSON-RPC representation of a transaction, as served by go-quai
+// nodes. The fields of every transaction type are listed, each type uses a
+// subset of them.
+type txJSON struct {
+	Type hexutil.Uint64 `json:"type"`
+
+	// Common fields
+	ChainID    *hexutil.Big    `json:"chainId"`
+	Nonce      *hexutil.Uint64 `json:"nonce"`
+	GasTipCap  *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	GasFeeCap  *hexutil.Big    `json:"maxFeePerGas"`
+	Gas        *hexutil.Uint64 `json:"gas"`
+	To         *common.Address `json:"to"`
+	Value      *hexutil.Big    `json:"value"`
+	Data       *hexutil.Bytes  `json:"input"`
+	AccessList *AccessList     `json:"accessList"`
+
+	// External transaction fields
+	Sender *common.Address `json:"sender,omitempty"`
+
+	// Fields of the ETX emitted by an internal to external transaction
+	ETXGasLimit   *hexutil.Uint64 `json:"etxGasLimit,omitempty"`
+	ETXGasPrice   *hexutil.Big    `json:"etxGasPrice,omitempty"`
+	ETXGasTip     *hexutil.Big    `json:"etxGasTip,omitempty"`
+	ETXData       *hexutil.Bytes  `json:"etxData,omitempty"`
+	ETXAccessList *AccessList     `json:"etxAccessList,omitempty"`
+
+	// Signature values, absent from external transactions
+	V *hexutil.Big `json:"v,omitempty"`
+	R *hexutil.Big `json:"r,omitempty"`
+	S *hexutil.Big `json:"s,omitempty"`
+
+	// Only used for encoding
+	Hash *common.Hash `json:"hash,omitempty"`
+}
+
+// commonTxJSON returns the JSON form of the fields shared by all transaction
+// types.
+func commonTxJSON(typ byte, chainID *big.Int, nonce uint64, tipCap, feeCap *big.Int, gas uint64, to *common.Address, value *big.Int, data []byte, accessList AccessList) *txJSON {
+	var (
+		enc     = &txJSON{Type: hexutil.Uint64(typ)}
+		encNum  = hexutil.Uint64(nonce)
+		encGas  = hexutil.Uint64(gas)
+		encData = hexutil.Bytes(data)
+	)
+	if accessList == nil {
+		accessList = AccessList{}
+	}
+	enc.ChainID = (*hexutil.Big)(chainID)
+	enc.Nonce = &encNum
+	enc.GasTipCap = (*hexutil.Big)(tipCap)
+	enc.GasFeeCap = (*hexutil.Big)(feeCap)
+	enc.Gas = &encGas
+	enc.To = to
+	enc.Value = (*hexutil.Big)(value)
+	enc.Data = &encData
+	enc.AccessList = &accessList
+	return enc
+}
+
+// decodeTxJSON decodes the JSON form of a transaction of type typ, checking the
+// fields shared by all transaction types are present.
+func decodeTxJSON(input []byte, typ byte, name string) (*txJSON, error) {
+	var dec txJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return nil, err
+	}
+	if byte(dec.Type) != typ {
+		return nil, fmt.Errorf("invalid type %d for %s, want %d", dec.Type, name, typ)
+	}
+	required := []struct {
+		field   string
+		missing bool
+	}{
+		{"chainId", dec.ChainID == nil},
+		{"nonce", dec.Nonce == nil},
+		{"maxPriorityFeePerGas", dec.GasTipCap == nil},
+		{"maxFeePerGas", dec.GasFeeCap == nil},
+		{"gas", dec.Gas == nil},
+		{"value", dec.Value == nil},
+		{"input", dec.Data == nil},
+	}
+	for _, field := range required {
+		if field.missing {
+			return nil, fmt.Errorf("missing required field '%s' for %s", field.field, name)
+		}
+	}
+	if dec.AccessList == nil {
+		dec.AccessList = new(AccessList)
+	}
+	return &dec, nil
+}
+
+// decodeSignatureJSON decodes the signature values of a transaction.
+func decodeSignatureJSON(dec *txJSON, name string) (v, r, s *big.Int, err error) {
+	if dec.V == nil {
+		return nil, nil, nil, fmt.Errorf("missing required field 'v' for %s", name)
+	}
+	if dec.R == nil {
+		return nil, nil, nil, fmt.Errorf("missing required field 'r' for %s", name)
+	}
+	if dec.S == nil {
+		return nil, nil, nil, fmt.Errorf("missing required field 's' for %s", name)
+	}
+	return (*big.Int)(dec.V), (*big.Int)(dec.R), (*big.Int)(dec.S), nil
+}
+
+// MarshalJSON marshals the transaction in the go-quai JSON-RPC format.
+func (tx *InternalTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tx.toJSON())
+}
+
+// toJSON returns the JSON-RPC representation of the transaction.
+func (tx *InternalTx) toJSON() *txJSON {
+	enc := commonTxJSON(InternalTxType, tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas, tx.To, tx.Value, tx.Data, tx.AccessList)
+	enc.V, enc.R, enc.S = (*hexutil.Big)(tx.V), (*hexutil.Big)(tx.R), (*hexutil.Big)(tx.S)
+	return enc
+}
+
+// UnmarshalJSON decodes the transaction from the go-quai JSON-RPC format.
+func (tx *InternalTx) UnmarshalJSON(input []byte) error {
+	dec, err := decodeTxJSON(input, InternalTxType, "InternalTx")
+	if err != nil {
+		return err
+	}
+	v, r, s, err := decodeSignatureJSON(dec, "InternalTx")
+	if err != nil {
+		return err
+	}
+	*tx = InternalTx{
+		ChainID:    (*big.Int)(dec.ChainID),
+		Nonce:      uint64(*dec.Nonce),
+		GasTipCap:  (*big.Int)(dec.GasTipCap),
+		GasFeeCap:  (*big.Int)(dec.GasFeeCap),
+		Gas:        uint64(*dec.Gas),
+		To:         dec.To,
+		Value:      (*big.Int)(dec.Value),
+		Data:       *dec.Data,
+		AccessList: *dec.AccessList,
+		V:          v,
+		R:          r,
+		S:          s,
+	}
+	return nil
+}
+
+// MarshalJSON marshals the transaction in the go-quai JSON-RPC format.
+func (tx *ExternalTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tx.toJSON())
+}
+
+// toJSON returns the JSON-RPC representation of the transaction.
+func (tx *ExternalTx) toJSON() *txJSON {
+	enc := commonTxJSON(ExternalTxType, tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas, tx.To, tx.Value, tx.Data, tx.AccessList)
+	sender := tx.Sender
+	enc.Sender = &sender
+	return enc
+}
+
+// UnmarshalJSON decodes the transaction from the go-quai JSON-RPC format.
+func (tx *ExternalTx) UnmarshalJSON(input []byte) error {
+	dec, err := decodeTxJSON(input, ExternalTxType, "ExternalTx")
+	if err != nil {
+		return err
+	}
+	if dec.Sender == nil {
+		return errors.New("missing required field 'sender' for ExternalTx")
+	}
+	*tx = ExternalTx{
+		ChainID:    (*big.Int)(dec.ChainID),
+		Nonce:      uint64(*dec.Nonce),
+		GasTipCap:  (*big.Int)(dec.GasTipCap),
+		GasFeeCap:  (*big.Int)(dec.GasFeeCap),
+		Gas:        uint64(*dec.Gas),
+		To:         dec.To,
+		Value:      (*big.Int)(dec.Value),
+		Data:       *dec.Data,
+		AccessList: *dec.AccessList,
+		Sender:     *dec.Sender,
+	}
+	return nil
+}
+
+// MarshalJSON marshals the transaction in the go-quai JSON-RPC format.
+func (tx *InternalToExternalTx) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tx.toJSON())
+}
+
+// toJSON returns the JSON-RPC representation of the transaction.
+func (tx *InternalToExternalTx) toJSON() *txJSON {
+	enc := commonTxJSON(InternalToExternalTxType, tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.Gas, tx.To, tx.Value, tx.Data, tx.AccessList)
+	var (
+		etxGasLimit   = hexutil.Uint64(tx.ETXGasLimit)
+		etxData       = hexutil.Bytes(tx.ETXData)
+		etxAccessList = tx.ETXAccessList
+	)
+	if etxAccessList == nil {
+		etxAccessList = AccessList{}
+	}
+	enc.ETXGasLimit = &etxGasLimit
+	enc.ETXGasPrice = (*hexutil.Big)(tx.ETXGasPrice)
+	enc.ETXGasTip = (*hexutil.Big)(tx.ETXGasTip)
+	enc.ETXData = &etxData
+	enc.ETXAccessList = &etxAccessList
+	enc.V, enc.R, enc.S = (*hexutil.Big)(tx.V), (*hexutil.Big)(tx.R), (*hexutil.Big)(tx.S)
+	return enc
+}
+
+// UnmarshalJSON decodes the transaction from the go-quai JSON-RPC format.
+func (tx *InternalToExternalTx) UnmarshalJSON(input []byte) error {
+	dec, err := decodeTxJSON(input, InternalToExternalTxType, "InternalToExternalTx")
+	if err != nil {
+		return err
+	}
+	required := []struct {
+		field   string
+		missing bool
+	}{
+		{"etxGasLimit", dec.ETXGasLimit == nil},
+		{"etxGasPrice", dec.ETXGasPrice == nil},
+		{"etxGasTip", dec.ETXGasTip == nil},
+		{"etxData", dec.ETXData == nil},
+	}
+	for _, field := range required {
+		if field.missing {
+			return fmt.Errorf("missing required field '%s' for InternalToExternalTx", field.field)
+		}
+	}
+	if dec.ETXAccessList == nil {
+		dec.ETXAccessList = new(AccessList)
+	}
+	v, r, s, err := decodeSignatureJSON(dec, "InternalToExternalTx")
+	if err != nil {
+		return err
+	}
+	*tx = InternalToExternalTx{
+		ChainID:       (*big.Int)(dec.ChainID),
+		Nonce:         uint64(*dec.Nonce),
+		GasTipCap:     (*big.Int)(dec.GasTipCap),
+		GasFeeCap:     (*big.Int)(dec.GasFeeCap),
+		Gas:           uint64(*dec.Gas),
+		To:            dec.To,
+		Value:         (*big.Int)(dec.Value),
+		Data:          *dec.Data,
+		AccessList:    *dec.AccessList,
+		ETXGasLimit:   uint64(*dec.ETXGasLimit),
+		ETXGasPrice:   (*big.Int)(dec.ETXGasPrice),
+		ETXGasTip:     (*big.Int)(dec.ETXGasTip),
+		ETXData:       *dec.ETXData,
+		ETXAccessList: *dec.ETXAccessList,
+		V:             v,
+		R:             r,
+		S:             s,
+	}
+	return nil
+}
+
+// MarshalJSON marshals the transaction in the go-quai JSON-RPC format of its
+// type, including the transaction hash. Transactions of types registered with
+// RegisterTxType are marshaled without the hash if they implement
+// json.Marshaler, and cannot be marshaled otherwise.
+func (tx *Transaction) MarshalJSON() ([]byte, error) {
+	switch inner := tx.inner.(type) {
+	case interface{ toJSON() *txJSON }:
+		enc := inner.toJSON()
+		hash := tx.Hash()
+		enc.Hash = &hash
+		return json.Marshal(enc)
+	case json.Marshaler:
+		return inner.MarshalJSON()
+	default:
+		return nil, fmt.Errorf("%w: 0x%02x has no JSON encoding", ErrTxTypeNotSupported, tx.Type())
+	}
+}
+
+// UnmarshalJSON decodes a transaction of a built-in type from the go-quai
+// JSON-RPC format, selecting the type by its type field. The hash field, if
+// present, is ignored; it is recomputed from the decoded fields.
+func (tx *Transaction) UnmarshalJSON(input []byte) error {
+	var header struct {
+		Type *hexutil.Uint64 `json:"type"`
+	}
+	if err := json.Unmarshal(input, &header); err != nil {
+		return err
+	}
+	if header.Type == nil {
+		return errors.New("missing required field 'type' for Transaction")
+	}
+	var inner TxData
+	switch *header.Type {
+	case InternalTxType:
+		inner = new(InternalTx)
+	case ExternalTxType:
+		inner = new(ExternalTx)
+	case InternalToExternalTxType:
+		inner = new(InternalToExternalTx)
+	default:
+		return fmt.Errorf("%w: 0x%02x", ErrTxTypeNotSupported, uint64(*header.Type))
+	}
+	if err := json.Unmarshal(input, inner); err != nil {
+		return err
+	}
+	*tx = Transaction{}
+	tx.setDecoded(inner, 0)
+	return nil
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+var (
+	// ErrEtxPlacement is returned if an external transaction follows a
+	// transaction originating in the chain itself.
+	ErrEtxPlacement = errors.New("external transaction after internal transaction")
+
+	// ErrTxNonceOrder is returned if a sender's transactions are not ordered by
+	// increasing nonce.
+	ErrTxNonceOrder = errors.New("transaction nonce out of order")
+
+	// ErrTxNonceGap is returned if a sender's nonces skip a value.
+	ErrTxNonceGap = errors.New("transaction nonce gap")
+)
+
+// ValidateTxOrdering checks the ordering rules of the transactions in a block
+// body:
+//
+//   - external transactions, which are included from the manifests of other
+//     chains, all precede the transactions originating in the chain itself
+//   - the transactions of every sender are consecutive in nonce, without gaps
+//     or repetitions
+//
+// External transactions are authorized by the consensus of their origin chain
+// rather than an account nonce, so the nonce rule only applies to internal and
+// internal to external transactions. The sender of such a transaction is only
+// known once it has been recovered and cached in the transaction; transactions
+// without a cached sender are exempt from the nonce rule.
+func ValidateTxOrdering(txs Transactions) error {
+	var (
+		internal bool
+		nonces   = make(map[common.AddressBytes]uint64)
+	)
+	for i, tx := range txs {
+		if tx.Type() == ExternalTxType {
+			if internal {
+				return fmt.Errorf("%w: tx %d (%s)", ErrEtxPlacement, i, tx.Hash().Hex())
+			}
+			continue
+		}
+		internal = true
+
+		if tx.Type() != InternalTxType && tx.Type() != InternalToExternalTxType {
+			continue
+		}
+		cached, ok := tx.from.Load().(sigCache)
+		if !ok {
+			continue
+		}
+		from := cached.from
+		nonce := tx.inner.nonce()
+		if prev, seen := nonces[from.Bytes20()]; seen {
+			switch {
+			case nonce <= prev:
+				return fmt.Errorf("%w: tx %d (%s) from %s has nonce %d after %d", ErrTxNonceOrder, i, tx.Hash().Hex(), from.Hex(), nonce, prev)
+			case nonce != prev+1:
+				return fmt.Errorf("%w: tx %d (%s) from %s has nonce %d after %d", ErrTxNonceGap, i, tx.Hash().Hex(), from.Hex(), nonce, prev)
+			}
+		}
+		nonces[from.Bytes20()] = nonce
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+	"github.com/dominant-strategies/progpow-verification-wasm/rlp"
+)
+
+var ErrTxTypeRegistered = errors.New("transaction type already registered")
+
+// TxDecoder decodes the payload following the type byte of a typed
+// transaction. Implementations outside this package can satisfy TxData by
+// embedding one of the built-in transaction types.
+type TxDecoder func(payload []byte) (TxData, error)
+
+var (
+	txRegistryLock sync.RWMutex
+	txRegistry     = make(map[byte]TxDecoder)
+
+	// lenientDecoding is set when unknown transaction types are retained as
+	// opaque payloads instead of failing the decode.
+	lenientDecoding atomic.Bool
+)
+
+// RegisterTxType installs a decoder for an additional transaction type, so
+// forks can introduce new types without patching the decoder. The built-in
+// types cannot be overridden.
+func RegisterTxType(typ byte, decode TxDecoder) error {
+	switch typ {
+	case InternalTxType, ExternalTxType, InternalToExternalTxType:
+		return fmt.Errorf("%w: 0x%02x is built in", ErrTxTypeRegistered, typ)
+	}
+	txRegistryLock.Lock()
+	defer txRegistryLock.Unlock()
+
+	if _, ok := txRegistry[typ]; ok {
+		return fmt.Errorf("%w: 0x%02x", ErrTxTypeRegistered, typ)
+	}
+	txRegistry[typ] = decode
+	return nil
+}
+
+// SetStrictDecoding selects how transactions of unknown type are decoded. In
+// strict mode, the default, they fail with ErrTxTypeNotSupported naming the
+// offending type. Otherwise they are kept as opaque transactions carrying
+// their raw payload, which re-encode byte for byte, so blocks of newer forks
+// can still be decoded and their headers verified.
+func SetStrictDecoding(strict bool) {
+	lenientDecoding.Store(!strict)
+}
+
+// decodeExtraTyped decodes a transaction whose type is not built in.
+func decodeExtraTyped(typ byte, payload []byte) (TxData, error) {
+	txRegistryLock.RLock()
+	decode, ok := txRegistry[typ]
+	txRegistryLock.RUnlock()
+
+	if ok {
+		return decode(payload)
+	}
+	if lenientDecoding.Load() {
+		return &OpaqueTx{Type: typ, Payload: common.CopyBytes(payload)}, nil
+	}
+	return nil, fmt.Errorf("%w: 0x%02x", ErrTxTypeNotSupported, typ)
+}
+
+// OpaqueTx is a transaction of an unknown type retained by non-strict
+// decoding. Only its type and raw payload are known, all other accessors
+// return zero values.
+type OpaqueTx struct {
+	Type    byte
+	Payload []byte // RLP encoded transaction body following the type byte
+}
+
+// EncodeRLP implements rlp.Encoder, writing the retained payload verbatim.
+func (tx *OpaqueTx) EncodeRLP(w io.Writer) error {
+	_, err := w.Write(tx.Payload)
+	return err
+}
+
+// copy creates a deep copy of the transaction data.
+func (tx *OpaqueTx) copy() TxData {
+	return &OpaqueTx{Type: tx.Type, Payload: common.CopyBytes(tx.Payload)}
+}
+
+// accessors for innerTx.
+func (tx *OpaqueTx) txType() byte              { return tx.Type }
+func (tx *OpaqueTx) chainID() *big.Int         { return new(big.Int) }
+func (tx *OpaqueTx) accessList() AccessList    { return nil }
+func (tx *OpaqueTx) data() []byte              { return nil }
+func (tx *OpaqueTx) gas() uint64               { return 0 }
+func (tx *OpaqueTx) gasFeeCap() *big.Int       { return new(big.Int) }
+func (tx *OpaqueTx) gasTipCap() *big.Int       { return new(big.Int) }
+func (tx *OpaqueTx) gasPrice() *big.Int        { return new(big.Int) }
+func (tx *OpaqueTx) value() *big.Int           { return new(big.Int) }
+func (tx *OpaqueTx) nonce() uint64             { return 0 }
+func (tx *OpaqueTx) to() *common.Address       { return nil }
+func (tx *OpaqueTx) etxGasLimit() uint64       { return 0 }
+func (tx *OpaqueTx) etxGasPrice() *big.Int     { return new(big.Int) }
+func (tx *OpaqueTx) etxGasTip() *big.Int       { return new(big.Int) }
+func (tx *OpaqueTx) etxData() []byte           { return nil }
+func (tx *OpaqueTx) etxAccessList() AccessList { return nil }
+
+func (tx *OpaqueTx) rawSignatureValues() (v, r, s *big.Int) {
+	return new(big.Int), new(big.Int), new(big.Int)
+}
+
+func (tx *OpaqueTx) setSignatureValues(chainID, v, r, s *big.Int) {}
+
+var _ rlp.Encoder = (*OpaqueTx)(nil)
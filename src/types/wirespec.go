@@ -0,0 +1,175 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/dominant-strategies/progpow-verification-wasm/common"
+)
+
+// WireField describes one field of an RLP encoded structure.
+type WireField struct {
+	Index int    `json:"index"`         // Position of the field in the RLP list
+	Name  string `json:"name"`          // Go name of the field
+	Type  string `json:"type"`          // Wire type, see WireSpec
+	Tag   string `json:"tag,omitempty"` // RLP struct tag altering the encoding, if any
+}
+
+// WireStruct describes the RLP encoding of a type. Structures are encoded as
+// a list of their fields; typed envelopes carry a type byte selecting one of
+// their variants, followed by the encoding of that variant.
+type WireStruct struct {
+	Name     string          `json:"name"`
+	Fields   []WireField     `json:"fields,omitempty"`
+	Variants map[byte]string `json:"variants,omitempty"`
+	Encoding string          `json:"encoding,omitempty"`
+}
+
+// wireRoots are the top-level structures of the wire format, by their public
+// name. Types encoded through an ext* helper are listed with the helper.
+var wireRoots = []struct {
+	name string
+	typ  reflect.Type
+}{
+	{"Header", reflect.TypeOf(extheader{})},
+	{"Block", reflect.TypeOf(extblock{})},
+	{"Termini", reflect.TypeOf(extTermini{})},
+	{"PendingHeader", reflect.TypeOf(extPendingHeader{})},
+}
+
+// wireTxVariants are the transaction types of the typed envelope.
+var wireTxVariants = map[byte]reflect.Type{
+	InternalTxType:           reflect.TypeOf(InternalTx{}),
+	ExternalTxType:           reflect.TypeOf(ExternalTx{}),
+	InternalToExternalTxType: reflect.TypeOf(InternalToExternalTx{}),
+}
+
+// wireNames maps types with a custom encoding to the name of the structure
+// describing it in the spec.
+var wireNames = map[reflect.Type]string{
+	reflect.TypeOf(Header{}):        "Header",
+	reflect.TypeOf(Termini{}):       "Termini",
+	reflect.TypeOf(PendingHeader{}): "PendingHeader",
+	reflect.TypeOf(Transaction{}):   "Transaction",
+}
+
+// wireScalars maps leaf types to their wire type: bytesN for fixed size byte
+// strings, bytes for variable ones, and uint for big endian integers without
+// leading zeros.
+var wireScalars = map[reflect.Type]string{
+	reflect.TypeOf(common.Hash{}):     "bytes32",
+	reflect.TypeOf(common.Address{}):  "bytes20",
+	reflect.TypeOf(common.Location{}): "bytes",
+	reflect.TypeOf(BlockNonce{}):      "bytes8",
+	reflect.TypeOf(big.Int{}):         "uint",
+	reflect.TypeOf([]byte{}):          "bytes",
+	reflect.TypeOf(uint64(0)):         "uint64",
+	reflect.TypeOf(uint32(0)):         "uint32",
+	reflect.TypeOf(uint8(0)):          "uint8",
+	reflect.TypeOf(false):             "bool",
+	reflect.TypeOf(""):                "string",
+}
+
+// WireSpec walks the RLP encoded structures of the package and returns the
+// order and wire types of their fields, so implementations in other languages
+// can follow encoding changes without reading the Go source. Wire types are
+// either a scalar (uint, uint64, bytes, bytesN, bool, string), a list<T> of
+// another wire type, or the name of a structure described in the spec. The
+// result starts with Header, Block, Termini and PendingHeader, followed by
+// the structures they reference.
+func WireSpec() []WireStruct {
+	var (
+		specs []WireStruct
+		seen  = make(map[string]bool)
+		queue []reflect.Type
+		names = make(map[reflect.Type]string)
+	)
+	// Types referenced by a field are queued and described after the roots
+	describe := func(t reflect.Type) string {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if name, ok := wireScalars[t]; ok {
+			return name
+		}
+		if name, ok := wireNames[t]; ok {
+			return name
+		}
+		return ""
+	}
+	var wireType func(t reflect.Type) string
+	wireType = func(t reflect.Type) string {
+		if name := describe(t); name != "" {
+			return name
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				if t.Kind() == reflect.Array {
+					return fmt.Sprintf("bytes%d", t.Len())
+				}
+				return "bytes"
+			}
+			return "list<" + wireType(t.Elem()) + ">"
+		case reflect.Struct:
+			if _, ok := names[t]; !ok {
+				names[t] = t.Name()
+				queue = append(queue, t)
+			}
+			return t.Name()
+		}
+		return t.String()
+	}
+	fields := func(t reflect.Type) []WireField {
+		var out []WireField
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("rlp") == "-" {
+				continue
+			}
+			out = append(out, WireField{
+				Index: len(out),
+				Name:  f.Name,
+				Type:  wireType(f.Type),
+				Tag:   f.Tag.Get("rlp"),
+			})
+		}
+		return out
+	}
+	for _, root := range wireRoots {
+		seen[root.name] = true
+		specs = append(specs, WireStruct{Name: root.name, Fields: fields(root.typ)})
+	}
+	// Transactions are typed envelopes rather than plain lists
+	tx := WireStruct{
+		Name:     "Transaction",
+		Variants: make(map[byte]string),
+		Encoding: "RLP string holding the type byte followed by the RLP list of the variant",
+	}
+	for typ, variant := range wireTxVariants {
+		tx.Variants[typ] = variant.Name()
+		names[variant] = variant.Name()
+	}
+	specs = append(specs, tx)
+	seen[tx.Name] = true
+	for _, typ := range []byte{InternalTxType, ExternalTxType, InternalToExternalTxType} {
+		variant := wireTxVariants[typ]
+		seen[variant.Name()] = true
+		specs = append(specs, WireStruct{Name: variant.Name(), Fields: fields(variant)})
+	}
+	// Describe the remaining structures referenced along the way
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if seen[t.Name()] {
+			continue
+		}
+		seen[t.Name()] = true
+		specs = append(specs, WireStruct{Name: t.Name(), Fields: fields(t)})
+	}
+	return specs
+}